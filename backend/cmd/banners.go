@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+
+	allergiesqueries "github.com/dksch/pococlinic/internal/features/allergies/queries"
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+)
+
+// allergyBanners shows a patient's allergies, or that none are known or
+// recorded, with the patient's record
+type allergyBanners struct {
+	allergies allergiesqueries.GetAllergiesHandler
+}
+
+// Banners returns the allergy banners of a patient
+func (b allergyBanners) Banners(ctx context.Context, patientID string) ([]domain.Banner, error) {
+	list, err := b.allergies.Handle(ctx, allergiesqueries.GetAllergiesQuery{PatientID: patientID})
+	if err != nil {
+		return nil, err
+	}
+
+	banners := []domain.Banner{}
+	for _, banner := range list.Banners() {
+		banners = append(banners, domain.Banner{Kind: "allergies", Level: banner.Level, Message: banner.Message})
+	}
+	return banners, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	authcommands "github.com/dksch/pococlinic/internal/features/auth/commands"
+	authdomain "github.com/dksch/pococlinic/internal/features/auth/domain"
+	authinfrastructure "github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/config"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrapAdminKeepsCredentialsOutOfLogs(t *testing.T) {
+	ctx := context.Background()
+	users := authinfrastructure.NewMemoryUserRepository()
+	createUser := authcommands.NewCreateUserHandler(users, authdomain.Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32})
+
+	var logs bytes.Buffer
+	logger := &logging.Logger{Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	cfg := config.AuthConfig{
+		BootstrapAdminEmail:           "admin@example.com",
+		BootstrapAdminName:            "Administrator",
+		BootstrapAdminCredentialsFile: filepath.Join(t.TempDir(), "bootstrap-admin.txt"),
+	}
+
+	require.NoError(t, bootstrapAdmin(ctx, cfg, users, createUser, logger))
+
+	info, err := os.Stat(cfg.BootstrapAdminCredentialsFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	credentials, err := os.ReadFile(cfg.BootstrapAdminCredentialsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(credentials), "initial PIN: "+authdomain.InitialPIN)
+
+	// The logs name the file, not what is in it
+	assert.Contains(t, logs.String(), cfg.BootstrapAdminCredentialsFile)
+	for _, line := range strings.Split(strings.TrimSpace(string(credentials)), "\n") {
+		if strings.HasPrefix(line, "email:") || strings.HasSuffix(line, ":") {
+			continue
+		}
+		secret := line[strings.LastIndex(line, " ")+1:]
+		if secret != authdomain.InitialPIN {
+			assert.NotContains(t, logs.String(), secret)
+		}
+	}
+
+	// Later starts leave the administrator and the file alone
+	require.NoError(t, bootstrapAdmin(ctx, cfg, users, createUser, logger))
+	unchanged, err := os.ReadFile(cfg.BootstrapAdminCredentialsFile)
+	require.NoError(t, err)
+	assert.Equal(t, credentials, unchanged)
+}
+
+func TestBootstrapAdminRefusesExistingCredentialsFile(t *testing.T) {
+	ctx := context.Background()
+	users := authinfrastructure.NewMemoryUserRepository()
+	createUser := authcommands.NewCreateUserHandler(users, authdomain.Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32})
+
+	path := filepath.Join(t.TempDir(), "bootstrap-admin.txt")
+	require.NoError(t, os.WriteFile(path, []byte("someone else's"), 0o644))
+	cfg := config.AuthConfig{BootstrapAdminEmail: "admin@example.com", BootstrapAdminCredentialsFile: path}
+
+	assert.Error(t, bootstrapAdmin(ctx, cfg, users, createUser, logging.NewLogger()))
+
+	// No administrator is created whose credentials were never handed over
+	_, err := users.GetByEmail(ctx, "admin@example.com")
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	allergiesqueries "github.com/dksch/pococlinic/internal/features/allergies/queries"
+	authdomain "github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// allergyDirectory reads the active allergens a prescription is checked
+// against from the allergy list
+type allergyDirectory struct {
+	allergies allergiesqueries.GetAllergiesHandler
+}
+
+// ActiveAllergens returns the substances of the patient's active allergies
+// and intolerances
+func (d allergyDirectory) ActiveAllergens(ctx context.Context, patientID string) ([]string, error) {
+	list, err := d.allergies.Handle(ctx, allergiesqueries.GetAllergiesQuery{PatientID: patientID})
+	if err != nil {
+		return nil, err
+	}
+
+	allergens := []string{}
+	for _, allergy := range list.Allergies {
+		if allergy.IsActive() {
+			allergens = append(allergens, allergy.Substance)
+		}
+	}
+	return allergens, nil
+}
+
+// userDirectory names users on records of other features
+type userDirectory struct {
+	users authdomain.UserRepository
+}
+
+// UserName returns the name of a user, or their ID if they cannot be found
+func (d userDirectory) UserName(ctx context.Context, userID string) string {
+	user, err := d.users.GetByID(ctx, userID)
+	if err != nil || user.Name == "" {
+		return userID
+	}
+	return user.Name
+}
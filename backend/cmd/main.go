@@ -6,13 +6,42 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	allergiescommands "github.com/dksch/pococlinic/internal/features/allergies/commands"
+	allergieshandlers "github.com/dksch/pococlinic/internal/features/allergies/handlers"
+	allergiesqueries "github.com/dksch/pococlinic/internal/features/allergies/queries"
+	auditcommands "github.com/dksch/pococlinic/internal/features/audit/commands"
+	audithandlers "github.com/dksch/pococlinic/internal/features/audit/handlers"
+	auditmiddleware "github.com/dksch/pococlinic/internal/features/audit/middleware"
+	auditqueries "github.com/dksch/pococlinic/internal/features/audit/queries"
+	authcommands "github.com/dksch/pococlinic/internal/features/auth/commands"
+	authdomain "github.com/dksch/pococlinic/internal/features/auth/domain"
+	authhandlers "github.com/dksch/pococlinic/internal/features/auth/handlers"
+	authmiddleware "github.com/dksch/pococlinic/internal/features/auth/middleware"
+	authqueries "github.com/dksch/pococlinic/internal/features/auth/queries"
+	encountercommands "github.com/dksch/pococlinic/internal/features/encounters/commands"
+	encounterhandlers "github.com/dksch/pococlinic/internal/features/encounters/handlers"
+	encounterqueries "github.com/dksch/pococlinic/internal/features/encounters/queries"
+	medicationcommands "github.com/dksch/pococlinic/internal/features/medications/commands"
+	medicationhandlers "github.com/dksch/pococlinic/internal/features/medications/handlers"
+	medicationinfrastructure "github.com/dksch/pococlinic/internal/features/medications/infrastructure"
+	medicationqueries "github.com/dksch/pococlinic/internal/features/medications/queries"
 	"github.com/dksch/pococlinic/internal/features/patients/commands"
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
 	"github.com/dksch/pococlinic/internal/features/patients/handlers"
-	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
 	"github.com/dksch/pococlinic/internal/features/patients/queries"
+	problemscommands "github.com/dksch/pococlinic/internal/features/problems/commands"
+	problemsdomain "github.com/dksch/pococlinic/internal/features/problems/domain"
+	problemshandlers "github.com/dksch/pococlinic/internal/features/problems/handlers"
+	problemsinfrastructure "github.com/dksch/pococlinic/internal/features/problems/infrastructure"
+	problemsqueries "github.com/dksch/pococlinic/internal/features/problems/queries"
+	vitalscommands "github.com/dksch/pococlinic/internal/features/vitals/commands"
+	vitalshandlers "github.com/dksch/pococlinic/internal/features/vitals/handlers"
+	vitalsqueries "github.com/dksch/pococlinic/internal/features/vitals/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
 	"github.com/dksch/pococlinic/internal/pkg/config"
 	"github.com/dksch/pococlinic/internal/pkg/logging"
 	"github.com/dksch/pococlinic/internal/pkg/middleware"
@@ -39,13 +68,202 @@ func main() {
 	)
 	rateLimiter.CleanupTask() // Start cleanup task
 
-	// Initialize repositories and handlers
-	patientRepo := infrastructure.NewMemoryRepository()
+	// Open the configured storage
+	store, err := openStorage(context.Background(), cfg.Database)
+	if err != nil {
+		logger.Error("Failed to open storage", err, "driver", cfg.Database.Driver)
+		os.Exit(1)
+	}
+
+	// Initialize auth repositories and handlers
+	tokenConfig := authdomain.TokenConfig{
+		AccessTokenSecret:  []byte(cfg.Auth.AccessTokenSecret),
+		RefreshTokenSecret: []byte(cfg.Auth.RefreshTokenSecret),
+		AccessTokenTTL:     cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL:    cfg.Auth.RefreshTokenTTL,
+		Issuer:             cfg.Auth.Issuer,
+	}
+	hashPolicy := authdomain.Argon2Params{
+		Time:      cfg.Auth.Hashing.Time,
+		Memory:    cfg.Auth.Hashing.MemoryKiB,
+		Threads:   cfg.Auth.Hashing.Threads,
+		KeyLength: authdomain.DefaultArgon2Params.KeyLength,
+	}
+	lockoutPolicy := authdomain.LockoutPolicy{
+		MaxAttempts: cfg.Auth.Lockout.MaxAttempts,
+		Duration:    cfg.Auth.Lockout.Duration,
+	}
+	permissions, err := authdomain.NewPermissionMatrix(cfg.Auth.Permissions)
+	if err != nil {
+		logger.Error("Invalid permission matrix", err)
+		os.Exit(1)
+	}
+	emergencyPolicy := authdomain.EmergencyAccessPolicy{TTL: cfg.Auth.EmergencyAccess.TTL}
+	for _, permission := range cfg.Auth.EmergencyAccess.Permissions {
+		emergencyPolicy.Permissions = append(emergencyPolicy.Permissions, authdomain.Permission(permission))
+	}
+	userRepo := store.users
+	sessionRepo := store.sessions
+	emergencyRepo := store.emergency
+	createUserHandler := authcommands.NewCreateUserHandler(userRepo, hashPolicy)
+	loginHandler := authcommands.NewLoginHandler(userRepo, sessionRepo, tokenConfig, hashPolicy, lockoutPolicy)
+	refreshHandler := authcommands.NewRefreshHandler(sessionRepo, userRepo, tokenConfig)
+	revokeSessionHandler := authcommands.NewRevokeSessionHandler(sessionRepo)
+	revokeUserSessionsHandler := authcommands.NewRevokeUserSessionsHandler(userRepo, sessionRepo)
+	changePINHandler := authcommands.NewChangePINHandler(userRepo, hashPolicy, lockoutPolicy)
+	resetKeyHandler := authcommands.NewResetKeyHandler(userRepo, sessionRepo, hashPolicy)
+	recoverKeyHandler := authcommands.NewRecoverKeyHandler(userRepo, sessionRepo, hashPolicy, lockoutPolicy)
+	unlockUserHandler := authcommands.NewUnlockUserHandler(userRepo)
+	changeRoleHandler := authcommands.NewChangeRoleHandler(userRepo, sessionRepo)
+	setUserDisabledHandler := authcommands.NewSetUserDisabledHandler(userRepo, sessionRepo)
+	deleteUserHandler := authcommands.NewDeleteUserHandler(userRepo, sessionRepo)
+	requestEmergencyHandler := authcommands.NewRequestEmergencyAccessHandler(userRepo, sessionRepo, emergencyRepo, tokenConfig, emergencyPolicy)
+	reviewEmergencyHandler := authcommands.NewReviewEmergencyAccessHandler(emergencyRepo)
+	getUserHandler := authqueries.NewGetUserHandler(userRepo)
+	listSessionsHandler := authqueries.NewListSessionsHandler(sessionRepo)
+	listUsersHandler := authqueries.NewListUsersHandler(userRepo)
+	getPermissionsHandler := authqueries.NewGetPermissionsHandler(permissions)
+	listEmergencyHandler := authqueries.NewListEmergencyAccessHandler(emergencyRepo)
+	authHandler := authhandlers.NewAuthHandler(
+		createUserHandler,
+		loginHandler,
+		refreshHandler,
+		revokeSessionHandler,
+		revokeUserSessionsHandler,
+		changePINHandler,
+		resetKeyHandler,
+		recoverKeyHandler,
+		unlockUserHandler,
+		changeRoleHandler,
+		setUserDisabledHandler,
+		deleteUserHandler,
+		requestEmergencyHandler,
+		reviewEmergencyHandler,
+		getUserHandler,
+		listSessionsHandler,
+		listUsersHandler,
+		getPermissionsHandler,
+		listEmergencyHandler,
+		tokenConfig.RefreshTokenTTL,
+	)
+	authMiddleware := authmiddleware.NewAuthMiddleware(tokenConfig, sessionRepo, permissions, emergencyPolicy, emergencyRepo)
+
+	if err := bootstrapAdmin(context.Background(), cfg.Auth, userRepo, createUserHandler, logger); err != nil {
+		logger.Error("Failed to create bootstrap administrator", err)
+		os.Exit(1)
+	}
+
+	// Initialize the audit log of patient data access
+	auditRepo := store.audit
+	auditRecorder := auditmiddleware.NewRecorder(auditcommands.NewRecordEntryHandler(auditRepo), logger)
+	auditHandler := audithandlers.NewAuditHandler(
+		auditqueries.NewListEntriesHandler(auditRepo),
+		auditqueries.NewVerifyChainHandler(auditRepo),
+		logger,
+	)
+
+	// Initialize patient repositories and handlers
+	patientRepo := store.patients
 	createPatientHandler := commands.NewCreatePatientHandler(patientRepo)
 	getPatientsHandler := queries.NewGetPatientsHandler(patientRepo)
 	getPatientHandler := queries.NewGetPatientHandler(patientRepo)
 	updatePatientHandler := commands.NewUpdatePatientHandler(patientRepo)
-	patientHandler := handlers.NewPatientHandler(createPatientHandler, getPatientsHandler, getPatientHandler, updatePatientHandler, logger)
+	// The records other features keep of each patient go with it when it is
+	// purged or merged
+	records := []domain.PatientRecords{store.encounters, store.vitals, store.allergies, store.medications, store.problems}
+	patientHandler := handlers.NewPatientHandler(
+		createPatientHandler,
+		getPatientsHandler,
+		getPatientHandler,
+		updatePatientHandler,
+		commands.NewPatchPatientHandler(patientRepo),
+		commands.NewArchivePatientHandler(patientRepo),
+		commands.NewRestorePatientHandler(patientRepo),
+		commands.NewPurgePatientHandler(patientRepo, domain.RetentionPolicy{
+			Years:         cfg.Patients.RetentionYears,
+			AgeOfMajority: cfg.Patients.AgeOfMajority,
+		}, records...),
+		commands.NewMergePatientsHandler(patientRepo, records...),
+		queries.NewListRevisionsHandler(patientRepo),
+		queries.NewDiffRevisionsHandler(patientRepo),
+		queries.NewGetRevisionAtHandler(patientRepo),
+		logger,
+	)
+
+	// Initialize encounter handlers, they only accept active patients
+	encounterRepo := store.encounters
+	encounterHandler := encounterhandlers.NewEncounterHandler(
+		encountercommands.NewCreateEncounterHandler(encounterRepo, patientDirectory{patients: patientRepo}),
+		encountercommands.NewUpdateEncounterHandler(encounterRepo),
+		encountercommands.NewCloseEncounterHandler(encounterRepo),
+		encountercommands.NewAddAddendumHandler(encounterRepo),
+		encounterqueries.NewListEncountersHandler(encounterRepo),
+		encounterqueries.NewGetEncounterHandler(encounterRepo),
+		logger,
+	)
+
+	// Initialize vital sign handlers
+	vitalsRepo := store.vitals
+	vitalsHandler := vitalshandlers.NewVitalsHandler(
+		vitalscommands.NewRecordVitalsHandler(vitalsRepo, patientDirectory{patients: patientRepo}),
+		vitalsqueries.NewGetVitalsHistoryHandler(vitalsRepo),
+		logger,
+	)
+
+	// Initialize allergy handlers, their banners are shown with the patient
+	// to those who may read allergies
+	allergyRepo := store.allergies
+	getAllergiesHandler := allergiesqueries.NewGetAllergiesHandler(allergyRepo)
+	allergyHandler := allergieshandlers.NewAllergyHandler(
+		allergiescommands.NewRecordAllergyHandler(allergyRepo, patientDirectory{patients: patientRepo}),
+		allergiescommands.NewUpdateAllergyHandler(allergyRepo),
+		allergiescommands.NewAssertNoKnownAllergiesHandler(allergyRepo, patientDirectory{patients: patientRepo}),
+		allergiescommands.NewWithdrawNoKnownAllergiesHandler(allergyRepo),
+		getAllergiesHandler,
+		logger,
+	)
+	patientHandler.AddBannerSource(allergyBanners{allergies: getAllergiesHandler}, holdsPermission(authMiddleware, allergieshandlers.AccessReadAllergies))
+
+	// Initialize medication handlers, prescriptions are checked for
+	// interactions against the local rules file
+	if cfg.Medications.InteractionRulesFile == "" {
+		logger.Warn("No interaction rules file configured, prescriptions are only checked against allergies to the drug itself")
+	}
+	interactionChecker, err := medicationinfrastructure.LoadRulesChecker(cfg.Medications.InteractionRulesFile)
+	if err != nil {
+		logger.Error("Failed to load interaction rules", err)
+		os.Exit(1)
+	}
+	medicationRepo := store.medications
+	medicationHandler := medicationhandlers.NewMedicationHandler(
+		medicationcommands.NewPrescribeMedicationHandler(
+			medicationRepo,
+			patientDirectory{patients: patientRepo},
+			allergyDirectory{allergies: getAllergiesHandler},
+			interactionChecker,
+		),
+		medicationcommands.NewStopMedicationHandler(medicationRepo),
+		medicationqueries.NewGetMedicationsHandler(medicationRepo),
+		medicationqueries.NewGetPrescriptionHandler(medicationRepo, patientDirectory{patients: patientRepo}, userDirectory{users: userRepo}),
+		logger,
+	)
+
+	// Initialize problem list handlers, problems are coded with the ICD-10
+	// code set imported from a local file
+	problemRepo := store.problems
+	codeIndex := problemsinfrastructure.NewCodeIndex()
+	importCodesHandler := problemscommands.NewImportCodesHandler(problemRepo, codeIndex)
+	if err := loadCodeSet(context.Background(), cfg.Problems.ICD10CodesFile, problemRepo, codeIndex, importCodesHandler, logger); err != nil {
+		logger.Error("Failed to load ICD-10 code set", err, "file", cfg.Problems.ICD10CodesFile)
+		os.Exit(1)
+	}
+	problemHandler := problemshandlers.NewProblemHandler(
+		problemscommands.NewRecordProblemHandler(problemRepo, patientDirectory{patients: patientRepo}, codeIndex),
+		problemscommands.NewUpdateProblemHandler(problemRepo, codeIndex),
+		problemsqueries.NewGetProblemsHandler(problemRepo),
+		problemsqueries.NewSearchCodesHandler(codeIndex),
+		logger,
+	)
 
 	// Initialize router with security middleware
 	router := gin.New() // Don't use Default() as we'll add our own middleware
@@ -60,7 +278,7 @@ func main() {
 	router.Use(cors.New(corsConfig))
 
 	// Initialize routes
-	initializeRoutes(router, patientHandler)
+	initializeRoutes(router, authHandler, authMiddleware, auditHandler, auditRecorder, patientHandler, encounterHandler, vitalsHandler, allergyHandler, medicationHandler, problemHandler)
 
 	// Configure server
 	srv := &http.Server{
@@ -98,10 +316,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := store.close(); err != nil {
+		logger.Error("Failed to close storage", err)
+	}
+
 	logger.Info("Server exited gracefully")
 }
 
-func initializeRoutes(router *gin.Engine, patientHandler *handlers.PatientHandler) {
+func initializeRoutes(
+	router *gin.Engine,
+	authHandler *authhandlers.AuthHandler,
+	authMiddleware *authmiddleware.AuthMiddleware,
+	auditHandler *audithandlers.AuditHandler,
+	auditRecorder *auditmiddleware.Recorder,
+	patientHandler *handlers.PatientHandler,
+	encounterHandler *encounterhandlers.EncounterHandler,
+	vitalsHandler *vitalshandlers.VitalsHandler,
+	allergyHandler *allergieshandlers.AllergyHandler,
+	medicationHandler *medicationhandlers.MedicationHandler,
+	problemHandler *problemshandlers.ProblemHandler,
+) {
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "healthy",
@@ -109,6 +343,137 @@ func initializeRoutes(router *gin.Engine, patientHandler *handlers.PatientHandle
 		})
 	})
 
-	v1 := router.Group("/api/v1")
-	patientHandler.RegisterRoutes(v1)
+	authHandler.RegisterRoutes(router, authMiddleware)
+
+	v1 := router.Group("/api/v1", authMiddleware.RequireAuth())
+	auditHandler.RegisterRoutes(v1, requirePermission[audithandlers.Access](authMiddleware))
+	problemHandler.RegisterCodeRoutes(v1, requirePermission[problemshandlers.Access](authMiddleware))
+
+	// Every request for patient data is recorded, including denied ones
+	phi := v1.Group("", auditRecorder.Record())
+	patientHandler.RegisterRoutes(phi, requirePermission[handlers.Access](authMiddleware))
+	encounterHandler.RegisterRoutes(phi, requirePermission[encounterhandlers.Access](authMiddleware))
+	vitalsHandler.RegisterRoutes(phi, requirePermission[vitalshandlers.Access](authMiddleware))
+	allergyHandler.RegisterRoutes(phi, requirePermission[allergieshandlers.Access](authMiddleware))
+	medicationHandler.RegisterRoutes(phi, requirePermission[medicationhandlers.Access](authMiddleware))
+	problemHandler.RegisterRoutes(phi, requirePermission[problemshandlers.Access](authMiddleware))
+}
+
+// requirePermission guards the routes of a feature with the permission named
+// by the access level each route requires
+func requirePermission[A ~string](authMiddleware *authmiddleware.AuthMiddleware) authz.Guard[A] {
+	return func(access A) gin.HandlerFunc {
+		return authMiddleware.RequirePermission(authdomain.Permission(access))
+	}
+}
+
+// holdsPermission reports whether the caller of a request holds the
+// feature's access, for data a response leaves out rather than rejecting
+func holdsPermission[A ~string](authMiddleware *authmiddleware.AuthMiddleware, access A) func(c *gin.Context) bool {
+	return func(c *gin.Context) bool {
+		return authMiddleware.Allows(c, authdomain.Permission(access))
+	}
+}
+
+// bootstrapAdmin creates the initial administrator on first start so that
+// further users can be registered. The generated key, recovery codes and
+// initial PIN are written exactly once to a new file that only the server's
+// user can read, never to the logs; the initial PIN must be changed on
+// first login.
+func bootstrapAdmin(
+	ctx context.Context,
+	cfg config.AuthConfig,
+	users authdomain.ValidateUserRepository,
+	createUser authcommands.CreateUserHandler,
+	logger *logging.Logger,
+) error {
+	if cfg.BootstrapAdminEmail == "" {
+		return nil
+	}
+	if _, err := users.GetByEmail(ctx, cfg.BootstrapAdminEmail); err == nil {
+		return nil
+	}
+
+	// Claim the file first, so that no administrator is created whose
+	// credentials cannot be handed over
+	path := cfg.BootstrapAdminCredentialsFile
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap credentials file: %w", err)
+	}
+	defer file.Close()
+
+	result, err := createUser.Handle(ctx, authcommands.CreateUserCommand{
+		Email: cfg.BootstrapAdminEmail,
+		Name:  cfg.BootstrapAdminName,
+		Role:  authdomain.RoleAdmin,
+	})
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "email: %s\nkey: %s\ninitial PIN: %s\nrecovery codes:\n%s\n",
+		result.User.Email,
+		result.Key,
+		authdomain.InitialPIN,
+		strings.Join(result.RecoveryCodes, "\n"),
+	)
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write bootstrap credentials file: %w", err)
+	}
+
+	logger.Warn("Created bootstrap administrator, store the credentials written to the file securely and delete it",
+		"email", result.User.Email,
+		"file", path,
+	)
+	return nil
+}
+
+// loadCodeSet fills the catalog with the stored ICD-10 code set and then
+// imports the code file, unless it is the one imported last. Without a
+// code file problems can only be coded once a code set has been imported.
+func loadCodeSet(
+	ctx context.Context,
+	path string,
+	codes problemsdomain.CodeRepository,
+	catalog problemsdomain.CodeCatalog,
+	importCodes problemscommands.ImportCodesHandler,
+	logger *logging.Logger,
+) error {
+	stored, err := codes.ListCodes(ctx)
+	if err != nil {
+		return err
+	}
+	catalog.Replace(stored)
+
+	if path == "" {
+		if catalog.Len() == 0 {
+			logger.Warn("No ICD-10 code set imported, set ICD10_CODES_FILE to code problems")
+		}
+		return nil
+	}
+
+	file, err := problemsinfrastructure.ReadCodeFile(path)
+	if err != nil {
+		return err
+	}
+	result, err := importCodes.Handle(ctx, problemscommands.ImportCodesCommand{
+		Source:   path,
+		Checksum: file.Checksum,
+		Codes:    file.Codes,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Unchanged {
+		logger.Info("ICD-10 code set is up to date", "file", result.Source, "codes", result.Codes)
+		return nil
+	}
+	logger.Info("Imported ICD-10 code set", "file", result.Source, "codes", result.Codes)
+	return nil
 }
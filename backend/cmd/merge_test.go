@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	allergiescommands "github.com/dksch/pococlinic/internal/features/allergies/commands"
+	allergiesdomain "github.com/dksch/pococlinic/internal/features/allergies/domain"
+	allergiesinfrastructure "github.com/dksch/pococlinic/internal/features/allergies/infrastructure"
+	allergiesqueries "github.com/dksch/pococlinic/internal/features/allergies/queries"
+	medicationcommands "github.com/dksch/pococlinic/internal/features/medications/commands"
+	medicationdomain "github.com/dksch/pococlinic/internal/features/medications/domain"
+	medicationinfrastructure "github.com/dksch/pococlinic/internal/features/medications/infrastructure"
+	"github.com/dksch/pococlinic/internal/features/patients/commands"
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeKeepsAllergiesOfDuplicate(t *testing.T) {
+	ctx := context.Background()
+	patientRepo := infrastructure.NewMemoryRepository()
+	allergyRepo := allergiesinfrastructure.NewMemoryRepository()
+
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	target := domain.NewPatient("Jane", "Doe", dob, domain.GenderFemale)
+	source := domain.NewPatient("Jane", "Doe", dob, domain.GenderFemale)
+	require.NoError(t, patientRepo.Create(ctx, target))
+	require.NoError(t, patientRepo.Create(ctx, source))
+
+	// The allergy is only known to the duplicate
+	_, err := allergiescommands.NewRecordAllergyHandler(allergyRepo, patientDirectory{patients: patientRepo}).Handle(ctx, allergiescommands.RecordAllergyCommand{
+		PatientID: source.ID.String(),
+		Details: allergiesdomain.Details{
+			Substance: "Penicillin",
+			Category:  allergiesdomain.CategoryAllergy,
+			Reaction:  "Anaphylaxis",
+			Severity:  allergiesdomain.SeveritySevere,
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = commands.NewMergePatientsHandler(patientRepo, allergyRepo).Handle(ctx, commands.MergePatientsCommand{
+		SourceID: source.ID.String(),
+		TargetID: target.ID.String(),
+		AuthorID: "admin-1",
+	})
+	require.NoError(t, err)
+
+	getAllergies := allergiesqueries.NewGetAllergiesHandler(allergyRepo)
+	banners, err := allergyBanners{allergies: getAllergies}.Banners(ctx, target.ID.String())
+	require.NoError(t, err)
+	require.Len(t, banners, 1)
+	assert.Equal(t, "critical", banners[0].Level)
+	assert.Equal(t, "Penicillin allergy: Anaphylaxis (severe)", banners[0].Message)
+
+	// Prescribing the allergen to the kept patient is caught
+	checker, err := medicationinfrastructure.LoadRulesChecker("")
+	require.NoError(t, err)
+	prescribe := medicationcommands.NewPrescribeMedicationHandler(
+		medicationinfrastructure.NewMemoryRepository(),
+		patientDirectory{patients: patientRepo},
+		allergyDirectory{allergies: getAllergies},
+		checker,
+	)
+	_, err = prescribe.Handle(ctx, medicationcommands.PrescribeMedicationCommand{
+		PatientID: target.ID.String(),
+		Prescription: medicationdomain.Prescription{
+			Drug:      "Penicillin",
+			Dose:      "500 mg",
+			Route:     medicationdomain.RouteOral,
+			Frequency: "every 6 hours",
+		},
+	})
+	var warning *medicationcommands.InteractionWarningError
+	require.ErrorAs(t, err, &warning)
+	require.Len(t, warning.Interactions, 1)
+	assert.Equal(t, medicationdomain.InteractionAllergy, warning.Interactions[0].Kind)
+	assert.Equal(t, "Penicillin", warning.Interactions[0].With)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	allergiesdomain "github.com/dksch/pococlinic/internal/features/allergies/domain"
+	allergiesinfrastructure "github.com/dksch/pococlinic/internal/features/allergies/infrastructure"
+	auditdomain "github.com/dksch/pococlinic/internal/features/audit/domain"
+	auditinfrastructure "github.com/dksch/pococlinic/internal/features/audit/infrastructure"
+	authdomain "github.com/dksch/pococlinic/internal/features/auth/domain"
+	authinfrastructure "github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	encountersdomain "github.com/dksch/pococlinic/internal/features/encounters/domain"
+	encountersinfrastructure "github.com/dksch/pococlinic/internal/features/encounters/infrastructure"
+	medicationsdomain "github.com/dksch/pococlinic/internal/features/medications/domain"
+	medicationsinfrastructure "github.com/dksch/pococlinic/internal/features/medications/infrastructure"
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
+	problemsdomain "github.com/dksch/pococlinic/internal/features/problems/domain"
+	problemsinfrastructure "github.com/dksch/pococlinic/internal/features/problems/infrastructure"
+	vitalsdomain "github.com/dksch/pococlinic/internal/features/vitals/domain"
+	vitalsinfrastructure "github.com/dksch/pococlinic/internal/features/vitals/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/config"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+)
+
+// patientStore is the full set of patient persistence operations the handlers need
+type patientStore interface {
+	domain.PatientRepository
+	domain.CreatePatientRepository
+	domain.GetPatientsRepository
+	domain.GetPatientRepository
+	domain.RevisionRepository
+	domain.MergePatientsRepository
+	domain.PurgePatientRepository
+}
+
+// problemStore holds both the problem lists and the ICD-10 code set
+type problemStore interface {
+	problemsdomain.Repository
+	problemsdomain.CodeRepository
+}
+
+// patientDirectory answers the patient lookups of the clinical features from
+// the patient repository
+type patientDirectory struct {
+	patients domain.PatientRepository
+}
+
+// IsActivePatient reports whether the patient exists and is not archived
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	patient, err := d.patients.GetByID(ctx, patientID)
+	if errors.Is(err, domain.ErrPatientNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !patient.IsArchived(), nil
+}
+
+// PatientSummary names a patient on a prescription, or returns nil if the
+// patient does not exist
+func (d patientDirectory) PatientSummary(ctx context.Context, patientID string) (*medicationsdomain.PatientSummary, error) {
+	patient, err := d.patients.GetByID(ctx, patientID)
+	if errors.Is(err, domain.ErrPatientNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &medicationsdomain.PatientSummary{
+		Name:        patient.FullName(),
+		DateOfBirth: time.Time(patient.DateOfBirth),
+	}, nil
+}
+
+// storage bundles the repositories selected by configuration
+type storage struct {
+	users       authdomain.UserRepository
+	sessions    authdomain.SessionRepository
+	emergency   authdomain.EmergencyAccessRepository
+	audit       auditdomain.Repository
+	patients    patientStore
+	encounters  encountersdomain.Repository
+	vitals      vitalsdomain.Repository
+	allergies   allergiesdomain.Repository
+	medications medicationsdomain.Repository
+	problems    problemStore
+	close       func() error
+}
+
+// openStorage creates the repositories for the configured driver. SQLite
+// databases are migrated to the latest schema before use.
+func openStorage(ctx context.Context, cfg config.DatabaseConfig) (*storage, error) {
+	if cfg.Driver == config.DriverMemory {
+		return &storage{
+			users:       authinfrastructure.NewMemoryUserRepository(),
+			sessions:    authinfrastructure.NewMemorySessionRepository(),
+			emergency:   authinfrastructure.NewMemoryEmergencyAccessRepository(),
+			audit:       auditinfrastructure.NewMemoryRepository(),
+			patients:    infrastructure.NewMemoryRepository(),
+			encounters:  encountersinfrastructure.NewMemoryRepository(),
+			vitals:      vitalsinfrastructure.NewMemoryRepository(),
+			allergies:   allergiesinfrastructure.NewMemoryRepository(),
+			medications: medicationsinfrastructure.NewMemoryRepository(),
+			problems:    problemsinfrastructure.NewMemoryRepository(),
+			close:       func() error { return nil },
+		}, nil
+	}
+
+	db, err := database.OpenSQLite(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []func(context.Context, *sql.DB) error{
+		authinfrastructure.MigrateSQLite,
+		auditinfrastructure.MigrateSQLite,
+		infrastructure.MigrateSQLite,
+		encountersinfrastructure.MigrateSQLite,
+		vitalsinfrastructure.MigrateSQLite,
+		allergiesinfrastructure.MigrateSQLite,
+		medicationsinfrastructure.MigrateSQLite,
+		problemsinfrastructure.MigrateSQLite,
+	}
+	for _, migrate := range migrations {
+		if err := migrate(ctx, db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return &storage{
+		users:       authinfrastructure.NewSQLiteUserRepository(db),
+		sessions:    authinfrastructure.NewSQLiteSessionRepository(db),
+		emergency:   authinfrastructure.NewSQLiteEmergencyAccessRepository(db),
+		audit:       auditinfrastructure.NewSQLiteRepository(db),
+		patients:    infrastructure.NewSQLiteRepository(db),
+		encounters:  encountersinfrastructure.NewSQLiteRepository(db),
+		vitals:      vitalsinfrastructure.NewSQLiteRepository(db),
+		allergies:   allergiesinfrastructure.NewSQLiteRepository(db),
+		medications: medicationsinfrastructure.NewSQLiteRepository(db),
+		problems:    problemsinfrastructure.NewSQLiteRepository(db),
+		close:       db.Close,
+	}, nil
+}
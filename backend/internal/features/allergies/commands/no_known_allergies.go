@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// AssertNoKnownAllergiesCommand represents the command to state that a
+// patient has no known allergies
+type AssertNoKnownAllergiesCommand struct {
+	PatientID string
+	UserID    string
+}
+
+// AssertNoKnownAllergiesHandler handles the assert no known allergies command
+type AssertNoKnownAllergiesHandler interface {
+	Handle(ctx context.Context, cmd AssertNoKnownAllergiesCommand) (*domain.NoKnownAllergies, error)
+}
+
+type assertNoKnownAllergiesHandler struct {
+	repo     domain.AssertNoKnownAllergiesRepository
+	patients patientref.Directory
+}
+
+// NewAssertNoKnownAllergiesHandler creates a new assert no known allergies handler
+func NewAssertNoKnownAllergiesHandler(repo domain.AssertNoKnownAllergiesRepository, patients patientref.Directory) AssertNoKnownAllergiesHandler {
+	return &assertNoKnownAllergiesHandler{repo: repo, patients: patients}
+}
+
+// Handle processes the assert no known allergies command. It is rejected
+// while the patient has active allergies; asserting again renews the
+// assertion.
+func (h *assertNoKnownAllergiesHandler) Handle(ctx context.Context, cmd AssertNoKnownAllergiesCommand) (*domain.NoKnownAllergies, error) {
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	allergies, err := h.repo.ListByPatient(ctx, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := domain.NewAllergyList(allergies, nil).AssertNoKnownAllergies(patientID, cmd.UserID)
+	if err == domain.ErrHasActiveAllergies {
+		return nil, errors.NewAPIError(errors.ErrConflict, "Patient has active allergies, resolve them first")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.repo.SaveNoKnownAllergies(ctx, assertion); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// WithdrawNoKnownAllergiesCommand represents the command to withdraw the
+// assertion that a patient has no known allergies, which leaves the
+// allergies not recorded
+type WithdrawNoKnownAllergiesCommand struct {
+	PatientID string
+}
+
+// WithdrawNoKnownAllergiesHandler handles the withdraw no known allergies command
+type WithdrawNoKnownAllergiesHandler interface {
+	Handle(ctx context.Context, cmd WithdrawNoKnownAllergiesCommand) error
+}
+
+type withdrawNoKnownAllergiesHandler struct {
+	repo domain.AssertNoKnownAllergiesRepository
+}
+
+// NewWithdrawNoKnownAllergiesHandler creates a new withdraw no known allergies handler
+func NewWithdrawNoKnownAllergiesHandler(repo domain.AssertNoKnownAllergiesRepository) WithdrawNoKnownAllergiesHandler {
+	return &withdrawNoKnownAllergiesHandler{repo: repo}
+}
+
+// Handle processes the withdraw no known allergies command
+func (h *withdrawNoKnownAllergiesHandler) Handle(ctx context.Context, cmd WithdrawNoKnownAllergiesCommand) error {
+	return h.repo.DeleteNoKnownAllergies(ctx, cmd.PatientID)
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/features/allergies/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of active patients
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestNoKnownAllergiesAndAllergies(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patientID := uuid.NewString()
+	patients := patientDirectory{patientID: true}
+
+	assertNone := NewAssertNoKnownAllergiesHandler(repo, patients)
+	record := NewRecordAllergyHandler(repo, patients)
+	update := NewUpdateAllergyHandler(repo)
+
+	_, err := assertNone.Handle(ctx, AssertNoKnownAllergiesCommand{PatientID: uuid.NewString(), UserID: "dr-1"})
+	assertAPIError(t, err, errors.ErrNotFound)
+
+	assertion, err := assertNone.Handle(ctx, AssertNoKnownAllergiesCommand{PatientID: patientID, UserID: "dr-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "dr-1", assertion.AssertedBy)
+
+	_, err = record.Handle(ctx, RecordAllergyCommand{PatientID: patientID, Details: domain.Details{Substance: "Penicillin", Severity: "fatal"}})
+	assertAPIError(t, err, errors.ErrValidation)
+	_, err = record.Handle(ctx, RecordAllergyCommand{PatientID: patientID, Details: domain.Details{Substance: "Penicillin", Severity: domain.SeverityMild, Onset: "2999"}})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	allergy, err := record.Handle(ctx, RecordAllergyCommand{
+		PatientID:  patientID,
+		RecordedBy: "nurse-1",
+		Details:    domain.Details{Substance: "Penicillin", Reaction: "Rash", Severity: domain.SeverityModerate, Onset: "2019-05"},
+	})
+	require.NoError(t, err)
+
+	stored, err := repo.GetNoKnownAllergies(ctx, patientID)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "Recording an active allergy should withdraw the assertion")
+
+	_, err = assertNone.Handle(ctx, AssertNoKnownAllergiesCommand{PatientID: patientID, UserID: "dr-1"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	_, err = update.Handle(ctx, UpdateAllergyCommand{
+		PatientID: uuid.NewString(),
+		AllergyID: allergy.ID.String(),
+		Details:   domain.Details{Substance: "Penicillin", Severity: domain.SeverityModerate},
+	})
+	assertAPIError(t, err, errors.ErrNotFound)
+
+	updated, err := update.Handle(ctx, UpdateAllergyCommand{
+		PatientID: patientID,
+		AllergyID: allergy.ID.String(),
+		UpdatedBy: "dr-1",
+		Details:   domain.Details{Substance: "Penicillin", Reaction: "Rash", Severity: domain.SeverityModerate, Status: domain.StatusEnteredInError},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusEnteredInError, updated.Status)
+
+	_, err = update.Handle(ctx, UpdateAllergyCommand{
+		PatientID: patientID,
+		AllergyID: allergy.ID.String(),
+		Details:   domain.Details{Substance: "Penicillin", Severity: domain.SeverityModerate},
+	})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	_, err = assertNone.Handle(ctx, AssertNoKnownAllergiesCommand{PatientID: patientID, UserID: "dr-1"})
+	require.NoError(t, err)
+
+	require.NoError(t, NewWithdrawNoKnownAllergiesHandler(repo).Handle(ctx, WithdrawNoKnownAllergiesCommand{PatientID: patientID}))
+	stored, err = repo.GetNoKnownAllergies(ctx, patientID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// RecordAllergyCommand represents the command to record an allergy of a
+// patient. Recording an active allergy withdraws an assertion of no known
+// allergies.
+type RecordAllergyCommand struct {
+	PatientID  string `json:"-"`
+	RecordedBy string `json:"-"`
+	domain.Details
+}
+
+// RecordAllergyHandler handles the record allergy command
+type RecordAllergyHandler interface {
+	Handle(ctx context.Context, cmd RecordAllergyCommand) (*domain.Allergy, error)
+}
+
+type recordAllergyHandler struct {
+	repo     domain.RecordAllergyRepository
+	patients patientref.Directory
+}
+
+// NewRecordAllergyHandler creates a new record allergy handler
+func NewRecordAllergyHandler(repo domain.RecordAllergyRepository, patients patientref.Directory) RecordAllergyHandler {
+	return &recordAllergyHandler{repo: repo, patients: patients}
+}
+
+// Handle processes the record allergy command
+func (h *recordAllergyHandler) Handle(ctx context.Context, cmd RecordAllergyCommand) (*domain.Allergy, error) {
+	if err := validateDetails(cmd.Details); err != nil {
+		return nil, err
+	}
+
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	allergy := domain.NewAllergy(patientID, cmd.Details, cmd.RecordedBy)
+	if err := h.repo.Create(ctx, allergy); err != nil {
+		return nil, err
+	}
+	return allergy, nil
+}
+
+// validateDetails checks the details of an allergy
+func validateDetails(details domain.Details) error {
+	details = details.Normalize()
+	if details.Substance == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Substance is required")
+	}
+	if !details.Category.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Category must be allergy or intolerance")
+	}
+	if !details.Severity.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Severity must be mild, moderate or severe")
+	}
+	if !details.Status.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Status must be active, inactive, resolved or entered_in_error")
+	}
+	if details.Onset != "" {
+		if err := domain.ValidateOnset(details.Onset, time.Now()); err != nil {
+			return errors.NewAPIError(errors.ErrValidation, "Onset must be a past year, month or day (YYYY, YYYY-MM or YYYY-MM-DD)")
+		}
+	}
+	return nil
+}
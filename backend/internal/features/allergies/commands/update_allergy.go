@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// UpdateAllergyCommand represents the command to change an allergy, for
+// instance to mark it resolved or entered in error
+type UpdateAllergyCommand struct {
+	PatientID string `json:"-"`
+	AllergyID string `json:"-"`
+	UpdatedBy string `json:"-"`
+	domain.Details
+}
+
+// UpdateAllergyHandler handles the update allergy command
+type UpdateAllergyHandler interface {
+	Handle(ctx context.Context, cmd UpdateAllergyCommand) (*domain.Allergy, error)
+}
+
+type updateAllergyHandler struct {
+	repo domain.UpdateAllergyRepository
+}
+
+// NewUpdateAllergyHandler creates a new update allergy handler
+func NewUpdateAllergyHandler(repo domain.UpdateAllergyRepository) UpdateAllergyHandler {
+	return &updateAllergyHandler{repo: repo}
+}
+
+// Handle processes the update allergy command
+func (h *updateAllergyHandler) Handle(ctx context.Context, cmd UpdateAllergyCommand) (*domain.Allergy, error) {
+	if err := validateDetails(cmd.Details); err != nil {
+		return nil, err
+	}
+
+	allergy, err := h.repo.GetByID(ctx, cmd.AllergyID)
+	if err == domain.ErrNotFound || (err == nil && !allergy.BelongsTo(cmd.PatientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Allergy not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := allergy.Edit(cmd.Details, cmd.UpdatedBy); err != nil {
+		if err == domain.ErrEnteredInError {
+			return nil, errors.NewAPIError(errors.ErrConflict, "Allergies entered in error cannot be changed")
+		}
+		return nil, err
+	}
+	if err := h.repo.Update(ctx, allergy); err != nil {
+		return nil, err
+	}
+	return allergy, nil
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category tells an allergy from an intolerance
+type Category string
+
+const (
+	CategoryAllergy     Category = "allergy"
+	CategoryIntolerance Category = "intolerance"
+)
+
+// IsValid reports whether the category is a known one
+func (c Category) IsValid() bool {
+	return c == CategoryAllergy || c == CategoryIntolerance
+}
+
+// Severity is how severe the reaction to a substance is
+type Severity string
+
+const (
+	SeverityMild     Severity = "mild"
+	SeverityModerate Severity = "moderate"
+	SeveritySevere   Severity = "severe"
+)
+
+// IsValid reports whether the severity is a known one
+func (s Severity) IsValid() bool {
+	switch s {
+	case SeverityMild, SeverityModerate, SeveritySevere:
+		return true
+	}
+	return false
+}
+
+// Status is whether an allergy still applies
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+	StatusResolved Status = "resolved"
+	// StatusEnteredInError marks an allergy recorded by mistake. It is kept
+	// for the record but can no longer be changed.
+	StatusEnteredInError Status = "entered_in_error"
+)
+
+// IsValid reports whether the status is a known one
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusActive, StatusInactive, StatusResolved, StatusEnteredInError:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrEnteredInError is returned when an allergy entered in error is changed
+	ErrEnteredInError = errors.New("allergy was entered in error")
+	// ErrInvalidOnset is returned for an onset that is not a past date
+	ErrInvalidOnset = errors.New("invalid onset")
+)
+
+// Allergy is a substance a patient reacts to. The onset may be given as a
+// year, a month or a day, as it is often only roughly known.
+type Allergy struct {
+	ID         uuid.UUID `json:"id"`
+	PatientID  uuid.UUID `json:"patientId"`
+	Substance  string    `json:"substance"`
+	Category   Category  `json:"category"`
+	Reaction   string    `json:"reaction"`
+	Severity   Severity  `json:"severity"`
+	Status     Status    `json:"status"`
+	Onset      string    `json:"onset,omitempty"`
+	RecordedBy string    `json:"recordedBy"`
+	RecordedAt time.Time `json:"recordedAt"`
+	UpdatedBy  string    `json:"updatedBy"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Details are the clinical details of an allergy
+type Details struct {
+	Substance string   `json:"substance" binding:"required"`
+	Category  Category `json:"category"`
+	Reaction  string   `json:"reaction"`
+	Severity  Severity `json:"severity" binding:"required"`
+	Status    Status   `json:"status"`
+	Onset     string   `json:"onset"`
+}
+
+// Normalize trims the details and fills in the defaults: an allergy that
+// is active unless stated otherwise
+func (d Details) Normalize() Details {
+	d.Substance = strings.TrimSpace(d.Substance)
+	d.Reaction = strings.TrimSpace(d.Reaction)
+	d.Onset = strings.TrimSpace(d.Onset)
+	if d.Category == "" {
+		d.Category = CategoryAllergy
+	}
+	if d.Status == "" {
+		d.Status = StatusActive
+	}
+	return d
+}
+
+// NewAllergy records an allergy of a patient
+func NewAllergy(patientID uuid.UUID, details Details, recordedBy string) *Allergy {
+	details = details.Normalize()
+	now := time.Now()
+	return &Allergy{
+		ID:         uuid.New(),
+		PatientID:  patientID,
+		Substance:  details.Substance,
+		Category:   details.Category,
+		Reaction:   details.Reaction,
+		Severity:   details.Severity,
+		Status:     details.Status,
+		Onset:      details.Onset,
+		RecordedBy: recordedBy,
+		RecordedAt: now,
+		UpdatedBy:  recordedBy,
+		UpdatedAt:  now,
+	}
+}
+
+// Edit replaces the details of an allergy
+func (a *Allergy) Edit(details Details, userID string) error {
+	if a.Status == StatusEnteredInError {
+		return ErrEnteredInError
+	}
+	details = details.Normalize()
+
+	a.Substance = details.Substance
+	a.Category = details.Category
+	a.Reaction = details.Reaction
+	a.Severity = details.Severity
+	a.Status = details.Status
+	a.Onset = details.Onset
+	a.UpdatedBy = userID
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// BelongsTo reports whether the allergy is one of the patient's
+func (a *Allergy) BelongsTo(patientID string) bool {
+	return a.PatientID.String() == patientID
+}
+
+// IsActive reports whether the allergy currently applies
+func (a *Allergy) IsActive() bool {
+	return a.Status == StatusActive
+}
+
+// onsetLayouts are the precisions an onset can be given in
+var onsetLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// ValidateOnset checks that an onset is a year (YYYY), month (YYYY-MM) or
+// day (YYYY-MM-DD) that has begun by now
+func ValidateOnset(onset string, now time.Time) error {
+	for _, layout := range onsetLayouts {
+		if len(onset) != len(layout) {
+			continue
+		}
+		start, err := time.Parse(layout, onset)
+		if err != nil {
+			break
+		}
+		if start.After(now) {
+			return fmt.Errorf("%w: %s is in the future", ErrInvalidOnset, onset)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: expected YYYY, YYYY-MM or YYYY-MM-DD", ErrInvalidOnset)
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListStatus is what is known about a patient's allergies as a whole
+type ListStatus string
+
+const (
+	// ListStatusNotRecorded means nobody has asked: the patient may or may
+	// not have allergies
+	ListStatusNotRecorded ListStatus = "not_recorded"
+	// ListStatusNoKnownAllergies means the patient was asked and has no
+	// known allergies
+	ListStatusNoKnownAllergies ListStatus = "no_known_allergies"
+	// ListStatusActive means the patient has at least one active allergy
+	ListStatusActive ListStatus = "active_allergies"
+)
+
+// ErrHasActiveAllergies is returned when no known allergies are asserted
+// for a patient with active allergies
+var ErrHasActiveAllergies = errors.New("patient has active allergies")
+
+// NoKnownAllergies is the explicit statement that a patient has no known
+// allergies, made by a user at a given time. Without it, the absence of
+// allergies only means that none were recorded.
+type NoKnownAllergies struct {
+	PatientID  uuid.UUID `json:"patientId"`
+	AssertedBy string    `json:"assertedBy"`
+	AssertedAt time.Time `json:"assertedAt"`
+}
+
+// AllergyList is the allergies of a patient along with what is known about
+// them as a whole
+type AllergyList struct {
+	Status           ListStatus        `json:"status"`
+	Allergies        []*Allergy        `json:"allergies"`
+	NoKnownAllergies *NoKnownAllergies `json:"noKnownAllergies,omitempty"`
+}
+
+// NewAllergyList derives the status of a patient's allergies. Active
+// allergies take precedence over an assertion of no known allergies.
+func NewAllergyList(allergies []*Allergy, assertion *NoKnownAllergies) *AllergyList {
+	list := &AllergyList{
+		Status:           ListStatusNotRecorded,
+		Allergies:        allergies,
+		NoKnownAllergies: assertion,
+	}
+	if list.Allergies == nil {
+		list.Allergies = []*Allergy{}
+	}
+
+	if assertion != nil {
+		list.Status = ListStatusNoKnownAllergies
+	}
+	for _, allergy := range allergies {
+		if allergy.IsActive() {
+			list.Status = ListStatusActive
+			list.NoKnownAllergies = nil
+			break
+		}
+	}
+	return list
+}
+
+// AssertNoKnownAllergies states that the patient has no known allergies.
+// It fails with ErrHasActiveAllergies if the list has active ones.
+func (l *AllergyList) AssertNoKnownAllergies(patientID uuid.UUID, userID string) (*NoKnownAllergies, error) {
+	if l.Status == ListStatusActive {
+		return nil, ErrHasActiveAllergies
+	}
+	return &NoKnownAllergies{
+		PatientID:  patientID,
+		AssertedBy: userID,
+		AssertedAt: time.Now(),
+	}, nil
+}
+
+// Banner levels, from the most to the least urgent
+const (
+	BannerLevelCritical = "critical"
+	BannerLevelWarning  = "warning"
+	BannerLevelInfo     = "info"
+)
+
+// Banner is a notice about a patient's allergies meant to be shown
+// prominently wherever the patient is
+type Banner struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Banners returns the notices to show for the list: one per active allergy,
+// severe ones first, or else whether the patient has no known allergies or
+// was never asked
+func (l *AllergyList) Banners() []Banner {
+	switch l.Status {
+	case ListStatusNoKnownAllergies:
+		return []Banner{{Level: BannerLevelInfo, Message: "No known allergies"}}
+	case ListStatusNotRecorded:
+		return []Banner{{Level: BannerLevelWarning, Message: "Allergies not recorded"}}
+	}
+
+	var severe, other []Banner
+	for _, allergy := range l.Allergies {
+		if !allergy.IsActive() {
+			continue
+		}
+		message := fmt.Sprintf("%s %s", allergy.Substance, allergy.Category)
+		if allergy.Reaction != "" {
+			message += ": " + allergy.Reaction
+		}
+		message += fmt.Sprintf(" (%s)", allergy.Severity)
+
+		if allergy.Severity == SeveritySevere {
+			severe = append(severe, Banner{Level: BannerLevelCritical, Message: message})
+		} else {
+			other = append(other, Banner{Level: BannerLevelWarning, Message: message})
+		}
+	}
+	return append(severe, other...)
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllergyListStatus(t *testing.T) {
+	patientID := uuid.New()
+
+	empty := NewAllergyList(nil, nil)
+	assert.Equal(t, ListStatusNotRecorded, empty.Status)
+	assert.NotNil(t, empty.Allergies)
+	assert.Equal(t, []Banner{{Level: BannerLevelWarning, Message: "Allergies not recorded"}}, empty.Banners())
+
+	assertion, err := empty.AssertNoKnownAllergies(patientID, "dr-1")
+	require.NoError(t, err)
+	none := NewAllergyList(nil, assertion)
+	assert.Equal(t, ListStatusNoKnownAllergies, none.Status)
+	assert.Equal(t, []Banner{{Level: BannerLevelInfo, Message: "No known allergies"}}, none.Banners())
+
+	resolved := NewAllergy(patientID, Details{Substance: "Latex", Severity: SeverityMild, Status: StatusResolved}, "dr-1")
+	assert.Equal(t, ListStatusNoKnownAllergies, NewAllergyList([]*Allergy{resolved}, assertion).Status,
+		"Resolved allergies should not contradict the assertion")
+	assert.Equal(t, ListStatusNotRecorded, NewAllergyList([]*Allergy{resolved}, nil).Status)
+
+	mild := NewAllergy(patientID, Details{Substance: "Lactose", Category: CategoryIntolerance, Severity: SeverityMild}, "dr-1")
+	severe := NewAllergy(patientID, Details{Substance: "Penicillin", Reaction: "Anaphylaxis", Severity: SeveritySevere}, "dr-1")
+	active := NewAllergyList([]*Allergy{resolved, mild, severe}, assertion)
+	assert.Equal(t, ListStatusActive, active.Status)
+	assert.Nil(t, active.NoKnownAllergies, "Active allergies should override the assertion")
+	assert.Equal(t, []Banner{
+		{Level: BannerLevelCritical, Message: "Penicillin allergy: Anaphylaxis (severe)"},
+		{Level: BannerLevelWarning, Message: "Lactose intolerance (mild)"},
+	}, active.Banners())
+
+	_, err = active.AssertNoKnownAllergies(patientID, "dr-1")
+	assert.ErrorIs(t, err, ErrHasActiveAllergies)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOnset(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	for _, onset := range []string{"2019", "2024-06", "2024-06-15", "1950-01-31"} {
+		assert.NoError(t, ValidateOnset(onset, now), onset)
+	}
+	for _, onset := range []string{"2025", "2024-07", "2024-06-16", "19", "2024-13", "2024-02-30", "June 2020"} {
+		assert.ErrorIs(t, ValidateOnset(onset, now), ErrInvalidOnset, onset)
+	}
+}
+
+func TestAllergyDefaultsAndEdit(t *testing.T) {
+	allergy := NewAllergy(uuid.New(), Details{
+		Substance: " Penicillin ",
+		Reaction:  "Hives",
+		Severity:  SeverityModerate,
+	}, "dr-1")
+	assert.Equal(t, "Penicillin", allergy.Substance)
+	assert.Equal(t, CategoryAllergy, allergy.Category)
+	assert.Equal(t, StatusActive, allergy.Status)
+	assert.True(t, allergy.IsActive())
+
+	require.NoError(t, allergy.Edit(Details{Substance: "Penicillin", Severity: SeverityModerate, Status: StatusEnteredInError}, "dr-2"))
+	assert.Equal(t, "dr-2", allergy.UpdatedBy)
+	assert.False(t, allergy.IsActive())
+
+	err := allergy.Edit(Details{Substance: "Penicillin", Severity: SeverityModerate, Status: StatusActive}, "dr-2")
+	assert.ErrorIs(t, err, ErrEnteredInError)
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when an allergy does not exist
+var ErrNotFound = errors.New("allergy not found")
+
+// Repository defines the interface for allergy persistence. A patient has at
+// most one assertion of no known allergies; recording an active allergy
+// withdraws it in the same step. DeleteByPatient removes the allergies and
+// the assertion of a purged patient. MoveToPatient hands the allergies of a
+// duplicate to the patient it is merged into; an assertion of no known
+// allergies only carries over if the merged patient has none of its own and
+// no active allergy.
+type Repository interface {
+	Create(ctx context.Context, allergy *Allergy) error
+	Update(ctx context.Context, allergy *Allergy) error
+	GetByID(ctx context.Context, id string) (*Allergy, error)
+	ListByPatient(ctx context.Context, patientID string) ([]*Allergy, error)
+	GetNoKnownAllergies(ctx context.Context, patientID string) (*NoKnownAllergies, error)
+	SaveNoKnownAllergies(ctx context.Context, assertion *NoKnownAllergies) error
+	DeleteNoKnownAllergies(ctx context.Context, patientID string) error
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// RecordAllergyRepository defines the minimal interface for recording allergies
+type RecordAllergyRepository interface {
+	Create(ctx context.Context, allergy *Allergy) error
+}
+
+// UpdateAllergyRepository defines the minimal interface for changing an allergy
+type UpdateAllergyRepository interface {
+	GetByID(ctx context.Context, id string) (*Allergy, error)
+	Update(ctx context.Context, allergy *Allergy) error
+}
+
+// AssertNoKnownAllergiesRepository defines the minimal interface for
+// asserting and withdrawing that a patient has no known allergies
+type AssertNoKnownAllergiesRepository interface {
+	ListByPatient(ctx context.Context, patientID string) ([]*Allergy, error)
+	SaveNoKnownAllergies(ctx context.Context, assertion *NoKnownAllergies) error
+	DeleteNoKnownAllergies(ctx context.Context, patientID string) error
+}
+
+// GetAllergiesRepository defines the minimal interface for reading the
+// allergy list of a patient
+type GetAllergiesRepository interface {
+	ListByPatient(ctx context.Context, patientID string) ([]*Allergy, error)
+	GetNoKnownAllergies(ctx context.Context, patientID string) (*NoKnownAllergies, error)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/commands"
+	"github.com/dksch/pococlinic/internal/features/allergies/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission an allergy route requires
+type Access string
+
+const (
+	AccessReadAllergies  Access = "patients:read:allergies"
+	AccessWriteAllergies Access = "patients:write:allergies"
+)
+
+// Guard checks whether the caller may read or change allergy lists
+type Guard = authz.Guard[Access]
+
+// AllergyHandler handles HTTP requests for allergy operations
+type AllergyHandler struct {
+	recordAllergyHandler            commands.RecordAllergyHandler
+	updateAllergyHandler            commands.UpdateAllergyHandler
+	assertNoKnownAllergiesHandler   commands.AssertNoKnownAllergiesHandler
+	withdrawNoKnownAllergiesHandler commands.WithdrawNoKnownAllergiesHandler
+	getAllergiesHandler             queries.GetAllergiesHandler
+	logger                          *logging.Logger
+}
+
+// NewAllergyHandler creates a new allergy handler
+func NewAllergyHandler(
+	recordHandler commands.RecordAllergyHandler,
+	updateHandler commands.UpdateAllergyHandler,
+	assertHandler commands.AssertNoKnownAllergiesHandler,
+	withdrawHandler commands.WithdrawNoKnownAllergiesHandler,
+	getHandler queries.GetAllergiesHandler,
+	logger *logging.Logger,
+) *AllergyHandler {
+	return &AllergyHandler{
+		recordAllergyHandler:            recordHandler,
+		updateAllergyHandler:            updateHandler,
+		assertNoKnownAllergiesHandler:   assertHandler,
+		withdrawNoKnownAllergiesHandler: withdrawHandler,
+		getAllergiesHandler:             getHandler,
+		logger:                          logger,
+	}
+}
+
+// RegisterRoutes registers the allergy routes below the patient they
+// belong to. Asserting or withdrawing no known allergies needs the same
+// write access as recording an allergy.
+func (h *AllergyHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	patient := router.Group("/patients/:id")
+	{
+		patient.GET("/allergies", guard(AccessReadAllergies), h.GetAllergies)
+		patient.POST("/allergies", guard(AccessWriteAllergies), h.RecordAllergy)
+		patient.PUT("/allergies/:allergyId", guard(AccessWriteAllergies), h.UpdateAllergy)
+		patient.PUT("/no-known-allergies", guard(AccessWriteAllergies), h.AssertNoKnownAllergies)
+		patient.DELETE("/no-known-allergies", guard(AccessWriteAllergies), h.WithdrawNoKnownAllergies)
+	}
+}
+
+// GetAllergies handles the request for the allergy list of a patient
+func (h *AllergyHandler) GetAllergies(c *gin.Context) {
+	list, err := h.getAllergiesHandler.Handle(c.Request.Context(), queries.GetAllergiesQuery{PatientID: c.Param("id")})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to fetch allergies")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RecordAllergy handles the request to record an allergy
+func (h *AllergyHandler) RecordAllergy(c *gin.Context) {
+	var cmd commands.RecordAllergyCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.RecordedBy = c.GetString("userID")
+
+	allergy, err := h.recordAllergyHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to record allergy")
+		return
+	}
+
+	c.JSON(http.StatusCreated, allergy)
+}
+
+// UpdateAllergy handles the request to change an allergy
+func (h *AllergyHandler) UpdateAllergy(c *gin.Context) {
+	var cmd commands.UpdateAllergyCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.AllergyID = c.Param("allergyId")
+	cmd.UpdatedBy = c.GetString("userID")
+
+	allergy, err := h.updateAllergyHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to update allergy")
+		return
+	}
+
+	c.JSON(http.StatusOK, allergy)
+}
+
+// AssertNoKnownAllergies handles the request to state that a patient has no
+// known allergies
+func (h *AllergyHandler) AssertNoKnownAllergies(c *gin.Context) {
+	assertion, err := h.assertNoKnownAllergiesHandler.Handle(c.Request.Context(), commands.AssertNoKnownAllergiesCommand{
+		PatientID: c.Param("id"),
+		UserID:    c.GetString("userID"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to assert no known allergies")
+		return
+	}
+
+	c.JSON(http.StatusOK, assertion)
+}
+
+// WithdrawNoKnownAllergies handles the request to withdraw the assertion
+// that a patient has no known allergies
+func (h *AllergyHandler) WithdrawNoKnownAllergies(c *gin.Context) {
+	err := h.withdrawNoKnownAllergiesHandler.Handle(c.Request.Context(), commands.WithdrawNoKnownAllergiesCommand{
+		PatientID: c.Param("id"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to withdraw no known allergies")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondWithError writes an API error with its status and hides any other
+// error behind the given message
+func (h *AllergyHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		c.JSON(errors.StatusCode(apiErr.Code), apiErr)
+		return
+	}
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+}
@@ -0,0 +1,175 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryRepository is a simple in-memory implementation of the allergy Repository interface
+type MemoryRepository struct {
+	allergies  map[string]*domain.Allergy
+	assertions map[string]*domain.NoKnownAllergies
+	mu         sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory allergy repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		allergies:  make(map[string]*domain.Allergy),
+		assertions: make(map[string]*domain.NoKnownAllergies),
+	}
+}
+
+// Create adds a new allergy to the repository. An active allergy withdraws
+// the patient's assertion of no known allergies.
+func (r *MemoryRepository) Create(ctx context.Context, allergy *domain.Allergy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.save(allergy)
+	return nil
+}
+
+// Update stores the changed allergy. An active allergy withdraws the
+// patient's assertion of no known allergies.
+func (r *MemoryRepository) Update(ctx context.Context, allergy *domain.Allergy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.allergies[allergy.ID.String()]; !exists {
+		return domain.ErrNotFound
+	}
+	r.save(allergy)
+	return nil
+}
+
+// save stores a copy of the allergy; the caller must hold the lock
+func (r *MemoryRepository) save(allergy *domain.Allergy) {
+	stored := *allergy
+	r.allergies[allergy.ID.String()] = &stored
+	if allergy.IsActive() {
+		delete(r.assertions, allergy.PatientID.String())
+	}
+}
+
+// GetByID retrieves an allergy by its ID
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Allergy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	allergy, exists := r.allergies[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	found := *allergy
+	return &found, nil
+}
+
+// ListByPatient returns the patient's allergies in the order they were recorded
+func (r *MemoryRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Allergy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	allergies := []*domain.Allergy{}
+	for _, allergy := range r.allergies {
+		if allergy.BelongsTo(patientID) {
+			found := *allergy
+			allergies = append(allergies, &found)
+		}
+	}
+	sort.Slice(allergies, func(i, j int) bool {
+		if !allergies[i].RecordedAt.Equal(allergies[j].RecordedAt) {
+			return allergies[i].RecordedAt.Before(allergies[j].RecordedAt)
+		}
+		return allergies[i].ID.String() < allergies[j].ID.String()
+	})
+	return allergies, nil
+}
+
+// GetNoKnownAllergies returns the patient's assertion of no known
+// allergies, or nil if there is none
+func (r *MemoryRepository) GetNoKnownAllergies(ctx context.Context, patientID string) (*domain.NoKnownAllergies, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assertion, exists := r.assertions[patientID]
+	if !exists {
+		return nil, nil
+	}
+	found := *assertion
+	return &found, nil
+}
+
+// SaveNoKnownAllergies stores the assertion, replacing an earlier one of the patient
+func (r *MemoryRepository) SaveNoKnownAllergies(ctx context.Context, assertion *domain.NoKnownAllergies) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *assertion
+	r.assertions[assertion.PatientID.String()] = &stored
+	return nil
+}
+
+// DeleteNoKnownAllergies withdraws the patient's assertion of no known allergies
+func (r *MemoryRepository) DeleteNoKnownAllergies(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.assertions, patientID)
+	return nil
+}
+
+// DeleteByPatient removes the patient's allergies and assertion of no known
+// allergies
+func (r *MemoryRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, allergy := range r.allergies {
+		if allergy.BelongsTo(patientID) {
+			delete(r.allergies, id)
+		}
+	}
+	delete(r.assertions, patientID)
+	return nil
+}
+
+// MoveToPatient hands the allergies of one patient to another. The
+// assertion of no known allergies of the latter wins, and none is kept once
+// it has an active allergy.
+func (r *MemoryRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	target, err := uuid.Parse(toID)
+	if err != nil {
+		return fmt.Errorf("invalid patient ID %q: %w", toID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hasActive := false
+	for _, allergy := range r.allergies {
+		if allergy.BelongsTo(fromID) {
+			allergy.PatientID = target
+		}
+		if allergy.BelongsTo(toID) && allergy.IsActive() {
+			hasActive = true
+		}
+	}
+
+	if assertion, ok := r.assertions[fromID]; ok {
+		if _, exists := r.assertions[toID]; !exists {
+			assertion.PatientID = target
+			r.assertions[toID] = assertion
+		}
+		delete(r.assertions, fromID)
+	}
+	if hasActive {
+		delete(r.assertions, toID)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the allergies feature, in order
+var sqliteMigrations = []string{
+	`CREATE TABLE allergies (
+		id          TEXT PRIMARY KEY,
+		patient_id  TEXT NOT NULL,
+		substance   TEXT NOT NULL,
+		category    TEXT NOT NULL,
+		reaction    TEXT NOT NULL DEFAULT '',
+		severity    TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		onset       TEXT NOT NULL DEFAULT '',
+		recorded_by TEXT NOT NULL DEFAULT '',
+		recorded_at TEXT NOT NULL,
+		updated_by  TEXT NOT NULL DEFAULT '',
+		updated_at  TEXT NOT NULL
+	);
+	CREATE INDEX idx_allergies_patient ON allergies (patient_id, recorded_at);
+	CREATE TABLE no_known_allergies (
+		patient_id  TEXT PRIMARY KEY,
+		asserted_by TEXT NOT NULL DEFAULT '',
+		asserted_at TEXT NOT NULL
+	);`,
+}
+
+// allergyColumns lists the allergy columns in the order scanAllergy expects
+const allergyColumns = `id, patient_id, substance, category, reaction, severity, status, onset,
+	recorded_by, recorded_at, updated_by, updated_at`
+
+// MigrateSQLite applies the allergies schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "allergies", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the allergy Repository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite allergy repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new allergy to the repository. An active allergy withdraws
+// the patient's assertion of no known allergies.
+func (r *SQLiteRepository) Create(ctx context.Context, allergy *domain.Allergy) error {
+	return r.save(ctx, allergy, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, `INSERT INTO allergies (`+allergyColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			allergy.ID.String(),
+			allergy.PatientID.String(),
+			allergy.Substance,
+			string(allergy.Category),
+			allergy.Reaction,
+			string(allergy.Severity),
+			string(allergy.Status),
+			allergy.Onset,
+			allergy.RecordedBy,
+			formatTime(allergy.RecordedAt),
+			allergy.UpdatedBy,
+			formatTime(allergy.UpdatedAt),
+		)
+	})
+}
+
+// Update stores the changed allergy. An active allergy withdraws the
+// patient's assertion of no known allergies.
+func (r *SQLiteRepository) Update(ctx context.Context, allergy *domain.Allergy) error {
+	return r.save(ctx, allergy, func(tx *sql.Tx) (sql.Result, error) {
+		return tx.ExecContext(ctx, `UPDATE allergies SET
+			substance = ?, category = ?, reaction = ?, severity = ?, status = ?, onset = ?,
+			updated_by = ?, updated_at = ?
+			WHERE id = ?`,
+			allergy.Substance,
+			string(allergy.Category),
+			allergy.Reaction,
+			string(allergy.Severity),
+			string(allergy.Status),
+			allergy.Onset,
+			allergy.UpdatedBy,
+			formatTime(allergy.UpdatedAt),
+			allergy.ID.String(),
+		)
+	})
+}
+
+// save writes an allergy with the given statement and withdraws the
+// assertion of no known allergies if it is active, in one transaction
+func (r *SQLiteRepository) save(ctx context.Context, allergy *domain.Allergy, write func(tx *sql.Tx) (sql.Result, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save allergy %s: %w", allergy.ID, err)
+	}
+	defer tx.Rollback()
+
+	result, err := write(tx)
+	if err != nil {
+		return fmt.Errorf("failed to save allergy %s: %w", allergy.ID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save allergy %s: %w", allergy.ID, err)
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+
+	if allergy.IsActive() {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM no_known_allergies WHERE patient_id = ?`,
+			allergy.PatientID.String()); err != nil {
+			return fmt.Errorf("failed to withdraw no known allergies of patient %s: %w", allergy.PatientID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetByID retrieves an allergy by its ID
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*domain.Allergy, error) {
+	allergy, err := scanAllergy(r.db.QueryRowContext(ctx,
+		`SELECT `+allergyColumns+` FROM allergies WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return allergy, err
+}
+
+// ListByPatient returns the patient's allergies in the order they were recorded
+func (r *SQLiteRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Allergy, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+allergyColumns+` FROM allergies
+		WHERE patient_id = ? ORDER BY recorded_at, id`, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list allergies: %w", err)
+	}
+	defer rows.Close()
+
+	allergies := []*domain.Allergy{}
+	for rows.Next() {
+		allergy, err := scanAllergy(rows)
+		if err != nil {
+			return nil, err
+		}
+		allergies = append(allergies, allergy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list allergies: %w", err)
+	}
+	return allergies, nil
+}
+
+// GetNoKnownAllergies returns the patient's assertion of no known
+// allergies, or nil if there is none
+func (r *SQLiteRepository) GetNoKnownAllergies(ctx context.Context, patientID string) (*domain.NoKnownAllergies, error) {
+	var (
+		assertion  domain.NoKnownAllergies
+		assertedAt string
+	)
+	err := r.db.QueryRowContext(ctx, `SELECT asserted_by, asserted_at FROM no_known_allergies
+		WHERE patient_id = ?`, patientID).Scan(&assertion.AssertedBy, &assertedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read no known allergies of patient %s: %w", patientID, err)
+	}
+
+	if assertion.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID %q: %w", patientID, err)
+	}
+	if assertion.AssertedAt, err = time.Parse(timestampLayout, assertedAt); err != nil {
+		return nil, fmt.Errorf("invalid time of no known allergies of patient %s: %w", patientID, err)
+	}
+	return &assertion, nil
+}
+
+// SaveNoKnownAllergies stores the assertion, replacing an earlier one of the patient
+func (r *SQLiteRepository) SaveNoKnownAllergies(ctx context.Context, assertion *domain.NoKnownAllergies) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO no_known_allergies (patient_id, asserted_by, asserted_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (patient_id) DO UPDATE SET asserted_by = excluded.asserted_by, asserted_at = excluded.asserted_at`,
+		assertion.PatientID.String(),
+		assertion.AssertedBy,
+		formatTime(assertion.AssertedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save no known allergies of patient %s: %w", assertion.PatientID, err)
+	}
+	return nil
+}
+
+// DeleteNoKnownAllergies withdraws the patient's assertion of no known allergies
+func (r *SQLiteRepository) DeleteNoKnownAllergies(ctx context.Context, patientID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM no_known_allergies WHERE patient_id = ?`, patientID); err != nil {
+		return fmt.Errorf("failed to withdraw no known allergies of patient %s: %w", patientID, err)
+	}
+	return nil
+}
+
+// DeleteByPatient removes the patient's allergies and assertion of no known
+// allergies
+func (r *SQLiteRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		for _, statement := range []string{
+			`DELETE FROM allergies WHERE patient_id = ?`,
+			`DELETE FROM no_known_allergies WHERE patient_id = ?`,
+		} {
+			if _, err := conn.ExecContext(ctx, statement, patientID); err != nil {
+				return fmt.Errorf("failed to delete allergies of patient %s: %w", patientID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MoveToPatient hands the allergies of one patient to another. The
+// assertion of no known allergies of the latter wins, and none is kept once
+// it has an active allergy.
+func (r *SQLiteRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		for _, statement := range []string{
+			`UPDATE allergies SET patient_id = :to WHERE patient_id = :from`,
+			`INSERT OR IGNORE INTO no_known_allergies (patient_id, asserted_by, asserted_at)
+				SELECT :to, asserted_by, asserted_at FROM no_known_allergies WHERE patient_id = :from`,
+			`DELETE FROM no_known_allergies WHERE patient_id = :from`,
+			`DELETE FROM no_known_allergies WHERE patient_id = :to
+				AND EXISTS (SELECT 1 FROM allergies WHERE patient_id = :to AND status = 'active')`,
+		} {
+			if _, err := conn.ExecContext(ctx, statement, sql.Named("from", fromID), sql.Named("to", toID)); err != nil {
+				return fmt.Errorf("failed to move allergies of patient %s: %w", fromID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAllergy reads an allergy selected with allergyColumns
+func scanAllergy(row rowScanner) (*domain.Allergy, error) {
+	var (
+		allergy                              domain.Allergy
+		id, patientID, recordedAt, updatedAt string
+		category, severity, status           string
+	)
+	err := row.Scan(
+		&id,
+		&patientID,
+		&allergy.Substance,
+		&category,
+		&allergy.Reaction,
+		&severity,
+		&status,
+		&allergy.Onset,
+		&allergy.RecordedBy,
+		&recordedAt,
+		&allergy.UpdatedBy,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if allergy.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid allergy ID %q: %w", id, err)
+	}
+	if allergy.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID of allergy %s: %w", id, err)
+	}
+	if allergy.RecordedAt, err = time.Parse(timestampLayout, recordedAt); err != nil {
+		return nil, fmt.Errorf("invalid recording time of allergy %s: %w", id, err)
+	}
+	if allergy.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("invalid update time of allergy %s: %w", id, err)
+	}
+	allergy.Category = domain.Category(category)
+	allergy.Severity = domain.Severity(severity)
+	allergy.Status = domain.Status(status)
+	return &allergy, nil
+}
+
+// formatTime formats a timestamp for storage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
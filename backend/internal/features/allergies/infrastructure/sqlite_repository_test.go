@@ -0,0 +1,136 @@
+package infrastructure
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) *SQLiteRepository {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "allergies.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db)
+}
+
+func TestSQLiteRepositoryAllergies(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patientID := uuid.New()
+
+	allergy := domain.NewAllergy(patientID, domain.Details{
+		Substance: "Latex",
+		Reaction:  "Contact dermatitis",
+		Severity:  domain.SeverityMild,
+		Status:    domain.StatusInactive,
+		Onset:     "2015",
+	}, "nurse-1")
+	require.NoError(t, repo.Create(ctx, allergy))
+
+	found, err := repo.GetByID(ctx, allergy.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, allergy.Substance, found.Substance)
+	assert.Equal(t, domain.CategoryAllergy, found.Category)
+	assert.Equal(t, domain.StatusInactive, found.Status)
+	assert.Equal(t, "2015", found.Onset)
+	assert.Equal(t, "nurse-1", found.RecordedBy)
+
+	_, err = repo.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	assertion := &domain.NoKnownAllergies{PatientID: patientID, AssertedBy: "dr-1", AssertedAt: allergy.RecordedAt}
+	require.NoError(t, repo.SaveNoKnownAllergies(ctx, assertion))
+	assertion.AssertedBy = "dr-2"
+	require.NoError(t, repo.SaveNoKnownAllergies(ctx, assertion))
+
+	stored, err := repo.GetNoKnownAllergies(ctx, patientID.String())
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "dr-2", stored.AssertedBy)
+
+	// An inactive allergy leaves the assertion, an active one withdraws it
+	require.NoError(t, found.Edit(domain.Details{Substance: "Latex", Severity: domain.SeverityMild, Status: domain.StatusActive}, "dr-1"))
+	require.NoError(t, repo.Update(ctx, found))
+
+	stored, err = repo.GetNoKnownAllergies(ctx, patientID.String())
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	allergies, err := repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	require.Len(t, allergies, 1)
+	assert.Equal(t, domain.StatusActive, allergies[0].Status)
+	assert.Equal(t, "dr-1", allergies[0].UpdatedBy)
+
+	missing := domain.NewAllergy(patientID, domain.Details{Substance: "Nuts", Severity: domain.SeveritySevere}, "dr-1")
+	assert.ErrorIs(t, repo.Update(ctx, missing), domain.ErrNotFound)
+}
+
+func TestSQLiteRepositoryDeleteByPatient(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patientID, otherID := uuid.New(), uuid.New()
+
+	allergy := domain.NewAllergy(patientID, domain.Details{Substance: "Penicillin", Severity: domain.SeveritySevere}, "dr-1")
+	require.NoError(t, repo.Create(ctx, allergy))
+	other := domain.NewAllergy(otherID, domain.Details{Substance: "Latex", Severity: domain.SeverityMild}, "dr-1")
+	require.NoError(t, repo.Create(ctx, other))
+	require.NoError(t, repo.SaveNoKnownAllergies(ctx, &domain.NoKnownAllergies{PatientID: patientID, AssertedBy: "dr-1", AssertedAt: allergy.RecordedAt}))
+
+	require.NoError(t, repo.DeleteByPatient(ctx, patientID.String()))
+
+	allergies, err := repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	assert.Empty(t, allergies)
+	assertion, err := repo.GetNoKnownAllergies(ctx, patientID.String())
+	require.NoError(t, err)
+	assert.Nil(t, assertion)
+
+	// Other patients keep their allergies
+	allergies, err = repo.ListByPatient(ctx, otherID.String())
+	require.NoError(t, err)
+	assert.Len(t, allergies, 1)
+}
+
+func TestSQLiteRepositoryMoveToPatient(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	duplicateID, patientID, otherID := uuid.New(), uuid.New(), uuid.New()
+
+	allergy := domain.NewAllergy(duplicateID, domain.Details{Substance: "Penicillin", Severity: domain.SeveritySevere}, "dr-1")
+	require.NoError(t, repo.Create(ctx, allergy))
+	asserted := allergy.RecordedAt
+	require.NoError(t, repo.SaveNoKnownAllergies(ctx, &domain.NoKnownAllergies{PatientID: patientID, AssertedBy: "dr-1", AssertedAt: asserted}))
+	require.NoError(t, repo.SaveNoKnownAllergies(ctx, &domain.NoKnownAllergies{PatientID: otherID, AssertedBy: "dr-2", AssertedAt: asserted}))
+
+	// The active allergy of the duplicate withdraws the kept patient's
+	// assertion of no known allergies
+	require.NoError(t, repo.MoveToPatient(ctx, duplicateID.String(), patientID.String()))
+	allergies, err := repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	require.Len(t, allergies, 1)
+	assert.Equal(t, allergy.ID, allergies[0].ID)
+	assert.Equal(t, patientID, allergies[0].PatientID)
+	assertion, err := repo.GetNoKnownAllergies(ctx, patientID.String())
+	require.NoError(t, err)
+	assert.Nil(t, assertion)
+
+	// An assertion carries over to a patient without allergies recorded
+	nextID := uuid.New()
+	require.NoError(t, repo.MoveToPatient(ctx, otherID.String(), nextID.String()))
+	assertion, err = repo.GetNoKnownAllergies(ctx, nextID.String())
+	require.NoError(t, err)
+	require.NotNil(t, assertion)
+	assert.Equal(t, "dr-2", assertion.AssertedBy)
+	assertion, err = repo.GetNoKnownAllergies(ctx, otherID.String())
+	require.NoError(t, err)
+	assert.Nil(t, assertion)
+}
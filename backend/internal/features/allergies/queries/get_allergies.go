@@ -0,0 +1,39 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/allergies/domain"
+)
+
+// GetAllergiesQuery represents the query for the allergy list of a patient
+type GetAllergiesQuery struct {
+	PatientID string
+}
+
+// GetAllergiesHandler handles the get allergies query
+type GetAllergiesHandler interface {
+	Handle(ctx context.Context, query GetAllergiesQuery) (*domain.AllergyList, error)
+}
+
+type getAllergiesHandler struct {
+	repo domain.GetAllergiesRepository
+}
+
+// NewGetAllergiesHandler creates a new get allergies handler
+func NewGetAllergiesHandler(repo domain.GetAllergiesRepository) GetAllergiesHandler {
+	return &getAllergiesHandler{repo: repo}
+}
+
+// Handle processes the get allergies query
+func (h *getAllergiesHandler) Handle(ctx context.Context, query GetAllergiesQuery) (*domain.AllergyList, error) {
+	allergies, err := h.repo.ListByPatient(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := h.repo.GetNoKnownAllergies(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewAllergyList(allergies, assertion), nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+)
+
+// RecordEntryCommand represents one access to be recorded in the audit log
+type RecordEntryCommand struct {
+	UserID            string
+	Role              string
+	Action            string
+	Path              string
+	PatientID         string
+	IPAddress         string
+	Status            int
+	EmergencyAccessID string
+}
+
+// RecordEntryHandler handles recording of audit entries
+type RecordEntryHandler interface {
+	Handle(ctx context.Context, cmd RecordEntryCommand) (*domain.Entry, error)
+}
+
+// recordEntryHandler implements RecordEntryHandler
+type recordEntryHandler struct {
+	auditRepository domain.RecordEntryRepository
+}
+
+// NewRecordEntryHandler creates a new handler for recording audit entries
+func NewRecordEntryHandler(repo domain.RecordEntryRepository) RecordEntryHandler {
+	return &recordEntryHandler{
+		auditRepository: repo,
+	}
+}
+
+// Handle processes the record entry command
+func (h *recordEntryHandler) Handle(ctx context.Context, cmd RecordEntryCommand) (*domain.Entry, error) {
+	entry := domain.NewEntry(
+		cmd.UserID,
+		cmd.Role,
+		cmd.Action,
+		cmd.Path,
+		cmd.PatientID,
+		cmd.IPAddress,
+		cmd.Status,
+		cmd.EmergencyAccessID,
+	)
+	if err := h.auditRepository.Append(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return entry, nil
+}
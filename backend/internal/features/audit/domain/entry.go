@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcome summarizes how an audited request ended
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeDenied  Outcome = "denied"
+	OutcomeFailure Outcome = "failure"
+)
+
+// OutcomeForStatus derives the outcome from an HTTP status code
+func OutcomeForStatus(status int) Outcome {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	case status >= http.StatusBadRequest:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// Entry is one record of the audit log. Entries form a hash chain: each
+// entry's hash covers its content and the hash of the entry before it, so
+// altering, removing or reordering an entry breaks every later link.
+type Entry struct {
+	Sequence          int64     `json:"sequence"`
+	ID                uuid.UUID `json:"id"`
+	Timestamp         time.Time `json:"timestamp"`
+	UserID            string    `json:"userId"`
+	Role              string    `json:"role"`
+	Action            string    `json:"action"`
+	Path              string    `json:"path"`
+	PatientID         string    `json:"patientId,omitempty"`
+	IPAddress         string    `json:"ipAddress"`
+	Status            int       `json:"status"`
+	Outcome           Outcome   `json:"outcome"`
+	EmergencyAccessID string    `json:"emergencyAccessId,omitempty"`
+	PrevHash          string    `json:"prevHash"`
+	Hash              string    `json:"hash"`
+}
+
+// NewEntry creates an unchained audit entry. The timestamp is kept at
+// microsecond precision so that it survives storage unchanged.
+func NewEntry(userID, role, action, path, patientID, ipAddress string, status int, emergencyAccessID string) *Entry {
+	return &Entry{
+		ID:                uuid.New(),
+		Timestamp:         time.Now().UTC().Truncate(time.Microsecond),
+		UserID:            userID,
+		Role:              role,
+		Action:            action,
+		Path:              path,
+		PatientID:         patientID,
+		IPAddress:         ipAddress,
+		Status:            status,
+		Outcome:           OutcomeForStatus(status),
+		EmergencyAccessID: emergencyAccessID,
+	}
+}
+
+// Chain links the entry to the previous entry of the log, or starts the log
+// when prev is nil, and seals it with its hash
+func (e *Entry) Chain(prev *Entry) {
+	e.Sequence = 1
+	e.PrevHash = ""
+	if prev != nil {
+		e.Sequence = prev.Sequence + 1
+		e.PrevHash = prev.Hash
+	}
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash returns the hex-encoded SHA-256 of the entry's content and the
+// previous hash. The stored Hash is not part of the input.
+func (e *Entry) ComputeHash() string {
+	content, _ := json.Marshal([]any{
+		e.Sequence,
+		e.ID.String(),
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.Role,
+		e.Action,
+		e.Path,
+		e.PatientID,
+		e.IPAddress,
+		e.Status,
+		string(e.Outcome),
+		e.EmergencyAccessID,
+		e.PrevHash,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyLink checks that the entry follows prev in an untampered chain;
+// prev is nil for the first entry of the log
+func (e *Entry) VerifyLink(prev *Entry) error {
+	wantSequence, wantPrevHash := int64(1), ""
+	if prev != nil {
+		wantSequence, wantPrevHash = prev.Sequence+1, prev.Hash
+	}
+
+	if e.Sequence != wantSequence {
+		return fmt.Errorf("expected entry %d, found entry %d", wantSequence, e.Sequence)
+	}
+	if e.PrevHash != wantPrevHash {
+		return fmt.Errorf("entry %d does not link to the previous entry", e.Sequence)
+	}
+	if e.Hash != e.ComputeHash() {
+		return fmt.Errorf("entry %d does not match its hash", e.Sequence)
+	}
+	return nil
+}
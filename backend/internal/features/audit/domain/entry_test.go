@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChain(n int) []*Entry {
+	entries := []*Entry{}
+	var prev *Entry
+	for i := 0; i < n; i++ {
+		entry := NewEntry("user-1", "doctor", "GET /api/v1/patients/:id", "/api/v1/patients/p-1", "p-1", "127.0.0.1", http.StatusOK, "")
+		entry.Chain(prev)
+		entries = append(entries, entry)
+		prev = entry
+	}
+	return entries
+}
+
+func TestOutcomeForStatus(t *testing.T) {
+	assert.Equal(t, OutcomeSuccess, OutcomeForStatus(http.StatusOK))
+	assert.Equal(t, OutcomeSuccess, OutcomeForStatus(http.StatusCreated))
+	assert.Equal(t, OutcomeDenied, OutcomeForStatus(http.StatusUnauthorized))
+	assert.Equal(t, OutcomeDenied, OutcomeForStatus(http.StatusForbidden))
+	assert.Equal(t, OutcomeFailure, OutcomeForStatus(http.StatusNotFound))
+	assert.Equal(t, OutcomeFailure, OutcomeForStatus(http.StatusInternalServerError))
+}
+
+func TestEntryChain(t *testing.T) {
+	entries := newTestChain(3)
+
+	assert.Equal(t, int64(1), entries[0].Sequence)
+	assert.Empty(t, entries[0].PrevHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, int64(3), entries[2].Sequence)
+
+	var prev *Entry
+	for _, entry := range entries {
+		require.NoError(t, entry.VerifyLink(prev))
+		prev = entry
+	}
+}
+
+func TestEntryVerifyLinkDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(entries []*Entry) (*Entry, *Entry)
+	}{
+		{
+			name: "changed content",
+			tamper: func(entries []*Entry) (*Entry, *Entry) {
+				entries[1].PatientID = "p-2"
+				return entries[1], entries[0]
+			},
+		},
+		{
+			name: "rehashed entry",
+			tamper: func(entries []*Entry) (*Entry, *Entry) {
+				entries[1].Outcome = OutcomeSuccess
+				entries[1].Status = http.StatusOK
+				entries[1].UserID = "someone-else"
+				entries[1].Hash = entries[1].ComputeHash()
+				return entries[2], entries[1]
+			},
+		},
+		{
+			name: "removed entry",
+			tamper: func(entries []*Entry) (*Entry, *Entry) {
+				return entries[2], entries[0]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, prev := tt.tamper(newTestChain(3))
+			assert.Error(t, entry.VerifyLink(prev))
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	entry := NewEntry("user-1", "nurse", "GET /api/v1/patients/:id", "/api/v1/patients/p-1", "p-1", "", http.StatusForbidden, "")
+
+	assert.True(t, Filter{}.Matches(entry))
+	assert.True(t, Filter{UserID: "user-1", PatientID: "p-1", Outcome: OutcomeDenied}.Matches(entry))
+	assert.False(t, Filter{UserID: "user-2"}.Matches(entry))
+	assert.False(t, Filter{Outcome: OutcomeSuccess}.Matches(entry))
+	assert.False(t, Filter{From: entry.Timestamp.Add(1)}.Matches(entry))
+	assert.True(t, Filter{From: entry.Timestamp, To: entry.Timestamp}.Matches(entry))
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for audit log persistence. The log is
+// append-only: there is no way to update or delete an entry.
+type Repository interface {
+	Append(ctx context.Context, entry *Entry) error
+	ListPaginated(ctx context.Context, page, pageSize int, filter Filter) ([]*Entry, int64, error)
+	ListAfter(ctx context.Context, sequence int64, limit int) ([]*Entry, error)
+}
+
+// Filter narrows down an audit log listing. Empty fields match every entry;
+// From and To bound the timestamp inclusively.
+type Filter struct {
+	UserID    string
+	PatientID string
+	Outcome   Outcome
+	From      time.Time
+	To        time.Time
+}
+
+// Matches reports whether the entry passes the filter
+func (f Filter) Matches(entry *Entry) bool {
+	switch {
+	case f.UserID != "" && entry.UserID != f.UserID:
+		return false
+	case f.PatientID != "" && entry.PatientID != f.PatientID:
+		return false
+	case f.Outcome != "" && entry.Outcome != f.Outcome:
+		return false
+	case !f.From.IsZero() && entry.Timestamp.Before(f.From):
+		return false
+	case !f.To.IsZero() && entry.Timestamp.After(f.To):
+		return false
+	}
+	return true
+}
+
+// RecordEntryRepository defines the minimal interface for recording audit entries.
+// Append chains the entry to the last entry of the log before storing it.
+type RecordEntryRepository interface {
+	Append(ctx context.Context, entry *Entry) error
+}
+
+// ListEntriesRepository defines the minimal interface for querying the audit log
+type ListEntriesRepository interface {
+	ListPaginated(ctx context.Context, page, pageSize int, filter Filter) ([]*Entry, int64, error)
+}
+
+// VerifyChainRepository defines the minimal interface for walking the audit
+// log in order, in batches of entries following the given sequence number
+type VerifyChainRepository interface {
+	ListAfter(ctx context.Context, sequence int64, limit int) ([]*Entry, error)
+}
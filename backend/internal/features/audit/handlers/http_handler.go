@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dksch/pococlinic/internal/features/audit/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission an audit route requires
+type Access string
+
+const (
+	AccessReadAudit Access = "audit:read"
+)
+
+// Guard checks whether the caller may read the audit log
+type Guard = authz.Guard[Access]
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	listEntriesHandler queries.ListEntriesHandler
+	verifyChainHandler queries.VerifyChainHandler
+	logger             *logging.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(
+	listEntries queries.ListEntriesHandler,
+	verifyChain queries.VerifyChainHandler,
+	logger *logging.Logger,
+) *AuditHandler {
+	return &AuditHandler{
+		listEntriesHandler: listEntries,
+		verifyChainHandler: verifyChain,
+		logger:             logger,
+	}
+}
+
+// RegisterRoutes registers the audit routes with the given router group,
+// all of them behind AccessReadAudit
+func (h *AuditHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	audit := router.Group("/audit", guard(AccessReadAudit))
+	{
+		audit.GET("", h.ListEntries)
+		audit.GET("/verify", h.VerifyChain)
+	}
+}
+
+// ListEntries searches the audit log, newest entries first
+func (h *AuditHandler) ListEntries(c *gin.Context) {
+	var query queries.ListEntriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid query parameters"))
+		return
+	}
+	if query.PageSize > 500 {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid page size"))
+		return
+	}
+
+	result, err := h.listEntriesHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to list audit entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyChain checks the hash chain of the whole audit log
+func (h *AuditHandler) VerifyChain(c *gin.Context) {
+	result, err := h.verifyChainHandler.Handle(c.Request.Context(), queries.VerifyChainQuery{})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to verify audit log")
+		return
+	}
+
+	if !result.Valid {
+		h.logger.Warn("Audit log verification failed",
+			"brokenAt", result.BrokenAt,
+			"reason", result.Reason,
+		)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// respondWithError writes the error response, hiding unexpected errors
+// behind the given message
+func (h *AuditHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok && apiErr.Code == errors.ErrValidation {
+		c.JSON(http.StatusBadRequest, apiErr)
+		return
+	}
+
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+}
@@ -0,0 +1,79 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+)
+
+// MemoryRepository is a simple in-memory implementation of the audit Repository interface
+type MemoryRepository struct {
+	entries []*domain.Entry // ordered by sequence
+	mu      sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory audit repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		entries: []*domain.Entry{},
+	}
+}
+
+// Append chains the entry to the last entry and adds it to the log
+func (r *MemoryRepository) Append(ctx context.Context, entry *domain.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var prev *domain.Entry
+	if len(r.entries) > 0 {
+		prev = r.entries[len(r.entries)-1]
+	}
+	entry.Chain(prev)
+
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+// ListPaginated returns a page of entries matching the filter, newest first
+func (r *MemoryRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.Filter) ([]*domain.Entry, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := []*domain.Entry{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if filter.Matches(r.entries[i]) {
+			matched = append(matched, r.copyOf(i))
+		}
+	}
+
+	totalCount := int64(len(matched))
+	start := (page - 1) * pageSize
+	if start >= len(matched) {
+		return []*domain.Entry{}, totalCount, nil
+	}
+	end := min(start+pageSize, len(matched))
+	return matched[start:end], totalCount, nil
+}
+
+// ListAfter returns up to limit entries following the given sequence number, in order
+func (r *MemoryRepository) ListAfter(ctx context.Context, sequence int64, limit int) ([]*domain.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []*domain.Entry{}
+	for i, entry := range r.entries {
+		if entry.Sequence > sequence && len(entries) < limit {
+			entries = append(entries, r.copyOf(i))
+		}
+	}
+	return entries, nil
+}
+
+// copyOf returns a copy of the entry at index i, so that callers cannot
+// alter the log
+func (r *MemoryRepository) copyOf(i int) *domain.Entry {
+	entry := *r.entries[i]
+	return &entry
+}
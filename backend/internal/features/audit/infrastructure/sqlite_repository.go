@@ -0,0 +1,210 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings and hash identically after reading
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the audit feature, in order
+var sqliteMigrations = []string{
+	`CREATE TABLE audit_log (
+		sequence            INTEGER PRIMARY KEY,
+		id                  TEXT NOT NULL UNIQUE,
+		timestamp           TEXT NOT NULL,
+		user_id             TEXT NOT NULL DEFAULT '',
+		role                TEXT NOT NULL DEFAULT '',
+		action              TEXT NOT NULL,
+		path                TEXT NOT NULL,
+		patient_id          TEXT NOT NULL DEFAULT '',
+		ip_address          TEXT NOT NULL DEFAULT '',
+		status              INTEGER NOT NULL,
+		outcome             TEXT NOT NULL,
+		emergency_access_id TEXT NOT NULL DEFAULT '',
+		prev_hash           TEXT NOT NULL,
+		hash                TEXT NOT NULL
+	);
+	CREATE INDEX idx_audit_log_user_id ON audit_log (user_id);
+	CREATE INDEX idx_audit_log_patient_id ON audit_log (patient_id);`,
+}
+
+// entryColumns lists the audit columns in the order scanEntry expects
+const entryColumns = `sequence, id, timestamp, user_id, role, action, path, patient_id,
+	ip_address, status, outcome, emergency_access_id, prev_hash, hash`
+
+// MigrateSQLite applies the audit schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "audit", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the audit Repository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite audit repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Append chains the entry to the last entry and adds it to the log. Reading
+// the last entry and inserting happen in one transaction.
+func (r *SQLiteRepository) Append(ctx context.Context, entry *domain.Entry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	defer tx.Rollback()
+
+	prev, err := scanEntry(tx.QueryRowContext(ctx,
+		`SELECT `+entryColumns+` FROM audit_log ORDER BY sequence DESC LIMIT 1`))
+	if errors.Is(err, sql.ErrNoRows) {
+		prev = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read last audit entry: %w", err)
+	}
+	entry.Chain(prev)
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (`+entryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Sequence,
+		entry.ID.String(),
+		entry.Timestamp.UTC().Format(timestampLayout),
+		entry.UserID,
+		entry.Role,
+		entry.Action,
+		entry.Path,
+		entry.PatientID,
+		entry.IPAddress,
+		entry.Status,
+		string(entry.Outcome),
+		entry.EmergencyAccessID,
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListPaginated returns a page of entries matching the filter, newest first
+func (r *SQLiteRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.Filter) ([]*domain.Entry, int64, error) {
+	where := `WHERE (? = '' OR user_id = ?)
+		AND (? = '' OR patient_id = ?)
+		AND (? = '' OR outcome = ?)
+		AND (? = '' OR timestamp >= ?)
+		AND (? = '' OR timestamp <= ?)`
+	from, to := formatBound(filter.From), formatBound(filter.To)
+	args := []any{
+		filter.UserID, filter.UserID,
+		filter.PatientID, filter.PatientID,
+		string(filter.Outcome), string(filter.Outcome),
+		from, from,
+		to, to,
+	}
+
+	var totalCount int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log `+where, args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	entries, err := r.query(ctx,
+		`SELECT `+entryColumns+` FROM audit_log `+where+` ORDER BY sequence DESC LIMIT ? OFFSET ?`,
+		append(args, pageSize, (page-1)*pageSize)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, totalCount, nil
+}
+
+// ListAfter returns up to limit entries following the given sequence number, in order
+func (r *SQLiteRepository) ListAfter(ctx context.Context, sequence int64, limit int) ([]*domain.Entry, error) {
+	return r.query(ctx,
+		`SELECT `+entryColumns+` FROM audit_log WHERE sequence > ? ORDER BY sequence LIMIT ?`,
+		sequence, limit,
+	)
+}
+
+// query runs a select of entryColumns and reads every resulting entry
+func (r *SQLiteRepository) query(ctx context.Context, query string, args ...any) ([]*domain.Entry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*domain.Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// formatBound formats a filter time bound, leaving unset bounds empty
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timestampLayout)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEntry reads an entry selected with entryColumns
+func scanEntry(row rowScanner) (*domain.Entry, error) {
+	var (
+		entry     domain.Entry
+		id        string
+		timestamp string
+		outcome   string
+	)
+	err := row.Scan(
+		&entry.Sequence,
+		&id,
+		&timestamp,
+		&entry.UserID,
+		&entry.Role,
+		&entry.Action,
+		&entry.Path,
+		&entry.PatientID,
+		&entry.IPAddress,
+		&entry.Status,
+		&outcome,
+		&entry.EmergencyAccessID,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Malformed values are kept as far as possible so that verification
+	// reports the entry instead of failing to read the log
+	entry.ID, _ = uuid.Parse(id)
+	entry.Timestamp, _ = time.Parse(timestampLayout, timestamp)
+	entry.Outcome = domain.Outcome(outcome)
+	return &entry, nil
+}
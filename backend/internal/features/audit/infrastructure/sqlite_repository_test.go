@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+	"github.com/dksch/pococlinic/internal/features/audit/queries"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) (*SQLiteRepository, *sql.DB) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "audit.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db), db
+}
+
+func appendTestEntries(t *testing.T, repo domain.RecordEntryRepository) []*domain.Entry {
+	entries := []*domain.Entry{
+		domain.NewEntry("user-1", "doctor", "GET /api/v1/patients/:id", "/api/v1/patients/p-1", "p-1", "10.0.0.1", http.StatusOK, ""),
+		domain.NewEntry("user-2", "staff", "PUT /api/v1/patients/:id", "/api/v1/patients/p-1", "p-1", "10.0.0.2", http.StatusForbidden, ""),
+		domain.NewEntry("user-1", "doctor", "GET /api/v1/patients", "/api/v1/patients", "", "10.0.0.1", http.StatusOK, "grant-1"),
+	}
+	for _, entry := range entries {
+		require.NoError(t, repo.Append(context.Background(), entry))
+	}
+	return entries
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := setupSQLiteRepository(t)
+	entries := appendTestEntries(t, repo)
+
+	assert.Equal(t, int64(3), entries[2].Sequence)
+	assert.Equal(t, entries[1].Hash, entries[2].PrevHash)
+
+	all, total, err := repo.ListPaginated(ctx, 1, 10, domain.Filter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	require.Len(t, all, 3)
+	assert.Equal(t, entries[2].ID, all[0].ID)
+	assert.Equal(t, "grant-1", all[0].EmergencyAccessID)
+	assert.True(t, entries[0].Timestamp.Equal(all[2].Timestamp))
+
+	denied, total, err := repo.ListPaginated(ctx, 1, 10, domain.Filter{Outcome: domain.OutcomeDenied})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, "user-2", denied[0].UserID)
+
+	byUser, total, err := repo.ListPaginated(ctx, 2, 1, domain.Filter{UserID: "user-1", PatientID: ""})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, byUser, 1)
+	assert.Equal(t, entries[0].ID, byUser[0].ID)
+
+	future, total, err := repo.ListPaginated(ctx, 1, 10, domain.Filter{From: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, future)
+
+	after, err := repo.ListAfter(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, int64(2), after[0].Sequence)
+}
+
+func TestSQLiteRepositoryVerification(t *testing.T) {
+	ctx := context.Background()
+	repo, db := setupSQLiteRepository(t)
+	entries := appendTestEntries(t, repo)
+	verify := queries.NewVerifyChainHandler(repo)
+
+	result, err := verify.Handle(ctx, queries.VerifyChainQuery{})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.EqualValues(t, 3, result.Entries)
+	assert.Equal(t, entries[2].Hash, result.LastHash)
+
+	// Editing a row behind the application's back breaks the chain
+	_, err = db.ExecContext(ctx, `UPDATE audit_log SET outcome = 'success', status = 200 WHERE sequence = 2`)
+	require.NoError(t, err)
+
+	result, err = verify.Handle(ctx, queries.VerifyChainQuery{})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.EqualValues(t, 2, result.BrokenAt)
+	assert.EqualValues(t, 1, result.Entries)
+}
+
+func TestMemoryRepositoryVerification(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	appendTestEntries(t, repo)
+
+	// Entries handed out are copies, changing them does not alter the log
+	listed, _, err := repo.ListPaginated(ctx, 1, 10, domain.Filter{})
+	require.NoError(t, err)
+	listed[0].UserID = "someone-else"
+
+	result, err := queries.NewVerifyChainHandler(repo).Handle(ctx, queries.VerifyChainQuery{})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.EqualValues(t, 3, result.Entries)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/audit/commands"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+	"github.com/gin-gonic/gin"
+)
+
+// PatientIDKey is the context key under which a handler names the patient a
+// request concerned, see patientref.ContextKey
+const PatientIDKey = patientref.ContextKey
+
+// Recorder records requests to the audit log
+type Recorder struct {
+	recordEntryHandler commands.RecordEntryHandler
+	logger             *logging.Logger
+}
+
+// NewRecorder creates a new audit recorder
+func NewRecorder(recordEntry commands.RecordEntryHandler, logger *logging.Logger) *Recorder {
+	return &Recorder{
+		recordEntryHandler: recordEntry,
+		logger:             logger,
+	}
+}
+
+// Record returns the middleware that records every request passing through
+// it once the response is known. It must run after authentication, which
+// provides the user, and before authorization, so that denied requests are
+// recorded as well.
+func (r *Recorder) Record() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		cmd := commands.RecordEntryCommand{
+			UserID:            c.GetString("userID"),
+			Action:            c.Request.Method + " " + c.FullPath(),
+			Path:              c.Request.URL.Path,
+			PatientID:         c.Param("id"),
+			IPAddress:         c.ClientIP(),
+			Status:            c.Writer.Status(),
+			EmergencyAccessID: c.GetString("emergencyAccessID"),
+		}
+		if role, exists := c.Get("userRole"); exists {
+			cmd.Role = fmt.Sprint(role)
+		}
+		if patientID := c.GetString(PatientIDKey); patientID != "" {
+			cmd.PatientID = patientID
+		}
+
+		// The entry is recorded even if the client has gone away meanwhile
+		ctx := context.WithoutCancel(c.Request.Context())
+		if _, err := r.recordEntryHandler.Handle(ctx, cmd); err != nil {
+			r.logger.Error("Failed to record audit entry", err,
+				"userId", cmd.UserID,
+				"action", cmd.Action,
+				"path", cmd.Path,
+				"status", cmd.Status,
+			)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/audit/commands"
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+	"github.com/dksch/pococlinic/internal/features/audit/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRole string
+
+func TestRecorder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := infrastructure.NewMemoryRepository()
+	recorder := NewRecorder(commands.NewRecordEntryHandler(repo), logging.NewLogger())
+
+	router := gin.New()
+	authenticated := router.Group("", func(c *gin.Context) {
+		c.Set("userID", "user-1")
+		c.Set("userRole", testRole("nurse"))
+		if c.GetHeader("X-Emergency") != "" {
+			c.Set("emergencyAccessID", "grant-1")
+		}
+		c.Next()
+	}, recorder.Record())
+	authenticated.GET("/patients/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	authenticated.PUT("/patients/:id", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	})
+	authenticated.POST("/patients", func(c *gin.Context) {
+		c.Set(PatientIDKey, "p-new")
+		c.Status(http.StatusCreated)
+	})
+
+	requests := []struct {
+		method    string
+		path      string
+		emergency bool
+	}{
+		{method: "GET", path: "/patients/p-1"},
+		{method: "PUT", path: "/patients/p-1"},
+		{method: "POST", path: "/patients", emergency: true},
+	}
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(r.method, r.path, nil)
+		if r.emergency {
+			req.Header.Set("X-Emergency", "1")
+		}
+		router.ServeHTTP(w, req)
+	}
+
+	entries, total, err := repo.ListPaginated(context.Background(), 1, 10, domain.Filter{})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+
+	created, denied, read := entries[0], entries[1], entries[2]
+	assert.Equal(t, "user-1", read.UserID)
+	assert.Equal(t, "nurse", read.Role)
+	assert.Equal(t, "GET /patients/:id", read.Action)
+	assert.Equal(t, "p-1", read.PatientID)
+	assert.Equal(t, domain.OutcomeSuccess, read.Outcome)
+
+	assert.Equal(t, "PUT /patients/:id", denied.Action)
+	assert.Equal(t, http.StatusForbidden, denied.Status)
+	assert.Equal(t, domain.OutcomeDenied, denied.Outcome)
+
+	assert.Equal(t, "p-new", created.PatientID)
+	assert.Equal(t, "grant-1", created.EmergencyAccessID)
+	assert.Empty(t, read.EmergencyAccessID)
+}
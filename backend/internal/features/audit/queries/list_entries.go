@@ -0,0 +1,88 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// ListEntriesQuery represents the query to search the audit log
+type ListEntriesQuery struct {
+	Page      int            `form:"page,default=1"`
+	PageSize  int            `form:"pageSize,default=50"`
+	UserID    string         `form:"userId"`
+	PatientID string         `form:"patientId"`
+	Outcome   domain.Outcome `form:"outcome"`
+	From      time.Time      `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time      `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// PaginatedEntries represents a paginated list of audit entries
+type PaginatedEntries struct {
+	Entries     []*domain.Entry `json:"entries"`
+	TotalCount  int64           `json:"totalCount"`
+	CurrentPage int             `json:"currentPage"`
+	PageSize    int             `json:"pageSize"`
+	TotalPages  int             `json:"totalPages"`
+}
+
+// ListEntriesHandler handles audit log searches
+type ListEntriesHandler interface {
+	Handle(ctx context.Context, query ListEntriesQuery) (*PaginatedEntries, error)
+}
+
+// listEntriesHandler implements ListEntriesHandler
+type listEntriesHandler struct {
+	auditRepository domain.ListEntriesRepository
+}
+
+// NewListEntriesHandler creates a new handler for audit log searches
+func NewListEntriesHandler(repo domain.ListEntriesRepository) ListEntriesHandler {
+	return &listEntriesHandler{
+		auditRepository: repo,
+	}
+}
+
+// Handle processes the list entries query
+func (h *listEntriesHandler) Handle(ctx context.Context, query ListEntriesQuery) (*PaginatedEntries, error) {
+	// Ensure valid pagination parameters
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 50
+	}
+	switch query.Outcome {
+	case "", domain.OutcomeSuccess, domain.OutcomeDenied, domain.OutcomeFailure:
+	default:
+		return nil, errors.NewAPIError(errors.ErrValidation, "Invalid outcome")
+	}
+
+	filter := domain.Filter{
+		UserID:    query.UserID,
+		PatientID: query.PatientID,
+		Outcome:   query.Outcome,
+		From:      query.From,
+		To:        query.To,
+	}
+	entries, totalCount, err := h.auditRepository.ListPaginated(ctx, query.Page, query.PageSize, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate total pages
+	totalPages := int(totalCount) / query.PageSize
+	if int(totalCount)%query.PageSize > 0 {
+		totalPages++
+	}
+
+	return &PaginatedEntries{
+		Entries:     entries,
+		TotalCount:  totalCount,
+		CurrentPage: query.Page,
+		PageSize:    query.PageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/audit/domain"
+)
+
+// verifyBatchSize is the number of entries read at a time while verifying
+const verifyBatchSize = 500
+
+// VerifyChainQuery represents the query to verify the integrity of the audit log
+type VerifyChainQuery struct{}
+
+// ChainVerification is the result of verifying the audit log. LastHash
+// identifies the verified log; recording it elsewhere also makes removal of
+// the newest entries detectable.
+type ChainVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	LastHash string `json:"lastHash,omitempty"`
+	BrokenAt int64  `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// VerifyChainHandler handles verification of the audit log
+type VerifyChainHandler interface {
+	Handle(ctx context.Context, query VerifyChainQuery) (*ChainVerification, error)
+}
+
+// verifyChainHandler implements VerifyChainHandler
+type verifyChainHandler struct {
+	auditRepository domain.VerifyChainRepository
+}
+
+// NewVerifyChainHandler creates a new handler for audit log verification
+func NewVerifyChainHandler(repo domain.VerifyChainRepository) VerifyChainHandler {
+	return &verifyChainHandler{
+		auditRepository: repo,
+	}
+}
+
+// Handle walks the whole log in order and stops at the first broken link
+func (h *verifyChainHandler) Handle(ctx context.Context, query VerifyChainQuery) (*ChainVerification, error) {
+	result := &ChainVerification{Valid: true}
+
+	var prev *domain.Entry
+	for {
+		after := int64(0)
+		if prev != nil {
+			after = prev.Sequence
+		}
+		entries, err := h.auditRepository.ListAfter(ctx, after, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return result, nil
+		}
+
+		for _, entry := range entries {
+			if err := entry.VerifyLink(prev); err != nil {
+				result.Valid = false
+				result.BrokenAt = entry.Sequence
+				result.Reason = err.Error()
+				return result, nil
+			}
+			result.Entries++
+			result.LastHash = entry.Hash
+			prev = entry
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// ChangePINCommand represents the command to change the caller's PIN. The
+// current key and PIN must be supplied again.
+type ChangePINCommand struct {
+	UserID     string `json:"-"`
+	Key        string `json:"key" binding:"required"`
+	CurrentPIN string `json:"currentPin" binding:"required,len=4"`
+	NewPIN     string `json:"newPin" binding:"required,len=4"`
+}
+
+// ChangePINHandler handles PIN changes
+type ChangePINHandler interface {
+	Handle(ctx context.Context, cmd ChangePINCommand) error
+}
+
+// changePINHandler implements ChangePINHandler
+type changePINHandler struct {
+	userRepository domain.ChangePINRepository
+	hashPolicy     domain.Argon2Params
+	lockoutPolicy  domain.LockoutPolicy
+}
+
+// NewChangePINHandler creates a new handler for changing PINs
+func NewChangePINHandler(
+	repo domain.ChangePINRepository,
+	hashPolicy domain.Argon2Params,
+	lockoutPolicy domain.LockoutPolicy,
+) ChangePINHandler {
+	return &changePINHandler{
+		userRepository: repo,
+		hashPolicy:     hashPolicy,
+		lockoutPolicy:  lockoutPolicy,
+	}
+}
+
+// Handle processes the change PIN command
+func (h *changePINHandler) Handle(ctx context.Context, cmd ChangePINCommand) error {
+	user, err := h.userRepository.GetByID(ctx, cmd.UserID)
+	if err != nil || user == nil {
+		return domain.ErrUserNotFoundError
+	}
+
+	if user.IsLocked() {
+		return domain.ErrAccountLockedError
+	}
+
+	// Re-authenticate with the current credentials
+	if valid, _ := user.ValidateCredentials(cmd.Key, cmd.CurrentPIN, h.hashPolicy); !valid {
+		if err := h.userRepository.RecordFailedAttempt(ctx, user.ID.String(), h.lockoutPolicy); err != nil {
+			return fmt.Errorf("failed to record failed attempt: %w", err)
+		}
+		return domain.ErrInvalidCredentialsError
+	}
+
+	if err := user.ChangePIN(cmd.NewPIN, h.hashPolicy); err != nil {
+		return err
+	}
+	user.ResetFailedAttempts()
+
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to save PIN: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatedUserMustChangePIN(t *testing.T) {
+	ctx := context.Background()
+	users := infrastructure.NewMemoryUserRepository()
+	created, err := NewCreateUserHandler(users, testHashParams).Handle(ctx, CreateUserCommand{
+		Email: "nurse@example.com",
+		Name:  "Nurse Joy",
+		Role:  domain.RoleNurse,
+	})
+	require.NoError(t, err)
+	user, key := created.User, created.Key
+	assert.True(t, user.MustChangePIN)
+
+	handler := NewChangePINHandler(users, testHashParams, domain.DefaultLockoutPolicy)
+
+	// Wrong credentials are rejected and counted
+	err = handler.Handle(ctx, ChangePINCommand{UserID: user.ID.String(), Key: key, CurrentPIN: "9999", NewPIN: "4821"})
+	assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+	stored, err := users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.FailedAttempts)
+
+	// Trivial PINs are rejected
+	err = handler.Handle(ctx, ChangePINCommand{UserID: user.ID.String(), Key: key, CurrentPIN: domain.InitialPIN, NewPIN: "1234"})
+	var authErr *domain.AuthError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, domain.ErrWeakPIN, authErr.Code)
+	stored, err = users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.True(t, stored.MustChangePIN)
+
+	err = handler.Handle(ctx, ChangePINCommand{UserID: user.ID.String(), Key: key, CurrentPIN: domain.InitialPIN, NewPIN: "4821"})
+	require.NoError(t, err)
+
+	stored, err = users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.False(t, stored.MustChangePIN)
+	assert.Equal(t, 0, stored.FailedAttempts)
+	valid, _ := stored.ValidateCredentials(key, "4821", testHashParams)
+	assert.True(t, valid)
+}
+
+func TestChangePINUnknownUser(t *testing.T) {
+	handler := NewChangePINHandler(infrastructure.NewMemoryUserRepository(), testHashParams, domain.DefaultLockoutPolicy)
+	err := handler.Handle(context.Background(), ChangePINCommand{UserID: "missing", Key: "key", CurrentPIN: "0000", NewPIN: "4821"})
+	assert.Equal(t, domain.ErrUserNotFoundError, err)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// ChangeRoleCommand represents an administrator's request to change the role
+// of a user
+type ChangeRoleCommand struct {
+	ActorID string      `json:"-"`
+	UserID  string      `json:"-"`
+	Role    domain.Role `json:"role" binding:"required"`
+}
+
+// ChangeRoleHandler handles role changes
+type ChangeRoleHandler interface {
+	Handle(ctx context.Context, cmd ChangeRoleCommand) (*domain.User, error)
+}
+
+// changeRoleHandler implements ChangeRoleHandler
+type changeRoleHandler struct {
+	userRepository    domain.ManageUserRepository
+	sessionRepository domain.RevokeSessionRepository
+}
+
+// NewChangeRoleHandler creates a new handler for role changes
+func NewChangeRoleHandler(userRepo domain.ManageUserRepository, sessionRepo domain.RevokeSessionRepository) ChangeRoleHandler {
+	return &changeRoleHandler{
+		userRepository:    userRepo,
+		sessionRepository: sessionRepo,
+	}
+}
+
+// Handle processes the change role command. The user's sessions are ended
+// because their tokens still carry the old role.
+func (h *changeRoleHandler) Handle(ctx context.Context, cmd ChangeRoleCommand) (*domain.User, error) {
+	if cmd.ActorID == cmd.UserID {
+		return nil, domain.ErrSelfModificationError
+	}
+
+	user, err := h.userRepository.GetByID(ctx, cmd.UserID)
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+
+	if user.Role == cmd.Role {
+		return user, nil
+	}
+	if err := user.ChangeRole(cmd.Role); err != nil {
+		return nil, err
+	}
+
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save role: %w", err)
+	}
+	if err := h.sessionRepository.DeleteByUserID(ctx, cmd.UserID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return user, nil
+}
@@ -14,46 +14,73 @@ type CreateUserCommand struct {
 	Role  domain.Role `json:"role" binding:"required"`
 }
 
+// CreateUserResponse represents the created user with the credentials that
+// are shown exactly once: the key and the recovery codes
+type CreateUserResponse struct {
+	User          *domain.User `json:"user"`
+	Key           string       `json:"key"`
+	RecoveryCodes []string     `json:"recoveryCodes"`
+}
+
 // CreateUserHandler handles user creation
 type CreateUserHandler interface {
-	Handle(ctx context.Context, cmd CreateUserCommand) (*domain.User, string, error)
+	Handle(ctx context.Context, cmd CreateUserCommand) (*CreateUserResponse, error)
 }
 
 // createUserHandler implements CreateUserHandler
 type createUserHandler struct {
 	userRepository domain.CreateUserRepository
+	hashPolicy     domain.Argon2Params
 }
 
 // NewCreateUserHandler creates a new handler for user creation
-func NewCreateUserHandler(repo domain.CreateUserRepository) CreateUserHandler {
+func NewCreateUserHandler(repo domain.CreateUserRepository, hashPolicy domain.Argon2Params) CreateUserHandler {
 	return &createUserHandler{
 		userRepository: repo,
+		hashPolicy:     hashPolicy,
 	}
 }
 
 // Handle processes the create user command
-func (h *createUserHandler) Handle(ctx context.Context, cmd CreateUserCommand) (*domain.User, string, error) {
+func (h *createUserHandler) Handle(ctx context.Context, cmd CreateUserCommand) (*CreateUserResponse, error) {
+	if !cmd.Role.IsValid() {
+		return nil, domain.ErrInvalidRoleError(cmd.Role)
+	}
+
 	user := domain.NewUser(cmd.Email, cmd.Name, cmd.Role)
 
 	// Generate the initial key and credentials
-	key, keyCred, err := domain.GenerateKey()
+	key, keyCred, err := domain.GenerateKey(h.hashPolicy)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+		return nil, fmt.Errorf("failed to generate key: %w", err)
 	}
 	user.SetKeyCredential(keyCred)
 
-	// Generate default PIN credentials
-	pinCred, err := domain.GeneratePINCredential("0000")
+	// Generate the initial PIN credentials; the user must replace the PIN
+	// on first login
+	pinCred, err := domain.GeneratePINCredential(domain.InitialPIN, h.hashPolicy)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate PIN: %w", err)
+		return nil, fmt.Errorf("failed to generate PIN: %w", err)
 	}
 	user.SetPINCredential(pinCred)
+	user.RequirePINChange()
+
+	// Generate the recovery codes for a lost key
+	codes, codeCreds, err := domain.GenerateRecoveryCodes(h.hashPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+	user.SetRecoveryCodes(codeCreds)
 
 	// Save the user
 	err = h.userRepository.Create(ctx, user)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create user: %w", err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return user, key, nil
+	return &CreateUserResponse{
+		User:          user,
+		Key:           key,
+		RecoveryCodes: codes,
+	}, nil
 }
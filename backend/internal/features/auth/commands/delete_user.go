@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// DeleteUserCommand represents an administrator's request to delete a user
+type DeleteUserCommand struct {
+	ActorID string `json:"-"`
+	UserID  string `json:"-"`
+}
+
+// DeleteUserHandler handles user deletion
+type DeleteUserHandler interface {
+	Handle(ctx context.Context, cmd DeleteUserCommand) error
+}
+
+// deleteUserHandler implements DeleteUserHandler
+type deleteUserHandler struct {
+	userRepository    domain.ManageUserRepository
+	sessionRepository domain.RevokeSessionRepository
+}
+
+// NewDeleteUserHandler creates a new handler for user deletion
+func NewDeleteUserHandler(userRepo domain.ManageUserRepository, sessionRepo domain.RevokeSessionRepository) DeleteUserHandler {
+	return &deleteUserHandler{
+		userRepository:    userRepo,
+		sessionRepository: sessionRepo,
+	}
+}
+
+// Handle processes the delete user command. The user's sessions are ended
+// before the account is removed.
+func (h *deleteUserHandler) Handle(ctx context.Context, cmd DeleteUserCommand) error {
+	if cmd.ActorID == cmd.UserID {
+		return domain.ErrSelfModificationError
+	}
+
+	if user, err := h.userRepository.GetByID(ctx, cmd.UserID); err != nil || user == nil {
+		return domain.ErrUserNotFoundError
+	}
+
+	if err := h.sessionRepository.DeleteByUserID(ctx, cmd.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := h.userRepository.Delete(ctx, cmd.UserID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestEmergencyAccess(t *testing.T) {
+	ctx := context.Background()
+	config := testTokenConfig()
+	policy := domain.EmergencyAccessPolicy{TTL: 10 * time.Minute, Permissions: []domain.Permission{"patients:read"}}
+
+	users := infrastructure.NewMemoryUserRepository()
+	sessions := infrastructure.NewMemorySessionRepository()
+	emergency := infrastructure.NewMemoryEmergencyAccessRepository()
+
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	require.NoError(t, users.Create(ctx, user))
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	require.NoError(t, sessions.Create(ctx, session))
+
+	handler := NewRequestEmergencyAccessHandler(users, sessions, emergency, config, policy)
+	cmd := RequestEmergencyAccessCommand{
+		UserID:    user.ID.String(),
+		SessionID: session.ID.String(),
+		Reason:    "short",
+		IPAddress: "10.0.0.5",
+	}
+
+	_, err := handler.Handle(ctx, cmd)
+	assert.Equal(t, domain.ErrEmergencyReasonRequiredError, err)
+
+	cmd.Reason = "Unconscious patient in ER"
+	result, err := handler.Handle(ctx, cmd)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(policy.TTL), result.Grant.ExpiresAt, time.Second)
+
+	claims, err := domain.ValidateToken(result.AccessToken, domain.TokenTypeAccess, config.AccessTokenSecret)
+	require.NoError(t, err)
+	assert.Equal(t, result.Grant.ID.String(), claims.EmergencyAccessID)
+	assert.Equal(t, session.ID.String(), claims.SessionID)
+
+	pending, err := emergency.List(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "10.0.0.5", pending[0].IPAddress)
+
+	// Grants are bound to a live session
+	cmd.SessionID = domain.NewSession(user.ID, "", "", time.Now()).ID.String()
+	_, err = handler.Handle(ctx, cmd)
+	assert.Equal(t, domain.ErrSessionNotFoundError, err)
+}
+
+func TestReviewEmergencyAccess(t *testing.T) {
+	ctx := context.Background()
+	emergency := infrastructure.NewMemoryEmergencyAccessRepository()
+	admin := domain.NewUser("admin@example.com", "Admin", domain.RoleAdmin)
+
+	grant, err := domain.NewEmergencyAccess(admin.ID, admin.ID, "Unconscious patient in ER", "", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, emergency.Create(ctx, grant))
+
+	handler := NewReviewEmergencyAccessHandler(emergency)
+	_, err = handler.Handle(ctx, ReviewEmergencyAccessCommand{ReviewerID: admin.ID.String(), GrantID: "missing"})
+	assert.Equal(t, domain.ErrEmergencyAccessNotFoundError, err)
+
+	reviewed, err := handler.Handle(ctx, ReviewEmergencyAccessCommand{
+		ReviewerID: admin.ID.String(),
+		GrantID:    grant.ID.String(),
+		Note:       "Confirmed with ER log",
+	})
+	require.NoError(t, err)
+	assert.True(t, reviewed.IsReviewed())
+	assert.Equal(t, "Confirmed with ER log", reviewed.ReviewNote)
+
+	pending, err := emergency.List(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -17,10 +17,12 @@ type LoginCommand struct {
 	IPAddress string `json:"ipAddress"`
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login response. The refresh token is not
+// serialized; it is delivered to the client in an HttpOnly cookie.
 type LoginResponse struct {
-	User        *domain.User `json:"user"`
-	AccessToken string       `json:"accessToken"`
+	User         *domain.User `json:"user"`
+	AccessToken  string       `json:"accessToken"`
+	RefreshToken string       `json:"-"`
 }
 
 // LoginHandler handles user login
@@ -33,6 +35,8 @@ type loginHandler struct {
 	userRepository    domain.ValidateUserRepository
 	sessionRepository domain.CreateSessionRepository
 	tokenConfig       domain.TokenConfig
+	hashPolicy        domain.Argon2Params
+	lockoutPolicy     domain.LockoutPolicy
 }
 
 // NewLoginHandler creates a new handler for user login
@@ -40,31 +44,57 @@ func NewLoginHandler(
 	userRepo domain.ValidateUserRepository,
 	sessionRepo domain.CreateSessionRepository,
 	tokenConfig domain.TokenConfig,
+	hashPolicy domain.Argon2Params,
+	lockoutPolicy domain.LockoutPolicy,
 ) LoginHandler {
 	return &loginHandler{
 		userRepository:    userRepo,
 		sessionRepository: sessionRepo,
 		tokenConfig:       tokenConfig,
+		hashPolicy:        hashPolicy,
+		lockoutPolicy:     lockoutPolicy,
 	}
 }
 
-// Handle processes the login command
+// Handle processes the login command. Failed attempts and successful logins
+// are saved so that the lockout survives restarts and works across stores.
 func (h *loginHandler) Handle(ctx context.Context, cmd LoginCommand) (*LoginResponse, error) {
 	// Get user by email
 	user, err := h.userRepository.GetByEmail(ctx, cmd.Email)
-	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+	if err != nil || user == nil {
+		return nil, domain.ErrInvalidCredentialsError
 	}
 
 	// Check if account is locked
 	if user.IsLocked() {
-		return nil, fmt.Errorf("account is locked")
+		return nil, domain.ErrAccountLockedError
 	}
 
 	// Validate credentials
-	if !user.ValidateCredentials(cmd.Key, cmd.PIN) {
-		user.RecordFailedAttempt()
-		return nil, fmt.Errorf("invalid credentials")
+	valid, needsRehash := user.ValidateCredentials(cmd.Key, cmd.PIN, h.hashPolicy)
+	if !valid {
+		if err := h.userRepository.RecordFailedAttempt(ctx, user.ID.String(), h.lockoutPolicy); err != nil {
+			return nil, fmt.Errorf("failed to record failed attempt: %w", err)
+		}
+		return nil, domain.ErrInvalidCredentialsError
+	}
+
+	// Disabled accounts are only reported to callers who know the credentials
+	if user.Disabled {
+		return nil, domain.ErrAccountDisabledError
+	}
+
+	// Upgrade credentials hashed with weaker parameters than the current policy
+	if needsRehash {
+		if err := user.RehashCredentials(cmd.Key, cmd.PIN, h.hashPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record successful login
+	user.RecordLogin()
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
 	}
 
 	// Create new session
@@ -72,11 +102,11 @@ func (h *loginHandler) Handle(ctx context.Context, cmd LoginCommand) (*LoginResp
 		user.ID,
 		cmd.UserAgent,
 		cmd.IPAddress,
-		time.Now().Add(24*time.Hour),
+		time.Now().Add(h.tokenConfig.RefreshTokenTTL),
 	)
 
 	// Generate tokens
-	accessToken, _, err := session.GenerateTokens(user, h.tokenConfig)
+	accessToken, refreshToken, err := session.GenerateTokens(user, h.tokenConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -87,11 +117,9 @@ func (h *loginHandler) Handle(ctx context.Context, cmd LoginCommand) (*LoginResp
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	// Record successful login
-	user.RecordLogin()
-
 	return &LoginResponse{
-		User:        user,
-		AccessToken: accessToken,
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingUserRepository counts the writes of user records. Like a real
+// store it hands out copies, so changes are only kept when they are saved.
+type recordingUserRepository struct {
+	*infrastructure.MemoryUserRepository
+	updates int
+}
+
+func (r *recordingUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := r.MemoryUserRepository.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	detached := *user
+	return &detached, nil
+}
+
+func (r *recordingUserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.updates++
+	detached := *user
+	return r.MemoryUserRepository.Update(ctx, &detached)
+}
+
+func (r *recordingUserRepository) RecordFailedAttempt(ctx context.Context, id string, policy domain.LockoutPolicy) error {
+	r.updates++
+	return r.MemoryUserRepository.RecordFailedAttempt(ctx, id, policy)
+}
+
+func setupLoginTest(t *testing.T, params domain.Argon2Params) (*recordingUserRepository, *domain.User, string) {
+	users := &recordingUserRepository{MemoryUserRepository: infrastructure.NewMemoryUserRepository()}
+
+	user := domain.NewUser("doctor@example.com", "Dr. Smith", domain.RoleDoctor)
+	key, keyCred, err := domain.GenerateKey(params)
+	require.NoError(t, err)
+	user.SetKeyCredential(keyCred)
+	pinCred, err := domain.GeneratePINCredential("4821", params)
+	require.NoError(t, err)
+	user.SetPINCredential(pinCred)
+	require.NoError(t, users.Create(context.Background(), user))
+
+	return users, user, key
+}
+
+func testTokenConfig() domain.TokenConfig {
+	return domain.TokenConfig{
+		AccessTokenSecret:  []byte("access-secret-key-for-test"),
+		RefreshTokenSecret: []byte("refresh-secret-key-for-test"),
+		AccessTokenTTL:     15 * time.Minute,
+		RefreshTokenTTL:    24 * time.Hour,
+		Issuer:             "poco-clinic-test",
+	}
+}
+
+func TestLoginUpgradesWeakCredentials(t *testing.T) {
+	weak := domain.Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32}
+	users, user, key := setupLoginTest(t, weak)
+
+	handler := NewLoginHandler(users, infrastructure.NewMemorySessionRepository(), testTokenConfig(), domain.DefaultArgon2Params, domain.DefaultLockoutPolicy)
+	result, err := handler.Handle(context.Background(), LoginCommand{Email: user.Email, Key: key, PIN: "4821"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+	assert.NotEmpty(t, result.RefreshToken)
+
+	stored, err := users.GetByID(context.Background(), user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultArgon2Params, stored.KeyCredential.Params)
+	assert.Equal(t, domain.DefaultArgon2Params, stored.PINCredential.Params)
+
+	valid, needsRehash := stored.ValidateCredentials(key, "4821", domain.DefaultArgon2Params)
+	assert.True(t, valid)
+	assert.False(t, needsRehash)
+}
+
+func TestLoginKeepsCurrentCredentials(t *testing.T) {
+	users, user, key := setupLoginTest(t, domain.DefaultArgon2Params)
+	original := user.KeyCredential
+
+	handler := NewLoginHandler(users, infrastructure.NewMemorySessionRepository(), testTokenConfig(), domain.DefaultArgon2Params, domain.DefaultLockoutPolicy)
+	_, err := handler.Handle(context.Background(), LoginCommand{Email: user.Email, Key: key, PIN: "4821"})
+	require.NoError(t, err)
+
+	stored, err := users.GetByID(context.Background(), user.ID.String())
+	require.NoError(t, err)
+	assert.Same(t, original, stored.KeyCredential)
+	assert.NotNil(t, stored.LastLogin)
+}
+
+func TestLoginLockoutIsPersisted(t *testing.T) {
+	ctx := context.Background()
+	users, user, key := setupLoginTest(t, testHashParams)
+	policy := domain.LockoutPolicy{MaxAttempts: 3, Duration: time.Hour}
+	handler := NewLoginHandler(users, infrastructure.NewMemorySessionRepository(), testTokenConfig(), testHashParams, policy)
+
+	for i := 0; i < policy.MaxAttempts; i++ {
+		_, err := handler.Handle(ctx, LoginCommand{Email: user.Email, Key: key, PIN: "9999"})
+		assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+	}
+	assert.Equal(t, policy.MaxAttempts, users.updates)
+
+	stored, err := users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, policy.MaxAttempts, stored.FailedAttempts)
+	assert.True(t, stored.IsLocked())
+
+	// Even the right credentials are refused while locked
+	_, err = handler.Handle(ctx, LoginCommand{Email: user.Email, Key: key, PIN: "4821"})
+	assert.Equal(t, domain.ErrAccountLockedError, err)
+
+	// Until an administrator lifts the lock
+	_, err = NewUnlockUserHandler(users).Handle(ctx, UnlockUserCommand{UserID: user.ID.String()})
+	require.NoError(t, err)
+	_, err = handler.Handle(ctx, LoginCommand{Email: user.Email, Key: key, PIN: "4821"})
+	assert.NoError(t, err)
+}
+
+func TestLoginCountsConcurrentFailures(t *testing.T) {
+	ctx := context.Background()
+	users, user, key := setupLoginTest(t, testHashParams)
+	policy := domain.LockoutPolicy{MaxAttempts: 100, Duration: time.Hour}
+	handler := NewLoginHandler(users.MemoryUserRepository, infrastructure.NewMemorySessionRepository(), testTokenConfig(), testHashParams, policy)
+
+	// Every wrong guess counts, however they interleave
+	const attempts = 8
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := handler.Handle(ctx, LoginCommand{Email: user.Email, Key: key, PIN: "9999"})
+			assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, attempts, stored.FailedAttempts)
+}
+
+func TestLoginUnknownUser(t *testing.T) {
+	users, _, key := setupLoginTest(t, testHashParams)
+	handler := NewLoginHandler(users, infrastructure.NewMemorySessionRepository(), testTokenConfig(), testHashParams, domain.DefaultLockoutPolicy)
+
+	_, err := handler.Handle(context.Background(), LoginCommand{Email: "nobody@example.com", Key: key, PIN: "4821"})
+	assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type manageUserTestSuite struct {
+	users    *infrastructure.MemoryUserRepository
+	sessions *infrastructure.MemorySessionRepository
+	admin    *domain.User
+	user     *domain.User
+}
+
+func setupManageUserTest(t *testing.T) manageUserTestSuite {
+	ctx := context.Background()
+	users := infrastructure.NewMemoryUserRepository()
+	sessions := infrastructure.NewMemorySessionRepository()
+
+	admin := domain.NewUser("admin@example.com", "Admin", domain.RoleAdmin)
+	require.NoError(t, users.Create(ctx, admin))
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	require.NoError(t, users.Create(ctx, user))
+	require.NoError(t, sessions.Create(ctx, domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))))
+
+	return manageUserTestSuite{users: users, sessions: sessions, admin: admin, user: user}
+}
+
+func (s manageUserTestSuite) sessionCount(t *testing.T) int {
+	sessions, err := s.sessions.ListByUserID(context.Background(), s.user.ID.String())
+	require.NoError(t, err)
+	return len(sessions)
+}
+
+func TestChangeRole(t *testing.T) {
+	ctx := context.Background()
+	s := setupManageUserTest(t)
+	handler := NewChangeRoleHandler(s.users, s.sessions)
+
+	user, err := handler.Handle(ctx, ChangeRoleCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String(), Role: domain.RoleDoctor})
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleDoctor, user.Role)
+	assert.Zero(t, s.sessionCount(t), "sessions carrying the old role must end")
+
+	_, err = handler.Handle(ctx, ChangeRoleCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String(), Role: "superuser"})
+	var authErr *domain.AuthError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, domain.ErrInvalidRole, authErr.Code)
+
+	_, err = handler.Handle(ctx, ChangeRoleCommand{ActorID: s.admin.ID.String(), UserID: s.admin.ID.String(), Role: domain.RoleStaff})
+	assert.Equal(t, domain.ErrSelfModificationError, err)
+}
+
+func TestDisableAndEnableUser(t *testing.T) {
+	ctx := context.Background()
+	s := setupManageUserTest(t)
+	handler := NewSetUserDisabledHandler(s.users, s.sessions)
+
+	user, err := handler.Handle(ctx, SetUserDisabledCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String(), Disabled: true})
+	require.NoError(t, err)
+	assert.True(t, user.Disabled)
+	assert.Zero(t, s.sessionCount(t))
+
+	user, err = handler.Handle(ctx, SetUserDisabledCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String(), Disabled: false})
+	require.NoError(t, err)
+	assert.False(t, user.Disabled)
+
+	_, err = handler.Handle(ctx, SetUserDisabledCommand{ActorID: s.admin.ID.String(), UserID: s.admin.ID.String(), Disabled: true})
+	assert.Equal(t, domain.ErrSelfModificationError, err)
+}
+
+func TestLoginRejectsDisabledUser(t *testing.T) {
+	users, user, key := setupLoginTest(t, testHashParams)
+	user.Disable()
+	require.NoError(t, users.Update(context.Background(), user))
+
+	handler := NewLoginHandler(users, infrastructure.NewMemorySessionRepository(), testTokenConfig(), testHashParams, domain.DefaultLockoutPolicy)
+	_, err := handler.Handle(context.Background(), LoginCommand{Email: user.Email, Key: key, PIN: "4821"})
+	assert.Equal(t, domain.ErrAccountDisabledError, err)
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	s := setupManageUserTest(t)
+	handler := NewDeleteUserHandler(s.users, s.sessions)
+
+	err := handler.Handle(ctx, DeleteUserCommand{ActorID: s.admin.ID.String(), UserID: s.admin.ID.String()})
+	assert.Equal(t, domain.ErrSelfModificationError, err)
+
+	require.NoError(t, handler.Handle(ctx, DeleteUserCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String()}))
+	_, err = s.users.GetByID(ctx, s.user.ID.String())
+	assert.Error(t, err)
+	assert.Zero(t, s.sessionCount(t))
+
+	err = handler.Handle(ctx, DeleteUserCommand{ActorID: s.admin.ID.String(), UserID: s.user.ID.String()})
+	assert.Equal(t, domain.ErrUserNotFoundError, err)
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// RecoverKeyCommand represents a user's request to replace a lost key. A
+// one-time recovery code stands in for the key; the PIN is still required.
+type RecoverKeyCommand struct {
+	Email        string `json:"email" binding:"required,email"`
+	RecoveryCode string `json:"recoveryCode" binding:"required"`
+	PIN          string `json:"pin" binding:"required,len=4"`
+}
+
+// RecoverKeyResponse carries the new key, which is shown exactly once
+type RecoverKeyResponse struct {
+	Key                    string `json:"key"`
+	RemainingRecoveryCodes int    `json:"remainingRecoveryCodes"`
+}
+
+// RecoverKeyHandler handles self-service key recovery
+type RecoverKeyHandler interface {
+	Handle(ctx context.Context, cmd RecoverKeyCommand) (*RecoverKeyResponse, error)
+}
+
+// recoverKeyHandler implements RecoverKeyHandler
+type recoverKeyHandler struct {
+	userRepository    domain.RecoverKeyRepository
+	sessionRepository domain.RevokeSessionRepository
+	hashPolicy        domain.Argon2Params
+	lockoutPolicy     domain.LockoutPolicy
+}
+
+// NewRecoverKeyHandler creates a new handler for key recovery
+func NewRecoverKeyHandler(
+	userRepo domain.RecoverKeyRepository,
+	sessionRepo domain.RevokeSessionRepository,
+	hashPolicy domain.Argon2Params,
+	lockoutPolicy domain.LockoutPolicy,
+) RecoverKeyHandler {
+	return &recoverKeyHandler{
+		userRepository:    userRepo,
+		sessionRepository: sessionRepo,
+		hashPolicy:        hashPolicy,
+		lockoutPolicy:     lockoutPolicy,
+	}
+}
+
+// Handle processes the recover key command. The recovery code is consumed,
+// the old key stops working and every session of the user is ended.
+func (h *recoverKeyHandler) Handle(ctx context.Context, cmd RecoverKeyCommand) (*RecoverKeyResponse, error) {
+	user, err := h.userRepository.GetByEmail(ctx, cmd.Email)
+	if err != nil || user == nil {
+		return nil, domain.ErrInvalidCredentialsError
+	}
+
+	if user.IsLocked() {
+		return nil, domain.ErrAccountLockedError
+	}
+
+	previousCodes := user.RecoveryCodes
+	pinValid := false
+	if user.PINCredential != nil {
+		pinValid, _ = user.PINCredential.Validate(cmd.PIN, h.hashPolicy)
+	}
+	if !pinValid || !user.ConsumeRecoveryCode(cmd.RecoveryCode, h.hashPolicy) {
+		if err := h.userRepository.RecordFailedAttempt(ctx, user.ID.String(), h.lockoutPolicy); err != nil {
+			return nil, fmt.Errorf("failed to record failed attempt: %w", err)
+		}
+		return nil, domain.ErrInvalidCredentialsError
+	}
+
+	if user.Disabled {
+		return nil, domain.ErrAccountDisabledError
+	}
+
+	// Use up the code before anything else, so that a concurrent recovery
+	// with the same code fails
+	if err := h.userRepository.SwapRecoveryCodes(ctx, user.ID.String(), previousCodes, user.RecoveryCodes); err != nil {
+		if err == domain.ErrInvalidCredentialsError {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	key, err := user.ResetKey(h.hashPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	user.ResetFailedAttempts()
+
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save key: %w", err)
+	}
+	if err := h.sessionRepository.DeleteByUserID(ctx, user.ID.String()); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return &RecoverKeyResponse{
+		Key:                    key,
+		RemainingRecoveryCodes: len(user.RecoveryCodes),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// RefreshCommand represents the command to exchange a refresh token for new tokens
+type RefreshCommand struct {
+	RefreshToken string `json:"-"`
+	UserAgent    string `json:"-"`
+	IPAddress    string `json:"-"`
+}
+
+// RefreshResponse represents the refresh response. As with login, the new
+// refresh token is delivered in a cookie rather than in the body.
+type RefreshResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"-"`
+}
+
+// RefreshHandler handles refresh token rotation
+type RefreshHandler interface {
+	Handle(ctx context.Context, cmd RefreshCommand) (*RefreshResponse, error)
+}
+
+// refreshHandler implements RefreshHandler
+type refreshHandler struct {
+	sessionRepository domain.RefreshSessionRepository
+	userRepository    domain.GetUserRepository
+	tokenConfig       domain.TokenConfig
+}
+
+// NewRefreshHandler creates a new handler for refresh token rotation
+func NewRefreshHandler(
+	sessionRepo domain.RefreshSessionRepository,
+	userRepo domain.GetUserRepository,
+	tokenConfig domain.TokenConfig,
+) RefreshHandler {
+	return &refreshHandler{
+		sessionRepository: sessionRepo,
+		userRepository:    userRepo,
+		tokenConfig:       tokenConfig,
+	}
+}
+
+// Handle processes the refresh command. Every refresh token can be used
+// exactly once; presenting a token that has already been rotated revokes the
+// whole session, since either the client or an attacker holds a stolen copy.
+func (h *refreshHandler) Handle(ctx context.Context, cmd RefreshCommand) (*RefreshResponse, error) {
+	claims, err := domain.ValidateToken(cmd.RefreshToken, domain.TokenTypeRefresh, h.tokenConfig.RefreshTokenSecret)
+	if err != nil {
+		return nil, domain.ErrInvalidTokenError
+	}
+
+	session, err := h.sessionRepository.GetByRefreshToken(ctx, cmd.RefreshToken)
+	if err != nil || session == nil {
+		// A validly signed token that is no longer current has been replayed
+		if stale, err := h.sessionRepository.GetByID(ctx, claims.SessionID); err == nil && stale != nil {
+			if err := h.sessionRepository.Delete(ctx, stale.ID.String()); err != nil {
+				return nil, fmt.Errorf("failed to revoke session: %w", err)
+			}
+			return nil, domain.ErrTokenReusedError
+		}
+		return nil, domain.ErrInvalidTokenError
+	}
+
+	if session.IsExpired() {
+		if err := h.sessionRepository.Delete(ctx, session.ID.String()); err != nil {
+			return nil, fmt.Errorf("failed to delete expired session: %w", err)
+		}
+		return nil, domain.ErrSessionExpiredError
+	}
+
+	user, err := h.userRepository.GetByID(ctx, session.UserID.String())
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+	if user.Disabled {
+		if err := h.sessionRepository.Delete(ctx, session.ID.String()); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		return nil, domain.ErrAccountDisabledError
+	}
+
+	// Rotate the refresh token and extend the session
+	accessToken, refreshToken, err := session.GenerateTokens(user, h.tokenConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+	session.Refresh(h.tokenConfig.RefreshTokenTTL)
+	session.UserAgent = cmd.UserAgent
+	session.IPAddress = cmd.IPAddress
+
+	// A concurrent refresh with the same token may have rotated it since it
+	// was looked up; only one of them can win. The session may also have
+	// been revoked meanwhile, which leaves the token invalid.
+	if err := h.sessionRepository.Rotate(ctx, session, cmd.RefreshToken); err != nil {
+		if err == domain.ErrSessionNotFoundError {
+			return nil, domain.ErrInvalidTokenError
+		}
+		if err == domain.ErrTokenReusedError {
+			if err := h.sessionRepository.Delete(ctx, session.ID.String()); err != nil {
+				return nil, fmt.Errorf("failed to revoke session: %w", err)
+			}
+			return nil, domain.ErrTokenReusedError
+		}
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return &RefreshResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type refreshTestSuite struct {
+	users    *infrastructure.MemoryUserRepository
+	sessions *infrastructure.MemorySessionRepository
+	handler  RefreshHandler
+	session  *domain.Session
+	token    string
+}
+
+func setupRefreshTest(t *testing.T) refreshTestSuite {
+	config := testTokenConfig()
+
+	users := infrastructure.NewMemoryUserRepository()
+	sessions := infrastructure.NewMemorySessionRepository()
+
+	user := domain.NewUser("doctor@example.com", "Dr. Smith", domain.RoleDoctor)
+	require.NoError(t, users.Create(context.Background(), user))
+
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(config.RefreshTokenTTL))
+	_, token, err := session.GenerateTokens(user, config)
+	require.NoError(t, err)
+	require.NoError(t, sessions.Create(context.Background(), session))
+
+	return refreshTestSuite{
+		users:    users,
+		sessions: sessions,
+		handler:  NewRefreshHandler(sessions, users, config),
+		session:  session,
+		token:    token,
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	suite := setupRefreshTest(t)
+	ctx := context.Background()
+
+	result, err := suite.handler.Handle(ctx, RefreshCommand{RefreshToken: suite.token})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+	assert.NotEqual(t, suite.token, result.RefreshToken)
+
+	// The new token is now the only valid one
+	session, err := suite.sessions.GetByRefreshToken(ctx, result.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, suite.session.ID, session.ID)
+
+	_, err = suite.sessions.GetByRefreshToken(ctx, suite.token)
+	assert.Error(t, err)
+
+	// And it can be rotated again
+	_, err = suite.handler.Handle(ctx, RefreshCommand{RefreshToken: result.RefreshToken})
+	assert.NoError(t, err)
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	suite := setupRefreshTest(t)
+	ctx := context.Background()
+
+	result, err := suite.handler.Handle(ctx, RefreshCommand{RefreshToken: suite.token})
+	require.NoError(t, err)
+
+	// Replaying the rotated token revokes the session
+	_, err = suite.handler.Handle(ctx, RefreshCommand{RefreshToken: suite.token})
+	assert.Equal(t, domain.ErrTokenReusedError, err)
+
+	_, err = suite.sessions.GetByID(ctx, suite.session.ID.String())
+	assert.Error(t, err)
+
+	// The legitimately rotated token is no longer usable either
+	_, err = suite.handler.Handle(ctx, RefreshCommand{RefreshToken: result.RefreshToken})
+	assert.Equal(t, domain.ErrInvalidTokenError, err)
+}
+
+func TestRefreshRejectsInvalidToken(t *testing.T) {
+	suite := setupRefreshTest(t)
+
+	_, err := suite.handler.Handle(context.Background(), RefreshCommand{RefreshToken: "not-a-token"})
+	assert.Equal(t, domain.ErrInvalidTokenError, err)
+}
+
+func TestRefreshConcurrentReuseRevokesSession(t *testing.T) {
+	suite := setupRefreshTest(t)
+	ctx := context.Background()
+
+	// Both refreshes present the same token; at most one may rotate it
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = suite.handler.Handle(ctx, RefreshCommand{RefreshToken: suite.token})
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Contains(t, errs, domain.ErrTokenReusedError)
+	_, err := suite.sessions.GetByID(ctx, suite.session.ID.String())
+	assert.Error(t, err)
+}
+
+func TestRefreshRejectsExpiredSession(t *testing.T) {
+	suite := setupRefreshTest(t)
+	suite.session.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, suite.sessions.Create(context.Background(), suite.session))
+
+	_, err := suite.handler.Handle(context.Background(), RefreshCommand{RefreshToken: suite.token})
+	assert.Equal(t, domain.ErrSessionExpiredError, err)
+}
+
+// revokingSessions revokes the session right before rotating it, as a
+// logout racing the refresh would
+type revokingSessions struct {
+	*infrastructure.MemorySessionRepository
+}
+
+func (r revokingSessions) Rotate(ctx context.Context, session *domain.Session, previousToken string) error {
+	if err := r.Delete(ctx, session.ID.String()); err != nil {
+		return err
+	}
+	return r.MemorySessionRepository.Rotate(ctx, session, previousToken)
+}
+
+func TestRefreshRejectsSessionRevokedDuringRefresh(t *testing.T) {
+	suite := setupRefreshTest(t)
+
+	handler := NewRefreshHandler(revokingSessions{suite.sessions}, suite.users, testTokenConfig())
+	_, err := handler.Handle(context.Background(), RefreshCommand{RefreshToken: suite.token})
+	assert.Equal(t, domain.ErrInvalidTokenError, err)
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// RequestEmergencyAccessCommand represents a break-the-glass request. The
+// reason is mandatory and is shown to the administrators who review it.
+type RequestEmergencyAccessCommand struct {
+	UserID    string `json:"-"`
+	SessionID string `json:"-"`
+	Reason    string `json:"reason" binding:"required"`
+	IPAddress string `json:"-"`
+}
+
+// RequestEmergencyAccessResponse carries the elevated access token, which
+// expires together with the grant
+type RequestEmergencyAccessResponse struct {
+	AccessToken string                  `json:"accessToken"`
+	Grant       *domain.EmergencyAccess `json:"grant"`
+}
+
+// RequestEmergencyAccessHandler handles break-the-glass requests
+type RequestEmergencyAccessHandler interface {
+	Handle(ctx context.Context, cmd RequestEmergencyAccessCommand) (*RequestEmergencyAccessResponse, error)
+}
+
+// requestEmergencyAccessHandler implements RequestEmergencyAccessHandler
+type requestEmergencyAccessHandler struct {
+	userRepository      domain.GetUserRepository
+	sessionRepository   domain.GetSessionRepository
+	emergencyRepository domain.RequestEmergencyAccessRepository
+	tokenConfig         domain.TokenConfig
+	policy              domain.EmergencyAccessPolicy
+}
+
+// NewRequestEmergencyAccessHandler creates a new handler for break-the-glass requests
+func NewRequestEmergencyAccessHandler(
+	userRepo domain.GetUserRepository,
+	sessionRepo domain.GetSessionRepository,
+	emergencyRepo domain.RequestEmergencyAccessRepository,
+	tokenConfig domain.TokenConfig,
+	policy domain.EmergencyAccessPolicy,
+) RequestEmergencyAccessHandler {
+	return &requestEmergencyAccessHandler{
+		userRepository:      userRepo,
+		sessionRepository:   sessionRepo,
+		emergencyRepository: emergencyRepo,
+		tokenConfig:         tokenConfig,
+		policy:              policy,
+	}
+}
+
+// Handle processes the request emergency access command. The grant is bound
+// to the caller's session and stored before the elevated token is issued.
+func (h *requestEmergencyAccessHandler) Handle(ctx context.Context, cmd RequestEmergencyAccessCommand) (*RequestEmergencyAccessResponse, error) {
+	user, err := h.userRepository.GetByID(ctx, cmd.UserID)
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+	if user.Disabled {
+		return nil, domain.ErrAccountDisabledError
+	}
+
+	session, err := h.sessionRepository.GetByID(ctx, cmd.SessionID)
+	if err != nil || session == nil {
+		return nil, domain.ErrSessionNotFoundError
+	}
+	if session.IsExpired() {
+		return nil, domain.ErrSessionExpiredError
+	}
+
+	grant, err := domain.NewEmergencyAccess(user.ID, session.ID, cmd.Reason, cmd.IPAddress, h.policy.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.emergencyRepository.Create(ctx, grant); err != nil {
+		return nil, fmt.Errorf("failed to grant emergency access: %w", err)
+	}
+
+	token, err := session.GenerateEmergencyToken(user, grant, h.tokenConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &RequestEmergencyAccessResponse{AccessToken: token, Grant: grant}, nil
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// ResetKeyCommand represents an administrator's request to issue a new key
+// for a user who lost theirs
+type ResetKeyCommand struct {
+	UserID string `json:"-"`
+}
+
+// ResetKeyResponse carries the new key, which is shown exactly once
+type ResetKeyResponse struct {
+	Key string `json:"key"`
+}
+
+// ResetKeyHandler handles administrative key resets
+type ResetKeyHandler interface {
+	Handle(ctx context.Context, cmd ResetKeyCommand) (*ResetKeyResponse, error)
+}
+
+// resetKeyHandler implements ResetKeyHandler
+type resetKeyHandler struct {
+	userRepository    domain.ResetKeyRepository
+	sessionRepository domain.RevokeSessionRepository
+	hashPolicy        domain.Argon2Params
+}
+
+// NewResetKeyHandler creates a new handler for key resets
+func NewResetKeyHandler(
+	userRepo domain.ResetKeyRepository,
+	sessionRepo domain.RevokeSessionRepository,
+	hashPolicy domain.Argon2Params,
+) ResetKeyHandler {
+	return &resetKeyHandler{
+		userRepository:    userRepo,
+		sessionRepository: sessionRepo,
+		hashPolicy:        hashPolicy,
+	}
+}
+
+// Handle processes the reset key command. The old key stops working and
+// every session of the user is ended.
+func (h *resetKeyHandler) Handle(ctx context.Context, cmd ResetKeyCommand) (*ResetKeyResponse, error) {
+	user, err := h.userRepository.GetByID(ctx, cmd.UserID)
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+
+	key, err := user.ResetKey(h.hashPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save key: %w", err)
+	}
+	if err := h.sessionRepository.DeleteByUserID(ctx, cmd.UserID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return &ResetKeyResponse{Key: key}, nil
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHashParams keeps hashing cheap in tests that create many credentials
+var testHashParams = domain.Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32}
+
+type keyTestSuite struct {
+	users    *infrastructure.MemoryUserRepository
+	sessions *infrastructure.MemorySessionRepository
+	created  *CreateUserResponse
+}
+
+func setupKeyTest(t *testing.T) keyTestSuite {
+	ctx := context.Background()
+	users := infrastructure.NewMemoryUserRepository()
+	sessions := infrastructure.NewMemorySessionRepository()
+
+	created, err := NewCreateUserHandler(users, testHashParams).Handle(ctx, CreateUserCommand{
+		Email: "doctor@example.com",
+		Name:  "Dr. Smith",
+		Role:  domain.RoleDoctor,
+	})
+	require.NoError(t, err)
+	require.Len(t, created.RecoveryCodes, domain.RecoveryCodeCount)
+
+	session := domain.NewSession(created.User.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	require.NoError(t, sessions.Create(ctx, session))
+
+	return keyTestSuite{users: users, sessions: sessions, created: created}
+}
+
+func TestResetKey(t *testing.T) {
+	ctx := context.Background()
+	s := setupKeyTest(t)
+	handler := NewResetKeyHandler(s.users, s.sessions, testHashParams)
+
+	result, err := handler.Handle(ctx, ResetKeyCommand{UserID: s.created.User.ID.String()})
+	require.NoError(t, err)
+	assert.NotEqual(t, s.created.Key, result.Key)
+
+	user, err := s.users.GetByID(ctx, s.created.User.ID.String())
+	require.NoError(t, err)
+	valid, _ := user.ValidateCredentials(s.created.Key, domain.InitialPIN, testHashParams)
+	assert.False(t, valid)
+	valid, _ = user.ValidateCredentials(result.Key, domain.InitialPIN, testHashParams)
+	assert.True(t, valid)
+
+	sessions, err := s.sessions.ListByUserID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, err = handler.Handle(ctx, ResetKeyCommand{UserID: "missing"})
+	assert.Equal(t, domain.ErrUserNotFoundError, err)
+}
+
+func TestRecoverKey(t *testing.T) {
+	ctx := context.Background()
+	s := setupKeyTest(t)
+	handler := NewRecoverKeyHandler(s.users, s.sessions, testHashParams, domain.DefaultLockoutPolicy)
+	code := s.created.RecoveryCodes[0]
+
+	// The PIN is still required
+	_, err := handler.Handle(ctx, RecoverKeyCommand{Email: "doctor@example.com", RecoveryCode: code, PIN: "9999"})
+	assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+	user, err := s.users.GetByEmail(ctx, "doctor@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.FailedAttempts)
+
+	result, err := handler.Handle(ctx, RecoverKeyCommand{Email: "doctor@example.com", RecoveryCode: code, PIN: domain.InitialPIN})
+	require.NoError(t, err)
+	assert.Equal(t, domain.RecoveryCodeCount-1, result.RemainingRecoveryCodes)
+
+	user, err = s.users.GetByEmail(ctx, "doctor@example.com")
+	require.NoError(t, err)
+	valid, _ := user.ValidateCredentials(result.Key, domain.InitialPIN, testHashParams)
+	assert.True(t, valid)
+	assert.Equal(t, 0, user.FailedAttempts)
+
+	sessions, err := s.sessions.ListByUserID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// Recovery codes are single use
+	_, err = handler.Handle(ctx, RecoverKeyCommand{Email: "doctor@example.com", RecoveryCode: code, PIN: domain.InitialPIN})
+	assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+}
+
+func TestRecoverKeyConcurrentUseOfCode(t *testing.T) {
+	ctx := context.Background()
+	s := setupKeyTest(t)
+	handler := NewRecoverKeyHandler(s.users, s.sessions, testHashParams, domain.DefaultLockoutPolicy)
+	code := s.created.RecoveryCodes[1]
+
+	// Only one of the recoveries presenting the same code may succeed
+	const attempts = 4
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := handler.Handle(ctx, RecoverKeyCommand{Email: "doctor@example.com", RecoveryCode: code, PIN: domain.InitialPIN})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else {
+				assert.Equal(t, domain.ErrInvalidCredentialsError, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded)
+
+	user, err := s.users.GetByEmail(ctx, "doctor@example.com")
+	require.NoError(t, err)
+	assert.Len(t, user.RecoveryCodes, domain.RecoveryCodeCount-1)
+}
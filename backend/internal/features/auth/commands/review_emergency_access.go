@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/google/uuid"
+)
+
+// ReviewEmergencyAccessCommand represents an administrator's review of a
+// break-the-glass grant
+type ReviewEmergencyAccessCommand struct {
+	ReviewerID string `json:"-"`
+	GrantID    string `json:"-"`
+	Note       string `json:"note"`
+}
+
+// ReviewEmergencyAccessHandler handles reviews of emergency access
+type ReviewEmergencyAccessHandler interface {
+	Handle(ctx context.Context, cmd ReviewEmergencyAccessCommand) (*domain.EmergencyAccess, error)
+}
+
+// reviewEmergencyAccessHandler implements ReviewEmergencyAccessHandler
+type reviewEmergencyAccessHandler struct {
+	emergencyRepository domain.ReviewEmergencyAccessRepository
+}
+
+// NewReviewEmergencyAccessHandler creates a new handler for reviewing emergency access
+func NewReviewEmergencyAccessHandler(repo domain.ReviewEmergencyAccessRepository) ReviewEmergencyAccessHandler {
+	return &reviewEmergencyAccessHandler{
+		emergencyRepository: repo,
+	}
+}
+
+// Handle processes the review emergency access command. Reviewing again
+// replaces the earlier review.
+func (h *reviewEmergencyAccessHandler) Handle(ctx context.Context, cmd ReviewEmergencyAccessCommand) (*domain.EmergencyAccess, error) {
+	reviewerID, err := uuid.Parse(cmd.ReviewerID)
+	if err != nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+
+	grant, err := h.emergencyRepository.GetByID(ctx, cmd.GrantID)
+	if err != nil || grant == nil {
+		return nil, domain.ErrEmergencyAccessNotFoundError
+	}
+
+	grant.Review(reviewerID, cmd.Note)
+	if err := h.emergencyRepository.Update(ctx, grant); err != nil {
+		return nil, fmt.Errorf("failed to review emergency access: %w", err)
+	}
+	return grant, nil
+}
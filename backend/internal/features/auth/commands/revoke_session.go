@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// RevokeSessionCommand represents the command to end one of a user's sessions
+type RevokeSessionCommand struct {
+	UserID    string `json:"-"`
+	SessionID string `json:"-"`
+}
+
+// RevokeSessionHandler handles revocation of a single session
+type RevokeSessionHandler interface {
+	Handle(ctx context.Context, cmd RevokeSessionCommand) error
+}
+
+// revokeSessionHandler implements RevokeSessionHandler
+type revokeSessionHandler struct {
+	sessionRepository domain.RevokeSessionRepository
+}
+
+// NewRevokeSessionHandler creates a new handler for session revocation
+func NewRevokeSessionHandler(repo domain.RevokeSessionRepository) RevokeSessionHandler {
+	return &revokeSessionHandler{
+		sessionRepository: repo,
+	}
+}
+
+// Handle processes the revoke session command. Sessions belonging to another
+// user are reported as not found so their existence is not disclosed.
+func (h *revokeSessionHandler) Handle(ctx context.Context, cmd RevokeSessionCommand) error {
+	session, err := h.sessionRepository.GetByID(ctx, cmd.SessionID)
+	if err != nil || session == nil || session.UserID.String() != cmd.UserID {
+		return domain.ErrSessionNotFoundError
+	}
+
+	if err := h.sessionRepository.Delete(ctx, cmd.SessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	sessions := infrastructure.NewMemorySessionRepository()
+	handler := NewRevokeSessionHandler(sessions)
+
+	owner := uuid.New()
+	session := domain.NewSession(owner, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	require.NoError(t, sessions.Create(ctx, session))
+
+	// Another user cannot revoke the session
+	err := handler.Handle(ctx, RevokeSessionCommand{UserID: uuid.NewString(), SessionID: session.ID.String()})
+	assert.Equal(t, domain.ErrSessionNotFoundError, err)
+
+	// The owner can
+	err = handler.Handle(ctx, RevokeSessionCommand{UserID: owner.String(), SessionID: session.ID.String()})
+	assert.NoError(t, err)
+
+	_, err = sessions.GetByID(ctx, session.ID.String())
+	assert.Error(t, err)
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	ctx := context.Background()
+	users := infrastructure.NewMemoryUserRepository()
+	sessions := infrastructure.NewMemorySessionRepository()
+	handler := NewRevokeUserSessionsHandler(users, sessions)
+
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	require.NoError(t, users.Create(ctx, user))
+	other := uuid.New()
+
+	for _, userID := range []uuid.UUID{user.ID, user.ID, other} {
+		session := domain.NewSession(userID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+		require.NoError(t, sessions.Create(ctx, session))
+	}
+
+	require.NoError(t, handler.Handle(ctx, RevokeUserSessionsCommand{UserID: user.ID.String()}))
+
+	remaining, err := sessions.ListByUserID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	remaining, err = sessions.ListByUserID(ctx, other.String())
+	require.NoError(t, err)
+	assert.Len(t, remaining, 1)
+
+	err = handler.Handle(ctx, RevokeUserSessionsCommand{UserID: uuid.NewString()})
+	assert.Equal(t, domain.ErrUserNotFoundError, err)
+}
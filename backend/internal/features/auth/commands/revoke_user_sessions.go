@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// RevokeUserSessionsCommand represents the command to end all sessions of a user
+type RevokeUserSessionsCommand struct {
+	UserID string `json:"-"`
+}
+
+// RevokeUserSessionsHandler handles revocation of every session of a user
+type RevokeUserSessionsHandler interface {
+	Handle(ctx context.Context, cmd RevokeUserSessionsCommand) error
+}
+
+// revokeUserSessionsHandler implements RevokeUserSessionsHandler
+type revokeUserSessionsHandler struct {
+	userRepository    domain.GetUserRepository
+	sessionRepository domain.RevokeSessionRepository
+}
+
+// NewRevokeUserSessionsHandler creates a new handler for revoking all sessions of a user
+func NewRevokeUserSessionsHandler(userRepo domain.GetUserRepository, sessionRepo domain.RevokeSessionRepository) RevokeUserSessionsHandler {
+	return &revokeUserSessionsHandler{
+		userRepository:    userRepo,
+		sessionRepository: sessionRepo,
+	}
+}
+
+// Handle processes the revoke user sessions command
+func (h *revokeUserSessionsHandler) Handle(ctx context.Context, cmd RevokeUserSessionsCommand) error {
+	if user, err := h.userRepository.GetByID(ctx, cmd.UserID); err != nil || user == nil {
+		return domain.ErrUserNotFoundError
+	}
+
+	if err := h.sessionRepository.DeleteByUserID(ctx, cmd.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
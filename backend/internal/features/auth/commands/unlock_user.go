@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// UnlockUserCommand represents an administrator's request to lift a lockout
+type UnlockUserCommand struct {
+	UserID string `json:"-"`
+}
+
+// UnlockUserHandler handles administrative unlocking of accounts
+type UnlockUserHandler interface {
+	Handle(ctx context.Context, cmd UnlockUserCommand) (*domain.User, error)
+}
+
+// unlockUserHandler implements UnlockUserHandler
+type unlockUserHandler struct {
+	userRepository domain.UnlockUserRepository
+}
+
+// NewUnlockUserHandler creates a new handler for unlocking users
+func NewUnlockUserHandler(repo domain.UnlockUserRepository) UnlockUserHandler {
+	return &unlockUserHandler{
+		userRepository: repo,
+	}
+}
+
+// Handle processes the unlock user command. The failed attempts counter is
+// reset along with the lock.
+func (h *unlockUserHandler) Handle(ctx context.Context, cmd UnlockUserCommand) (*domain.User, error) {
+	user, err := h.userRepository.GetByID(ctx, cmd.UserID)
+	if err != nil || user == nil {
+		return nil, domain.ErrUserNotFoundError
+	}
+
+	user.ResetFailedAttempts()
+	if err := h.userRepository.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to unlock user: %w", err)
+	}
+	return user, nil
+}
@@ -3,20 +3,52 @@ package domain
 import (
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding/base32"
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
+// AlgorithmArgon2id identifies credentials hashed with Argon2id
+const AlgorithmArgon2id = "argon2id"
+
+// Argon2Params holds the cost parameters of an Argon2id hash
+type Argon2Params struct {
+	Time      uint32 // number of passes
+	Memory    uint32 // memory in KiB
+	Threads   uint8
+	KeyLength uint32
+}
+
+// DefaultArgon2Params are the default hashing policy. They are also the
+// parameters of every credential stored before the encoding recorded them.
+var DefaultArgon2Params = Argon2Params{
+	Time:      1,
+	Memory:    64 * 1024,
+	Threads:   4,
+	KeyLength: 32,
+}
+
+// WeakerThan reports whether hashes made with p are cheaper to brute force
+// than hashes made with policy. Parallelism does not change the total work
+// and is not compared.
+func (p Argon2Params) WeakerThan(policy Argon2Params) bool {
+	return p.Time < policy.Time || p.Memory < policy.Memory || p.KeyLength < policy.KeyLength
+}
+
 // Credential represents a hashed authentication credential (key or PIN)
 type Credential struct {
-	Hash []byte
-	Salt []byte
+	Algorithm string
+	Params    Argon2Params
+	Hash      []byte
+	Salt      []byte
 }
 
-// NewCredential creates a new credential from a plaintext value
-func NewCredential(value string) (*Credential, error) {
+// NewCredential creates a new credential from a plaintext value, hashed
+// with the given parameters
+func NewCredential(value string, params Argon2Params) (*Credential, error) {
 	// Generate random salt
 	salt := make([]byte, 16)
 	if _, err := rand.Read(salt); err != nil {
@@ -24,54 +56,150 @@ func NewCredential(value string) (*Credential, error) {
 	}
 
 	// Hash the value with the salt
-	hash := hashValue(value, salt)
+	hash := hashValue(value, salt, params)
 
 	return &Credential{
-		Hash: hash,
-		Salt: salt,
+		Algorithm: AlgorithmArgon2id,
+		Params:    params,
+		Hash:      hash,
+		Salt:      salt,
 	}, nil
 }
 
-// Validate checks if the provided value matches this credential
-func (c *Credential) Validate(value string) bool {
-	hash := hashValue(value, c.Salt)
-	return subtle.ConstantTimeCompare(hash, c.Hash) == 1
+// Validate checks if the provided value matches this credential. It also
+// reports whether the credential was hashed with weaker parameters than the
+// policy and should be re-hashed now that the plaintext is known.
+func (c *Credential) Validate(value string, policy Argon2Params) (valid bool, needsRehash bool) {
+	hash := hashValue(value, c.Salt, c.Params)
+	if subtle.ConstantTimeCompare(hash, c.Hash) != 1 {
+		return false, false
+	}
+	return true, c.Algorithm != AlgorithmArgon2id || c.Params.WeakerThan(policy)
 }
 
 // hashValue creates a cryptographic hash of the value using the provided salt
-func hashValue(value string, salt []byte) []byte {
+func hashValue(value string, salt []byte, params Argon2Params) []byte {
 	// Use Argon2id for password hashing
 	return argon2.IDKey(
 		[]byte(value),
 		salt,
-		1,       // time cost
-		64*1024, // memory cost
-		4,       // threads
-		32,      // key length
+		params.Time,
+		params.Memory,
+		params.Threads,
+		params.KeyLength,
 	)
 }
 
-// String returns a base64 encoded representation of the credential
+// String encodes the credential in the PHC string format, recording the
+// algorithm and its parameters alongside salt and hash:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
 func (c *Credential) String() string {
-	hash := base64.StdEncoding.EncodeToString(c.Hash)
-	salt := base64.StdEncoding.EncodeToString(c.Salt)
-	return hash + ":" + salt
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		c.Params.Memory,
+		c.Params.Time,
+		c.Params.Threads,
+		base64.RawStdEncoding.EncodeToString(c.Salt),
+		base64.RawStdEncoding.EncodeToString(c.Hash),
+	)
 }
 
-// ParseCredential parses a base64 encoded credential string
+// ParseCredential parses a credential encoded by String. The unversioned
+// "hash:salt" encoding of earlier releases is accepted as well and is read
+// with DefaultArgon2Params.
 func ParseCredential(s string) (*Credential, error) {
-	// TODO: Implement if needed for persistence
-	return nil, nil
+	if !strings.HasPrefix(s, "$") {
+		return parseLegacyCredential(s)
+	}
+
+	parts := strings.Split(s, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return nil, fmt.Errorf("invalid credential encoding")
+	}
+	if parts[1] != AlgorithmArgon2id {
+		return nil, fmt.Errorf("unsupported credential algorithm %q", parts[1])
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid credential version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, fmt.Errorf("invalid credential parameters: %w", err)
+	}
+	if params.Memory == 0 || params.Time == 0 || params.Threads == 0 {
+		return nil, fmt.Errorf("invalid credential parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential salt: %w", err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential hash: %w", err)
+	}
+	if len(salt) == 0 || len(hash) == 0 {
+		return nil, fmt.Errorf("invalid credential encoding")
+	}
+	params.KeyLength = uint32(len(hash))
+
+	return &Credential{
+		Algorithm: AlgorithmArgon2id,
+		Params:    params,
+		Hash:      hash,
+		Salt:      salt,
+	}, nil
+}
+
+// parseLegacyCredential parses the unversioned "hash:salt" encoding
+func parseLegacyCredential(s string) (*Credential, error) {
+	hashPart, saltPart, found := strings.Cut(s, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid credential encoding")
+	}
+
+	hash, err := base64.StdEncoding.DecodeString(hashPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential hash: %w", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential salt: %w", err)
+	}
+	if len(salt) == 0 || len(hash) == 0 {
+		return nil, fmt.Errorf("invalid credential encoding")
+	}
+
+	return NewLegacyCredential(hash, salt), nil
+}
+
+// NewLegacyCredential wraps a hash and salt stored before credentials
+// recorded their parameters
+func NewLegacyCredential(hash, salt []byte) *Credential {
+	return &Credential{
+		Algorithm: AlgorithmArgon2id,
+		Params:    DefaultArgon2Params,
+		Hash:      hash,
+		Salt:      salt,
+	}
 }
 
 // GenerateKey generates a new 64-bit key and returns its credentials
-func GenerateKey() (string, *Credential, error) {
+func GenerateKey(params Argon2Params) (string, *Credential, error) {
 	key := make([]byte, 8) // 8 bytes = 64 bits
 	if _, err := rand.Read(key); err != nil {
 		return "", nil, fmt.Errorf("failed to generate key: %w", err)
 	}
 
-	cred, err := NewCredential(base64.URLEncoding.EncodeToString(key))
+	cred, err := NewCredential(base64.URLEncoding.EncodeToString(key), params)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to create credential: %w", err)
 	}
@@ -80,10 +208,78 @@ func GenerateKey() (string, *Credential, error) {
 }
 
 // GeneratePINCredential generates credentials for a PIN
-func GeneratePINCredential(pin string) (*Credential, error) {
+func GeneratePINCredential(pin string, params Argon2Params) (*Credential, error) {
 	if len(pin) != 4 {
 		return nil, fmt.Errorf("PIN must be exactly 4 digits")
 	}
 
-	return NewCredential(pin)
+	return NewCredential(pin, params)
+}
+
+// InitialPIN is the PIN assigned to new users. It is deliberately trivial;
+// users must replace it before they can do anything else.
+const InitialPIN = "0000"
+
+// ValidatePIN checks a PIN chosen by a user against the PIN policy. PINs
+// must be four digits and must not be guessable: repeated digits such as
+// 0000 and straight runs such as 1234 or 9876 are rejected.
+func ValidatePIN(pin string) error {
+	if len(pin) != 4 {
+		return ErrWeakPINError("PIN must be exactly 4 digits")
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return ErrWeakPINError("PIN must only contain digits")
+		}
+	}
+
+	repeated, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		step := int(pin[i]) - int(pin[i-1])
+		repeated = repeated && step == 0
+		ascending = ascending && step == 1
+		descending = descending && step == -1
+	}
+	if repeated {
+		return ErrWeakPINError("PIN must not repeat a single digit")
+	}
+	if ascending || descending {
+		return ErrWeakPINError("PIN must not be a sequence of consecutive digits")
+	}
+	return nil
+}
+
+// RecoveryCodeCount is the number of recovery codes issued at once
+const RecoveryCodeCount = 10
+
+// recoveryCodeEncoding renders recovery codes in unambiguous upper case
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes generates a set of one-time recovery codes of the
+// form XXXXX-XXXXX and returns them together with their credentials
+func GenerateRecoveryCodes(params Argon2Params) ([]string, []*Credential, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	creds := make([]*Credential, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, 6) // 48 bits, 10 base32 characters
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(raw)
+
+		cred, err := NewCredential(code, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create credential: %w", err)
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		creds = append(creds, cred)
+	}
+	return codes, creds, nil
+}
+
+// normalizeRecoveryCode strips separators and case from a recovery code as
+// typed by a user
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
 }
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialEncodingRoundTrip(t *testing.T) {
+	cred, err := NewCredential("4821", DefaultArgon2Params)
+	require.NoError(t, err)
+
+	encoded := cred.String()
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=1,p=4$"))
+
+	parsed, err := ParseCredential(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, cred, parsed)
+	valid, _ := parsed.Validate("4821", DefaultArgon2Params)
+	assert.True(t, valid)
+	valid, _ = parsed.Validate("4822", DefaultArgon2Params)
+	assert.False(t, valid)
+	assert.Equal(t, encoded, parsed.String())
+}
+
+func TestCredentialEncodingRecordsParameters(t *testing.T) {
+	params := Argon2Params{Time: 2, Memory: 32 * 1024, Threads: 2, KeyLength: 32}
+	salt := []byte("0123456789abcdef")
+	cred := &Credential{
+		Algorithm: AlgorithmArgon2id,
+		Params:    params,
+		Hash:      hashValue("secret", salt, params),
+		Salt:      salt,
+	}
+
+	parsed, err := ParseCredential(cred.String())
+	require.NoError(t, err)
+	assert.Equal(t, params, parsed.Params)
+	valid, _ := parsed.Validate("secret", params)
+	assert.True(t, valid)
+}
+
+func TestParseLegacyCredential(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	hash := hashValue("4821", salt, DefaultArgon2Params)
+	legacy := base64.StdEncoding.EncodeToString(hash) + ":" + base64.StdEncoding.EncodeToString(salt)
+
+	parsed, err := ParseCredential(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultArgon2Params, parsed.Params)
+	valid, _ := parsed.Validate("4821", DefaultArgon2Params)
+	assert.True(t, valid)
+}
+
+func TestParseCredentialRejectsInvalidInput(t *testing.T) {
+	cred, err := NewCredential("4821", DefaultArgon2Params)
+	require.NoError(t, err)
+	parts := strings.Split(cred.String(), "$")
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ""},
+		{name: "unknown algorithm", input: "$bcrypt$v=19$m=65536,t=1,p=4$" + parts[4] + "$" + parts[5]},
+		{name: "unknown version", input: "$argon2id$v=16$m=65536,t=1,p=4$" + parts[4] + "$" + parts[5]},
+		{name: "missing parameters", input: "$argon2id$v=19$$" + parts[4] + "$" + parts[5]},
+		{name: "zero parameters", input: "$argon2id$v=19$m=0,t=0,p=0$" + parts[4] + "$" + parts[5]},
+		{name: "bad salt", input: "$argon2id$v=19$m=65536,t=1,p=4$!!$" + parts[5]},
+		{name: "missing hash", input: "$argon2id$v=19$m=65536,t=1,p=4$" + parts[4]},
+		{name: "bad legacy", input: "not-base64:also-not"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCredential(tt.input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateReportsOutdatedParameters(t *testing.T) {
+	weak := Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32}
+	cred, err := NewCredential("4821", weak)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		value       string
+		policy      Argon2Params
+		valid       bool
+		needsRehash bool
+	}{
+		{name: "same policy", value: "4821", policy: weak, valid: true, needsRehash: false},
+		{name: "more memory", value: "4821", policy: Argon2Params{Time: 1, Memory: 16 * 1024, Threads: 1, KeyLength: 32}, valid: true, needsRehash: true},
+		{name: "more passes", value: "4821", policy: Argon2Params{Time: 2, Memory: 8 * 1024, Threads: 1, KeyLength: 32}, valid: true, needsRehash: true},
+		{name: "more threads only", value: "4821", policy: Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 4, KeyLength: 32}, valid: true, needsRehash: false},
+		{name: "weaker policy", value: "4821", policy: Argon2Params{Time: 1, Memory: 4 * 1024, Threads: 1, KeyLength: 32}, valid: true, needsRehash: false},
+		{name: "wrong value", value: "4822", policy: DefaultArgon2Params, valid: false, needsRehash: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, needsRehash := cred.Validate(tt.value, tt.policy)
+			assert.Equal(t, tt.valid, valid)
+			assert.Equal(t, tt.needsRehash, needsRehash)
+		})
+	}
+}
+
+func TestValidatePIN(t *testing.T) {
+	tests := []struct {
+		pin   string
+		valid bool
+	}{
+		{pin: "4821", valid: true},
+		{pin: "1357", valid: true},
+		{pin: "1123", valid: true},
+		{pin: "0000", valid: false},
+		{pin: "7777", valid: false},
+		{pin: "1234", valid: false},
+		{pin: "0123", valid: false},
+		{pin: "9876", valid: false},
+		{pin: "123", valid: false},
+		{pin: "12a4", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pin, func(t *testing.T) {
+			err := ValidatePIN(tt.pin)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			var authErr *AuthError
+			require.ErrorAs(t, err, &authErr)
+			assert.Equal(t, ErrWeakPIN, authErr.Code)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MinEmergencyReasonLength is the shortest reason accepted for emergency access
+const MinEmergencyReasonLength = 10
+
+const (
+	// PermissionRequestEmergency allows a role to request break-the-glass access
+	PermissionRequestEmergency Permission = "emergency:request"
+	// PermissionReviewEmergency allows a role to list and review the grants
+	PermissionReviewEmergency Permission = "emergency:review"
+)
+
+// EmergencyAccessPolicy defines the break-the-glass elevation: how long a
+// grant lasts and which permissions it adds on top of the caller's role
+type EmergencyAccessPolicy struct {
+	TTL         time.Duration
+	Permissions []Permission
+}
+
+// Grants reports whether emergency access includes the required permission
+func (p EmergencyAccessPolicy) Grants(required Permission) bool {
+	for _, granted := range p.Permissions {
+		if granted.Grants(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmergencyAccess is a time-boxed break-the-glass grant. Every request made
+// under it is recorded as an event, and administrators review each grant.
+type EmergencyAccess struct {
+	ID         uuid.UUID              `json:"id"`
+	UserID     uuid.UUID              `json:"userId"`
+	SessionID  uuid.UUID              `json:"sessionId"`
+	Reason     string                 `json:"reason"`
+	IPAddress  string                 `json:"ipAddress"`
+	GrantedAt  time.Time              `json:"grantedAt"`
+	ExpiresAt  time.Time              `json:"expiresAt"`
+	ReviewedBy *uuid.UUID             `json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time             `json:"reviewedAt,omitempty"`
+	ReviewNote string                 `json:"reviewNote,omitempty"`
+	Events     []EmergencyAccessEvent `json:"events"`
+}
+
+// EmergencyAccessEvent records one request made under an emergency grant
+type EmergencyAccessEvent struct {
+	GrantID    uuid.UUID `json:"-"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	IPAddress  string    `json:"ipAddress"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+// NewEmergencyAccess grants emergency access for the session. A reason is
+// mandatory so that the access can be reviewed.
+func NewEmergencyAccess(userID, sessionID uuid.UUID, reason, ipAddress string, ttl time.Duration) (*EmergencyAccess, error) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) < MinEmergencyReasonLength {
+		return nil, ErrEmergencyReasonRequiredError
+	}
+
+	now := time.Now()
+	return &EmergencyAccess{
+		ID:        uuid.New(),
+		UserID:    userID,
+		SessionID: sessionID,
+		Reason:    reason,
+		IPAddress: ipAddress,
+		GrantedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Events:    []EmergencyAccessEvent{},
+	}, nil
+}
+
+// IsActive reports whether the grant has not expired yet
+func (g *EmergencyAccess) IsActive() bool {
+	return time.Now().Before(g.ExpiresAt)
+}
+
+// IsReviewed reports whether an administrator has reviewed the grant
+func (g *EmergencyAccess) IsReviewed() bool {
+	return g.ReviewedAt != nil
+}
+
+// Review marks the grant as reviewed by the given administrator
+func (g *EmergencyAccess) Review(reviewerID uuid.UUID, note string) {
+	now := time.Now()
+	g.ReviewedBy = &reviewerID
+	g.ReviewedAt = &now
+	g.ReviewNote = strings.TrimSpace(note)
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewEmergencyAccess(t *testing.T) {
+	userID, sessionID := uuid.New(), uuid.New()
+
+	for _, reason := range []string{"", "   ", "urgent", "  too short  "} {
+		if _, err := NewEmergencyAccess(userID, sessionID, reason, "127.0.0.1", time.Hour); err != ErrEmergencyReasonRequiredError {
+			t.Errorf("Expected reason %q to be rejected, got %v", reason, err)
+		}
+	}
+
+	grant, err := NewEmergencyAccess(userID, sessionID, "  Unconscious patient in ER  ", "127.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatalf("Expected grant, got %v", err)
+	}
+	if grant.Reason != "Unconscious patient in ER" {
+		t.Errorf("Expected trimmed reason, got %q", grant.Reason)
+	}
+	if !grant.IsActive() {
+		t.Error("Expected new grant to be active")
+	}
+	if grant.IsReviewed() {
+		t.Error("Expected new grant to be pending review")
+	}
+
+	grant.ExpiresAt = time.Now().Add(-time.Second)
+	if grant.IsActive() {
+		t.Error("Expected expired grant to be inactive")
+	}
+}
+
+func TestEmergencyAccessReview(t *testing.T) {
+	grant, err := NewEmergencyAccess(uuid.New(), uuid.New(), "Unconscious patient in ER", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Expected grant, got %v", err)
+	}
+
+	reviewer := uuid.New()
+	grant.Review(reviewer, " Justified ")
+	if !grant.IsReviewed() {
+		t.Fatal("Expected grant to be reviewed")
+	}
+	if *grant.ReviewedBy != reviewer {
+		t.Errorf("Expected reviewer %s, got %s", reviewer, *grant.ReviewedBy)
+	}
+	if grant.ReviewNote != "Justified" {
+		t.Errorf("Expected trimmed note, got %q", grant.ReviewNote)
+	}
+}
+
+func TestEmergencyAccessPolicyGrants(t *testing.T) {
+	policy := EmergencyAccessPolicy{Permissions: []Permission{"patients:read"}}
+
+	if !policy.Grants("patients:read:demographics") {
+		t.Error("Expected policy to grant a more specific permission")
+	}
+	if policy.Grants("patients:write") {
+		t.Error("Expected policy not to grant an unlisted permission")
+	}
+}
+
+func TestGenerateEmergencyToken(t *testing.T) {
+	config := TokenConfig{
+		AccessTokenSecret:  []byte("access-secret"),
+		RefreshTokenSecret: []byte("refresh-secret"),
+		AccessTokenTTL:     15 * time.Minute,
+		RefreshTokenTTL:    time.Hour,
+		Issuer:             "test",
+	}
+	user := NewUser("nurse@example.com", "Nurse Joy", RoleNurse)
+	session := NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	grant, err := NewEmergencyAccess(user.ID, session.ID, "Unconscious patient in ER", "", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Expected grant, got %v", err)
+	}
+
+	token, err := session.GenerateEmergencyToken(user, grant, config)
+	if err != nil {
+		t.Fatalf("Expected token, got %v", err)
+	}
+	claims, err := ValidateToken(token, TokenTypeAccess, config.AccessTokenSecret)
+	if err != nil {
+		t.Fatalf("Expected valid token, got %v", err)
+	}
+	if claims.EmergencyAccessID != grant.ID.String() {
+		t.Errorf("Expected grant %s in claims, got %q", grant.ID, claims.EmergencyAccessID)
+	}
+	if claims.ExpiresAt.After(grant.ExpiresAt.Add(time.Second)) {
+		t.Errorf("Expected token to expire with the grant at %s, got %s", grant.ExpiresAt, claims.ExpiresAt)
+	}
+}
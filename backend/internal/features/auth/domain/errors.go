@@ -20,6 +20,15 @@ const (
 	ErrUserNotFound       = "USER_NOT_FOUND"
 	ErrSessionNotFound    = "SESSION_NOT_FOUND"
 	ErrInvalidToken       = "INVALID_TOKEN"
+	ErrTokenReused        = "TOKEN_REUSED"
+	ErrSessionExpired     = "SESSION_EXPIRED"
+	ErrWeakPIN            = "WEAK_PIN"
+	ErrPINChangeRequired  = "PIN_CHANGE_REQUIRED"
+	ErrAccountDisabled    = "ACCOUNT_DISABLED"
+	ErrInvalidRole        = "INVALID_ROLE"
+	ErrSelfModification   = "SELF_MODIFICATION"
+	ErrEmergencyReason    = "EMERGENCY_REASON_REQUIRED"
+	ErrEmergencyNotFound  = "EMERGENCY_ACCESS_NOT_FOUND"
 )
 
 // NewAuthError creates a new auth error
@@ -40,4 +49,18 @@ var (
 	ErrUserNotFoundError    = NewAuthError(ErrUserNotFound, "user not found")
 	ErrSessionNotFoundError = NewAuthError(ErrSessionNotFound, "session not found")
 	ErrInvalidTokenError    = NewAuthError(ErrInvalidToken, "invalid token")
+	ErrTokenReusedError     = NewAuthError(ErrTokenReused, "refresh token has already been used")
+	ErrSessionExpiredError  = NewAuthError(ErrSessionExpired, "session has expired")
+	ErrWeakPINError         = func(reason string) *AuthError {
+		return NewAuthError(ErrWeakPIN, reason)
+	}
+	ErrPINChangeRequiredError = NewAuthError(ErrPINChangeRequired, "PIN must be changed before continuing")
+	ErrAccountDisabledError   = NewAuthError(ErrAccountDisabled, "account is disabled")
+	ErrInvalidRoleError       = func(role Role) *AuthError {
+		return NewAuthError(ErrInvalidRole, fmt.Sprintf("unknown role %q", role))
+	}
+	ErrSelfModificationError        = NewAuthError(ErrSelfModification, "administrators cannot change their own account this way")
+	ErrEmergencyReasonRequiredError = NewAuthError(ErrEmergencyReason,
+		fmt.Sprintf("emergency access requires a reason of at least %d characters", MinEmergencyReasonLength))
+	ErrEmergencyAccessNotFoundError = NewAuthError(ErrEmergencyNotFound, "emergency access not found")
 )
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Permission names an action on a resource, such as "patients:read" or
+// "patients:write:demographics". A granted permission covers every more
+// specific permission below it: "patients:write" grants
+// "patients:write:demographics", and "*" grants everything.
+type Permission string
+
+const (
+	// PermissionAll grants every permission
+	PermissionAll Permission = "*"
+	// PermissionManageUsers allows a role to create, list and change user
+	// accounts, including their roles, keys and locks
+	PermissionManageUsers Permission = "users:manage"
+)
+
+// Grants reports whether holding p allows an action requiring required
+func (p Permission) Grants(required Permission) bool {
+	return p == PermissionAll ||
+		p == required ||
+		strings.HasPrefix(string(required), string(p)+":")
+}
+
+// PermissionMatrix maps each role to the permissions granted to it. Roles
+// missing from the matrix have no permissions.
+type PermissionMatrix map[Role][]Permission
+
+// NewPermissionMatrix builds a permission matrix from its configuration form,
+// rejecting unknown roles and empty permissions
+func NewPermissionMatrix(config map[string][]string) (PermissionMatrix, error) {
+	matrix := make(PermissionMatrix, len(config))
+	for name, permissions := range config {
+		role := Role(name)
+		if !role.IsValid() {
+			return nil, fmt.Errorf("unknown role %q in permission matrix", name)
+		}
+		for _, permission := range permissions {
+			if strings.TrimSpace(permission) == "" {
+				return nil, fmt.Errorf("empty permission for role %q", name)
+			}
+			matrix[role] = append(matrix[role], Permission(permission))
+		}
+	}
+	return matrix, nil
+}
+
+// Allows reports whether the role holds the required permission
+func (m PermissionMatrix) Allows(role Role, required Permission) bool {
+	for _, granted := range m[role] {
+		if granted.Grants(required) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsOf returns the permissions granted to the role, sorted
+func (m PermissionMatrix) PermissionsOf(role Role) []Permission {
+	permissions := append([]Permission{}, m[role]...)
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i] < permissions[j]
+	})
+	return permissions
+}
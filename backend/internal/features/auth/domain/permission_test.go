@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionGrants(t *testing.T) {
+	tests := []struct {
+		granted  Permission
+		required Permission
+		want     bool
+	}{
+		{granted: "patients:read", required: "patients:read", want: true},
+		{granted: "patients:write", required: "patients:write:demographics", want: true},
+		{granted: "patients:write:vitals", required: "patients:write:demographics", want: false},
+		{granted: "patients:write:vitals", required: "patients:write", want: false},
+		{granted: "patients:read", required: "patients:readme", want: false},
+		{granted: PermissionAll, required: "users:manage", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.granted)+" grants "+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.granted.Grants(tt.required))
+		})
+	}
+}
+
+func TestPermissionMatrix(t *testing.T) {
+	matrix, err := NewPermissionMatrix(map[string][]string{
+		"nurse": {"patients:write:vitals", "patients:read"},
+		"staff": {"patients:read:demographics"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, matrix.Allows(RoleNurse, "patients:read:clinical"))
+	assert.True(t, matrix.Allows(RoleNurse, "patients:write:vitals"))
+	assert.False(t, matrix.Allows(RoleNurse, "patients:write:demographics"))
+	assert.True(t, matrix.Allows(RoleStaff, "patients:read:demographics"))
+	assert.False(t, matrix.Allows(RoleStaff, "patients:read:clinical"))
+	assert.False(t, matrix.Allows(RoleDoctor, "patients:read"))
+
+	assert.Equal(t, []Permission{"patients:read", "patients:write:vitals"}, matrix.PermissionsOf(RoleNurse))
+	assert.Empty(t, matrix.PermissionsOf(RolePatient))
+}
+
+func TestNewPermissionMatrixRejectsInvalidConfig(t *testing.T) {
+	_, err := NewPermissionMatrix(map[string][]string{"janitor": {"patients:read"}})
+	assert.Error(t, err)
+
+	_, err = NewPermissionMatrix(map[string][]string{"staff": {" "}})
+	assert.Error(t, err)
+}
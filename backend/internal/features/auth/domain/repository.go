@@ -4,22 +4,39 @@ import (
 	"context"
 )
 
-// UserRepository defines the interface for user persistence
+// UserRepository defines the interface for user persistence.
+// RecordFailedAttempt counts a failed attempt and applies the lockout policy
+// in a single write, so that concurrent failures cannot undo each other.
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
+	RecordFailedAttempt(ctx context.Context, id string, policy LockoutPolicy) error
+	SwapRecoveryCodes(ctx context.Context, id string, previous, remaining []*Credential) error
 	Delete(ctx context.Context, id string) error
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	ListPaginated(ctx context.Context, page, pageSize int, filter UserFilter) ([]*User, int64, error)
 }
 
-// SessionRepository defines the interface for session persistence
+// UserFilter narrows down a user listing. Search matches a part of the name
+// or email, case-insensitively. Empty fields match every user.
+type UserFilter struct {
+	Search string
+	Role   Role
+}
+
+// SessionRepository defines the interface for session persistence. Rotate
+// stores a refreshed session only while it still holds previousToken, and
+// fails with ErrTokenReusedError once another refresh has replaced it or
+// with ErrSessionNotFoundError once the session has been revoked.
 type SessionRepository interface {
 	Create(ctx context.Context, session *Session) error
-	Update(ctx context.Context, session *Session) error
+	Rotate(ctx context.Context, session *Session, previousToken string) error
 	Delete(ctx context.Context, id string) error
 	GetByID(ctx context.Context, id string) (*Session, error)
 	GetByRefreshToken(ctx context.Context, token string) (*Session, error)
+	ListByUserID(ctx context.Context, userID string) ([]*Session, error)
+	DeleteByUserID(ctx context.Context, userID string) error
 	DeleteExpired(ctx context.Context) error
 }
 
@@ -31,6 +48,8 @@ type CreateUserRepository interface {
 // ValidateUserRepository defines the minimal interface for user validation
 type ValidateUserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	RecordFailedAttempt(ctx context.Context, id string, policy LockoutPolicy) error
 }
 
 // CreateSessionRepository defines the minimal interface for session creation
@@ -41,5 +60,103 @@ type CreateSessionRepository interface {
 // RefreshSessionRepository defines the minimal interface for session refresh
 type RefreshSessionRepository interface {
 	GetByRefreshToken(ctx context.Context, token string) (*Session, error)
-	Update(ctx context.Context, session *Session) error
+	GetByID(ctx context.Context, id string) (*Session, error)
+	Rotate(ctx context.Context, session *Session, previousToken string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// GetUserRepository defines the minimal interface for user lookup by ID
+type GetUserRepository interface {
+	GetByID(ctx context.Context, id string) (*User, error)
+}
+
+// GetSessionRepository defines the minimal interface for session lookup by ID
+type GetSessionRepository interface {
+	GetByID(ctx context.Context, id string) (*Session, error)
+}
+
+// ListSessionsRepository defines the minimal interface for listing a user's sessions
+type ListSessionsRepository interface {
+	ListByUserID(ctx context.Context, userID string) ([]*Session, error)
+}
+
+// RevokeSessionRepository defines the minimal interface for session revocation
+type RevokeSessionRepository interface {
+	GetByID(ctx context.Context, id string) (*Session, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// ChangePINRepository defines the minimal interface for changing a user's PIN
+type ChangePINRepository interface {
+	GetByID(ctx context.Context, id string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	RecordFailedAttempt(ctx context.Context, id string, policy LockoutPolicy) error
+}
+
+// ResetKeyRepository defines the minimal interface for resetting a user's key
+type ResetKeyRepository interface {
+	GetByID(ctx context.Context, id string) (*User, error)
+	Update(ctx context.Context, user *User) error
+}
+
+// RecoverKeyRepository defines the minimal interface for recovering a lost
+// key. SwapRecoveryCodes replaces the user's recovery codes only while they
+// are still the previous ones, and fails with ErrInvalidCredentialsError
+// otherwise, so that each code can be used once even by concurrent requests.
+type RecoverKeyRepository interface {
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	RecordFailedAttempt(ctx context.Context, id string, policy LockoutPolicy) error
+	SwapRecoveryCodes(ctx context.Context, id string, previous, remaining []*Credential) error
+}
+
+// UnlockUserRepository defines the minimal interface for unlocking a user
+type UnlockUserRepository interface {
+	GetByID(ctx context.Context, id string) (*User, error)
+	Update(ctx context.Context, user *User) error
+}
+
+// ListUsersRepository defines the minimal interface for listing users
+type ListUsersRepository interface {
+	ListPaginated(ctx context.Context, page, pageSize int, filter UserFilter) ([]*User, int64, error)
+}
+
+// ManageUserRepository defines the minimal interface for administrative
+// changes to a user account
+type ManageUserRepository interface {
+	GetByID(ctx context.Context, id string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id string) error
+}
+
+// EmergencyAccessRepository defines the interface for emergency access persistence
+type EmergencyAccessRepository interface {
+	Create(ctx context.Context, grant *EmergencyAccess) error
+	Update(ctx context.Context, grant *EmergencyAccess) error
+	GetByID(ctx context.Context, id string) (*EmergencyAccess, error)
+	List(ctx context.Context, pendingOnly bool) ([]*EmergencyAccess, error)
+	RecordEvent(ctx context.Context, event EmergencyAccessEvent) error
+}
+
+// RequestEmergencyAccessRepository defines the minimal interface for granting emergency access
+type RequestEmergencyAccessRepository interface {
+	Create(ctx context.Context, grant *EmergencyAccess) error
+}
+
+// RecordEmergencyAccessRepository defines the minimal interface for recording
+// requests made under emergency access
+type RecordEmergencyAccessRepository interface {
+	RecordEvent(ctx context.Context, event EmergencyAccessEvent) error
+}
+
+// ListEmergencyAccessRepository defines the minimal interface for listing emergency access grants
+type ListEmergencyAccessRepository interface {
+	List(ctx context.Context, pendingOnly bool) ([]*EmergencyAccess, error)
+}
+
+// ReviewEmergencyAccessRepository defines the minimal interface for reviewing emergency access
+type ReviewEmergencyAccessRepository interface {
+	GetByID(ctx context.Context, id string) (*EmergencyAccess, error)
+	Update(ctx context.Context, grant *EmergencyAccess) error
 }
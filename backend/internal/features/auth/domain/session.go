@@ -20,11 +20,17 @@ const (
 type Claims struct {
 	jwt.RegisteredClaims
 	UserID    string    `json:"uid"`
+	SessionID string    `json:"sid"`
 	Role      Role      `json:"role"`
 	TokenType TokenType `json:"type"`
+	// PINChangeRequired restricts the token to changing the PIN
+	PINChangeRequired bool `json:"pcr,omitempty"`
+	// EmergencyAccessID marks a break-the-glass token and names its grant
+	EmergencyAccessID string `json:"eag,omitempty"`
 }
 
-// Session represents an active user session
+// Session represents an active user session. RefreshToken is only known
+// right after the tokens were generated; stores keep a hash of it.
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	UserID       uuid.UUID `json:"userId"`
@@ -61,12 +67,12 @@ func NewSession(userID uuid.UUID, userAgent, ipAddress string, expiresAt time.Ti
 
 // GenerateTokens creates both access and refresh tokens
 func (s *Session) GenerateTokens(user *User, config TokenConfig) (accessToken string, refreshToken string, err error) {
-	accessToken, err = generateToken(TokenTypeAccess, user, config.AccessTokenSecret, config.AccessTokenTTL, config.Issuer)
+	accessToken, err = generateToken(TokenTypeAccess, user, s.ID, config.AccessTokenSecret, config.AccessTokenTTL, config.Issuer)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err = generateToken(TokenTypeRefresh, user, config.RefreshTokenSecret, config.RefreshTokenTTL, config.Issuer)
+	refreshToken, err = generateToken(TokenTypeRefresh, user, s.ID, config.RefreshTokenSecret, config.RefreshTokenTTL, config.Issuer)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -98,11 +104,30 @@ func ValidateToken(tokenString string, tokenType TokenType, secret []byte) (*Cla
 	return nil, fmt.Errorf("invalid token")
 }
 
-// generateToken creates a new JWT token
-func generateToken(tokenType TokenType, user *User, secret []byte, ttl time.Duration, issuer string) (string, error) {
+// GenerateEmergencyToken creates an access token for an emergency grant of
+// this session. The token expires together with the grant and cannot be
+// refreshed.
+func (s *Session) GenerateEmergencyToken(user *User, grant *EmergencyAccess, config TokenConfig) (string, error) {
+	claims := newClaims(TokenTypeAccess, user, s.ID, time.Until(grant.ExpiresAt), config.Issuer)
+	claims.EmergencyAccessID = grant.ID.String()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.AccessTokenSecret)
+}
+
+// generateToken creates a new JWT token bound to the given session
+func generateToken(tokenType TokenType, user *User, sessionID uuid.UUID, secret []byte, ttl time.Duration, issuer string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(tokenType, user, sessionID, ttl, issuer))
+	return token.SignedString(secret)
+}
+
+// newClaims builds the claims of a token bound to the given session. Every
+// token carries a unique ID so that rotated tokens never repeat.
+func newClaims(tokenType TokenType, user *User, sessionID uuid.UUID, ttl time.Duration, issuer string) Claims {
 	now := time.Now()
-	claims := Claims{
+	return Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -110,12 +135,12 @@ func generateToken(tokenType TokenType, user *User, secret []byte, ttl time.Dura
 			Subject:   user.ID.String(),
 		},
 		UserID:    user.ID.String(),
+		SessionID: sessionID.String(),
 		Role:      user.Role,
 		TokenType: tokenType,
-	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+		PINChangeRequired: user.MustChangePIN,
+	}
 }
 
 // IsExpired checks if the session has expired
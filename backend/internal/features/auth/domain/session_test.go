@@ -78,6 +78,19 @@ func TestTokenGeneration(t *testing.T) {
 	assert.Equal(t, suite.defaultUser.ID.String(), refreshClaims.UserID)
 	assert.Equal(t, suite.defaultUser.Role, refreshClaims.Role)
 	assert.Equal(t, TokenTypeRefresh, refreshClaims.TokenType)
+	assert.Equal(t, suite.defaultSession.ID.String(), refreshClaims.SessionID)
+}
+
+func TestTokenRotationProducesUniqueTokens(t *testing.T) {
+	suite := setupSessionTest()
+
+	_, first, err := suite.defaultSession.GenerateTokens(suite.defaultUser, suite.defaultConfig)
+	assert.NoError(t, err)
+	_, second, err := suite.defaultSession.GenerateTokens(suite.defaultUser, suite.defaultConfig)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, second, suite.defaultSession.RefreshToken)
 }
 
 func TestSessionExpiration(t *testing.T) {
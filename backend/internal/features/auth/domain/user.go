@@ -5,6 +5,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,19 +22,44 @@ const (
 	RolePatient Role = "patient"
 )
 
+// IsValid reports whether r is one of the known roles
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleDoctor, RoleNurse, RoleStaff, RolePatient:
+		return true
+	default:
+		return false
+	}
+}
+
+// LockoutPolicy defines when repeated failed attempts lock an account
+type LockoutPolicy struct {
+	MaxAttempts int
+	Duration    time.Duration
+}
+
+// DefaultLockoutPolicy locks an account for 15 minutes after 5 failed attempts
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxAttempts: 5,
+	Duration:    15 * time.Minute,
+}
+
 // User represents a user in the system
 type User struct {
-	ID             uuid.UUID   `json:"id"`
-	Email          string      `json:"email"`
-	Name           string      `json:"name"`
-	Role           Role        `json:"role"`
-	KeyCredential  *Credential `json:"-"`
-	PINCredential  *Credential `json:"-"`
-	FailedAttempts int         `json:"-"`
-	LockedUntil    *time.Time  `json:"-"`
-	LastLogin      *time.Time  `json:"lastLogin,omitempty"`
-	CreatedAt      time.Time   `json:"createdAt"`
-	UpdatedAt      time.Time   `json:"updatedAt"`
+	ID             uuid.UUID     `json:"id"`
+	Email          string        `json:"email"`
+	Name           string        `json:"name"`
+	Role           Role          `json:"role"`
+	KeyCredential  *Credential   `json:"-"`
+	PINCredential  *Credential   `json:"-"`
+	RecoveryCodes  []*Credential `json:"-"`
+	MustChangePIN  bool          `json:"mustChangePin"`
+	Disabled       bool          `json:"disabled"`
+	FailedAttempts int           `json:"-"`
+	LockedUntil    *time.Time    `json:"-"`
+	LastLogin      *time.Time    `json:"lastLogin,omitempty"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
 }
 
 // NewUser creates a new user with a generated ID and timestamps
@@ -61,12 +87,123 @@ func (u *User) SetPINCredential(cred *Credential) {
 	u.UpdatedAt = time.Now()
 }
 
-// ValidateCredentials validates both key and PIN
-func (u *User) ValidateCredentials(key, pin string) bool {
+// ValidateCredentials validates both key and PIN. It also reports whether
+// either credential is weaker than the hashing policy.
+func (u *User) ValidateCredentials(key, pin string, policy Argon2Params) (valid bool, needsRehash bool) {
 	if u.KeyCredential == nil || u.PINCredential == nil {
-		return false
+		return false, false
+	}
+
+	keyValid, keyRehash := u.KeyCredential.Validate(key, policy)
+	if !keyValid {
+		return false, false
+	}
+	pinValid, pinRehash := u.PINCredential.Validate(pin, policy)
+	if !pinValid {
+		return false, false
 	}
-	return u.KeyCredential.Validate(key) && u.PINCredential.Validate(pin)
+	return true, keyRehash || pinRehash
+}
+
+// RehashCredentials replaces the key and PIN credentials with fresh hashes
+// made with the given parameters. The plaintexts must already be validated.
+func (u *User) RehashCredentials(key, pin string, params Argon2Params) error {
+	keyCred, err := NewCredential(key, params)
+	if err != nil {
+		return fmt.Errorf("failed to rehash key: %w", err)
+	}
+	pinCred, err := NewCredential(pin, params)
+	if err != nil {
+		return fmt.Errorf("failed to rehash PIN: %w", err)
+	}
+
+	u.SetKeyCredential(keyCred)
+	u.SetPINCredential(pinCred)
+	return nil
+}
+
+// ResetKey replaces the key with a newly generated one and returns it. The
+// old key stops working immediately.
+func (u *User) ResetKey(params Argon2Params) (string, error) {
+	key, keyCred, err := GenerateKey(params)
+	if err != nil {
+		return "", err
+	}
+	u.SetKeyCredential(keyCred)
+	return key, nil
+}
+
+// SetRecoveryCodes replaces the user's recovery codes
+func (u *User) SetRecoveryCodes(creds []*Credential) {
+	u.RecoveryCodes = creds
+	u.UpdatedAt = time.Now()
+}
+
+// ConsumeRecoveryCode checks a recovery code and, when it matches, removes it
+// so that it cannot be used again
+func (u *User) ConsumeRecoveryCode(code string, policy Argon2Params) bool {
+	code = normalizeRecoveryCode(code)
+	for i, cred := range u.RecoveryCodes {
+		if valid, _ := cred.Validate(code, policy); valid {
+			remaining := make([]*Credential, 0, len(u.RecoveryCodes)-1)
+			remaining = append(remaining, u.RecoveryCodes[:i]...)
+			remaining = append(remaining, u.RecoveryCodes[i+1:]...)
+			u.SetRecoveryCodes(remaining)
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePINChange forces the user to choose a new PIN before the account
+// can be used for anything else
+func (u *User) RequirePINChange() {
+	u.MustChangePIN = true
+	u.UpdatedAt = time.Now()
+}
+
+// ChangePIN replaces the PIN with a new one that satisfies the PIN policy
+// and lifts a pending forced PIN change. The caller must have validated the
+// current credentials.
+func (u *User) ChangePIN(newPIN string, params Argon2Params) error {
+	if err := ValidatePIN(newPIN); err != nil {
+		return err
+	}
+	if u.PINCredential != nil {
+		if same, _ := u.PINCredential.Validate(newPIN, params); same {
+			return ErrWeakPINError("new PIN must differ from the current PIN")
+		}
+	}
+
+	pinCred, err := GeneratePINCredential(newPIN, params)
+	if err != nil {
+		return err
+	}
+	u.SetPINCredential(pinCred)
+	u.MustChangePIN = false
+	return nil
+}
+
+// ChangeRole assigns the user a new role
+func (u *User) ChangeRole(role Role) error {
+	if !role.IsValid() {
+		return ErrInvalidRoleError(role)
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// Disable prevents the user from logging in until the account is enabled again
+func (u *User) Disable() {
+	u.Disabled = true
+	u.UpdatedAt = time.Now()
+}
+
+// Enable lifts a previous Disable
+func (u *User) Enable() {
+	u.Disabled = false
+	u.UpdatedAt = time.Now()
 }
 
 // IsLocked checks if the user account is locked
@@ -77,17 +214,24 @@ func (u *User) IsLocked() bool {
 	return time.Now().Before(*u.LockedUntil)
 }
 
-// RecordFailedAttempt records a failed authentication attempt
-func (u *User) RecordFailedAttempt() {
+// RecordFailedAttempt records a failed authentication attempt and locks the
+// account once the policy's threshold is reached. Counting starts over after
+// an expired lock.
+func (u *User) RecordFailedAttempt(policy LockoutPolicy) {
+	if u.LockedUntil != nil && !u.IsLocked() {
+		u.FailedAttempts = 0
+		u.LockedUntil = nil
+	}
+
 	u.FailedAttempts++
-	if u.FailedAttempts >= 5 {
-		lockUntil := time.Now().Add(15 * time.Minute)
+	if u.FailedAttempts >= policy.MaxAttempts {
+		lockUntil := time.Now().Add(policy.Duration)
 		u.LockedUntil = &lockUntil
 	}
 	u.UpdatedAt = time.Now()
 }
 
-// ResetFailedAttempts resets the failed attempts counter
+// ResetFailedAttempts resets the failed attempts counter and lifts any lock
 func (u *User) ResetFailedAttempts() {
 	u.FailedAttempts = 0
 	u.LockedUntil = nil
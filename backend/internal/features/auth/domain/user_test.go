@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +39,7 @@ func TestCredentials(t *testing.T) {
 	user := NewUser("test@example.com", "Test User", RoleDoctor)
 
 	// Test key credential
-	key, keyCred, err := GenerateKey()
+	key, keyCred, err := GenerateKey(DefaultArgon2Params)
 	if err != nil {
 		t.Fatalf("Failed to generate key: %v", err)
 	}
@@ -49,7 +50,7 @@ func TestCredentials(t *testing.T) {
 
 	// Test PIN credential
 	pin := "1234"
-	pinCred, err := GeneratePINCredential(pin)
+	pinCred, err := GeneratePINCredential(pin, DefaultArgon2Params)
 	if err != nil {
 		t.Fatalf("Failed to generate PIN credential: %v", err)
 	}
@@ -59,11 +60,44 @@ func TestCredentials(t *testing.T) {
 	}
 
 	// Test credential validation
-	if !user.ValidateCredentials(key, pin) {
+	if valid, _ := user.ValidateCredentials(key, pin, DefaultArgon2Params); !valid {
 		t.Error("Expected credential validation to succeed")
 	}
 }
 
+func TestRehashCredentials(t *testing.T) {
+	user := NewUser("test@example.com", "Test User", RoleDoctor)
+	weak := Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32}
+
+	key, keyCred, err := GenerateKey(weak)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	user.SetKeyCredential(keyCred)
+	pinCred, err := GeneratePINCredential("4821", weak)
+	if err != nil {
+		t.Fatalf("Failed to generate PIN credential: %v", err)
+	}
+	user.SetPINCredential(pinCred)
+
+	valid, needsRehash := user.ValidateCredentials(key, "4821", DefaultArgon2Params)
+	if !valid || !needsRehash {
+		t.Fatalf("Expected valid credentials needing rehash, got valid=%v needsRehash=%v", valid, needsRehash)
+	}
+
+	if err := user.RehashCredentials(key, "4821", DefaultArgon2Params); err != nil {
+		t.Fatalf("Failed to rehash credentials: %v", err)
+	}
+	if user.KeyCredential.Params != DefaultArgon2Params || user.PINCredential.Params != DefaultArgon2Params {
+		t.Error("Expected credentials to use the new parameters")
+	}
+
+	valid, needsRehash = user.ValidateCredentials(key, "4821", DefaultArgon2Params)
+	if !valid || needsRehash {
+		t.Errorf("Expected valid up-to-date credentials, got valid=%v needsRehash=%v", valid, needsRehash)
+	}
+}
+
 func TestAccountLocking(t *testing.T) {
 	user := NewUser("test@example.com", "Test User", RoleDoctor)
 
@@ -74,14 +108,14 @@ func TestAccountLocking(t *testing.T) {
 
 	// Test failed attempts
 	for i := 0; i < 4; i++ {
-		user.RecordFailedAttempt()
+		user.RecordFailedAttempt(DefaultLockoutPolicy)
 		if user.IsLocked() {
 			t.Errorf("Expected user to be unlocked after %d attempts", i+1)
 		}
 	}
 
 	// Test locking after 5 attempts
-	user.RecordFailedAttempt()
+	user.RecordFailedAttempt(DefaultLockoutPolicy)
 	if !user.IsLocked() {
 		t.Error("Expected user to be locked after 5 attempts")
 	}
@@ -100,8 +134,8 @@ func TestRecordLogin(t *testing.T) {
 	user := NewUser("test@example.com", "Test User", RoleDoctor)
 
 	// Record some failed attempts
-	user.RecordFailedAttempt()
-	user.RecordFailedAttempt()
+	user.RecordFailedAttempt(DefaultLockoutPolicy)
+	user.RecordFailedAttempt(DefaultLockoutPolicy)
 
 	// Record login
 	originalUpdatedAt := user.UpdatedAt
@@ -121,3 +155,105 @@ func TestRecordLogin(t *testing.T) {
 		t.Error("Expected UpdatedAt to be updated")
 	}
 }
+
+func TestChangePIN(t *testing.T) {
+	user := NewUser("test@example.com", "Test User", RoleDoctor)
+	pinCred, err := GeneratePINCredential(InitialPIN, DefaultArgon2Params)
+	if err != nil {
+		t.Fatalf("Failed to generate PIN credential: %v", err)
+	}
+	user.SetPINCredential(pinCred)
+	user.RequirePINChange()
+
+	if err := user.ChangePIN("1234", DefaultArgon2Params); err == nil {
+		t.Error("Expected trivial PIN to be rejected")
+	}
+	if !user.MustChangePIN {
+		t.Error("Expected PIN change to still be required")
+	}
+
+	if err := user.ChangePIN("4821", DefaultArgon2Params); err != nil {
+		t.Fatalf("Failed to change PIN: %v", err)
+	}
+	if user.MustChangePIN {
+		t.Error("Expected PIN change requirement to be lifted")
+	}
+	if valid, _ := user.PINCredential.Validate("4821", DefaultArgon2Params); !valid {
+		t.Error("Expected new PIN to be valid")
+	}
+
+	if err := user.ChangePIN("4821", DefaultArgon2Params); err == nil {
+		t.Error("Expected reusing the current PIN to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	params := Argon2Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLength: 32}
+	user := NewUser("test@example.com", "Test User", RoleDoctor)
+
+	codes, creds, err := GenerateRecoveryCodes(params)
+	if err != nil {
+		t.Fatalf("Failed to generate recovery codes: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount || len(creds) != RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", RecoveryCodeCount, len(codes))
+	}
+	user.SetRecoveryCodes(creds)
+
+	if user.ConsumeRecoveryCode("AAAAA-AAAAA", params) {
+		t.Error("Expected unknown recovery code to be rejected")
+	}
+
+	// Codes are accepted regardless of case and separators, exactly once
+	typed := strings.ToLower(strings.ReplaceAll(codes[3], "-", " "))
+	if !user.ConsumeRecoveryCode(typed, params) {
+		t.Error("Expected recovery code to be accepted")
+	}
+	if len(user.RecoveryCodes) != RecoveryCodeCount-1 {
+		t.Errorf("Expected %d remaining recovery codes, got %d", RecoveryCodeCount-1, len(user.RecoveryCodes))
+	}
+	if user.ConsumeRecoveryCode(codes[3], params) {
+		t.Error("Expected used recovery code to be rejected")
+	}
+}
+
+func TestResetKey(t *testing.T) {
+	user := NewUser("test@example.com", "Test User", RoleDoctor)
+	oldKey, keyCred, err := GenerateKey(DefaultArgon2Params)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	user.SetKeyCredential(keyCred)
+
+	newKey, err := user.ResetKey(DefaultArgon2Params)
+	if err != nil {
+		t.Fatalf("Failed to reset key: %v", err)
+	}
+	if valid, _ := user.KeyCredential.Validate(oldKey, DefaultArgon2Params); valid {
+		t.Error("Expected old key to be invalid")
+	}
+	if valid, _ := user.KeyCredential.Validate(newKey, DefaultArgon2Params); !valid {
+		t.Error("Expected new key to be valid")
+	}
+}
+
+func TestExpiredLockRestartsCounting(t *testing.T) {
+	policy := LockoutPolicy{MaxAttempts: 2, Duration: time.Hour}
+	user := NewUser("test@example.com", "Test User", RoleDoctor)
+
+	user.RecordFailedAttempt(policy)
+	user.RecordFailedAttempt(policy)
+	if !user.IsLocked() {
+		t.Fatal("Expected user to be locked after reaching the threshold")
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	user.LockedUntil = &expired
+	user.RecordFailedAttempt(policy)
+	if user.IsLocked() {
+		t.Error("Expected a single failure after an expired lock not to lock again")
+	}
+	if user.FailedAttempts != 1 {
+		t.Errorf("Expected failed attempts to restart at 1, got %d", user.FailedAttempts)
+	}
+}
@@ -1,42 +1,134 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/dksch/pococlinic/internal/features/auth/commands"
 	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/middleware"
 	"github.com/dksch/pococlinic/internal/features/auth/queries"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// refreshTokenCookie is the name of the HttpOnly cookie carrying the refresh token
+const refreshTokenCookie = "refresh_token"
+
 // AuthHandler handles HTTP requests for authentication operations
 type AuthHandler struct {
-	createUserHandler commands.CreateUserHandler
-	loginHandler      commands.LoginHandler
-	getUserHandler    queries.GetUserHandler
+	createUserHandler         commands.CreateUserHandler
+	loginHandler              commands.LoginHandler
+	refreshHandler            commands.RefreshHandler
+	revokeSessionHandler      commands.RevokeSessionHandler
+	revokeUserSessionsHandler commands.RevokeUserSessionsHandler
+	changePINHandler          commands.ChangePINHandler
+	resetKeyHandler           commands.ResetKeyHandler
+	recoverKeyHandler         commands.RecoverKeyHandler
+	unlockUserHandler         commands.UnlockUserHandler
+	changeRoleHandler         commands.ChangeRoleHandler
+	setUserDisabledHandler    commands.SetUserDisabledHandler
+	deleteUserHandler         commands.DeleteUserHandler
+	requestEmergencyHandler   commands.RequestEmergencyAccessHandler
+	reviewEmergencyHandler    commands.ReviewEmergencyAccessHandler
+	getUserHandler            queries.GetUserHandler
+	listSessionsHandler       queries.ListSessionsHandler
+	listUsersHandler          queries.ListUsersHandler
+	getPermissionsHandler     queries.GetPermissionsHandler
+	listEmergencyHandler      queries.ListEmergencyAccessHandler
+	refreshTokenTTL           time.Duration
 }
 
 // NewAuthHandler creates a new authentication handler
 func NewAuthHandler(
 	createUser commands.CreateUserHandler,
 	login commands.LoginHandler,
+	refresh commands.RefreshHandler,
+	revokeSession commands.RevokeSessionHandler,
+	revokeUserSessions commands.RevokeUserSessionsHandler,
+	changePIN commands.ChangePINHandler,
+	resetKey commands.ResetKeyHandler,
+	recoverKey commands.RecoverKeyHandler,
+	unlockUser commands.UnlockUserHandler,
+	changeRole commands.ChangeRoleHandler,
+	setUserDisabled commands.SetUserDisabledHandler,
+	deleteUser commands.DeleteUserHandler,
+	requestEmergency commands.RequestEmergencyAccessHandler,
+	reviewEmergency commands.ReviewEmergencyAccessHandler,
 	getUser queries.GetUserHandler,
+	listSessions queries.ListSessionsHandler,
+	listUsers queries.ListUsersHandler,
+	getPermissions queries.GetPermissionsHandler,
+	listEmergency queries.ListEmergencyAccessHandler,
+	refreshTokenTTL time.Duration,
 ) *AuthHandler {
 	return &AuthHandler{
-		createUserHandler: createUser,
-		loginHandler:      login,
-		getUserHandler:    getUser,
+		createUserHandler:         createUser,
+		loginHandler:              login,
+		refreshHandler:            refresh,
+		revokeSessionHandler:      revokeSession,
+		revokeUserSessionsHandler: revokeUserSessions,
+		changePINHandler:          changePIN,
+		resetKeyHandler:           resetKey,
+		recoverKeyHandler:         recoverKey,
+		unlockUserHandler:         unlockUser,
+		changeRoleHandler:         changeRole,
+		setUserDisabledHandler:    setUserDisabled,
+		deleteUserHandler:         deleteUser,
+		requestEmergencyHandler:   requestEmergency,
+		reviewEmergencyHandler:    reviewEmergency,
+		getUserHandler:            getUser,
+		listSessionsHandler:       listSessions,
+		listUsersHandler:          listUsers,
+		getPermissionsHandler:     getPermissions,
+		listEmergencyHandler:      listEmergency,
+		refreshTokenTTL:           refreshTokenTTL,
 	}
 }
 
-// RegisterRoutes registers the authentication routes with the given router group
-func (h *AuthHandler) RegisterRoutes(router *gin.Engine) {
+// RegisterRoutes registers the authentication routes with the given router.
+// Login, refresh and key recovery are public; registering and managing user
+// accounts requires the users:manage permission.
+// Users who must change their PIN can only reach the change PIN route.
+// Break-the-glass access is requested by clinical staff and reviewed with
+// the emergency:review permission.
+func (h *AuthHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middleware.AuthMiddleware) {
 	auth := router.Group("/auth")
 	{
-		auth.POST("/register", h.CreateUser)
 		auth.POST("/login", h.Login)
-		auth.GET("/users/:id", h.GetUser)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/recover", h.RecoverKey)
+		auth.PUT("/pin", authMiddleware.RequireAuthAllowingPINChange(), h.ChangePIN)
+	}
+
+	protected := auth.Group("", authMiddleware.RequireAuth())
+	{
+		protected.POST("/register", authMiddleware.RequirePermission(domain.PermissionManageUsers), h.CreateUser)
+		protected.GET("/users/:id", h.GetUser)
+		protected.POST("/logout", h.Logout)
+		protected.GET("/sessions", h.ListSessions)
+		protected.GET("/permissions", h.GetPermissions)
+		protected.DELETE("/sessions/:id", h.RevokeSession)
+	}
+
+	admin := protected.Group("/users", authMiddleware.RequirePermission(domain.PermissionManageUsers))
+	{
+		admin.GET("", h.ListUsers)
+		admin.DELETE("/:id", h.DeleteUser)
+		admin.PUT("/:id/role", h.ChangeRole)
+		admin.POST("/:id/disable", h.DisableUser)
+		admin.POST("/:id/enable", h.EnableUser)
+		admin.DELETE("/:id/sessions", h.RevokeUserSessions)
+		admin.POST("/:id/key", h.ResetKey)
+		admin.DELETE("/:id/lock", h.UnlockUser)
+	}
+
+	emergency := protected.Group("/emergency-access")
+	{
+		emergency.POST("", authMiddleware.RequirePermission(domain.PermissionRequestEmergency), h.RequestEmergencyAccess)
+		emergency.GET("", authMiddleware.RequirePermission(domain.PermissionReviewEmergency), h.ListEmergencyAccess)
+		emergency.POST("/:id/review", authMiddleware.RequirePermission(domain.PermissionReviewEmergency), h.ReviewEmergencyAccess)
 	}
 }
 
@@ -48,7 +140,7 @@ func (h *AuthHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, key, err := h.createUserHandler.Handle(c.Request.Context(), cmd)
+	result, err := h.createUserHandler.Handle(c.Request.Context(), cmd)
 	if err != nil {
 		var status int
 		var response gin.H
@@ -71,10 +163,7 @@ func (h *AuthHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"user": user,
-		"key":  key,
-	})
+	c.JSON(http.StatusCreated, result)
 }
 
 // Login handles user authentication
@@ -90,33 +179,64 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	cmd.UserAgent = c.Request.UserAgent()
 
 	session, err := h.loginHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	h.setRefreshCookie(c, session.RefreshToken)
+	c.JSON(http.StatusOK, session)
+}
+
+// Refresh exchanges the refresh token cookie for a new access token and
+// rotates the refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	token, err := c.Cookie(refreshTokenCookie)
+	if err != nil || token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing refresh token"})
+		return
+	}
+
+	cmd := commands.RefreshCommand{
+		RefreshToken: token,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	}
+
+	result, err := h.refreshHandler.Handle(c.Request.Context(), cmd)
 	if err != nil {
 		var status int
 		var response gin.H
 
 		switch e := err.(type) {
 		case *domain.AuthError:
-			switch e.Code {
-			case domain.ErrInvalidCredentials:
-				status = http.StatusUnauthorized
-			case domain.ErrAccountLocked:
-				status = http.StatusForbidden
-			case domain.ErrUserNotFound:
-				status = http.StatusNotFound
-			default:
-				status = http.StatusBadRequest
-			}
+			status = http.StatusUnauthorized
 			response = gin.H{"error": e.Message, "code": e.Code}
 		default:
 			status = http.StatusInternalServerError
 			response = gin.H{"error": "internal server error"}
 		}
 
+		h.clearRefreshCookie(c)
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, session)
+	h.setRefreshCookie(c, result.RefreshToken)
+	c.JSON(http.StatusOK, result)
+}
+
+// setRefreshCookie stores the refresh token in a secure HttpOnly cookie that
+// is only sent back to the auth endpoints
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshTokenCookie, token, int(h.refreshTokenTTL.Seconds()), "/auth", "", true, true)
+}
+
+// clearRefreshCookie removes the refresh token cookie from the client
+func (h *AuthHandler) clearRefreshCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshTokenCookie, "", -1, "/auth", "", true, true)
 }
 
 // GetUser handles user retrieval
@@ -153,3 +273,353 @@ func (h *AuthHandler) GetUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// Logout ends the caller's current session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	cmd := commands.RevokeSessionCommand{
+		UserID:    c.GetString("userID"),
+		SessionID: c.GetString("sessionID"),
+	}
+
+	if err := h.revokeSessionHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	h.clearRefreshCookie(c)
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions returns the caller's active sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	query := queries.ListSessionsQuery{
+		UserID:           c.GetString("userID"),
+		CurrentSessionID: c.GetString("sessionID"),
+	}
+
+	sessions, err := h.listSessionsHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// GetPermissions returns the effective permissions of the caller
+func (h *AuthHandler) GetPermissions(c *gin.Context) {
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(domain.Role)
+
+	result, err := h.getPermissionsHandler.Handle(c.Request.Context(), queries.GetPermissionsQuery{Role: userRole})
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeSession ends one of the caller's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	cmd := commands.RevokeSessionCommand{
+		UserID:    c.GetString("userID"),
+		SessionID: id.String(),
+	}
+
+	if err := h.revokeSessionHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	if id.String() == c.GetString("sessionID") {
+		h.clearRefreshCookie(c)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeUserSessions ends every session of the given user
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	cmd := commands.RevokeUserSessionsCommand{UserID: id.String()}
+	if err := h.revokeUserSessionsHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePIN replaces the caller's PIN. After a forced change the client
+// refreshes its tokens to obtain an unrestricted access token.
+func (h *AuthHandler) ChangePIN(c *gin.Context) {
+	var cmd commands.ChangePINCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	cmd.UserID = c.GetString("userID")
+
+	if err := h.changePINHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResetKey issues a new key for the given user, invalidating the old key and
+// every session of the user
+func (h *AuthHandler) ResetKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	result, err := h.resetKeyHandler.Handle(c.Request.Context(), commands.ResetKeyCommand{UserID: id.String()})
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UnlockUser lifts the lockout of the given user
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := h.unlockUserHandler.Handle(c.Request.Context(), commands.UnlockUserCommand{UserID: id.String()})
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListUsers lists and searches user accounts
+func (h *AuthHandler) ListUsers(c *gin.Context) {
+	var query queries.ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	if query.PageSize > queries.MaxUsersPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page size must not exceed %d", queries.MaxUsersPageSize)})
+		return
+	}
+
+	result, err := h.listUsersHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ChangeRole assigns a user a new role
+func (h *AuthHandler) ChangeRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var cmd commands.ChangeRoleCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	cmd.ActorID = c.GetString("userID")
+	cmd.UserID = id.String()
+
+	user, err := h.changeRoleHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DisableUser disables a user account and ends its sessions
+func (h *AuthHandler) DisableUser(c *gin.Context) {
+	h.setUserDisabled(c, true)
+}
+
+// EnableUser re-enables a disabled user account
+func (h *AuthHandler) EnableUser(c *gin.Context) {
+	h.setUserDisabled(c, false)
+}
+
+// setUserDisabled disables or enables the user account named in the path
+func (h *AuthHandler) setUserDisabled(c *gin.Context, disabled bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	cmd := commands.SetUserDisabledCommand{
+		ActorID:  c.GetString("userID"),
+		UserID:   id.String(),
+		Disabled: disabled,
+	}
+
+	user, err := h.setUserDisabledHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser deletes a user account
+func (h *AuthHandler) DeleteUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	cmd := commands.DeleteUserCommand{
+		ActorID: c.GetString("userID"),
+		UserID:  id.String(),
+	}
+
+	if err := h.deleteUserHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RecoverKey exchanges a one-time recovery code and the PIN for a new key
+func (h *AuthHandler) RecoverKey(c *gin.Context) {
+	var cmd commands.RecoverKeyCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	result, err := h.recoverKeyHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	h.clearRefreshCookie(c)
+	c.JSON(http.StatusOK, result)
+}
+
+// RequestEmergencyAccess grants the caller time-boxed elevated access for
+// the stated reason and returns the elevated access token
+func (h *AuthHandler) RequestEmergencyAccess(c *gin.Context) {
+	var cmd commands.RequestEmergencyAccessCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		err := domain.ErrEmergencyReasonRequiredError
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Message, "code": err.Code})
+		return
+	}
+	cmd.UserID = c.GetString("userID")
+	cmd.SessionID = c.GetString("sessionID")
+	cmd.IPAddress = c.ClientIP()
+
+	result, err := h.requestEmergencyHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// ListEmergencyAccess lists break-the-glass grants with the requests made
+// under them
+func (h *AuthHandler) ListEmergencyAccess(c *gin.Context) {
+	var query queries.ListEmergencyAccessQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	grants, err := h.listEmergencyHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": grants})
+}
+
+// ReviewEmergencyAccess marks a break-the-glass grant as reviewed
+func (h *AuthHandler) ReviewEmergencyAccess(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid emergency access id"})
+		return
+	}
+
+	var cmd commands.ReviewEmergencyAccessCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	cmd.ReviewerID = c.GetString("userID")
+	cmd.GrantID = id.String()
+
+	grant, err := h.reviewEmergencyHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// respondWithError writes the error response for an auth error
+func (h *AuthHandler) respondWithError(c *gin.Context, err error) {
+	e, ok := err.(*domain.AuthError)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(getStatusCodeForError(e.Code), gin.H{"error": e.Message, "code": e.Code})
+}
+
+// getStatusCodeForError returns the appropriate HTTP status code for an auth error code
+func getStatusCodeForError(code string) int {
+	switch code {
+	case domain.ErrInvalidCredentials, domain.ErrInvalidToken, domain.ErrTokenReused, domain.ErrSessionExpired:
+		return http.StatusUnauthorized
+	case domain.ErrAccountLocked, domain.ErrAccountDisabled, domain.ErrSelfModification:
+		return http.StatusForbidden
+	case domain.ErrUserNotFound, domain.ErrSessionNotFound, domain.ErrEmergencyNotFound:
+		return http.StatusNotFound
+	case domain.ErrEmailTaken:
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -3,12 +3,16 @@ package infrastructure
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/dksch/pococlinic/internal/features/auth/domain"
 )
 
-// MemoryUserRepository is a simple in-memory implementation of the user repository
+// MemoryUserRepository is a simple in-memory implementation of the user
+// repository. It stores and hands out copies, so that callers cannot change
+// a user without saving it.
 type MemoryUserRepository struct {
 	users  map[string]*domain.User // key: user ID
 	emails map[string]string       // key: email, value: user ID
@@ -32,7 +36,7 @@ func (r *MemoryUserRepository) Create(ctx context.Context, user *domain.User) er
 		return fmt.Errorf("email %s already registered", user.Email)
 	}
 
-	r.users[user.ID.String()] = user
+	r.users[user.ID.String()] = copyUser(user)
 	r.emails[user.Email] = user.ID.String()
 	return nil
 }
@@ -46,7 +50,37 @@ func (r *MemoryUserRepository) Update(ctx context.Context, user *domain.User) er
 		return fmt.Errorf("user not found")
 	}
 
-	r.users[user.ID.String()] = user
+	r.users[user.ID.String()] = copyUser(user)
+	return nil
+}
+
+// RecordFailedAttempt counts a failed attempt of the stored user and locks
+// the account once the policy's threshold is reached
+func (r *MemoryUserRepository) RecordFailedAttempt(ctx context.Context, id string, policy domain.LockoutPolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	user.RecordFailedAttempt(policy)
+	return nil
+}
+
+// SwapRecoveryCodes replaces the recovery codes of a user, provided that
+// they are still the previous ones
+func (r *MemoryUserRepository) SwapRecoveryCodes(ctx context.Context, id string, previous, remaining []*domain.Credential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists || !sameCredentials(user.RecoveryCodes, previous) {
+		return domain.ErrInvalidCredentialsError
+	}
+
+	user.SetRecoveryCodes(append([]*domain.Credential(nil), remaining...))
 	return nil
 }
 
@@ -75,7 +109,7 @@ func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*domain.
 		return nil, fmt.Errorf("user not found")
 	}
 
-	return user, nil
+	return copyUser(user), nil
 }
 
 // GetByEmail retrieves a user by email
@@ -88,10 +122,72 @@ func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*d
 		return nil, fmt.Errorf("user not found")
 	}
 
-	return r.users[id], nil
+	return copyUser(r.users[id]), nil
+}
+
+// ListPaginated returns a page of users matching the filter, ordered by name
+func (r *MemoryUserRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.UserFilter) ([]*domain.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	matches := make([]*domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		matches = append(matches, copyUser(user))
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := strings.ToLower(matches[i].Name), strings.ToLower(matches[j].Name)
+		if a != b {
+			return a < b
+		}
+		return matches[i].ID.String() < matches[j].ID.String()
+	})
+
+	totalCount := int64(len(matches))
+	start := (page - 1) * pageSize
+	if start >= len(matches) {
+		return []*domain.User{}, totalCount, nil
+	}
+	end := start + pageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+	return matches[start:end], totalCount, nil
 }
 
-// MemorySessionRepository is a simple in-memory implementation of the session repository
+// copyUser returns a copy of a user. Credentials are replaced rather than
+// changed, so they can be shared.
+func copyUser(user *domain.User) *domain.User {
+	copied := *user
+	copied.RecoveryCodes = append([]*domain.Credential(nil), user.RecoveryCodes...)
+	return &copied
+}
+
+// sameCredentials reports whether two lists hold the same credentials
+func sameCredentials(a, b []*domain.Credential) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// MemorySessionRepository is a simple in-memory implementation of the
+// session repository. It stores and hands out copies, so that callers
+// cannot change a session without saving it.
 type MemorySessionRepository struct {
 	sessions map[string]*domain.Session // key: session ID
 	tokens   map[string]string          // key: refresh token, value: session ID
@@ -111,26 +207,39 @@ func (r *MemorySessionRepository) Create(ctx context.Context, session *domain.Se
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.sessions[session.ID.String()] = session
-	if session.RefreshToken != "" {
-		r.tokens[session.RefreshToken] = session.ID.String()
-	}
+	r.store(session)
 	return nil
 }
 
-// Update modifies an existing session
-func (r *MemorySessionRepository) Update(ctx context.Context, session *domain.Session) error {
+// Rotate stores the refreshed session, provided that it still holds the
+// previous refresh token
+func (r *MemorySessionRepository) Rotate(ctx context.Context, session *domain.Session, previousToken string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.sessions[session.ID.String()]; !exists {
-		return fmt.Errorf("session not found")
+	stored, exists := r.sessions[session.ID.String()]
+	if !exists {
+		return domain.ErrSessionNotFoundError
+	}
+	if stored.RefreshToken != previousToken {
+		return domain.ErrTokenReusedError
 	}
 
-	r.sessions[session.ID.String()] = session
+	delete(r.tokens, previousToken)
+	r.store(session)
 	return nil
 }
 
+// store saves a copy of the session and indexes its refresh token; the
+// caller must hold the write lock
+func (r *MemorySessionRepository) store(session *domain.Session) {
+	stored := *session
+	r.sessions[stored.ID.String()] = &stored
+	if stored.RefreshToken != "" {
+		r.tokens[stored.RefreshToken] = stored.ID.String()
+	}
+}
+
 // Delete removes a session
 func (r *MemorySessionRepository) Delete(ctx context.Context, id string) error {
 	r.mu.Lock()
@@ -158,7 +267,8 @@ func (r *MemorySessionRepository) GetByID(ctx context.Context, id string) (*doma
 		return nil, fmt.Errorf("session not found")
 	}
 
-	return session, nil
+	found := *session
+	return &found, nil
 }
 
 // GetByRefreshToken retrieves a session by refresh token
@@ -171,7 +281,48 @@ func (r *MemorySessionRepository) GetByRefreshToken(ctx context.Context, token s
 		return nil, fmt.Errorf("session not found")
 	}
 
-	return r.sessions[id], nil
+	session, exists := r.sessions[id]
+	if !exists || session.RefreshToken != token {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	found := *session
+	return &found, nil
+}
+
+// ListByUserID returns all sessions of a user, oldest first
+func (r *MemorySessionRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sessions []*domain.Session
+	for _, session := range r.sessions {
+		if session.UserID.String() == userID {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// DeleteByUserID removes all sessions of a user
+func (r *MemorySessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID.String() == userID {
+			delete(r.sessions, id)
+			if session.RefreshToken != "" {
+				delete(r.tokens, session.RefreshToken)
+			}
+		}
+	}
+	return nil
 }
 
 // DeleteExpired removes all expired sessions
@@ -189,3 +340,94 @@ func (r *MemorySessionRepository) DeleteExpired(ctx context.Context) error {
 	}
 	return nil
 }
+
+// MemoryEmergencyAccessRepository is a simple in-memory implementation of the emergency access repository
+type MemoryEmergencyAccessRepository struct {
+	grants map[string]*domain.EmergencyAccess       // key: grant ID
+	events map[string][]domain.EmergencyAccessEvent // key: grant ID
+	mu     sync.RWMutex
+}
+
+// NewMemoryEmergencyAccessRepository creates a new in-memory emergency access repository
+func NewMemoryEmergencyAccessRepository() *MemoryEmergencyAccessRepository {
+	return &MemoryEmergencyAccessRepository{
+		grants: make(map[string]*domain.EmergencyAccess),
+		events: make(map[string][]domain.EmergencyAccessEvent),
+	}
+}
+
+// Create adds a new emergency access grant
+func (r *MemoryEmergencyAccessRepository) Create(ctx context.Context, grant *domain.EmergencyAccess) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *grant
+	r.grants[grant.ID.String()] = &stored
+	return nil
+}
+
+// Update modifies an existing emergency access grant
+func (r *MemoryEmergencyAccessRepository) Update(ctx context.Context, grant *domain.EmergencyAccess) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.grants[grant.ID.String()]; !exists {
+		return fmt.Errorf("emergency access not found")
+	}
+	stored := *grant
+	r.grants[grant.ID.String()] = &stored
+	return nil
+}
+
+// GetByID retrieves an emergency access grant with its events
+func (r *MemoryEmergencyAccessRepository) GetByID(ctx context.Context, id string) (*domain.EmergencyAccess, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grant, exists := r.grants[id]
+	if !exists {
+		return nil, fmt.Errorf("emergency access not found")
+	}
+	return r.withEvents(grant), nil
+}
+
+// List returns emergency access grants with their events, newest first.
+// With pendingOnly only grants that have not been reviewed are returned.
+func (r *MemoryEmergencyAccessRepository) List(ctx context.Context, pendingOnly bool) ([]*domain.EmergencyAccess, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grants := []*domain.EmergencyAccess{}
+	for _, grant := range r.grants {
+		if pendingOnly && grant.IsReviewed() {
+			continue
+		}
+		grants = append(grants, r.withEvents(grant))
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].GrantedAt.After(grants[j].GrantedAt)
+	})
+	return grants, nil
+}
+
+// withEvents returns a copy of the stored grant together with its events;
+// the caller must hold the lock
+func (r *MemoryEmergencyAccessRepository) withEvents(grant *domain.EmergencyAccess) *domain.EmergencyAccess {
+	found := *grant
+	found.Events = append([]domain.EmergencyAccessEvent{}, r.events[grant.ID.String()]...)
+	return &found
+}
+
+// RecordEvent records a request made under an emergency access grant
+func (r *MemoryEmergencyAccessRepository) RecordEvent(ctx context.Context, event domain.EmergencyAccessEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := event.GrantID.String()
+	if _, exists := r.grants[id]; !exists {
+		return fmt.Errorf("emergency access not found")
+	}
+	r.events[id] = append(r.events[id], event)
+	return nil
+}
@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryEmergencyAccessRepositoryConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryEmergencyAccessRepository()
+
+	grant, err := domain.NewEmergencyAccess(uuid.New(), uuid.New(), "Unconscious patient in the ER", "127.0.0.1", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, grant))
+
+	// Readers and writers run side by side; the race detector reports any
+	// reader that changes the stored grant
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.RecordEvent(ctx, domain.EmergencyAccessEvent{GrantID: grant.ID, Method: "GET", Path: "/patients"}))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.GetByID(ctx, grant.ID.String())
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.List(ctx, true)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	found, err := repo.GetByID(ctx, grant.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, found.Events, 4)
+
+	// Changing a returned grant does not change the stored one
+	found.ReviewNote = "changed"
+	stored, err := repo.GetByID(ctx, grant.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, stored.ReviewNote)
+}
@@ -0,0 +1,779 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// sqliteMigrations holds the schema of the auth feature, in order
+var sqliteMigrations = []string{
+	`CREATE TABLE users (
+		id              TEXT PRIMARY KEY,
+		email           TEXT NOT NULL UNIQUE,
+		name            TEXT NOT NULL,
+		role            TEXT NOT NULL,
+		key_hash        BLOB,
+		key_salt        BLOB,
+		pin_hash        BLOB,
+		pin_salt        BLOB,
+		failed_attempts INTEGER NOT NULL DEFAULT 0,
+		locked_until    TIMESTAMP,
+		last_login      TIMESTAMP,
+		created_at      TIMESTAMP NOT NULL,
+		updated_at      TIMESTAMP NOT NULL
+	);
+	CREATE TABLE sessions (
+		id            TEXT PRIMARY KEY,
+		user_id       TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		refresh_token TEXT NOT NULL DEFAULT '',
+		user_agent    TEXT NOT NULL DEFAULT '',
+		ip_address    TEXT NOT NULL DEFAULT '',
+		expires_at    TIMESTAMP NOT NULL,
+		created_at    TIMESTAMP NOT NULL,
+		updated_at    TIMESTAMP NOT NULL
+	);
+	CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+	CREATE INDEX idx_sessions_refresh_token ON sessions (refresh_token);`,
+	// Credentials are stored in their self-describing encoding; the raw
+	// hash and salt columns are only read for rows written before this.
+	`ALTER TABLE users ADD COLUMN key_credential TEXT;
+	ALTER TABLE users ADD COLUMN pin_credential TEXT;`,
+	`ALTER TABLE users ADD COLUMN must_change_pin INTEGER NOT NULL DEFAULT 0;`,
+	// One encoded credential per line
+	`ALTER TABLE users ADD COLUMN recovery_codes TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE users ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX idx_users_name ON users (name COLLATE NOCASE);`,
+	// Emergency access is kept for review even after the user is deleted,
+	// so it does not reference the users table
+	`CREATE TABLE emergency_access (
+		id          TEXT PRIMARY KEY,
+		user_id     TEXT NOT NULL,
+		session_id  TEXT NOT NULL,
+		reason      TEXT NOT NULL,
+		ip_address  TEXT NOT NULL DEFAULT '',
+		granted_at  TIMESTAMP NOT NULL,
+		expires_at  TIMESTAMP NOT NULL,
+		reviewed_by TEXT,
+		reviewed_at TIMESTAMP,
+		review_note TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE emergency_access_events (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		grant_id    TEXT NOT NULL REFERENCES emergency_access (id),
+		method      TEXT NOT NULL,
+		path        TEXT NOT NULL,
+		ip_address  TEXT NOT NULL DEFAULT '',
+		accessed_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX idx_emergency_access_events_grant_id ON emergency_access_events (grant_id);`,
+	// Refresh tokens are stored as hashes from here on; sessions that still
+	// hold a plain token are ended and their users sign in again
+	`DELETE FROM sessions;`,
+}
+
+// MigrateSQLite applies the auth schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "auth", sqliteMigrations)
+}
+
+// userColumns lists the user columns in the order scanUser expects
+const userColumns = `id, email, name, role, key_credential, pin_credential,
+	key_hash, key_salt, pin_hash, pin_salt, recovery_codes, must_change_pin, disabled,
+	failed_attempts, locked_until, last_login, created_at, updated_at`
+
+// SQLiteUserRepository is a SQLite implementation of the user repository
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteUserRepository creates a new SQLite user repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteUserRepository(db *sql.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{db: db}
+}
+
+// Create adds a new user
+func (r *SQLiteUserRepository) Create(ctx context.Context, user *domain.User) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, NULL, NULL, NULL, NULL, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID.String(),
+		user.Email,
+		user.Name,
+		string(user.Role),
+		encodeCredential(user.KeyCredential),
+		encodeCredential(user.PINCredential),
+		encodeCredentials(user.RecoveryCodes),
+		user.MustChangePIN,
+		user.Disabled,
+		user.FailedAttempts,
+		nullTime(user.LockedUntil),
+		nullTime(user.LastLogin),
+		user.CreatedAt.UTC(),
+		user.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user %s: %w", user.Email, err)
+	}
+	return nil
+}
+
+// Update modifies an existing user
+func (r *SQLiteUserRepository) Update(ctx context.Context, user *domain.User) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET
+		email = ?, name = ?, role = ?, key_credential = ?, pin_credential = ?,
+		key_hash = NULL, key_salt = NULL, pin_hash = NULL, pin_salt = NULL,
+		recovery_codes = ?, must_change_pin = ?, disabled = ?, failed_attempts = ?, locked_until = ?, last_login = ?, updated_at = ?
+		WHERE id = ?`,
+		user.Email,
+		user.Name,
+		string(user.Role),
+		encodeCredential(user.KeyCredential),
+		encodeCredential(user.PINCredential),
+		encodeCredentials(user.RecoveryCodes),
+		user.MustChangePIN,
+		user.Disabled,
+		user.FailedAttempts,
+		nullTime(user.LockedUntil),
+		nullTime(user.LastLogin),
+		user.UpdatedAt.UTC(),
+		user.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user %s: %w", user.ID, err)
+	}
+	return expectOneRow(result, "user not found")
+}
+
+// RecordFailedAttempt counts a failed attempt and locks the account once
+// the policy's threshold is reached, in a single statement so that
+// concurrent failures are all counted. As in domain.User, counting starts
+// over after an expired lock.
+func (r *SQLiteUserRepository) RecordFailedAttempt(ctx context.Context, id string, policy domain.LockoutPolicy) error {
+	now := time.Now().UTC()
+	lockUntil := now.Add(policy.Duration)
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET
+		failed_attempts = CASE WHEN locked_until <= ? THEN 1 ELSE failed_attempts + 1 END,
+		locked_until = CASE
+			WHEN locked_until <= ? THEN CASE WHEN 1 >= ? THEN ? END
+			WHEN failed_attempts + 1 >= ? THEN ?
+			ELSE locked_until END,
+		updated_at = ?
+		WHERE id = ?`,
+		now,
+		now, policy.MaxAttempts, lockUntil,
+		policy.MaxAttempts, lockUntil,
+		now,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record failed attempt of user %s: %w", id, err)
+	}
+	return expectOneRow(result, "user not found")
+}
+
+// SwapRecoveryCodes replaces the recovery codes of a user, provided that
+// they are still the previous ones
+func (r *SQLiteUserRepository) SwapRecoveryCodes(ctx context.Context, id string, previous, remaining []*domain.Credential) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET recovery_codes = ?, updated_at = ?
+		WHERE id = ? AND recovery_codes = ?`,
+		encodeCredentials(remaining),
+		time.Now().UTC(),
+		id,
+		encodeCredentials(previous),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update recovery codes of user %s: %w", id, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update recovery codes of user %s: %w", id, err)
+	}
+	if rows == 0 {
+		return domain.ErrInvalidCredentialsError
+	}
+	return nil
+}
+
+// Delete removes a user together with their sessions
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user %s: %w", id, err)
+	}
+	return expectOneRow(result, "user not found")
+}
+
+// GetByID retrieves a user by ID
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+	return scanUser(row)
+}
+
+// GetByEmail retrieves a user by email
+func (r *SQLiteUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+	return scanUser(row)
+}
+
+// ListPaginated returns a page of users matching the filter, ordered by name
+func (r *SQLiteUserRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.UserFilter) ([]*domain.User, int64, error) {
+	where := `WHERE (? = '' OR role = ?)
+		AND (lower(name) LIKE '%' || lower(?) || '%' ESCAPE '\'
+			OR lower(email) LIKE '%' || lower(?) || '%' ESCAPE '\')`
+	pattern := database.EscapeLike(filter.Search)
+	args := []any{string(filter.Role), string(filter.Role), pattern, pattern}
+
+	var totalCount int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+` FROM users `+where+` ORDER BY name COLLATE NOCASE, id LIMIT ? OFFSET ?`,
+		append(args, pageSize, (page-1)*pageSize)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*domain.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, totalCount, nil
+}
+
+// scanUser reads a user selected with userColumns
+func scanUser(row rowScanner) (*domain.User, error) {
+	var (
+		user             domain.User
+		id, role         string
+		keyEncoded       sql.NullString
+		pinEncoded       sql.NullString
+		keyHash, keySalt []byte
+		pinHash, pinSalt []byte
+		recoveryCodes    string
+		lockedUntil      sql.NullTime
+		lastLogin        sql.NullTime
+	)
+	err := row.Scan(
+		&id,
+		&user.Email,
+		&user.Name,
+		&role,
+		&keyEncoded,
+		&pinEncoded,
+		&keyHash,
+		&keySalt,
+		&pinHash,
+		&pinSalt,
+		&recoveryCodes,
+		&user.MustChangePIN,
+		&user.Disabled,
+		&user.FailedAttempts,
+		&lockedUntil,
+		&lastLogin,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user: %w", err)
+	}
+
+	if user.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid user ID %q: %w", id, err)
+	}
+	user.Role = domain.Role(role)
+	if user.KeyCredential, err = decodeCredential(keyEncoded, keyHash, keySalt); err != nil {
+		return nil, fmt.Errorf("invalid key credential for user %s: %w", id, err)
+	}
+	if user.PINCredential, err = decodeCredential(pinEncoded, pinHash, pinSalt); err != nil {
+		return nil, fmt.Errorf("invalid PIN credential for user %s: %w", id, err)
+	}
+	if user.RecoveryCodes, err = decodeCredentials(recoveryCodes); err != nil {
+		return nil, fmt.Errorf("invalid recovery codes for user %s: %w", id, err)
+	}
+	user.LockedUntil = timePtr(lockedUntil)
+	user.LastLogin = timePtr(lastLogin)
+
+	return &user, nil
+}
+
+// sessionColumns lists the session columns in the order scanSession expects
+const sessionColumns = `id, user_id, refresh_token, user_agent, ip_address,
+	expires_at, created_at, updated_at`
+
+// SQLiteSessionRepository is a SQLite implementation of the session repository
+type SQLiteSessionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteSessionRepository creates a new SQLite session repository. The
+// schema must have been migrated with MigrateSQLite.
+func NewSQLiteSessionRepository(db *sql.DB) *SQLiteSessionRepository {
+	return &SQLiteSessionRepository{db: db}
+}
+
+// Create adds a new session
+func (r *SQLiteSessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO sessions (`+sessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID.String(),
+		session.UserID.String(),
+		hashToken(session.RefreshToken),
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt.UTC(),
+		session.CreatedAt.UTC(),
+		session.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// Rotate stores the refreshed session, provided that it still holds the
+// previous refresh token
+func (r *SQLiteSessionRepository) Rotate(ctx context.Context, session *domain.Session, previousToken string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE sessions SET
+		refresh_token = ?, user_agent = ?, ip_address = ?, expires_at = ?, updated_at = ?
+		WHERE id = ? AND refresh_token = ?`,
+		hashToken(session.RefreshToken),
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt.UTC(),
+		session.UpdatedAt.UTC(),
+		session.ID.String(),
+		hashToken(previousToken),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if rows == 0 {
+		var exists int
+		err := r.db.QueryRowContext(ctx, `SELECT 1 FROM sessions WHERE id = ?`, session.ID.String()).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrSessionNotFoundError
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read session: %w", err)
+		}
+		return domain.ErrTokenReusedError
+	}
+	return nil
+}
+
+// Delete removes a session
+func (r *SQLiteSessionRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return expectOneRow(result, "session not found")
+}
+
+// GetByID retrieves a session by ID
+func (r *SQLiteSessionRepository) GetByID(ctx context.Context, id string) (*domain.Session, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id)
+	return scanSession(row)
+}
+
+// GetByRefreshToken retrieves a session by refresh token
+func (r *SQLiteSessionRepository) GetByRefreshToken(ctx context.Context, token string) (*domain.Session, error) {
+	if token == "" {
+		return nil, fmt.Errorf("session not found")
+	}
+	row := r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE refresh_token = ?`, hashToken(token))
+	return scanSession(row)
+}
+
+// ListByUserID returns all sessions of a user, oldest first
+func (r *SQLiteSessionRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.Session, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? ORDER BY created_at, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteByUserID removes all sessions of a user
+func (r *SQLiteSessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes all expired sessions
+func (r *SQLiteSessionRepository) DeleteExpired(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
+
+// emergencyAccessColumns lists the emergency access columns in the order
+// scanEmergencyAccess expects
+const emergencyAccessColumns = `id, user_id, session_id, reason, ip_address,
+	granted_at, expires_at, reviewed_by, reviewed_at, review_note`
+
+// SQLiteEmergencyAccessRepository is a SQLite implementation of the emergency access repository
+type SQLiteEmergencyAccessRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteEmergencyAccessRepository creates a new SQLite emergency access
+// repository. The schema must have been migrated with MigrateSQLite.
+func NewSQLiteEmergencyAccessRepository(db *sql.DB) *SQLiteEmergencyAccessRepository {
+	return &SQLiteEmergencyAccessRepository{db: db}
+}
+
+// Create adds a new emergency access grant
+func (r *SQLiteEmergencyAccessRepository) Create(ctx context.Context, grant *domain.EmergencyAccess) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO emergency_access (`+emergencyAccessColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		grant.ID.String(),
+		grant.UserID.String(),
+		grant.SessionID.String(),
+		grant.Reason,
+		grant.IPAddress,
+		grant.GrantedAt.UTC(),
+		grant.ExpiresAt.UTC(),
+		nullUUID(grant.ReviewedBy),
+		nullTime(grant.ReviewedAt),
+		grant.ReviewNote,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create emergency access: %w", err)
+	}
+	return nil
+}
+
+// Update modifies the review of an existing emergency access grant
+func (r *SQLiteEmergencyAccessRepository) Update(ctx context.Context, grant *domain.EmergencyAccess) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE emergency_access SET
+		reviewed_by = ?, reviewed_at = ?, review_note = ?
+		WHERE id = ?`,
+		nullUUID(grant.ReviewedBy),
+		nullTime(grant.ReviewedAt),
+		grant.ReviewNote,
+		grant.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update emergency access: %w", err)
+	}
+	return expectOneRow(result, "emergency access not found")
+}
+
+// GetByID retrieves an emergency access grant with its events
+func (r *SQLiteEmergencyAccessRepository) GetByID(ctx context.Context, id string) (*domain.EmergencyAccess, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+emergencyAccessColumns+` FROM emergency_access WHERE id = ?`, id)
+	grant, err := scanEmergencyAccess(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("emergency access not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.Events, err = r.listEvents(ctx, id); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// List returns emergency access grants with their events, newest first.
+// With pendingOnly only grants that have not been reviewed are returned.
+func (r *SQLiteEmergencyAccessRepository) List(ctx context.Context, pendingOnly bool) ([]*domain.EmergencyAccess, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+emergencyAccessColumns+` FROM emergency_access
+		WHERE NOT ? OR reviewed_at IS NULL
+		ORDER BY granted_at DESC, id`,
+		pendingOnly,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list emergency access: %w", err)
+	}
+
+	grants := []*domain.EmergencyAccess{}
+	for rows.Next() {
+		grant, err := scanEmergencyAccess(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list emergency access: %w", err)
+	}
+
+	// Events are read once the grant rows are closed; the pool has a single connection
+	for _, grant := range grants {
+		if grant.Events, err = r.listEvents(ctx, grant.ID.String()); err != nil {
+			return nil, err
+		}
+	}
+	return grants, nil
+}
+
+// RecordEvent records a request made under an emergency access grant
+func (r *SQLiteEmergencyAccessRepository) RecordEvent(ctx context.Context, event domain.EmergencyAccessEvent) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO emergency_access_events
+		(grant_id, method, path, ip_address, accessed_at) VALUES (?, ?, ?, ?, ?)`,
+		event.GrantID.String(),
+		event.Method,
+		event.Path,
+		event.IPAddress,
+		event.AccessedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record emergency access: %w", err)
+	}
+	return nil
+}
+
+// listEvents returns the events of a grant in the order they happened
+func (r *SQLiteEmergencyAccessRepository) listEvents(ctx context.Context, grantID string) ([]domain.EmergencyAccessEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT grant_id, method, path, ip_address, accessed_at
+		FROM emergency_access_events WHERE grant_id = ? ORDER BY id`,
+		grantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list emergency access events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.EmergencyAccessEvent{}
+	for rows.Next() {
+		var (
+			event domain.EmergencyAccessEvent
+			id    string
+		)
+		if err := rows.Scan(&id, &event.Method, &event.Path, &event.IPAddress, &event.AccessedAt); err != nil {
+			return nil, fmt.Errorf("failed to read emergency access event: %w", err)
+		}
+		if event.GrantID, err = uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("invalid emergency access ID %q: %w", id, err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// scanEmergencyAccess reads a grant selected with emergencyAccessColumns
+func scanEmergencyAccess(row rowScanner) (*domain.EmergencyAccess, error) {
+	var (
+		grant                 domain.EmergencyAccess
+		id, userID, sessionID string
+		reviewedBy            sql.NullString
+		reviewedAt            sql.NullTime
+	)
+	err := row.Scan(
+		&id,
+		&userID,
+		&sessionID,
+		&grant.Reason,
+		&grant.IPAddress,
+		&grant.GrantedAt,
+		&grant.ExpiresAt,
+		&reviewedBy,
+		&reviewedAt,
+		&grant.ReviewNote,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid emergency access ID %q: %w", id, err)
+	}
+	if grant.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("invalid user ID %q: %w", userID, err)
+	}
+	if grant.SessionID, err = uuid.Parse(sessionID); err != nil {
+		return nil, fmt.Errorf("invalid session ID %q: %w", sessionID, err)
+	}
+	if reviewedBy.Valid {
+		reviewer, err := uuid.Parse(reviewedBy.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reviewer ID %q: %w", reviewedBy.String, err)
+		}
+		grant.ReviewedBy = &reviewer
+	}
+	grant.ReviewedAt = timePtr(reviewedAt)
+	grant.Events = []domain.EmergencyAccessEvent{}
+
+	return &grant, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSession reads a session selected with sessionColumns
+func scanSession(row rowScanner) (*domain.Session, error) {
+	var (
+		session           domain.Session
+		id, userID, token string
+	)
+	err := row.Scan(
+		&id,
+		&userID,
+		&token, // only the hash is stored
+
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	if session.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid session ID %q: %w", id, err)
+	}
+	if session.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("invalid user ID %q: %w", userID, err)
+	}
+	return &session, nil
+}
+
+// hashToken returns the stored form of a refresh token. Only its SHA-256
+// hash is kept, so that a copy of the database cannot be used to refresh
+// sessions.
+func hashToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// encodeCredential converts an optional credential into its stored encoding
+func encodeCredential(cred *domain.Credential) sql.NullString {
+	if cred == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: cred.String(), Valid: true}
+}
+
+// decodeCredential rebuilds a credential from its stored encoding, falling
+// back to the raw hash and salt columns of rows that predate it
+func decodeCredential(encoded sql.NullString, hash, salt []byte) (*domain.Credential, error) {
+	if encoded.Valid {
+		return domain.ParseCredential(encoded.String)
+	}
+	if hash == nil {
+		return nil, nil
+	}
+	return domain.NewLegacyCredential(hash, salt), nil
+}
+
+// encodeCredentials stores a list of credentials one per line
+func encodeCredentials(creds []*domain.Credential) string {
+	encoded := make([]string, len(creds))
+	for i, cred := range creds {
+		encoded[i] = cred.String()
+	}
+	return strings.Join(encoded, "\n")
+}
+
+// decodeCredentials reads a list of credentials stored by encodeCredentials
+func decodeCredentials(encoded string) ([]*domain.Credential, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	lines := strings.Split(encoded, "\n")
+	creds := make([]*domain.Credential, len(lines))
+	for i, line := range lines {
+		cred, err := domain.ParseCredential(line)
+		if err != nil {
+			return nil, err
+		}
+		creds[i] = cred
+	}
+	return creds, nil
+}
+
+// nullTime converts an optional time into a nullable column value
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// nullUUID converts an optional ID into a nullable column value
+func nullUUID(id *uuid.UUID) sql.NullString {
+	if id == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.String(), Valid: true}
+}
+
+// timePtr converts a nullable column value into an optional time
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// expectOneRow reports a not found error when a write matched no row
+func expectOneRow(result sql.Result, notFound string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New(notFound)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package infrastructure
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepositories(t *testing.T) (*SQLiteUserRepository, *SQLiteSessionRepository) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "auth.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteUserRepository(db), NewSQLiteSessionRepository(db)
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	ctx := context.Background()
+	users, _ := setupSQLiteRepositories(t)
+
+	user := domain.NewUser("doctor@example.com", "Dr. Smith", domain.RoleDoctor)
+	key, keyCred, err := domain.GenerateKey(domain.DefaultArgon2Params)
+	require.NoError(t, err)
+	user.SetKeyCredential(keyCred)
+	pinCred, err := domain.GeneratePINCredential("4821", domain.DefaultArgon2Params)
+	require.NoError(t, err)
+	user.SetPINCredential(pinCred)
+	user.RequirePINChange()
+	codes, codeCreds, err := domain.GenerateRecoveryCodes(domain.DefaultArgon2Params)
+	require.NoError(t, err)
+	user.SetRecoveryCodes(codeCreds)
+	require.NoError(t, users.Create(ctx, user))
+
+	// Duplicate emails are rejected
+	assert.Error(t, users.Create(ctx, domain.NewUser("doctor@example.com", "Other", domain.RoleNurse)))
+
+	stored, err := users.GetByEmail(ctx, "doctor@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.ID)
+	valid, _ := stored.ValidateCredentials(key, "4821", domain.DefaultArgon2Params)
+	assert.True(t, valid)
+	assert.Nil(t, stored.LockedUntil)
+	assert.True(t, stored.MustChangePIN)
+	assert.Len(t, stored.RecoveryCodes, domain.RecoveryCodeCount)
+	previous := stored.RecoveryCodes
+	assert.True(t, stored.ConsumeRecoveryCode(codes[0], domain.DefaultArgon2Params))
+	require.NoError(t, users.SwapRecoveryCodes(ctx, user.ID.String(), previous, stored.RecoveryCodes))
+	// The code is used up, a second swap from the same codes fails
+	assert.Equal(t, domain.ErrInvalidCredentialsError,
+		users.SwapRecoveryCodes(ctx, user.ID.String(), previous, stored.RecoveryCodes))
+
+	stored.RecordFailedAttempt(domain.DefaultLockoutPolicy)
+	lockedUntil := time.Now().Add(time.Hour)
+	stored.LockedUntil = &lockedUntil
+	stored.MustChangePIN = false
+	require.NoError(t, users.Update(ctx, stored))
+
+	reloaded, err := users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reloaded.FailedAttempts)
+	assert.True(t, reloaded.IsLocked())
+	assert.False(t, reloaded.MustChangePIN)
+	assert.Len(t, reloaded.RecoveryCodes, domain.RecoveryCodeCount-1)
+
+	require.NoError(t, users.Delete(ctx, user.ID.String()))
+	_, err = users.GetByID(ctx, user.ID.String())
+	assert.Error(t, err)
+}
+
+func TestSQLiteUserRepositoryRecordFailedAttempt(t *testing.T) {
+	ctx := context.Background()
+	users, _ := setupSQLiteRepositories(t)
+	policy := domain.LockoutPolicy{MaxAttempts: 3, Duration: time.Hour}
+
+	user := domain.NewUser("clerk@example.com", "Clerk", domain.RoleStaff)
+	require.NoError(t, users.Create(ctx, user))
+
+	for i := 1; i <= policy.MaxAttempts; i++ {
+		require.NoError(t, users.RecordFailedAttempt(ctx, user.ID.String(), policy))
+		stored, err := users.GetByID(ctx, user.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, i, stored.FailedAttempts)
+		assert.Equal(t, i == policy.MaxAttempts, stored.IsLocked())
+	}
+
+	// Counting starts over once the lock has expired
+	stored, err := users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+	stored.LockedUntil = &expired
+	require.NoError(t, users.Update(ctx, stored))
+	require.NoError(t, users.RecordFailedAttempt(ctx, user.ID.String(), policy))
+	stored, err = users.GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.FailedAttempts)
+	assert.False(t, stored.IsLocked())
+
+	assert.Error(t, users.RecordFailedAttempt(ctx, "missing", policy))
+}
+
+func TestSQLiteSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	users, sessions := setupSQLiteRepositories(t)
+
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	require.NoError(t, users.Create(ctx, user))
+
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	session.RefreshToken = "token-1"
+	require.NoError(t, sessions.Create(ctx, session))
+
+	expired := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(-time.Hour))
+	require.NoError(t, sessions.Create(ctx, expired))
+
+	found, err := sessions.GetByRefreshToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, found.ID)
+
+	// Only a hash of the token is stored
+	var stored string
+	require.NoError(t, sessions.db.QueryRowContext(ctx,
+		`SELECT refresh_token FROM sessions WHERE id = ?`, session.ID.String()).Scan(&stored))
+	assert.NotContains(t, stored, "token-1")
+
+	// Rotating the token replaces the lookup key
+	found.RefreshToken = "token-2"
+	require.NoError(t, sessions.Rotate(ctx, found, "token-1"))
+	_, err = sessions.GetByRefreshToken(ctx, "token-1")
+	assert.Error(t, err)
+	_, err = sessions.GetByRefreshToken(ctx, "token-2")
+	assert.NoError(t, err)
+
+	// A second rotation of the same token loses
+	found.RefreshToken = "token-3"
+	assert.Equal(t, domain.ErrTokenReusedError, sessions.Rotate(ctx, found, "token-1"))
+	_, err = sessions.GetByRefreshToken(ctx, "token-2")
+	assert.NoError(t, err)
+
+	// nor can a revoked session be rotated
+	require.NoError(t, sessions.Delete(ctx, expired.ID.String()))
+	expired.RefreshToken = "token-4"
+	assert.Equal(t, domain.ErrSessionNotFoundError, sessions.Rotate(ctx, expired, "expired-token"))
+
+	require.NoError(t, sessions.DeleteExpired(ctx))
+	list, err := sessions.ListByUserID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	// Deleting the user removes their sessions
+	require.NoError(t, users.Delete(ctx, user.ID.String()))
+	list, err = sessions.ListByUserID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestSQLiteUserRepositoryReadsLegacyCredentials(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "auth.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, MigrateSQLite(ctx, db))
+
+	// Simulate a row written before credentials were encoded
+	key, keyCred, err := domain.GenerateKey(domain.DefaultArgon2Params)
+	require.NoError(t, err)
+	pinCred, err := domain.GeneratePINCredential("4821", domain.DefaultArgon2Params)
+	require.NoError(t, err)
+	now := time.Now().UTC()
+	_, err = db.ExecContext(ctx, `INSERT INTO users
+		(id, email, name, role, key_hash, key_salt, pin_hash, pin_salt, created_at, updated_at)
+		VALUES ('7d3c3a4e-8f7b-4a51-9d36-0d4c3b2a1f00', 'legacy@example.com', 'Legacy', 'staff', ?, ?, ?, ?, ?, ?)`,
+		keyCred.Hash, keyCred.Salt, pinCred.Hash, pinCred.Salt, now, now,
+	)
+	require.NoError(t, err)
+
+	users := NewSQLiteUserRepository(db)
+	user, err := users.GetByEmail(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	valid, _ := user.ValidateCredentials(key, "4821", domain.DefaultArgon2Params)
+	assert.True(t, valid)
+
+	// Saving the user rewrites the credentials in the encoded format
+	require.NoError(t, users.Update(ctx, user))
+	var encoded string
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT key_credential FROM users WHERE email = 'legacy@example.com'`,
+	).Scan(&encoded))
+	assert.Equal(t, keyCred.String(), encoded)
+
+	reloaded, err := users.GetByEmail(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	valid, _ = reloaded.ValidateCredentials(key, "4821", domain.DefaultArgon2Params)
+	assert.True(t, valid)
+}
+
+func TestSQLiteUserRepositoryListPaginated(t *testing.T) {
+	ctx := context.Background()
+	users, _ := setupSQLiteRepositories(t)
+
+	for _, u := range []*domain.User{
+		domain.NewUser("zoe@example.com", "Zoe Brown", domain.RoleNurse),
+		domain.NewUser("adam@example.com", "adam Smith", domain.RoleDoctor),
+		domain.NewUser("maria@clinic.org", "Maria Smith", domain.RoleDoctor),
+		domain.NewUser("under_score@example.com", "Percent 100%", domain.RoleStaff),
+	} {
+		require.NoError(t, users.Create(ctx, u))
+	}
+
+	all, total, err := users.ListPaginated(ctx, 1, 10, domain.UserFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+	require.Len(t, all, 4)
+	assert.Equal(t, []string{"adam Smith", "Maria Smith", "Percent 100%", "Zoe Brown"},
+		[]string{all[0].Name, all[1].Name, all[2].Name, all[3].Name})
+
+	smiths, total, err := users.ListPaginated(ctx, 2, 1, domain.UserFilter{Search: "SMITH"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, smiths, 1)
+	assert.Equal(t, "Maria Smith", smiths[0].Name)
+
+	byEmail, _, err := users.ListPaginated(ctx, 1, 10, domain.UserFilter{Search: "clinic.org"})
+	require.NoError(t, err)
+	require.Len(t, byEmail, 1)
+	assert.Equal(t, "maria@clinic.org", byEmail[0].Email)
+
+	doctors, total, err := users.ListPaginated(ctx, 1, 10, domain.UserFilter{Role: domain.RoleDoctor, Search: "adam"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, "adam Smith", doctors[0].Name)
+
+	// Wildcards in the search are matched literally
+	literal, _, err := users.ListPaginated(ctx, 1, 10, domain.UserFilter{Search: "%"})
+	require.NoError(t, err)
+	require.Len(t, literal, 1)
+	assert.Equal(t, "Percent 100%", literal[0].Name)
+}
+
+func TestSQLiteEmergencyAccessRepository(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "auth.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, MigrateSQLite(ctx, db))
+	repo := NewSQLiteEmergencyAccessRepository(db)
+
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	grant, err := domain.NewEmergencyAccess(user.ID, session.ID, "Unconscious patient in ER", "127.0.0.1", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, grant))
+
+	for _, path := range []string{"/api/v1/patients", "/api/v1/patients/42"} {
+		require.NoError(t, repo.RecordEvent(ctx, domain.EmergencyAccessEvent{
+			GrantID:    grant.ID,
+			Method:     "GET",
+			Path:       path,
+			IPAddress:  "127.0.0.1",
+			AccessedAt: time.Now(),
+		}))
+	}
+
+	stored, err := repo.GetByID(ctx, grant.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Unconscious patient in ER", stored.Reason)
+	assert.Equal(t, session.ID, stored.SessionID)
+	require.Len(t, stored.Events, 2)
+	assert.Equal(t, "/api/v1/patients/42", stored.Events[1].Path)
+
+	pending, err := repo.List(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Len(t, pending[0].Events, 2)
+
+	stored.Review(user.ID, "Justified")
+	require.NoError(t, repo.Update(ctx, stored))
+
+	pending, err = repo.List(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	all, err := repo.List(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.NotNil(t, all[0].ReviewedBy)
+	assert.Equal(t, user.ID, *all[0].ReviewedBy)
+	assert.Equal(t, "Justified", all[0].ReviewNote)
+
+	_, err = repo.GetByID(ctx, "missing")
+	assert.Error(t, err)
+}
@@ -3,25 +3,53 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/dksch/pococlinic/internal/features/auth/domain"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AuthMiddleware provides authentication and authorization middleware
 type AuthMiddleware struct {
-	tokenConfig domain.TokenConfig
+	tokenConfig       domain.TokenConfig
+	sessionRepository domain.GetSessionRepository
+	permissions       domain.PermissionMatrix
+	emergencyPolicy   domain.EmergencyAccessPolicy
+	emergencyRecorder domain.RecordEmergencyAccessRepository
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(config domain.TokenConfig) *AuthMiddleware {
+func NewAuthMiddleware(
+	config domain.TokenConfig,
+	sessionRepo domain.GetSessionRepository,
+	permissions domain.PermissionMatrix,
+	emergencyPolicy domain.EmergencyAccessPolicy,
+	emergencyRepo domain.RecordEmergencyAccessRepository,
+) *AuthMiddleware {
 	return &AuthMiddleware{
-		tokenConfig: config,
+		tokenConfig:       config,
+		sessionRepository: sessionRepo,
+		permissions:       permissions,
+		emergencyPolicy:   emergencyPolicy,
+		emergencyRecorder: emergencyRepo,
 	}
 }
 
-// RequireAuth validates the access token and adds user claims to the context
+// RequireAuth validates the access token and adds user claims to the context.
+// Tokens of users who still have to change their PIN are rejected.
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return m.authenticate(false)
+}
+
+// RequireAuthAllowingPINChange is RequireAuth for the change PIN route, the
+// only route that accepts tokens of users with a pending forced PIN change
+func (m *AuthMiddleware) RequireAuthAllowingPINChange() gin.HandlerFunc {
+	return m.authenticate(true)
+}
+
+// authenticate builds the access token middleware
+func (m *AuthMiddleware) authenticate(allowPINChange bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -44,9 +72,47 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens whose session has been revoked or has expired
+		session, err := m.sessionRepository.GetByID(c.Request.Context(), claims.SessionID)
+		if err != nil || session == nil || session.IsExpired() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+
+		// Users with a pending forced PIN change may only change their PIN
+		if claims.PINChangeRequired && !allowPINChange {
+			err := domain.ErrPINChangeRequiredError
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Message, "code": err.Code})
+			return
+		}
+
+		// Requests under emergency access are recorded for review before
+		// they are served; if that fails the request is refused
+		if claims.EmergencyAccessID != "" {
+			grantID, err := uuid.Parse(claims.EmergencyAccessID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+
+			event := domain.EmergencyAccessEvent{
+				GrantID:    grantID,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				IPAddress:  c.ClientIP(),
+				AccessedAt: time.Now(),
+			}
+			if err := m.emergencyRecorder.RecordEvent(c.Request.Context(), event); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to record emergency access"})
+				return
+			}
+			c.Set("emergencyAccessID", claims.EmergencyAccessID)
+		}
+
 		// Add claims to context
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
+		c.Set("sessionID", claims.SessionID)
 		c.Next()
 	}
 }
@@ -71,3 +137,35 @@ func (m *AuthMiddleware) RequireRole(roles ...domain.Role) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
 	}
 }
+
+// RequirePermission ensures the user's role is granted the permission.
+// Under emergency access the permissions of the emergency policy apply too.
+func (m *AuthMiddleware) RequirePermission(permission domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("userRole"); !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing user role"})
+			return
+		}
+		if !m.Allows(c, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Allows reports whether the authenticated user holds the permission, the
+// same way RequirePermission does, for handlers that leave out what the
+// user may not see instead of rejecting the request
+func (m *AuthMiddleware) Allows(c *gin.Context, permission domain.Permission) bool {
+	role, exists := c.Get("userRole")
+	if !exists {
+		return false
+	}
+
+	if m.permissions.Allows(role.(domain.Role), permission) {
+		return true
+	}
+	return c.GetString("emergencyAccessID") != "" && m.emergencyPolicy.Grants(permission)
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+	"github.com/dksch/pococlinic/internal/features/auth/infrastructure"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthTest(t *testing.T, role domain.Role) (*gin.Engine, *infrastructure.MemorySessionRepository, string) {
+	return setupAuthTestForUser(t, domain.NewUser("test@example.com", "Test User", role))
+}
+
+var testTokenConfig = domain.TokenConfig{
+	AccessTokenSecret:  []byte("access-secret-key-for-test"),
+	RefreshTokenSecret: []byte("refresh-secret-key-for-test"),
+	AccessTokenTTL:     15 * time.Minute,
+	RefreshTokenTTL:    24 * time.Hour,
+	Issuer:             "poco-clinic-test",
+}
+
+func setupAuthTestForUser(t *testing.T, user *domain.User) (*gin.Engine, *infrastructure.MemorySessionRepository, string) {
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+	accessToken, _, err := session.GenerateTokens(user, testTokenConfig)
+	assert.NoError(t, err)
+
+	sessions := infrastructure.NewMemorySessionRepository()
+	assert.NoError(t, sessions.Create(context.Background(), session))
+
+	router := newAuthTestRouter(t, sessions, infrastructure.NewMemoryEmergencyAccessRepository())
+	return router, sessions, accessToken
+}
+
+func newAuthTestRouter(t *testing.T, sessions domain.GetSessionRepository, emergency domain.RecordEmergencyAccessRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	permissions, err := domain.NewPermissionMatrix(map[string][]string{
+		"admin": {"*"},
+		"nurse": {"patients:read", "patients:write:vitals"},
+	})
+	assert.NoError(t, err)
+
+	emergencyPolicy := domain.EmergencyAccessPolicy{
+		TTL:         30 * time.Minute,
+		Permissions: []domain.Permission{"patients:write:demographics"},
+	}
+
+	m := NewAuthMiddleware(testTokenConfig, sessions, permissions, emergencyPolicy, emergency)
+	router := gin.New()
+	router.GET("/protected", m.RequireAuth(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/admin", m.RequireAuth(), m.RequireRole(domain.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/vitals", m.RequireAuth(), m.RequirePermission("patients:write:vitals"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/demographics", m.RequireAuth(), m.RequirePermission("patients:write:demographics"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/users", m.RequireAuth(), m.RequirePermission(domain.PermissionManageUsers), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/record", m.RequireAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"vitals": m.Allows(c, "patients:write:vitals")})
+	})
+	router.PUT("/pin", m.RequireAuthAllowingPINChange(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func TestRequireAuth(t *testing.T) {
+	router, _, accessToken := setupAuthTest(t, domain.RoleDoctor)
+
+	tests := []struct {
+		name         string
+		header       string
+		expectedCode int
+	}{
+		{name: "missing header", header: "", expectedCode: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic " + accessToken, expectedCode: http.StatusUnauthorized},
+		{name: "invalid token", header: "Bearer not-a-token", expectedCode: http.StatusUnauthorized},
+		{name: "valid token", header: "Bearer " + accessToken, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/protected", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         domain.Role
+		expectedCode int
+	}{
+		{name: "admin allowed", role: domain.RoleAdmin, expectedCode: http.StatusOK},
+		{name: "doctor forbidden", role: domain.RoleDoctor, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, accessToken := setupAuthTest(t, tt.role)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         domain.Role
+		path         string
+		expectedCode int
+	}{
+		{name: "nurse records vitals", role: domain.RoleNurse, path: "/vitals", expectedCode: http.StatusOK},
+		{name: "nurse edits demographics", role: domain.RoleNurse, path: "/demographics", expectedCode: http.StatusForbidden},
+		{name: "admin edits demographics", role: domain.RoleAdmin, path: "/demographics", expectedCode: http.StatusOK},
+		{name: "role without permissions", role: domain.RolePatient, path: "/vitals", expectedCode: http.StatusForbidden},
+		{name: "admin manages users", role: domain.RoleAdmin, path: "/users", expectedCode: http.StatusOK},
+		{name: "nurse manages users", role: domain.RoleNurse, path: "/users", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, accessToken := setupAuthTest(t, tt.role)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	for role, expected := range map[domain.Role]string{
+		domain.RoleNurse:   `{"vitals": true}`,
+		domain.RoleAdmin:   `{"vitals": true}`,
+		domain.RolePatient: `{"vitals": false}`,
+	} {
+		t.Run(string(role), func(t *testing.T) {
+			router, _, accessToken := setupAuthTest(t, role)
+
+			// The request is served either way, only the answer differs
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/record", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, expected, w.Body.String())
+		})
+	}
+}
+
+func TestRequireAuthRejectsRevokedSession(t *testing.T) {
+	router, sessions, accessToken := setupAuthTest(t, domain.RoleDoctor)
+
+	claims, err := domain.ValidateToken(accessToken, domain.TokenTypeAccess, []byte("access-secret-key-for-test"))
+	assert.NoError(t, err)
+	assert.NoError(t, sessions.Delete(context.Background(), claims.SessionID))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestPendingPINChangeRestrictsToken(t *testing.T) {
+	user := domain.NewUser("test@example.com", "Test User", domain.RoleDoctor)
+	user.RequirePINChange()
+	router, _, accessToken := setupAuthTestForUser(t, user)
+
+	tests := []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{method: "GET", path: "/protected", expectedCode: http.StatusForbidden},
+		{method: "PUT", path: "/pin", expectedCode: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestEmergencyAccessElevatesAndRecords(t *testing.T) {
+	ctx := context.Background()
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+
+	sessions := infrastructure.NewMemorySessionRepository()
+	assert.NoError(t, sessions.Create(ctx, session))
+	emergency := infrastructure.NewMemoryEmergencyAccessRepository()
+	router := newAuthTestRouter(t, sessions, emergency)
+
+	grant, err := domain.NewEmergencyAccess(user.ID, session.ID, "Unconscious patient in ER", "127.0.0.1", 30*time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, emergency.Create(ctx, grant))
+	accessToken, _, err := session.GenerateTokens(user, testTokenConfig)
+	assert.NoError(t, err)
+	emergencyToken, err := session.GenerateEmergencyToken(user, grant, testTokenConfig)
+	assert.NoError(t, err)
+
+	// The regular token stays limited to the role's permissions
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/demographics", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/demographics", nil)
+	req.Header.Set("Authorization", "Bearer "+emergencyToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stored, err := emergency.GetByID(ctx, grant.ID.String())
+	assert.NoError(t, err)
+	if assert.Len(t, stored.Events, 1) {
+		assert.Equal(t, "GET", stored.Events[0].Method)
+		assert.Equal(t, "/demographics", stored.Events[0].Path)
+	}
+}
+
+func TestEmergencyAccessFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	user := domain.NewUser("nurse@example.com", "Nurse Joy", domain.RoleNurse)
+	session := domain.NewSession(user.ID, "test-agent", "127.0.0.1", time.Now().Add(time.Hour))
+
+	sessions := infrastructure.NewMemorySessionRepository()
+	assert.NoError(t, sessions.Create(ctx, session))
+	router := newAuthTestRouter(t, sessions, infrastructure.NewMemoryEmergencyAccessRepository())
+
+	// A grant that was never stored cannot be recorded, so nothing is served
+	grant, err := domain.NewEmergencyAccess(user.ID, session.ID, "Unconscious patient in ER", "127.0.0.1", 30*time.Minute)
+	assert.NoError(t, err)
+	emergencyToken, err := session.GenerateEmergencyToken(user, grant, testTokenConfig)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+emergencyToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
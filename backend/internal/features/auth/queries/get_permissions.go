@@ -0,0 +1,43 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// GetPermissionsQuery represents the query for the permissions of a role
+type GetPermissionsQuery struct {
+	Role domain.Role `json:"-"`
+}
+
+// EffectivePermissions lists what a role is allowed to do
+type EffectivePermissions struct {
+	Role        domain.Role         `json:"role"`
+	Permissions []domain.Permission `json:"permissions"`
+}
+
+// GetPermissionsHandler handles permission lookups
+type GetPermissionsHandler interface {
+	Handle(ctx context.Context, query GetPermissionsQuery) (*EffectivePermissions, error)
+}
+
+// getPermissionsHandler implements GetPermissionsHandler
+type getPermissionsHandler struct {
+	permissions domain.PermissionMatrix
+}
+
+// NewGetPermissionsHandler creates a new handler for permission lookups
+func NewGetPermissionsHandler(permissions domain.PermissionMatrix) GetPermissionsHandler {
+	return &getPermissionsHandler{
+		permissions: permissions,
+	}
+}
+
+// Handle processes the get permissions query
+func (h *getPermissionsHandler) Handle(ctx context.Context, query GetPermissionsQuery) (*EffectivePermissions, error) {
+	return &EffectivePermissions{
+		Role:        query.Role,
+		Permissions: h.permissions.PermissionsOf(query.Role),
+	}, nil
+}
@@ -0,0 +1,35 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// ListEmergencyAccessQuery represents the query to list break-the-glass
+// grants for review
+type ListEmergencyAccessQuery struct {
+	PendingOnly bool `form:"pending"`
+}
+
+// ListEmergencyAccessHandler handles emergency access listing
+type ListEmergencyAccessHandler interface {
+	Handle(ctx context.Context, query ListEmergencyAccessQuery) ([]*domain.EmergencyAccess, error)
+}
+
+// listEmergencyAccessHandler implements ListEmergencyAccessHandler
+type listEmergencyAccessHandler struct {
+	emergencyRepository domain.ListEmergencyAccessRepository
+}
+
+// NewListEmergencyAccessHandler creates a new handler for emergency access listing
+func NewListEmergencyAccessHandler(repo domain.ListEmergencyAccessRepository) ListEmergencyAccessHandler {
+	return &listEmergencyAccessHandler{
+		emergencyRepository: repo,
+	}
+}
+
+// Handle processes the list emergency access query
+func (h *listEmergencyAccessHandler) Handle(ctx context.Context, query ListEmergencyAccessQuery) ([]*domain.EmergencyAccess, error) {
+	return h.emergencyRepository.List(ctx, query.PendingOnly)
+}
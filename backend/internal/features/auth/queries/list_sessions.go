@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// ListSessionsQuery represents the query to list a user's active sessions
+type ListSessionsQuery struct {
+	UserID           string `json:"-"`
+	CurrentSessionID string `json:"-"`
+}
+
+// SessionSummary describes an active session as shown to its owner
+type SessionSummary struct {
+	*domain.Session
+	Current bool `json:"current"`
+}
+
+// ListSessionsHandler handles session listing
+type ListSessionsHandler interface {
+	Handle(ctx context.Context, query ListSessionsQuery) ([]*SessionSummary, error)
+}
+
+// listSessionsHandler implements ListSessionsHandler
+type listSessionsHandler struct {
+	sessionRepository domain.ListSessionsRepository
+}
+
+// NewListSessionsHandler creates a new handler for session listing
+func NewListSessionsHandler(repo domain.ListSessionsRepository) ListSessionsHandler {
+	return &listSessionsHandler{
+		sessionRepository: repo,
+	}
+}
+
+// Handle processes the list sessions query, skipping expired sessions
+func (h *listSessionsHandler) Handle(ctx context.Context, query ListSessionsQuery) ([]*SessionSummary, error) {
+	sessions, err := h.sessionRepository.ListByUserID(ctx, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*SessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		if session.IsExpired() {
+			continue
+		}
+		summaries = append(summaries, &SessionSummary{
+			Session: session,
+			Current: session.ID.String() == query.CurrentSessionID,
+		})
+	}
+	return summaries, nil
+}
@@ -0,0 +1,82 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/auth/domain"
+)
+
+// MaxUsersPageSize is the largest page of users that can be requested, as
+// for the patient listings
+const MaxUsersPageSize = 100
+
+// ListUsersQuery represents the query to list and search user accounts
+type ListUsersQuery struct {
+	Page     int         `form:"page,default=1"`
+	PageSize int         `form:"pageSize,default=20"`
+	Search   string      `form:"search"`
+	Role     domain.Role `form:"role"`
+}
+
+// PaginatedUsers represents a paginated list of users
+type PaginatedUsers struct {
+	Users       []*domain.User `json:"users"`
+	TotalCount  int64          `json:"totalCount"`
+	CurrentPage int            `json:"currentPage"`
+	PageSize    int            `json:"pageSize"`
+	TotalPages  int            `json:"totalPages"`
+}
+
+// ListUsersHandler handles user listing
+type ListUsersHandler interface {
+	Handle(ctx context.Context, query ListUsersQuery) (*PaginatedUsers, error)
+}
+
+// listUsersHandler implements ListUsersHandler
+type listUsersHandler struct {
+	userRepository domain.ListUsersRepository
+}
+
+// NewListUsersHandler creates a new handler for user listing
+func NewListUsersHandler(repo domain.ListUsersRepository) ListUsersHandler {
+	return &listUsersHandler{
+		userRepository: repo,
+	}
+}
+
+// Handle processes the list users query
+func (h *listUsersHandler) Handle(ctx context.Context, query ListUsersQuery) (*PaginatedUsers, error) {
+	// Ensure valid pagination parameters
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+	if query.PageSize > MaxUsersPageSize {
+		query.PageSize = MaxUsersPageSize
+	}
+	if query.Role != "" && !query.Role.IsValid() {
+		return nil, domain.ErrInvalidRoleError(query.Role)
+	}
+
+	filter := domain.UserFilter{Search: query.Search, Role: query.Role}
+	users, totalCount, err := h.userRepository.ListPaginated(ctx, query.Page, query.PageSize, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate total pages
+	totalPages := int(totalCount) / query.PageSize
+	if int(totalCount)%query.PageSize > 0 {
+		totalPages++
+	}
+
+	return &PaginatedUsers{
+		Users:       users,
+		TotalCount:  totalCount,
+		CurrentPage: query.Page,
+		PageSize:    query.PageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
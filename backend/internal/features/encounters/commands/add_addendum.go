@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// AddAddendumCommand represents the command to add a note to a closed
+// encounter
+type AddAddendumCommand struct {
+	PatientID   string `json:"-"`
+	EncounterID string `json:"-"`
+	AuthorID    string `json:"-"`
+	Text        string `json:"text" binding:"required"`
+}
+
+// AddAddendumHandler handles the add addendum command
+type AddAddendumHandler interface {
+	Handle(ctx context.Context, cmd AddAddendumCommand) (*domain.Addendum, error)
+}
+
+type addAddendumHandler struct {
+	repo domain.AddAddendumRepository
+}
+
+// NewAddAddendumHandler creates a new add addendum handler
+func NewAddAddendumHandler(repo domain.AddAddendumRepository) AddAddendumHandler {
+	return &addAddendumHandler{repo: repo}
+}
+
+// Handle processes the add addendum command
+func (h *addAddendumHandler) Handle(ctx context.Context, cmd AddAddendumCommand) (*domain.Addendum, error) {
+	encounter, err := findEncounter(ctx, h.repo, cmd.PatientID, cmd.EncounterID)
+	if err != nil {
+		return nil, err
+	}
+
+	addendum, err := encounter.AddAddendum(cmd.AuthorID, cmd.Text)
+	switch err {
+	case nil:
+	case domain.ErrNotClosed:
+		return nil, errors.NewAPIError(errors.ErrConflict, "Only closed encounters take addenda, edit the notes instead")
+	case domain.ErrEmptyAddendum:
+		return nil, errors.NewAPIError(errors.ErrValidation, "Addendum text is required")
+	default:
+		return nil, err
+	}
+
+	if err := h.repo.AppendAddendum(ctx, cmd.EncounterID, addendum); err != nil {
+		return nil, err
+	}
+	return addendum, nil
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// CloseEncounterCommand represents the command to close an encounter, which
+// locks it against further edits
+type CloseEncounterCommand struct {
+	PatientID   string
+	EncounterID string
+	UserID      string
+}
+
+// CloseEncounterHandler handles the close encounter command
+type CloseEncounterHandler interface {
+	Handle(ctx context.Context, cmd CloseEncounterCommand) (*domain.Encounter, error)
+}
+
+type closeEncounterHandler struct {
+	repo domain.UpdateEncounterRepository
+}
+
+// NewCloseEncounterHandler creates a new close encounter handler
+func NewCloseEncounterHandler(repo domain.UpdateEncounterRepository) CloseEncounterHandler {
+	return &closeEncounterHandler{repo: repo}
+}
+
+// Handle processes the close encounter command
+func (h *closeEncounterHandler) Handle(ctx context.Context, cmd CloseEncounterCommand) (*domain.Encounter, error) {
+	encounter, err := findEncounter(ctx, h.repo, cmd.PatientID, cmd.EncounterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encounter.Close(cmd.UserID); err != nil {
+		return nil, errors.NewAPIError(errors.ErrConflict, "Encounter is already closed")
+	}
+	if err := h.repo.Update(ctx, encounter); err != nil {
+		return nil, updateError(ctx, h.repo, cmd.EncounterID, err)
+	}
+	return encounter, nil
+}
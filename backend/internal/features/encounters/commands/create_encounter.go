@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// CreateEncounterCommand represents the command to schedule an encounter of
+// a patient. The attending user defaults to the user creating it.
+type CreateEncounterCommand struct {
+	PatientID      string      `json:"-"`
+	CreatedBy      string      `json:"-"`
+	Date           time.Time   `json:"date" binding:"required"`
+	Type           domain.Type `json:"type" binding:"required"`
+	AttendingID    string      `json:"attendingId"`
+	ChiefComplaint string      `json:"chiefComplaint"`
+}
+
+// CreateEncounterHandler handles the create encounter command
+type CreateEncounterHandler interface {
+	Handle(ctx context.Context, cmd CreateEncounterCommand) (*domain.Encounter, error)
+}
+
+type createEncounterHandler struct {
+	repo     domain.CreateEncounterRepository
+	patients patientref.Directory
+}
+
+// NewCreateEncounterHandler creates a new create encounter handler
+func NewCreateEncounterHandler(repo domain.CreateEncounterRepository, patients patientref.Directory) CreateEncounterHandler {
+	return &createEncounterHandler{repo: repo, patients: patients}
+}
+
+// Handle processes the create encounter command
+func (h *createEncounterHandler) Handle(ctx context.Context, cmd CreateEncounterCommand) (*domain.Encounter, error) {
+	if err := validateDetails(cmd.Date, cmd.Type); err != nil {
+		return nil, err
+	}
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	attendingID := strings.TrimSpace(cmd.AttendingID)
+	if attendingID == "" {
+		attendingID = cmd.CreatedBy
+	}
+
+	encounter := domain.NewEncounter(patientID, cmd.Date, cmd.Type, attendingID, strings.TrimSpace(cmd.ChiefComplaint), cmd.CreatedBy)
+	if err := h.repo.Create(ctx, encounter); err != nil {
+		return nil, err
+	}
+	return encounter, nil
+}
+
+// validateDetails checks the details every encounter needs
+func validateDetails(date time.Time, encounterType domain.Type) error {
+	if date.IsZero() {
+		return errors.NewAPIError(errors.ErrValidation, "Date is required")
+	}
+	if !encounterType.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation,
+			"Type must be one of consultation, follow_up, emergency, telehealth, procedure or other")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// UpdateEncounterCommand represents the command to change an open
+// encounter. It must name the version the changes were made on.
+type UpdateEncounterCommand struct {
+	PatientID       string        `json:"-"`
+	EncounterID     string        `json:"-"`
+	ExpectedVersion int           `json:"-"`
+	Date            time.Time     `json:"date" binding:"required"`
+	Type            domain.Type   `json:"type" binding:"required"`
+	AttendingID     string        `json:"attendingId" binding:"required"`
+	ChiefComplaint  string        `json:"chiefComplaint"`
+	Status          domain.Status `json:"status" binding:"required"`
+	Notes           string        `json:"notes"`
+}
+
+// StaleVersionError reports a change made on an outdated version of an
+// encounter, along with the encounter as currently stored
+type StaleVersionError struct {
+	Current *domain.Encounter
+}
+
+func (e *StaleVersionError) Error() string {
+	return "encounter was modified since it was read"
+}
+
+// UpdateEncounterHandler handles the update encounter command
+type UpdateEncounterHandler interface {
+	Handle(ctx context.Context, cmd UpdateEncounterCommand) (*domain.Encounter, error)
+}
+
+type updateEncounterHandler struct {
+	repo domain.UpdateEncounterRepository
+}
+
+// NewUpdateEncounterHandler creates a new update encounter handler
+func NewUpdateEncounterHandler(repo domain.UpdateEncounterRepository) UpdateEncounterHandler {
+	return &updateEncounterHandler{repo: repo}
+}
+
+// Handle processes the update encounter command
+func (h *updateEncounterHandler) Handle(ctx context.Context, cmd UpdateEncounterCommand) (*domain.Encounter, error) {
+	if err := validateDetails(cmd.Date, cmd.Type); err != nil {
+		return nil, err
+	}
+
+	encounter, err := findEncounter(ctx, h.repo, cmd.PatientID, cmd.EncounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.Version != cmd.ExpectedVersion {
+		return nil, &StaleVersionError{Current: encounter}
+	}
+
+	err = encounter.Edit(cmd.Date, cmd.Type, strings.TrimSpace(cmd.AttendingID), strings.TrimSpace(cmd.ChiefComplaint), cmd.Status, cmd.Notes)
+	switch err {
+	case nil:
+	case domain.ErrClosed:
+		return nil, errors.NewAPIError(errors.ErrConflict, "Closed encounters cannot be edited, add an addendum instead")
+	case domain.ErrInvalidStatus:
+		return nil, errors.NewAPIError(errors.ErrValidation, "Status must be scheduled or in_progress, encounters are closed separately")
+	default:
+		return nil, err
+	}
+
+	if err := h.repo.Update(ctx, encounter); err != nil {
+		return nil, updateError(ctx, h.repo, cmd.EncounterID, err)
+	}
+	return encounter, nil
+}
+
+// findEncounter reads an encounter of the patient
+func findEncounter(ctx context.Context, repo domain.GetEncounterRepository, patientID, encounterID string) (*domain.Encounter, error) {
+	encounter, err := repo.GetByID(ctx, encounterID)
+	if err == domain.ErrNotFound || (err == nil && !encounter.BelongsTo(patientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Encounter not found")
+	}
+	return encounter, err
+}
+
+// updateError translates a failed update. A concurrent change is reported
+// with the encounter as now stored, or as a conflict if it was closed.
+func updateError(ctx context.Context, repo domain.GetEncounterRepository, id string, err error) error {
+	if err != domain.ErrVersionConflict {
+		return err
+	}
+	current, getErr := repo.GetByID(ctx, id)
+	if getErr != nil {
+		return getErr
+	}
+	if current.IsClosed() {
+		return errors.NewAPIError(errors.ErrConflict, "Encounter was closed meanwhile")
+	}
+	return &StaleVersionError{Current: current}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/features/encounters/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of active patients
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestCreateEncounter(t *testing.T) {
+	ctx := context.Background()
+	patientID := uuid.NewString()
+	handler := NewCreateEncounterHandler(infrastructure.NewMemoryRepository(), patientDirectory{patientID: true})
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+
+	encounter, err := handler.Handle(ctx, CreateEncounterCommand{
+		PatientID: patientID,
+		CreatedBy: "dr-1",
+		Date:      date,
+		Type:      domain.TypeConsultation,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "dr-1", encounter.AttendingID, "Attending user should default to the creator")
+	assert.Equal(t, domain.StatusScheduled, encounter.Status)
+
+	_, err = handler.Handle(ctx, CreateEncounterCommand{PatientID: patientID, Date: date, Type: "visit"})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	_, err = handler.Handle(ctx, CreateEncounterCommand{PatientID: uuid.NewString(), Date: date, Type: domain.TypeOther})
+	assertAPIError(t, err, errors.ErrNotFound)
+}
+
+func TestEncounterIsLockedWhenClosed(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patientID := uuid.New()
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+	encounter := domain.NewEncounter(patientID, date, domain.TypeConsultation, "dr-1", "Headache", "dr-1")
+	require.NoError(t, repo.Create(ctx, encounter))
+
+	update := NewUpdateEncounterHandler(repo)
+	closeHandler := NewCloseEncounterHandler(repo)
+	addAddendum := NewAddAddendumHandler(repo)
+
+	cmd := UpdateEncounterCommand{
+		PatientID:       patientID.String(),
+		EncounterID:     encounter.ID.String(),
+		ExpectedVersion: 1,
+		Date:            date,
+		Type:            domain.TypeConsultation,
+		AttendingID:     "dr-1",
+		ChiefComplaint:  "Headache",
+		Status:          domain.StatusInProgress,
+		Notes:           "Tension type headache",
+	}
+	updated, err := update.Handle(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	_, err = update.Handle(ctx, cmd)
+	stale, ok := err.(*StaleVersionError)
+	require.True(t, ok, "Expected a StaleVersionError, got %v", err)
+	assert.Equal(t, 2, stale.Current.Version)
+
+	_, err = update.Handle(ctx, UpdateEncounterCommand{
+		PatientID:       uuid.NewString(),
+		EncounterID:     encounter.ID.String(),
+		ExpectedVersion: 2,
+		Date:            date,
+		Type:            domain.TypeConsultation,
+		AttendingID:     "dr-1",
+		Status:          domain.StatusInProgress,
+	})
+	assertAPIError(t, err, errors.ErrNotFound)
+
+	_, err = addAddendum.Handle(ctx, AddAddendumCommand{PatientID: patientID.String(), EncounterID: encounter.ID.String(), Text: "Too early"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	closed, err := closeHandler.Handle(ctx, CloseEncounterCommand{PatientID: patientID.String(), EncounterID: encounter.ID.String(), UserID: "dr-1"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusClosed, closed.Status)
+
+	_, err = closeHandler.Handle(ctx, CloseEncounterCommand{PatientID: patientID.String(), EncounterID: encounter.ID.String(), UserID: "dr-1"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	cmd.ExpectedVersion = closed.Version
+	cmd.Notes = "Rewritten"
+	_, err = update.Handle(ctx, cmd)
+	assertAPIError(t, err, errors.ErrConflict)
+
+	addendum, err := addAddendum.Handle(ctx, AddAddendumCommand{
+		PatientID:   patientID.String(),
+		EncounterID: encounter.ID.String(),
+		AuthorID:    "dr-1",
+		Text:        "Lab results normal",
+	})
+	require.NoError(t, err)
+
+	stored, err := repo.GetByID(ctx, encounter.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Tension type headache", stored.Notes)
+	require.Len(t, stored.Addenda, 1)
+	assert.Equal(t, addendum.ID, stored.Addenda[0].ID)
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the stage of an encounter
+type Status string
+
+const (
+	StatusScheduled  Status = "scheduled"
+	StatusInProgress Status = "in_progress"
+	StatusClosed     Status = "closed"
+)
+
+// IsValid reports whether the status is a known one
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusScheduled, StatusInProgress, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// Type is the kind of an encounter
+type Type string
+
+const (
+	TypeConsultation Type = "consultation"
+	TypeFollowUp     Type = "follow_up"
+	TypeEmergency    Type = "emergency"
+	TypeTelehealth   Type = "telehealth"
+	TypeProcedure    Type = "procedure"
+	TypeOther        Type = "other"
+)
+
+// IsValid reports whether the type is a known one
+func (t Type) IsValid() bool {
+	switch t {
+	case TypeConsultation, TypeFollowUp, TypeEmergency, TypeTelehealth, TypeProcedure, TypeOther:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrClosed is returned when a closed encounter is to be changed
+	ErrClosed = errors.New("encounter is closed")
+	// ErrNotClosed is returned when an addendum is added to an open encounter,
+	// whose notes can still be edited
+	ErrNotClosed = errors.New("encounter is not closed")
+	// ErrEmptyAddendum is returned for an addendum without text
+	ErrEmptyAddendum = errors.New("addendum text is required")
+	// ErrInvalidStatus is returned when an edit sets a status it cannot,
+	// encounters are closed with Close
+	ErrInvalidStatus = errors.New("invalid encounter status")
+)
+
+// Encounter is a visit of a patient: a consultation, a procedure or any
+// other contact with the clinic. Once closed, it is locked; later
+// corrections and additions are recorded as addenda.
+type Encounter struct {
+	ID             uuid.UUID  `json:"id"`
+	PatientID      uuid.UUID  `json:"patientId"`
+	Date           time.Time  `json:"date"`
+	Type           Type       `json:"type"`
+	AttendingID    string     `json:"attendingId"`
+	ChiefComplaint string     `json:"chiefComplaint"`
+	Status         Status     `json:"status"`
+	Notes          string     `json:"notes"`
+	Addenda        []Addendum `json:"addenda"`
+	Version        int        `json:"version"`
+	CreatedBy      string     `json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	ClosedBy       string     `json:"closedBy,omitempty"`
+	ClosedAt       *time.Time `json:"closedAt,omitempty"`
+}
+
+// Addendum is a note added to a closed encounter. Addenda cannot be
+// changed or removed.
+type Addendum struct {
+	ID        uuid.UUID `json:"id"`
+	AuthorID  string    `json:"authorId"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewEncounter creates a scheduled encounter of a patient
+func NewEncounter(patientID uuid.UUID, date time.Time, encounterType Type, attendingID, chiefComplaint, createdBy string) *Encounter {
+	now := time.Now()
+	return &Encounter{
+		ID:             uuid.New(),
+		PatientID:      patientID,
+		Date:           date,
+		Type:           encounterType,
+		AttendingID:    attendingID,
+		ChiefComplaint: chiefComplaint,
+		Status:         StatusScheduled,
+		Addenda:        []Addendum{},
+		Version:        1,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// BelongsTo reports whether the encounter is one of the patient's
+func (e *Encounter) BelongsTo(patientID string) bool {
+	return e.PatientID.String() == patientID
+}
+
+// IsClosed reports whether the encounter is locked
+func (e *Encounter) IsClosed() bool {
+	return e.Status == StatusClosed
+}
+
+// Edit replaces the details of an open encounter. The status may move
+// between scheduled and in progress; closing is done with Close.
+func (e *Encounter) Edit(date time.Time, encounterType Type, attendingID, chiefComplaint string, status Status, notes string) error {
+	if e.IsClosed() {
+		return ErrClosed
+	}
+	if status != StatusScheduled && status != StatusInProgress {
+		return ErrInvalidStatus
+	}
+
+	e.Date = date
+	e.Type = encounterType
+	e.AttendingID = attendingID
+	e.ChiefComplaint = chiefComplaint
+	e.Status = status
+	e.Notes = notes
+	e.UpdatedAt = time.Now()
+	return nil
+}
+
+// Close locks the encounter
+func (e *Encounter) Close(userID string) error {
+	if e.IsClosed() {
+		return ErrClosed
+	}
+
+	now := time.Now()
+	e.Status = StatusClosed
+	e.ClosedBy = userID
+	e.ClosedAt = &now
+	e.UpdatedAt = now
+	return nil
+}
+
+// AddAddendum appends a note to a closed encounter and returns it
+func (e *Encounter) AddAddendum(authorID, text string) (*Addendum, error) {
+	if !e.IsClosed() {
+		return nil, ErrNotClosed
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyAddendum
+	}
+
+	addendum := Addendum{
+		ID:        uuid.New(),
+		AuthorID:  authorID,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	e.Addenda = append(e.Addenda, addendum)
+	return &addendum, nil
+}
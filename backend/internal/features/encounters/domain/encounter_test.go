@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncounterLifecycle(t *testing.T) {
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+	encounter := NewEncounter(uuid.New(), date, TypeConsultation, "dr-1", "Headache", "nurse-1")
+	assert.Equal(t, StatusScheduled, encounter.Status)
+	assert.Equal(t, 1, encounter.Version)
+	assert.False(t, encounter.IsClosed())
+
+	_, err := encounter.AddAddendum("dr-1", "Too early")
+	assert.ErrorIs(t, err, ErrNotClosed)
+
+	assert.ErrorIs(t, encounter.Edit(date, TypeConsultation, "dr-1", "Headache", StatusClosed, ""), ErrInvalidStatus)
+	assert.ErrorIs(t, encounter.Edit(date, TypeConsultation, "dr-1", "Headache", Status("done"), ""), ErrInvalidStatus)
+	require.NoError(t, encounter.Edit(date, TypeConsultation, "dr-2", "Headache", StatusInProgress, "Tension type"))
+	assert.Equal(t, "dr-2", encounter.AttendingID)
+	assert.Equal(t, StatusInProgress, encounter.Status)
+
+	require.NoError(t, encounter.Close("dr-2"))
+	assert.True(t, encounter.IsClosed())
+	assert.Equal(t, "dr-2", encounter.ClosedBy)
+	require.NotNil(t, encounter.ClosedAt)
+
+	assert.ErrorIs(t, encounter.Close("dr-2"), ErrClosed)
+	assert.ErrorIs(t, encounter.Edit(date, TypeConsultation, "dr-2", "Migraine", StatusInProgress, ""), ErrClosed)
+	assert.Equal(t, "Headache", encounter.ChiefComplaint)
+
+	_, err = encounter.AddAddendum("dr-2", "   ")
+	assert.ErrorIs(t, err, ErrEmptyAddendum)
+
+	addendum, err := encounter.AddAddendum("dr-2", " Lab results normal ")
+	require.NoError(t, err)
+	assert.Equal(t, "Lab results normal", addendum.Text)
+	assert.Len(t, encounter.Addenda, 1)
+}
+
+func TestEncounterTypesAndStatuses(t *testing.T) {
+	assert.True(t, TypeFollowUp.IsValid())
+	assert.False(t, Type("visit").IsValid())
+	assert.True(t, StatusInProgress.IsValid())
+	assert.False(t, Status("").IsValid())
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned when an encounter does not exist
+	ErrNotFound = errors.New("encounter not found")
+	// ErrVersionConflict is returned by Update when the stored encounter is
+	// no longer at the version the change started from
+	ErrVersionConflict = errors.New("encounter was modified by another update")
+)
+
+// Repository defines the interface for encounter persistence. Update stores
+// the changed details and bumps the version; it fails with
+// ErrVersionConflict unless the encounter's version matches the stored one
+// and never changes a closed encounter. Addenda are only ever appended.
+// DeleteByPatient and MoveToPatient are the exceptions: they remove all
+// encounters of a purged patient with their addenda, or hand them from a
+// duplicate to the patient it is merged into, closed ones included.
+type Repository interface {
+	Create(ctx context.Context, encounter *Encounter) error
+	Update(ctx context.Context, encounter *Encounter) error
+	AppendAddendum(ctx context.Context, encounterID string, addendum *Addendum) error
+	GetByID(ctx context.Context, id string) (*Encounter, error)
+	ListByPatient(ctx context.Context, patientID string, page, pageSize int) ([]*Encounter, int64, error)
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// CreateEncounterRepository defines the minimal interface for encounter creation
+type CreateEncounterRepository interface {
+	Create(ctx context.Context, encounter *Encounter) error
+}
+
+// UpdateEncounterRepository defines the minimal interface for changing an encounter
+type UpdateEncounterRepository interface {
+	GetByID(ctx context.Context, id string) (*Encounter, error)
+	Update(ctx context.Context, encounter *Encounter) error
+}
+
+// AddAddendumRepository defines the minimal interface for adding addenda
+type AddAddendumRepository interface {
+	GetByID(ctx context.Context, id string) (*Encounter, error)
+	AppendAddendum(ctx context.Context, encounterID string, addendum *Addendum) error
+}
+
+// GetEncounterRepository defines the minimal interface for reading an encounter
+type GetEncounterRepository interface {
+	GetByID(ctx context.Context, id string) (*Encounter, error)
+}
+
+// ListEncountersRepository defines the minimal interface for listing the
+// encounters of a patient, most recent first
+type ListEncountersRepository interface {
+	ListByPatient(ctx context.Context, patientID string, page, pageSize int) ([]*Encounter, int64, error)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/commands"
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/features/encounters/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission an encounter route requires. Encounters
+// are clinical data and kept apart from the demographics.
+type Access string
+
+const (
+	AccessReadEncounters  Access = "patients:read:encounters"
+	AccessWriteEncounters Access = "patients:write:encounters"
+)
+
+// Guard checks the caller's access to a patient's encounters
+type Guard = authz.Guard[Access]
+
+// EncounterHandler handles HTTP requests for encounter operations
+type EncounterHandler struct {
+	createEncounterHandler commands.CreateEncounterHandler
+	updateEncounterHandler commands.UpdateEncounterHandler
+	closeEncounterHandler  commands.CloseEncounterHandler
+	addAddendumHandler     commands.AddAddendumHandler
+	listEncountersHandler  queries.ListEncountersHandler
+	getEncounterHandler    queries.GetEncounterHandler
+	logger                 *logging.Logger
+}
+
+// NewEncounterHandler creates a new encounter handler
+func NewEncounterHandler(
+	createHandler commands.CreateEncounterHandler,
+	updateHandler commands.UpdateEncounterHandler,
+	closeHandler commands.CloseEncounterHandler,
+	addAddendumHandler commands.AddAddendumHandler,
+	listHandler queries.ListEncountersHandler,
+	getHandler queries.GetEncounterHandler,
+	logger *logging.Logger,
+) *EncounterHandler {
+	return &EncounterHandler{
+		createEncounterHandler: createHandler,
+		updateEncounterHandler: updateHandler,
+		closeEncounterHandler:  closeHandler,
+		addAddendumHandler:     addAddendumHandler,
+		listEncountersHandler:  listHandler,
+		getEncounterHandler:    getHandler,
+		logger:                 logger,
+	}
+}
+
+// RegisterRoutes registers the encounter routes below the patient they
+// belong to. Closing an encounter and adding an addendum need write access,
+// like editing it.
+func (h *EncounterHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	encounters := router.Group("/patients/:id/encounters")
+	{
+		encounters.POST("", guard(AccessWriteEncounters), h.CreateEncounter)
+		encounters.GET("", guard(AccessReadEncounters), h.ListEncounters)
+		encounters.GET("/:encounterId", guard(AccessReadEncounters), h.GetEncounter)
+		encounters.PUT("/:encounterId", guard(AccessWriteEncounters), h.UpdateEncounter)
+		encounters.POST("/:encounterId/close", guard(AccessWriteEncounters), h.CloseEncounter)
+		encounters.POST("/:encounterId/addenda", guard(AccessWriteEncounters), h.AddAddendum)
+	}
+}
+
+// CreateEncounter handles the request to schedule an encounter
+func (h *EncounterHandler) CreateEncounter(c *gin.Context) {
+	var cmd commands.CreateEncounterCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.CreatedBy = c.GetString("userID")
+
+	encounter, err := h.createEncounterHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to create encounter")
+		return
+	}
+
+	h.logger.Info("Created encounter", "id", encounter.ID, "patientId", encounter.PatientID)
+
+	c.Header("ETag", etag(encounter))
+	c.JSON(http.StatusCreated, encounter)
+}
+
+// ListEncounters handles the request to list a patient's encounters
+func (h *EncounterHandler) ListEncounters(c *gin.Context) {
+	var query queries.ListEncountersQuery
+	if err := c.ShouldBindQuery(&query); err != nil || query.PageSize > 100 {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid query parameters"))
+		return
+	}
+	query.PatientID = c.Param("id")
+
+	result, err := h.listEncountersHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to list encounters")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetEncounter handles the request to fetch one encounter
+func (h *EncounterHandler) GetEncounter(c *gin.Context) {
+	encounter, err := h.getEncounterHandler.Handle(c.Request.Context(), queries.GetEncounterQuery{
+		PatientID:   c.Param("id"),
+		EncounterID: c.Param("encounterId"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to fetch encounter")
+		return
+	}
+
+	c.Header("ETag", etag(encounter))
+	c.JSON(http.StatusOK, encounter)
+}
+
+// UpdateEncounter handles the request to change an open encounter. Like
+// patient updates, it must name the version it was made on in If-Match.
+func (h *EncounterHandler) UpdateEncounter(c *gin.Context) {
+	version, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, errors.NewAPIError(errors.ErrPreconditionRequired, "If-Match header with the encounter's ETag is required"))
+		return
+	}
+
+	var cmd commands.UpdateEncounterCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.EncounterID = c.Param("encounterId")
+	cmd.ExpectedVersion = version
+
+	encounter, err := h.updateEncounterHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to update encounter")
+		return
+	}
+
+	c.Header("ETag", etag(encounter))
+	c.JSON(http.StatusOK, encounter)
+}
+
+// CloseEncounter handles the request to close an encounter
+func (h *EncounterHandler) CloseEncounter(c *gin.Context) {
+	encounter, err := h.closeEncounterHandler.Handle(c.Request.Context(), commands.CloseEncounterCommand{
+		PatientID:   c.Param("id"),
+		EncounterID: c.Param("encounterId"),
+		UserID:      c.GetString("userID"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to close encounter")
+		return
+	}
+
+	h.logger.Info("Closed encounter", "id", encounter.ID, "by", encounter.ClosedBy)
+
+	c.Header("ETag", etag(encounter))
+	c.JSON(http.StatusOK, encounter)
+}
+
+// AddAddendum handles the request to add a note to a closed encounter
+func (h *EncounterHandler) AddAddendum(c *gin.Context) {
+	var cmd commands.AddAddendumCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Addendum text is required"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.EncounterID = c.Param("encounterId")
+	cmd.AuthorID = c.GetString("userID")
+
+	addendum, err := h.addAddendumHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to add addendum")
+		return
+	}
+
+	c.JSON(http.StatusCreated, addendum)
+}
+
+// staleVersionResponse is the body of a rejected update, it shows the
+// encounter as currently stored
+type staleVersionResponse struct {
+	*errors.APIError
+	Current *domain.Encounter `json:"current"`
+}
+
+// respondWithError writes an API error with its status, reports a stale
+// update with the current encounter and hides any other error behind the
+// given message
+func (h *EncounterHandler) respondWithError(c *gin.Context, err error, message string) {
+	switch err := err.(type) {
+	case *commands.StaleVersionError:
+		c.Header("ETag", etag(err.Current))
+		c.JSON(http.StatusPreconditionFailed, staleVersionResponse{
+			APIError: errors.NewAPIError(errors.ErrPreconditionFailed, "Encounter was modified since it was read"),
+			Current:  err.Current,
+		})
+	case *errors.APIError:
+		c.JSON(errors.StatusCode(err.Code), err)
+	default:
+		h.logger.Error(message, err)
+		c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+	}
+}
+
+// etag returns the entity tag of an encounter, its quoted version
+func etag(encounter *domain.Encounter) string {
+	return fmt.Sprintf(`"%d"`, encounter.Version)
+}
+
+// parseIfMatch returns the version named by an If-Match header. A missing
+// header or "*" does not name a version. Tags that are not a version, such
+// as weak ones, match no version at all.
+func parseIfMatch(header string) (int, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return -1, true
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil {
+		return -1, true
+	}
+	return version, true
+}
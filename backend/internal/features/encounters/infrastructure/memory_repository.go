@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryRepository is a simple in-memory implementation of the encounter Repository interface
+type MemoryRepository struct {
+	encounters map[string]*domain.Encounter
+	mu         sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory encounter repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		encounters: make(map[string]*domain.Encounter),
+	}
+}
+
+// Create adds a new encounter to the repository
+func (r *MemoryRepository) Create(ctx context.Context, encounter *domain.Encounter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.encounters[encounter.ID.String()] = copyOf(encounter)
+	return nil
+}
+
+// Update stores the changed details of an open encounter and bumps its
+// version. Addenda are kept as stored.
+func (r *MemoryRepository) Update(ctx context.Context, encounter *domain.Encounter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.encounters[encounter.ID.String()]
+	if !exists {
+		return domain.ErrNotFound
+	}
+	if stored.Version != encounter.Version || stored.IsClosed() {
+		return domain.ErrVersionConflict
+	}
+
+	encounter.Version++
+	updated := copyOf(encounter)
+	updated.Addenda = stored.Addenda
+	r.encounters[encounter.ID.String()] = updated
+	return nil
+}
+
+// AppendAddendum adds an addendum to the stored encounter
+func (r *MemoryRepository) AppendAddendum(ctx context.Context, encounterID string, addendum *domain.Addendum) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.encounters[encounterID]
+	if !exists {
+		return domain.ErrNotFound
+	}
+	stored.Addenda = append(stored.Addenda, *addendum)
+	return nil
+}
+
+// GetByID retrieves an encounter by its ID
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Encounter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	encounter, exists := r.encounters[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return copyOf(encounter), nil
+}
+
+// ListByPatient returns a page of the patient's encounters, most recent first
+func (r *MemoryRepository) ListByPatient(ctx context.Context, patientID string, page, pageSize int) ([]*domain.Encounter, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := []*domain.Encounter{}
+	for _, encounter := range r.encounters {
+		if encounter.BelongsTo(patientID) {
+			matched = append(matched, copyOf(encounter))
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].Date.Equal(matched[j].Date) {
+			return matched[i].Date.After(matched[j].Date)
+		}
+		return matched[i].ID.String() < matched[j].ID.String()
+	})
+
+	totalCount := int64(len(matched))
+	start := (page - 1) * pageSize
+	if start >= len(matched) {
+		return []*domain.Encounter{}, totalCount, nil
+	}
+	end := min(start+pageSize, len(matched))
+	return matched[start:end], totalCount, nil
+}
+
+// DeleteByPatient removes all encounters of the patient with their addenda
+func (r *MemoryRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, encounter := range r.encounters {
+		if encounter.BelongsTo(patientID) {
+			delete(r.encounters, id)
+		}
+	}
+	return nil
+}
+
+// MoveToPatient hands all encounters of one patient to another
+func (r *MemoryRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	target, err := uuid.Parse(toID)
+	if err != nil {
+		return fmt.Errorf("invalid patient ID %q: %w", toID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, encounter := range r.encounters {
+		if encounter.BelongsTo(fromID) {
+			encounter.PatientID = target
+		}
+	}
+	return nil
+}
+
+// copyOf returns a copy of the encounter that shares nothing with it
+func copyOf(encounter *domain.Encounter) *domain.Encounter {
+	found := *encounter
+	found.Addenda = append([]domain.Addendum{}, encounter.Addenda...)
+	return &found
+}
@@ -0,0 +1,392 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the encounters feature, in order.
+// Closed encounters and addenda are also protected by triggers, so that
+// no code path can change them. Only while a patient is listed in
+// encounter_purges can their encounters be deleted, and only while listed in
+// encounter_transfers can they be handed to another patient; DeleteByPatient
+// and MoveToPatient do so within a single transaction.
+var sqliteMigrations = []string{
+	`CREATE TABLE encounters (
+		id              TEXT PRIMARY KEY,
+		patient_id      TEXT NOT NULL,
+		date            TEXT NOT NULL,
+		type            TEXT NOT NULL,
+		attending_id    TEXT NOT NULL DEFAULT '',
+		chief_complaint TEXT NOT NULL DEFAULT '',
+		status          TEXT NOT NULL,
+		notes           TEXT NOT NULL DEFAULT '',
+		version         INTEGER NOT NULL,
+		created_by      TEXT NOT NULL DEFAULT '',
+		created_at      TEXT NOT NULL,
+		updated_at      TEXT NOT NULL,
+		closed_by       TEXT NOT NULL DEFAULT '',
+		closed_at       TEXT
+	);
+	CREATE INDEX idx_encounters_patient_date ON encounters (patient_id, date);
+	CREATE TABLE encounter_addenda (
+		id           TEXT PRIMARY KEY,
+		encounter_id TEXT NOT NULL REFERENCES encounters (id),
+		author_id    TEXT NOT NULL DEFAULT '',
+		text         TEXT NOT NULL,
+		created_at   TEXT NOT NULL
+	);
+	CREATE INDEX idx_encounter_addenda_encounter ON encounter_addenda (encounter_id, created_at);
+	CREATE TRIGGER encounters_locked_when_closed BEFORE UPDATE ON encounters
+	WHEN OLD.status = 'closed'
+	BEGIN SELECT RAISE(ABORT, 'encounter is closed'); END;
+	CREATE TRIGGER encounters_not_deleted_when_closed BEFORE DELETE ON encounters
+	WHEN OLD.status = 'closed'
+	BEGIN SELECT RAISE(ABORT, 'encounter is closed'); END;
+	CREATE TRIGGER encounter_addenda_append_only_update BEFORE UPDATE ON encounter_addenda
+	BEGIN SELECT RAISE(ABORT, 'addenda cannot be changed'); END;
+	CREATE TRIGGER encounter_addenda_append_only_delete BEFORE DELETE ON encounter_addenda
+	BEGIN SELECT RAISE(ABORT, 'addenda cannot be removed'); END;`,
+	`CREATE TABLE encounter_purges (
+		patient_id TEXT PRIMARY KEY
+	);
+	DROP TRIGGER encounters_not_deleted_when_closed;
+	CREATE TRIGGER encounters_not_deleted_when_closed BEFORE DELETE ON encounters
+	WHEN OLD.status = 'closed'
+		AND OLD.patient_id NOT IN (SELECT patient_id FROM encounter_purges)
+	BEGIN SELECT RAISE(ABORT, 'encounter is closed'); END;
+	DROP TRIGGER encounter_addenda_append_only_delete;
+	CREATE TRIGGER encounter_addenda_append_only_delete BEFORE DELETE ON encounter_addenda
+	WHEN OLD.encounter_id NOT IN (SELECT id FROM encounters
+		WHERE patient_id IN (SELECT patient_id FROM encounter_purges))
+	BEGIN SELECT RAISE(ABORT, 'addenda cannot be removed'); END;`,
+	`CREATE TABLE encounter_transfers (
+		patient_id TEXT PRIMARY KEY
+	);
+	DROP TRIGGER encounters_locked_when_closed;
+	CREATE TRIGGER encounters_locked_when_closed BEFORE UPDATE ON encounters
+	WHEN OLD.status = 'closed'
+		AND OLD.patient_id NOT IN (SELECT patient_id FROM encounter_transfers)
+	BEGIN SELECT RAISE(ABORT, 'encounter is closed'); END;`,
+}
+
+// encounterColumns lists the encounter columns in the order scanEncounter expects
+const encounterColumns = `id, patient_id, date, type, attending_id, chief_complaint, status,
+	notes, version, created_by, created_at, updated_at, closed_by, closed_at`
+
+// MigrateSQLite applies the encounters schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "encounters", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the encounter Repository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite encounter repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new encounter to the repository
+func (r *SQLiteRepository) Create(ctx context.Context, encounter *domain.Encounter) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO encounters (`+encounterColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		encounter.ID.String(),
+		encounter.PatientID.String(),
+		formatTime(encounter.Date),
+		string(encounter.Type),
+		encounter.AttendingID,
+		encounter.ChiefComplaint,
+		string(encounter.Status),
+		encounter.Notes,
+		encounter.Version,
+		encounter.CreatedBy,
+		formatTime(encounter.CreatedAt),
+		formatTime(encounter.UpdatedAt),
+		encounter.ClosedBy,
+		formatNullTime(encounter.ClosedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create encounter: %w", err)
+	}
+	return nil
+}
+
+// Update stores the changed details of an open encounter and bumps its
+// version. Addenda are kept as stored.
+func (r *SQLiteRepository) Update(ctx context.Context, encounter *domain.Encounter) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE encounters SET
+		date = ?, type = ?, attending_id = ?, chief_complaint = ?, status = ?, notes = ?,
+		version = version + 1, updated_at = ?, closed_by = ?, closed_at = ?
+		WHERE id = ? AND version = ? AND status != 'closed'`,
+		formatTime(encounter.Date),
+		string(encounter.Type),
+		encounter.AttendingID,
+		encounter.ChiefComplaint,
+		string(encounter.Status),
+		encounter.Notes,
+		formatTime(encounter.UpdatedAt),
+		encounter.ClosedBy,
+		formatNullTime(encounter.ClosedAt),
+		encounter.ID.String(),
+		encounter.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update encounter %s: %w", encounter.ID, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update encounter %s: %w", encounter.ID, err)
+	}
+	if rows == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM encounters WHERE id = ?)`,
+			encounter.ID.String()).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to update encounter %s: %w", encounter.ID, err)
+		}
+		if !exists {
+			return domain.ErrNotFound
+		}
+		return domain.ErrVersionConflict
+	}
+
+	encounter.Version++
+	return nil
+}
+
+// AppendAddendum adds an addendum to the stored encounter
+func (r *SQLiteRepository) AppendAddendum(ctx context.Context, encounterID string, addendum *domain.Addendum) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO encounter_addenda (id, encounter_id, author_id, text, created_at)
+		SELECT ?, id, ?, ?, ? FROM encounters WHERE id = ?`,
+		addendum.ID.String(),
+		addendum.AuthorID,
+		addendum.Text,
+		formatTime(addendum.CreatedAt),
+		encounterID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add addendum to encounter %s: %w", encounterID, err)
+	}
+	return nil
+}
+
+// GetByID retrieves an encounter by its ID, including its addenda
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*domain.Encounter, error) {
+	encounter, err := scanEncounter(r.db.QueryRowContext(ctx,
+		`SELECT `+encounterColumns+` FROM encounters WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadAddenda(ctx, []*domain.Encounter{encounter}); err != nil {
+		return nil, err
+	}
+	return encounter, nil
+}
+
+// ListByPatient returns a page of the patient's encounters, most recent first
+func (r *SQLiteRepository) ListByPatient(ctx context.Context, patientID string, page, pageSize int) ([]*domain.Encounter, int64, error) {
+	var totalCount int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM encounters WHERE patient_id = ?`,
+		patientID).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count encounters: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+encounterColumns+` FROM encounters WHERE patient_id = ?
+		ORDER BY date DESC, id LIMIT ? OFFSET ?`,
+		patientID, pageSize, (page-1)*pageSize,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list encounters: %w", err)
+	}
+	defer rows.Close()
+
+	encounters := []*domain.Encounter{}
+	for rows.Next() {
+		encounter, err := scanEncounter(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		encounters = append(encounters, encounter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list encounters: %w", err)
+	}
+
+	if err := r.loadAddenda(ctx, encounters); err != nil {
+		return nil, 0, err
+	}
+	return encounters, totalCount, nil
+}
+
+// DeleteByPatient removes all encounters of the patient with their addenda.
+// The patient is listed in encounter_purges meanwhile, so that the triggers
+// let closed encounters go.
+func (r *SQLiteRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		statements := []string{
+			`INSERT INTO encounter_purges (patient_id) VALUES (?)`,
+			`DELETE FROM encounter_addenda WHERE encounter_id IN (SELECT id FROM encounters WHERE patient_id = ?)`,
+			`DELETE FROM encounters WHERE patient_id = ?`,
+			`DELETE FROM encounter_purges WHERE patient_id = ?`,
+		}
+		for _, statement := range statements {
+			if _, err := conn.ExecContext(ctx, statement, patientID); err != nil {
+				return fmt.Errorf("failed to delete encounters of patient %s: %w", patientID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MoveToPatient hands all encounters of one patient to another; their
+// addenda follow them. The patient is listed in encounter_transfers
+// meanwhile, so that the triggers let closed encounters change hands.
+func (r *SQLiteRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		if _, err := conn.ExecContext(ctx, `INSERT INTO encounter_transfers (patient_id) VALUES (?)`, fromID); err != nil {
+			return fmt.Errorf("failed to move encounters of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `UPDATE encounters SET patient_id = ? WHERE patient_id = ?`, toID, fromID); err != nil {
+			return fmt.Errorf("failed to move encounters of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM encounter_transfers WHERE patient_id = ?`, fromID); err != nil {
+			return fmt.Errorf("failed to move encounters of patient %s: %w", fromID, err)
+		}
+		return nil
+	})
+}
+
+// loadAddenda reads the addenda of the encounters, oldest first
+func (r *SQLiteRepository) loadAddenda(ctx context.Context, encounters []*domain.Encounter) error {
+	for _, encounter := range encounters {
+		rows, err := r.db.QueryContext(ctx, `SELECT id, author_id, text, created_at
+			FROM encounter_addenda WHERE encounter_id = ? ORDER BY created_at, id`,
+			encounter.ID.String(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to read addenda of encounter %s: %w", encounter.ID, err)
+		}
+
+		for rows.Next() {
+			var (
+				addendum  domain.Addendum
+				id        string
+				createdAt string
+			)
+			if err := rows.Scan(&id, &addendum.AuthorID, &addendum.Text, &createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to read addendum of encounter %s: %w", encounter.ID, err)
+			}
+			if addendum.ID, err = uuid.Parse(id); err != nil {
+				rows.Close()
+				return fmt.Errorf("invalid addendum ID %q: %w", id, err)
+			}
+			if addendum.CreatedAt, err = time.Parse(timestampLayout, createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("invalid time of addendum %s: %w", id, err)
+			}
+			encounter.Addenda = append(encounter.Addenda, addendum)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read addenda of encounter %s: %w", encounter.ID, err)
+		}
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEncounter reads an encounter selected with encounterColumns, without
+// its addenda
+func scanEncounter(row rowScanner) (*domain.Encounter, error) {
+	var (
+		encounter                                         domain.Encounter
+		id, patientID                                     string
+		date, createdAt, updatedAt, encounterType, status string
+		closedAt                                          sql.NullString
+	)
+	err := row.Scan(
+		&id,
+		&patientID,
+		&date,
+		&encounterType,
+		&encounter.AttendingID,
+		&encounter.ChiefComplaint,
+		&status,
+		&encounter.Notes,
+		&encounter.Version,
+		&encounter.CreatedBy,
+		&createdAt,
+		&updatedAt,
+		&encounter.ClosedBy,
+		&closedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if encounter.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid encounter ID %q: %w", id, err)
+	}
+	if encounter.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID of encounter %s: %w", id, err)
+	}
+	for _, field := range []struct {
+		value string
+		dest  *time.Time
+	}{{date, &encounter.Date}, {createdAt, &encounter.CreatedAt}, {updatedAt, &encounter.UpdatedAt}} {
+		if *field.dest, err = time.Parse(timestampLayout, field.value); err != nil {
+			return nil, fmt.Errorf("invalid time of encounter %s: %w", id, err)
+		}
+	}
+	if closedAt.Valid {
+		closed, err := time.Parse(timestampLayout, closedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid closing time of encounter %s: %w", id, err)
+		}
+		encounter.ClosedAt = &closed
+	}
+	encounter.Type = domain.Type(encounterType)
+	encounter.Status = domain.Status(status)
+	encounter.Addenda = []domain.Addendum{}
+	return &encounter, nil
+}
+
+// formatTime formats a timestamp for storage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// formatNullTime formats an optional timestamp for storage
+func formatNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
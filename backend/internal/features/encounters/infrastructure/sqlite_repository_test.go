@@ -0,0 +1,182 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) (*SQLiteRepository, *sql.DB) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "encounters.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db), db
+}
+
+func TestSQLiteRepositoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := setupSQLiteRepository(t)
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+
+	encounter := domain.NewEncounter(uuid.New(), date, domain.TypeFollowUp, "dr-1", "Cough", "nurse-1")
+	require.NoError(t, repo.Create(ctx, encounter))
+
+	found, err := repo.GetByID(ctx, encounter.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, encounter.PatientID, found.PatientID)
+	assert.True(t, date.Equal(found.Date))
+	assert.Equal(t, domain.TypeFollowUp, found.Type)
+	assert.Equal(t, "Cough", found.ChiefComplaint)
+	assert.Empty(t, found.Addenda)
+
+	_, err = repo.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	require.NoError(t, found.Edit(date, domain.TypeFollowUp, "dr-1", "Cough", domain.StatusInProgress, "Bronchitis"))
+	require.NoError(t, repo.Update(ctx, found))
+	assert.Equal(t, 2, found.Version)
+
+	outdated := *found
+	outdated.Version = 1
+	assert.ErrorIs(t, repo.Update(ctx, &outdated), domain.ErrVersionConflict)
+}
+
+func TestSQLiteRepositoryListByPatient(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := setupSQLiteRepository(t)
+	patientID := uuid.New()
+	base := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		encounter := domain.NewEncounter(patientID, base.AddDate(0, 0, i), domain.TypeConsultation, "dr-1", "", "dr-1")
+		require.NoError(t, repo.Create(ctx, encounter))
+	}
+	require.NoError(t, repo.Create(ctx, domain.NewEncounter(uuid.New(), base, domain.TypeConsultation, "dr-1", "", "dr-1")))
+
+	encounters, total, err := repo.ListByPatient(ctx, patientID.String(), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, encounters, 2)
+	assert.True(t, base.AddDate(0, 0, 2).Equal(encounters[0].Date), "Most recent encounter should come first")
+	assert.True(t, base.AddDate(0, 0, 1).Equal(encounters[1].Date))
+}
+
+func TestSQLiteRepositoryLocksClosedEncounters(t *testing.T) {
+	ctx := context.Background()
+	repo, db := setupSQLiteRepository(t)
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+
+	encounter := domain.NewEncounter(uuid.New(), date, domain.TypeConsultation, "dr-1", "Headache", "dr-1")
+	require.NoError(t, repo.Create(ctx, encounter))
+	require.NoError(t, encounter.Close("dr-1"))
+	require.NoError(t, repo.Update(ctx, encounter))
+
+	addendum, err := encounter.AddAddendum("dr-1", "Lab results normal")
+	require.NoError(t, err)
+	require.NoError(t, repo.AppendAddendum(ctx, encounter.ID.String(), addendum))
+
+	found, err := repo.GetByID(ctx, encounter.ID.String())
+	require.NoError(t, err)
+	assert.True(t, found.IsClosed())
+	assert.Equal(t, "dr-1", found.ClosedBy)
+	require.NotNil(t, found.ClosedAt)
+	require.Len(t, found.Addenda, 1)
+	assert.Equal(t, "Lab results normal", found.Addenda[0].Text)
+
+	// Even a matching version cannot change a closed encounter
+	found.Notes = "Rewritten"
+	assert.ErrorIs(t, repo.Update(ctx, found), domain.ErrVersionConflict)
+
+	// Nor can any statement that bypasses the repository
+	_, err = db.ExecContext(ctx, `UPDATE encounters SET notes = 'Rewritten' WHERE id = ?`, encounter.ID.String())
+	assert.Error(t, err)
+	_, err = db.ExecContext(ctx, `DELETE FROM encounters WHERE id = ?`, encounter.ID.String())
+	assert.Error(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE encounter_addenda SET text = 'Rewritten'`)
+	assert.Error(t, err)
+	_, err = db.ExecContext(ctx, `DELETE FROM encounter_addenda`)
+	assert.Error(t, err)
+
+	found, err = repo.GetByID(ctx, encounter.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, found.Notes)
+	assert.Len(t, found.Addenda, 1)
+}
+
+func TestSQLiteRepositoryDeleteByPatient(t *testing.T) {
+	ctx := context.Background()
+	repo, db := setupSQLiteRepository(t)
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+	patientID := uuid.New()
+
+	closeWithAddendum := func(encounter *domain.Encounter) {
+		require.NoError(t, repo.Create(ctx, encounter))
+		require.NoError(t, encounter.Close("dr-1"))
+		require.NoError(t, repo.Update(ctx, encounter))
+		addendum, err := encounter.AddAddendum("dr-1", "Lab results normal")
+		require.NoError(t, err)
+		require.NoError(t, repo.AppendAddendum(ctx, encounter.ID.String(), addendum))
+	}
+	closeWithAddendum(domain.NewEncounter(patientID, date, domain.TypeConsultation, "dr-1", "Headache", "dr-1"))
+	require.NoError(t, repo.Create(ctx, domain.NewEncounter(patientID, date.Add(time.Hour), domain.TypeFollowUp, "dr-1", "Headache", "dr-1")))
+	other := domain.NewEncounter(uuid.New(), date, domain.TypeConsultation, "dr-1", "Cough", "dr-1")
+	closeWithAddendum(other)
+
+	// Purging the patient removes closed encounters and their addenda too
+	require.NoError(t, repo.DeleteByPatient(ctx, patientID.String()))
+	encounters, total, err := repo.ListByPatient(ctx, patientID.String(), 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, encounters)
+	assert.Zero(t, total)
+
+	// while the encounters of other patients stay locked
+	_, err = db.ExecContext(ctx, `DELETE FROM encounter_addenda`)
+	assert.Error(t, err)
+	_, err = db.ExecContext(ctx, `DELETE FROM encounters WHERE id = ?`, other.ID.String())
+	assert.Error(t, err)
+
+	found, err := repo.GetByID(ctx, other.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, found.Addenda, 1)
+}
+
+func TestSQLiteRepositoryMoveToPatient(t *testing.T) {
+	ctx := context.Background()
+	repo, db := setupSQLiteRepository(t)
+	date := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+	duplicateID, patientID := uuid.New(), uuid.New()
+
+	closed := domain.NewEncounter(duplicateID, date, domain.TypeConsultation, "dr-1", "Headache", "dr-1")
+	require.NoError(t, repo.Create(ctx, closed))
+	require.NoError(t, closed.Close("dr-1"))
+	require.NoError(t, repo.Update(ctx, closed))
+	addendum, err := closed.AddAddendum("dr-1", "Lab results normal")
+	require.NoError(t, err)
+	require.NoError(t, repo.AppendAddendum(ctx, closed.ID.String(), addendum))
+	require.NoError(t, repo.Create(ctx, domain.NewEncounter(patientID, date.Add(time.Hour), domain.TypeFollowUp, "dr-1", "Headache", "dr-1")))
+
+	// Merging the duplicate hands over its closed encounters with their addenda
+	require.NoError(t, repo.MoveToPatient(ctx, duplicateID.String(), patientID.String()))
+	encounters, total, err := repo.ListByPatient(ctx, patientID.String(), 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, encounters, 2)
+	found, err := repo.GetByID(ctx, closed.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, patientID, found.PatientID)
+	assert.Len(t, found.Addenda, 1)
+
+	// while closed encounters stay locked otherwise
+	_, err = db.ExecContext(ctx, `UPDATE encounters SET patient_id = ? WHERE id = ?`, duplicateID.String(), closed.ID.String())
+	assert.Error(t, err)
+}
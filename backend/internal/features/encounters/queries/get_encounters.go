@@ -0,0 +1,98 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/encounters/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// ListEncountersQuery represents the query to list a patient's encounters,
+// most recent first
+type ListEncountersQuery struct {
+	PatientID string `form:"-"`
+	Page      int    `form:"page,default=1"`
+	PageSize  int    `form:"pageSize,default=20"`
+}
+
+// PaginatedEncounters represents a paginated list of encounters
+type PaginatedEncounters struct {
+	Encounters  []*domain.Encounter `json:"encounters"`
+	TotalCount  int64               `json:"totalCount"`
+	CurrentPage int                 `json:"currentPage"`
+	PageSize    int                 `json:"pageSize"`
+	TotalPages  int                 `json:"totalPages"`
+}
+
+// ListEncountersHandler handles the list encounters query
+type ListEncountersHandler interface {
+	Handle(ctx context.Context, query ListEncountersQuery) (*PaginatedEncounters, error)
+}
+
+type listEncountersHandler struct {
+	repo domain.ListEncountersRepository
+}
+
+// NewListEncountersHandler creates a new list encounters handler
+func NewListEncountersHandler(repo domain.ListEncountersRepository) ListEncountersHandler {
+	return &listEncountersHandler{repo: repo}
+}
+
+// Handle processes the list encounters query
+func (h *listEncountersHandler) Handle(ctx context.Context, query ListEncountersQuery) (*PaginatedEncounters, error) {
+	// Ensure valid pagination parameters
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = 20
+	}
+
+	encounters, totalCount, err := h.repo.ListByPatient(ctx, query.PatientID, query.Page, query.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate total pages
+	totalPages := int(totalCount) / query.PageSize
+	if int(totalCount)%query.PageSize > 0 {
+		totalPages++
+	}
+
+	return &PaginatedEncounters{
+		Encounters:  encounters,
+		TotalCount:  totalCount,
+		CurrentPage: query.Page,
+		PageSize:    query.PageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// GetEncounterQuery represents the query to read one encounter of a patient
+type GetEncounterQuery struct {
+	PatientID   string
+	EncounterID string
+}
+
+// GetEncounterHandler handles the get encounter query
+type GetEncounterHandler interface {
+	Handle(ctx context.Context, query GetEncounterQuery) (*domain.Encounter, error)
+}
+
+type getEncounterHandler struct {
+	repo domain.GetEncounterRepository
+}
+
+// NewGetEncounterHandler creates a new get encounter handler
+func NewGetEncounterHandler(repo domain.GetEncounterRepository) GetEncounterHandler {
+	return &getEncounterHandler{repo: repo}
+}
+
+// Handle processes the get encounter query
+func (h *getEncounterHandler) Handle(ctx context.Context, query GetEncounterQuery) (*domain.Encounter, error) {
+	encounter, err := h.repo.GetByID(ctx, query.EncounterID)
+	if err == domain.ErrNotFound || (err == nil && !encounter.BelongsTo(query.PatientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Encounter not found")
+	}
+	return encounter, err
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// PrescribeMedicationCommand represents the command to prescribe a
+// medication to a patient. A prescription that interacts with the
+// patient's medications or allergies is rejected with an
+// InteractionWarningError unless the warnings are acknowledged.
+type PrescribeMedicationCommand struct {
+	PatientID               string `json:"-"`
+	PrescribedBy            string `json:"-"`
+	AcknowledgeInteractions bool   `json:"-"`
+	domain.Prescription
+}
+
+// InteractionWarningError reports the interactions found for a
+// prescription that have not been acknowledged
+type InteractionWarningError struct {
+	Interactions []domain.Interaction
+}
+
+func (e *InteractionWarningError) Error() string {
+	return "prescription interacts with the patient's medications or allergies"
+}
+
+// PrescribeMedicationHandler handles the prescribe medication command
+type PrescribeMedicationHandler interface {
+	Handle(ctx context.Context, cmd PrescribeMedicationCommand) (*domain.Medication, error)
+}
+
+type prescribeMedicationHandler struct {
+	repo      domain.PrescribeMedicationRepository
+	patients  domain.PatientDirectory
+	allergies domain.AllergyDirectory
+	checker   domain.InteractionChecker
+}
+
+// NewPrescribeMedicationHandler creates a new prescribe medication handler
+func NewPrescribeMedicationHandler(
+	repo domain.PrescribeMedicationRepository,
+	patients domain.PatientDirectory,
+	allergies domain.AllergyDirectory,
+	checker domain.InteractionChecker,
+) PrescribeMedicationHandler {
+	return &prescribeMedicationHandler{repo: repo, patients: patients, allergies: allergies, checker: checker}
+}
+
+// Handle processes the prescribe medication command
+func (h *prescribeMedicationHandler) Handle(ctx context.Context, cmd PrescribeMedicationCommand) (*domain.Medication, error) {
+	prescription := cmd.Prescription.Normalize()
+	if err := validatePrescription(prescription); err != nil {
+		return nil, err
+	}
+
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	interactions, err := h.checkInteractions(ctx, cmd.PatientID, prescription.Drug, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(interactions) > 0 && !cmd.AcknowledgeInteractions {
+		return nil, &InteractionWarningError{Interactions: interactions}
+	}
+
+	medication := domain.NewMedication(patientID, prescription, interactions, cmd.PrescribedBy, now)
+	if err := h.repo.Create(ctx, medication); err != nil {
+		return nil, err
+	}
+	return medication, nil
+}
+
+// checkInteractions checks a drug against the patient's active medications
+// and allergies
+func (h *prescribeMedicationHandler) checkInteractions(ctx context.Context, patientID, drug string, now time.Time) ([]domain.Interaction, error) {
+	medications, err := h.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	check := domain.InteractionCheck{Drug: drug}
+	for _, medication := range medications {
+		medication.Refresh(now)
+		if medication.Status == domain.StatusActive {
+			check.ActiveDrugs = append(check.ActiveDrugs, medication.Drug)
+		}
+	}
+	if check.Allergens, err = h.allergies.ActiveAllergens(ctx, patientID); err != nil {
+		return nil, err
+	}
+	return h.checker.Check(ctx, check)
+}
+
+// validatePrescription checks a normalized prescription
+func validatePrescription(prescription domain.Prescription) error {
+	if prescription.Drug == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Drug is required")
+	}
+	if prescription.Dose == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Dose is required")
+	}
+	if !prescription.Route.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Route must be oral, sublingual, iv, im, sc, topical, inhaled, rectal or other")
+	}
+	if prescription.Frequency == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Frequency is required")
+	}
+	if prescription.DurationDays < 0 || prescription.DurationDays > domain.MaxDurationDays {
+		return errors.NewAPIError(errors.ErrValidation,
+			fmt.Sprintf("Duration must be between 0 (until stopped) and %d days", domain.MaxDurationDays))
+	}
+	return nil
+}
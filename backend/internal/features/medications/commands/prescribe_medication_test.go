@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/features/medications/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of active patients
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func (d patientDirectory) PatientSummary(ctx context.Context, patientID string) (*domain.PatientSummary, error) {
+	return nil, nil
+}
+
+// allergyDirectory knows the active allergens of each patient
+type allergyDirectory map[string][]string
+
+func (d allergyDirectory) ActiveAllergens(ctx context.Context, patientID string) ([]string, error) {
+	return d[patientID], nil
+}
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestPrescribeMedication(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patientID := uuid.NewString()
+	checker, err := infrastructure.NewRulesChecker(infrastructure.InteractionRules{
+		Groups: map[string][]string{"nsaids": {"ibuprofen", "naproxen"}},
+		DrugInteractions: []infrastructure.DrugRule{
+			{Drugs: [2]string{"warfarin", "nsaids"}, Severity: domain.SeverityMajor, Description: "Bleeding"},
+		},
+	})
+	require.NoError(t, err)
+	prescribe := NewPrescribeMedicationHandler(repo, patientDirectory{patientID: true}, allergyDirectory{patientID: {"Penicillin"}}, checker)
+
+	prescription := domain.Prescription{Drug: "Warfarin", Dose: "5 mg", Route: domain.RouteOral, Frequency: "once daily"}
+
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: uuid.NewString(), Prescription: prescription})
+	assertAPIError(t, err, errors.ErrNotFound)
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, Prescription: domain.Prescription{
+		Drug: "Warfarin", Dose: "5 mg", Route: "nasal", Frequency: "once daily",
+	}})
+	assertAPIError(t, err, errors.ErrValidation)
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, Prescription: domain.Prescription{
+		Drug: "Warfarin", Dose: "5 mg", Route: domain.RouteOral, Frequency: "once daily", DurationDays: -1,
+	}})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	warfarin, err := prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, PrescribedBy: "dr-1", Prescription: prescription})
+	require.NoError(t, err)
+	assert.Empty(t, warfarin.Interactions)
+	assert.Equal(t, "dr-1", warfarin.PrescribedBy)
+
+	ibuprofen := domain.Prescription{Drug: "Ibuprofen", Dose: "400 mg", Route: domain.RouteOral, Frequency: "as needed", DurationDays: 5}
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, Prescription: ibuprofen})
+	warning, ok := err.(*InteractionWarningError)
+	require.True(t, ok, "Expected an interaction warning, got %v", err)
+	require.Len(t, warning.Interactions, 1)
+	assert.Equal(t, "Warfarin", warning.Interactions[0].With)
+
+	medications, err := repo.ListByPatient(ctx, patientID)
+	require.NoError(t, err)
+	assert.Len(t, medications, 1, "A prescription with warnings should not be saved unless acknowledged")
+
+	acknowledged, err := prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, AcknowledgeInteractions: true, Prescription: ibuprofen})
+	require.NoError(t, err)
+	assert.Equal(t, warning.Interactions, acknowledged.Interactions)
+
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, Prescription: domain.Prescription{
+		Drug: "penicillin", Dose: "1 g", Route: domain.RouteIV, Frequency: "every 6 hours",
+	}})
+	warning, ok = err.(*InteractionWarningError)
+	require.True(t, ok, "Expected an allergy warning, got %v", err)
+	assert.Equal(t, domain.InteractionAllergy, warning.Interactions[0].Kind)
+
+	stop := NewStopMedicationHandler(repo)
+	_, err = stop.Handle(ctx, StopMedicationCommand{PatientID: patientID, MedicationID: warfarin.ID.String()})
+	assertAPIError(t, err, errors.ErrValidation)
+	_, err = stop.Handle(ctx, StopMedicationCommand{PatientID: uuid.NewString(), MedicationID: warfarin.ID.String(), Reason: "Bleeding"})
+	assertAPIError(t, err, errors.ErrNotFound)
+
+	stopped, err := stop.Handle(ctx, StopMedicationCommand{PatientID: patientID, MedicationID: warfarin.ID.String(), StoppedBy: "dr-2", Reason: "Bleeding"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, stopped.Status)
+	_, err = stop.Handle(ctx, StopMedicationCommand{PatientID: patientID, MedicationID: warfarin.ID.String(), Reason: "Bleeding"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	_, err = prescribe.Handle(ctx, PrescribeMedicationCommand{PatientID: patientID, Prescription: domain.Prescription{
+		Drug: "Naproxen", Dose: "250 mg", Route: domain.RouteOral, Frequency: "twice daily",
+	}})
+	assert.NoError(t, err, "A stopped medication should no longer interact")
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// StopMedicationCommand represents the command to discontinue a medication
+// before the end of its prescribed duration, for the given reason
+type StopMedicationCommand struct {
+	PatientID    string `json:"-"`
+	MedicationID string `json:"-"`
+	StoppedBy    string `json:"-"`
+	Reason       string `json:"reason" binding:"required"`
+}
+
+// StopMedicationHandler handles the stop medication command
+type StopMedicationHandler interface {
+	Handle(ctx context.Context, cmd StopMedicationCommand) (*domain.Medication, error)
+}
+
+type stopMedicationHandler struct {
+	repo domain.StopMedicationRepository
+}
+
+// NewStopMedicationHandler creates a new stop medication handler
+func NewStopMedicationHandler(repo domain.StopMedicationRepository) StopMedicationHandler {
+	return &stopMedicationHandler{repo: repo}
+}
+
+// Handle processes the stop medication command
+func (h *stopMedicationHandler) Handle(ctx context.Context, cmd StopMedicationCommand) (*domain.Medication, error) {
+	if strings.TrimSpace(cmd.Reason) == "" {
+		return nil, errors.NewAPIError(errors.ErrValidation, "A reason is required")
+	}
+
+	medication, err := h.repo.GetByID(ctx, cmd.MedicationID)
+	if err == domain.ErrNotFound || (err == nil && !medication.BelongsTo(cmd.PatientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Medication not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := medication.Stop(cmd.Reason, cmd.StoppedBy, time.Now()); err != nil {
+		if err == domain.ErrAlreadyEnded {
+			return nil, errors.NewAPIError(errors.ErrConflict, "Medication has already ended")
+		}
+		return nil, err
+	}
+	if err := h.repo.Update(ctx, medication); err != nil {
+		return nil, err
+	}
+	return medication, nil
+}
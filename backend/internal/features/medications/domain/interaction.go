@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"strings"
+)
+
+// InteractionKind tells what a prescribed drug interacts with
+type InteractionKind string
+
+const (
+	// InteractionDrug is an interaction with another active medication
+	InteractionDrug InteractionKind = "drug-drug"
+	// InteractionAllergy is an interaction with an active allergy
+	InteractionAllergy InteractionKind = "drug-allergy"
+)
+
+// InteractionSeverity is how serious an interaction is
+type InteractionSeverity string
+
+const (
+	SeverityMinor           InteractionSeverity = "minor"
+	SeverityModerate        InteractionSeverity = "moderate"
+	SeverityMajor           InteractionSeverity = "major"
+	SeverityContraindicated InteractionSeverity = "contraindicated"
+)
+
+// IsValid reports whether the severity is a known one
+func (s InteractionSeverity) IsValid() bool {
+	switch s {
+	case SeverityMinor, SeverityModerate, SeverityMajor, SeverityContraindicated:
+		return true
+	}
+	return false
+}
+
+// Interaction warns that a prescribed drug interacts with another drug the
+// patient takes or with a substance the patient is allergic to
+type Interaction struct {
+	Kind        InteractionKind     `json:"kind"`
+	Severity    InteractionSeverity `json:"severity"`
+	Drug        string              `json:"drug"`
+	With        string              `json:"with"`
+	Description string              `json:"description"`
+}
+
+// InteractionCheck is what a prescription is checked against: the drugs the
+// patient currently takes and the substances the patient is allergic to
+type InteractionCheck struct {
+	Drug        string
+	ActiveDrugs []string
+	Allergens   []string
+}
+
+// InteractionChecker finds the interactions of a prescribed drug. It is
+// pluggable so that clinics can bring their own drug knowledge base.
+type InteractionChecker interface {
+	Check(ctx context.Context, check InteractionCheck) ([]Interaction, error)
+}
+
+// NormalizeName folds a drug or substance name for comparison
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
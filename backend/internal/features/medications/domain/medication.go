@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Route is how a medication is administered
+type Route string
+
+const (
+	RouteOral       Route = "oral"
+	RouteSublingual Route = "sublingual"
+	RouteIV         Route = "iv"
+	RouteIM         Route = "im"
+	RouteSC         Route = "sc"
+	RouteTopical    Route = "topical"
+	RouteInhaled    Route = "inhaled"
+	RouteRectal     Route = "rectal"
+	RouteOther      Route = "other"
+)
+
+// IsValid reports whether the route is a known one
+func (r Route) IsValid() bool {
+	switch r {
+	case RouteOral, RouteSublingual, RouteIV, RouteIM, RouteSC, RouteTopical, RouteInhaled, RouteRectal, RouteOther:
+		return true
+	}
+	return false
+}
+
+// Status tells a medication the patient is taking from a past one
+type Status string
+
+const (
+	StatusActive Status = "active"
+	// StatusCompleted marks a medication whose prescribed duration is over
+	StatusCompleted Status = "completed"
+	// StatusStopped marks a medication discontinued before its end
+	StatusStopped Status = "stopped"
+)
+
+// IsPast reports whether the patient no longer takes the medication
+func (s Status) IsPast() bool {
+	return s == StatusCompleted || s == StatusStopped
+}
+
+// MaxDurationDays is the longest duration a prescription may be given for
+const MaxDurationDays = 366
+
+// ErrAlreadyEnded is returned when a medication that is no longer active is stopped
+var ErrAlreadyEnded = errors.New("medication has already ended")
+
+// Prescription holds what a prescriber orders. A duration of zero days
+// means the medication is taken until it is stopped.
+type Prescription struct {
+	Drug         string `json:"drug" binding:"required"`
+	Dose         string `json:"dose" binding:"required"`
+	Route        Route  `json:"route" binding:"required"`
+	Frequency    string `json:"frequency" binding:"required"`
+	DurationDays int    `json:"durationDays"`
+	Instructions string `json:"instructions"`
+}
+
+// Normalize trims the free text of a prescription
+func (p Prescription) Normalize() Prescription {
+	p.Drug = strings.Join(strings.Fields(p.Drug), " ")
+	p.Dose = strings.TrimSpace(p.Dose)
+	p.Route = Route(strings.ToLower(strings.TrimSpace(string(p.Route))))
+	p.Frequency = strings.TrimSpace(p.Frequency)
+	p.Instructions = strings.TrimSpace(p.Instructions)
+	return p
+}
+
+// Medication is a drug prescribed to a patient. It stays on the patient's
+// medication list once it has ended, together with the interaction
+// warnings the prescriber acknowledged.
+type Medication struct {
+	ID        uuid.UUID `json:"id"`
+	PatientID uuid.UUID `json:"patientId"`
+	Prescription
+	Status       Status        `json:"status"`
+	Interactions []Interaction `json:"interactions"`
+	PrescribedBy string        `json:"prescribedBy"`
+	PrescribedAt time.Time     `json:"prescribedAt"`
+	StoppedBy    string        `json:"stoppedBy,omitempty"`
+	StoppedAt    *time.Time    `json:"stoppedAt,omitempty"`
+	StopReason   string        `json:"stopReason,omitempty"`
+}
+
+// NewMedication creates an active medication from a prescription. The
+// interactions are the warnings the prescriber acknowledged.
+func NewMedication(patientID uuid.UUID, prescription Prescription, interactions []Interaction, by string, now time.Time) *Medication {
+	if interactions == nil {
+		interactions = []Interaction{}
+	}
+	return &Medication{
+		ID:           uuid.New(),
+		PatientID:    patientID,
+		Prescription: prescription.Normalize(),
+		Status:       StatusActive,
+		Interactions: interactions,
+		PrescribedBy: by,
+		PrescribedAt: now.UTC(),
+	}
+}
+
+// EndsAt returns when the prescribed duration is over, or nil if the
+// medication is taken until it is stopped
+func (m *Medication) EndsAt() *time.Time {
+	if m.DurationDays <= 0 {
+		return nil
+	}
+	end := m.PrescribedAt.AddDate(0, 0, m.DurationDays)
+	return &end
+}
+
+// Refresh marks an active medication completed once its duration is over
+func (m *Medication) Refresh(now time.Time) {
+	if m.Status != StatusActive {
+		return
+	}
+	if end := m.EndsAt(); end != nil && !now.Before(*end) {
+		m.Status = StatusCompleted
+	}
+}
+
+// Stop discontinues an active medication
+func (m *Medication) Stop(reason, by string, now time.Time) error {
+	m.Refresh(now)
+	if m.Status != StatusActive {
+		return ErrAlreadyEnded
+	}
+	stoppedAt := now.UTC()
+	m.Status = StatusStopped
+	m.StoppedBy = by
+	m.StoppedAt = &stoppedAt
+	m.StopReason = strings.TrimSpace(reason)
+	return nil
+}
+
+// BelongsTo reports whether the medication was prescribed to the patient
+func (m *Medication) BelongsTo(patientID string) bool {
+	return m.PatientID.String() == patientID
+}
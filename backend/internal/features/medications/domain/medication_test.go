@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMedication(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	medication := NewMedication(uuid.New(), Prescription{
+		Drug:      "  Amoxicillin   500 ",
+		Dose:      " 500 mg ",
+		Route:     " Oral",
+		Frequency: "three times daily ",
+	}, nil, "dr-1", now)
+
+	assert.Equal(t, "Amoxicillin 500", medication.Drug)
+	assert.Equal(t, "500 mg", medication.Dose)
+	assert.Equal(t, RouteOral, medication.Route)
+	assert.Equal(t, "three times daily", medication.Frequency)
+	assert.Equal(t, StatusActive, medication.Status)
+	assert.NotNil(t, medication.Interactions)
+	assert.Nil(t, medication.EndsAt(), "A medication without a duration is taken until stopped")
+}
+
+func TestMedicationCompletesAfterItsDuration(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	medication := NewMedication(uuid.New(), Prescription{Drug: "Amoxicillin", DurationDays: 7}, nil, "dr-1", now)
+
+	require.NotNil(t, medication.EndsAt())
+	assert.Equal(t, now.AddDate(0, 0, 7), *medication.EndsAt())
+
+	medication.Refresh(now.AddDate(0, 0, 6))
+	assert.Equal(t, StatusActive, medication.Status)
+	medication.Refresh(now.AddDate(0, 0, 7))
+	assert.Equal(t, StatusCompleted, medication.Status)
+	assert.True(t, medication.Status.IsPast())
+
+	assert.ErrorIs(t, medication.Stop("No longer needed", "dr-1", now.AddDate(0, 0, 8)), ErrAlreadyEnded)
+}
+
+func TestStopMedication(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	medication := NewMedication(uuid.New(), Prescription{Drug: "Ibuprofen"}, nil, "dr-1", now)
+
+	require.NoError(t, medication.Stop(" Gastric pain ", "dr-2", now.Add(time.Hour)))
+	assert.Equal(t, StatusStopped, medication.Status)
+	assert.Equal(t, "Gastric pain", medication.StopReason)
+	assert.Equal(t, "dr-2", medication.StoppedBy)
+	require.NotNil(t, medication.StoppedAt)
+	assert.Equal(t, now.Add(time.Hour), *medication.StoppedAt)
+
+	assert.ErrorIs(t, medication.Stop("Again", "dr-2", now.Add(2*time.Hour)), ErrAlreadyEnded)
+}
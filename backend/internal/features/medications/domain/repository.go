@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// ErrNotFound is returned when a medication does not exist
+var ErrNotFound = errors.New("medication not found")
+
+// Repository defines the interface for medication persistence.
+// DeleteByPatient removes the medications of a purged patient and
+// MoveToPatient hands them from a duplicate to the patient it is merged into.
+type Repository interface {
+	Create(ctx context.Context, medication *Medication) error
+	Update(ctx context.Context, medication *Medication) error
+	GetByID(ctx context.Context, id string) (*Medication, error)
+	ListByPatient(ctx context.Context, patientID string) ([]*Medication, error)
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// PatientSummary is what a printed prescription shows of the patient
+type PatientSummary struct {
+	Name        string
+	DateOfBirth time.Time
+}
+
+// PatientDirectory tells whether medications can be prescribed to a
+// patient and names them on prescriptions. PatientSummary returns nil for
+// an unknown patient, whose old prescriptions can then no longer be printed.
+type PatientDirectory interface {
+	patientref.Directory
+	PatientSummary(ctx context.Context, patientID string) (*PatientSummary, error)
+}
+
+// AllergyDirectory returns the substances a patient is actively allergic
+// or intolerant to, so that a prescription is checked against the allergy
+// list without reading it directly.
+type AllergyDirectory interface {
+	ActiveAllergens(ctx context.Context, patientID string) ([]string, error)
+}
+
+// UserDirectory names the users who prescribe medications. A user who
+// cannot be found is named by their ID, so that old prescriptions can
+// still be printed.
+type UserDirectory interface {
+	UserName(ctx context.Context, userID string) string
+}
+
+// PrescribeMedicationRepository defines the minimal interface for prescribing medications
+type PrescribeMedicationRepository interface {
+	Create(ctx context.Context, medication *Medication) error
+	ListByPatient(ctx context.Context, patientID string) ([]*Medication, error)
+}
+
+// StopMedicationRepository defines the minimal interface for stopping medications
+type StopMedicationRepository interface {
+	GetByID(ctx context.Context, id string) (*Medication, error)
+	Update(ctx context.Context, medication *Medication) error
+}
+
+// GetMedicationsRepository defines the minimal interface for reading medications
+type GetMedicationsRepository interface {
+	GetByID(ctx context.Context, id string) (*Medication, error)
+	ListByPatient(ctx context.Context, patientID string) ([]*Medication, error)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/dksch/pococlinic/internal/features/medications/commands"
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/features/medications/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission a medication route requires
+type Access string
+
+const (
+	AccessReadMedications  Access = "patients:read:medications"
+	AccessWriteMedications Access = "patients:write:medications"
+)
+
+// Guard checks whether the caller may read or prescribe medications
+type Guard = authz.Guard[Access]
+
+// MedicationHandler handles HTTP requests for medication operations
+type MedicationHandler struct {
+	prescribeMedicationHandler commands.PrescribeMedicationHandler
+	stopMedicationHandler      commands.StopMedicationHandler
+	getMedicationsHandler      queries.GetMedicationsHandler
+	getPrescriptionHandler     queries.GetPrescriptionHandler
+	logger                     *logging.Logger
+}
+
+// NewMedicationHandler creates a new medication handler
+func NewMedicationHandler(
+	prescribeHandler commands.PrescribeMedicationHandler,
+	stopHandler commands.StopMedicationHandler,
+	getHandler queries.GetMedicationsHandler,
+	prescriptionHandler queries.GetPrescriptionHandler,
+	logger *logging.Logger,
+) *MedicationHandler {
+	return &MedicationHandler{
+		prescribeMedicationHandler: prescribeHandler,
+		stopMedicationHandler:      stopHandler,
+		getMedicationsHandler:      getHandler,
+		getPrescriptionHandler:     prescriptionHandler,
+		logger:                     logger,
+	}
+}
+
+// RegisterRoutes registers the medication routes below the patient they
+// belong to. Printing a prescription only needs read access.
+func (h *MedicationHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	patient := router.Group("/patients/:id")
+	{
+		patient.GET("/medications", guard(AccessReadMedications), h.GetMedications)
+		patient.POST("/prescriptions", guard(AccessWriteMedications), h.PrescribeMedication)
+		patient.POST("/medications/:medicationId/stop", guard(AccessWriteMedications), h.StopMedication)
+		patient.GET("/medications/:medicationId/prescription", guard(AccessReadMedications), h.PrintPrescription)
+	}
+}
+
+// GetMedications handles the request for the medication list of a patient
+func (h *MedicationHandler) GetMedications(c *gin.Context) {
+	medications, err := h.getMedicationsHandler.Handle(c.Request.Context(), queries.GetMedicationsQuery{
+		PatientID: c.Param("id"),
+		Status:    c.Query("status"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to fetch medications")
+		return
+	}
+
+	c.JSON(http.StatusOK, medications)
+}
+
+// PrescribeMedication handles the request to prescribe a medication
+func (h *MedicationHandler) PrescribeMedication(c *gin.Context) {
+	var cmd commands.PrescribeMedicationCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.PrescribedBy = c.GetString("userID")
+
+	acknowledge, err := strconv.ParseBool(c.DefaultQuery("acknowledgeInteractions", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid acknowledgeInteractions flag"))
+		return
+	}
+	cmd.AcknowledgeInteractions = acknowledge
+
+	medication, err := h.prescribeMedicationHandler.Handle(c.Request.Context(), cmd)
+	if warning, ok := err.(*commands.InteractionWarningError); ok {
+		c.JSON(http.StatusConflict, interactionWarningResponse{
+			APIError:     errors.NewAPIError(errors.ErrConflict, "Prescription has interactions, confirm with acknowledgeInteractions=true to prescribe anyway"),
+			Interactions: warning.Interactions,
+		})
+		return
+	}
+	if err != nil {
+		h.respondWithError(c, err, "Failed to prescribe medication")
+		return
+	}
+
+	c.JSON(http.StatusCreated, medication)
+}
+
+// StopMedication handles the request to discontinue a medication
+func (h *MedicationHandler) StopMedication(c *gin.Context) {
+	var cmd commands.StopMedicationCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "A reason is required"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.MedicationID = c.Param("medicationId")
+	cmd.StoppedBy = c.GetString("userID")
+
+	medication, err := h.stopMedicationHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to stop medication")
+		return
+	}
+
+	c.JSON(http.StatusOK, medication)
+}
+
+// PrintPrescription handles the request for a printable prescription. It
+// is a standalone HTML page that only allows its own inline styles.
+func (h *MedicationHandler) PrintPrescription(c *gin.Context) {
+	document, err := h.getPrescriptionHandler.Handle(c.Request.Context(), queries.GetPrescriptionQuery{
+		PatientID:    c.Param("id"),
+		MedicationID: c.Param("medicationId"),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to print prescription")
+		return
+	}
+
+	var page bytes.Buffer
+	if err := prescriptionTemplate.Execute(&page, document); err != nil {
+		h.respondWithError(c, err, "Failed to print prescription")
+		return
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// interactionWarningResponse is the body of a rejected prescription, it
+// lists the interactions to acknowledge
+type interactionWarningResponse struct {
+	*errors.APIError
+	Interactions []domain.Interaction `json:"interactions"`
+}
+
+// respondWithError writes an API error with its status and hides any other
+// error behind the given message
+func (h *MedicationHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		c.JSON(errors.StatusCode(apiErr.Code), apiErr)
+		return
+	}
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"html/template"
+	"time"
+)
+
+// prescriptionTemplate renders a prescription as a page to print. The
+// acknowledged interactions are printed too, so that the pharmacist sees
+// that the prescriber was aware of them.
+var prescriptionTemplate = template.Must(template.New("prescription").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Prescription for {{.Patient.Name}}</title>
+<style>
+	body { font-family: sans-serif; max-width: 40em; margin: 2em auto; color: #000; }
+	h1 { font-size: 1.4em; border-bottom: 2px solid #000; padding-bottom: 0.3em; }
+	dl { display: grid; grid-template-columns: 10em 1fr; gap: 0.4em 1em; }
+	dt { font-weight: bold; }
+	dd { margin: 0; }
+	.warnings { margin-top: 1.5em; }
+	.signature { margin-top: 4em; border-top: 1px solid #000; width: 20em; padding-top: 0.3em; }
+	@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Prescription</h1>
+<dl>
+	<dt>Patient</dt><dd>{{.Patient.Name}}</dd>
+	<dt>Date of birth</dt><dd>{{date .Patient.DateOfBirth}}</dd>
+	<dt>Date</dt><dd>{{date .Medication.PrescribedAt}}</dd>
+</dl>
+{{with .Medication}}<dl>
+	<dt>Drug</dt><dd>{{.Drug}}</dd>
+	<dt>Dose</dt><dd>{{.Dose}}</dd>
+	<dt>Route</dt><dd>{{.Route}}</dd>
+	<dt>Frequency</dt><dd>{{.Frequency}}</dd>
+	<dt>Duration</dt><dd>{{if .DurationDays}}{{.DurationDays}} days{{else}}Until stopped{{end}}</dd>
+	{{- if .Instructions}}
+	<dt>Instructions</dt><dd>{{.Instructions}}</dd>
+	{{- end}}
+	{{- if .StoppedAt}}
+	<dt>Stopped</dt><dd>{{date .StoppedAt}}: {{.StopReason}}</dd>
+	{{- end}}
+</dl>
+{{- if .Interactions}}
+<div class="warnings">
+<strong>Interactions acknowledged by the prescriber</strong>
+<ul>
+	{{- range .Interactions}}
+	<li>{{.Severity}}: {{.Drug}} with {{.With}}{{if .Description}}, {{.Description}}{{end}}</li>
+	{{- end}}
+</ul>
+</div>
+{{- end}}{{end}}
+<p class="signature">{{.PrescriberName}}</p>
+</body>
+</html>
+`))
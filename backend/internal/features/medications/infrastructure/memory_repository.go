@@ -0,0 +1,119 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryRepository is a simple in-memory implementation of the medication Repository interface
+type MemoryRepository struct {
+	medications map[string]*domain.Medication
+	mu          sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory medication repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		medications: make(map[string]*domain.Medication),
+	}
+}
+
+// Create adds a new medication to the repository
+func (r *MemoryRepository) Create(ctx context.Context, medication *domain.Medication) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.medications[medication.ID.String()] = copyMedication(medication)
+	return nil
+}
+
+// Update stores the changed medication
+func (r *MemoryRepository) Update(ctx context.Context, medication *domain.Medication) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.medications[medication.ID.String()]; !exists {
+		return domain.ErrNotFound
+	}
+	r.medications[medication.ID.String()] = copyMedication(medication)
+	return nil
+}
+
+// GetByID retrieves a medication by its ID
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Medication, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	medication, exists := r.medications[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return copyMedication(medication), nil
+}
+
+// ListByPatient returns the patient's medications, the latest prescribed first
+func (r *MemoryRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Medication, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	medications := []*domain.Medication{}
+	for _, medication := range r.medications {
+		if medication.BelongsTo(patientID) {
+			medications = append(medications, copyMedication(medication))
+		}
+	}
+	sort.Slice(medications, func(i, j int) bool {
+		if !medications[i].PrescribedAt.Equal(medications[j].PrescribedAt) {
+			return medications[i].PrescribedAt.After(medications[j].PrescribedAt)
+		}
+		return medications[i].ID.String() > medications[j].ID.String()
+	})
+	return medications, nil
+}
+
+// DeleteByPatient removes all medications of the patient
+func (r *MemoryRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, medication := range r.medications {
+		if medication.BelongsTo(patientID) {
+			delete(r.medications, id)
+		}
+	}
+	return nil
+}
+
+// MoveToPatient hands all medications of one patient to another
+func (r *MemoryRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	target, err := uuid.Parse(toID)
+	if err != nil {
+		return fmt.Errorf("invalid patient ID %q: %w", toID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, medication := range r.medications {
+		if medication.BelongsTo(fromID) {
+			medication.PatientID = target
+		}
+	}
+	return nil
+}
+
+// copyMedication returns a copy that shares no state with the original
+func copyMedication(medication *domain.Medication) *domain.Medication {
+	copied := *medication
+	copied.Interactions = append([]domain.Interaction{}, medication.Interactions...)
+	if medication.StoppedAt != nil {
+		stoppedAt := *medication.StoppedAt
+		copied.StoppedAt = &stoppedAt
+	}
+	return &copied
+}
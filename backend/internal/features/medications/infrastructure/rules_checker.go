@@ -0,0 +1,213 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+)
+
+// InteractionRules is the local knowledge base of the RulesChecker. Groups
+// name classes of drugs, such as "nsaids", that rules may refer to in place
+// of a single drug. Names are compared case-insensitively.
+type InteractionRules struct {
+	Groups              map[string][]string `json:"groups"`
+	DrugInteractions    []DrugRule          `json:"drugInteractions"`
+	AllergyInteractions []AllergyRule       `json:"allergyInteractions"`
+}
+
+// DrugRule states that two drugs or groups of drugs interact
+type DrugRule struct {
+	Drugs       [2]string                  `json:"drugs"`
+	Severity    domain.InteractionSeverity `json:"severity"`
+	Description string                     `json:"description"`
+}
+
+// AllergyRule states that a drug or group of drugs must be used with care
+// by patients allergic to a substance or group, such as cephalosporins for
+// patients allergic to penicillins
+type AllergyRule struct {
+	Allergen    string                     `json:"allergen"`
+	Drug        string                     `json:"drug"`
+	Severity    domain.InteractionSeverity `json:"severity"`
+	Description string                     `json:"description"`
+}
+
+// RulesChecker checks prescriptions against a rules file kept with the
+// clinic, so that it works without a network connection. Prescribing a
+// drug the patient is allergic to, or one of a group they are allergic to,
+// is always reported, even without rules.
+type RulesChecker struct {
+	groups  map[string]map[string]bool
+	drugs   []DrugRule
+	allergy []AllergyRule
+}
+
+// LoadRulesChecker reads the interaction rules from a JSON file. Without a
+// file only allergies to the prescribed drug itself are reported.
+func LoadRulesChecker(path string) (*RulesChecker, error) {
+	if path == "" {
+		return NewRulesChecker(InteractionRules{})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read interaction rules: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var rules InteractionRules
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("invalid interaction rules %s: %w", path, err)
+	}
+	checker, err := NewRulesChecker(rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interaction rules %s: %w", path, err)
+	}
+	return checker, nil
+}
+
+// NewRulesChecker creates a checker for the given rules
+func NewRulesChecker(rules InteractionRules) (*RulesChecker, error) {
+	checker := &RulesChecker{groups: make(map[string]map[string]bool)}
+	for name, members := range rules.Groups {
+		group := domain.NormalizeName(name)
+		if group == "" {
+			return nil, errors.New("interaction group without a name")
+		}
+		checker.groups[group] = make(map[string]bool, len(members))
+		for _, member := range members {
+			checker.groups[group][domain.NormalizeName(member)] = true
+		}
+	}
+
+	for i, rule := range rules.DrugInteractions {
+		rule.Drugs = [2]string{domain.NormalizeName(rule.Drugs[0]), domain.NormalizeName(rule.Drugs[1])}
+		if rule.Drugs[0] == "" || rule.Drugs[1] == "" {
+			return nil, fmt.Errorf("drug interaction %d must name two drugs", i+1)
+		}
+		if !rule.Severity.IsValid() {
+			return nil, fmt.Errorf("drug interaction %d has unknown severity %q", i+1, rule.Severity)
+		}
+		checker.drugs = append(checker.drugs, rule)
+	}
+
+	for i, rule := range rules.AllergyInteractions {
+		rule.Allergen = domain.NormalizeName(rule.Allergen)
+		rule.Drug = domain.NormalizeName(rule.Drug)
+		if rule.Allergen == "" || rule.Drug == "" {
+			return nil, fmt.Errorf("allergy interaction %d must name an allergen and a drug", i+1)
+		}
+		if !rule.Severity.IsValid() {
+			return nil, fmt.Errorf("allergy interaction %d has unknown severity %q", i+1, rule.Severity)
+		}
+		checker.allergy = append(checker.allergy, rule)
+	}
+	return checker, nil
+}
+
+// Check returns the interactions of the prescribed drug, the most severe
+// first. Each drug and allergen is reported once, with its most severe
+// interaction.
+func (c *RulesChecker) Check(ctx context.Context, check domain.InteractionCheck) ([]domain.Interaction, error) {
+	drug := domain.NormalizeName(check.Drug)
+	found := interactionSet{}
+
+	for _, active := range check.ActiveDrugs {
+		name := domain.NormalizeName(active)
+		if name == drug {
+			found.add(domain.Interaction{
+				Kind:        domain.InteractionDrug,
+				Severity:    domain.SeverityModerate,
+				Drug:        check.Drug,
+				With:        active,
+				Description: "The patient already takes this drug",
+			})
+		}
+		for _, rule := range c.drugs {
+			if (c.matches(rule.Drugs[0], drug) && c.matches(rule.Drugs[1], name)) ||
+				(c.matches(rule.Drugs[1], drug) && c.matches(rule.Drugs[0], name)) {
+				found.add(domain.Interaction{
+					Kind:        domain.InteractionDrug,
+					Severity:    rule.Severity,
+					Drug:        check.Drug,
+					With:        active,
+					Description: rule.Description,
+				})
+			}
+		}
+	}
+
+	for _, allergen := range check.Allergens {
+		name := domain.NormalizeName(allergen)
+		if c.matches(name, drug) {
+			found.add(domain.Interaction{
+				Kind:        domain.InteractionAllergy,
+				Severity:    domain.SeverityContraindicated,
+				Drug:        check.Drug,
+				With:        allergen,
+				Description: "The patient is allergic to this drug",
+			})
+		}
+		for _, rule := range c.allergy {
+			if c.matches(rule.Allergen, name) && c.matches(rule.Drug, drug) {
+				found.add(domain.Interaction{
+					Kind:        domain.InteractionAllergy,
+					Severity:    rule.Severity,
+					Drug:        check.Drug,
+					With:        allergen,
+					Description: rule.Description,
+				})
+			}
+		}
+	}
+	return found.sorted(), nil
+}
+
+// matches reports whether a name in a rule, a drug or a group, covers the
+// given drug or substance
+func (c *RulesChecker) matches(term, name string) bool {
+	return term == name || c.groups[term][name]
+}
+
+// severityRank orders severities from the least to the most serious
+var severityRank = map[domain.InteractionSeverity]int{
+	domain.SeverityMinor:           1,
+	domain.SeverityModerate:        2,
+	domain.SeverityMajor:           3,
+	domain.SeverityContraindicated: 4,
+}
+
+// interactionSet keeps the most severe interaction with each drug and allergen
+type interactionSet map[string]domain.Interaction
+
+func (s interactionSet) add(interaction domain.Interaction) {
+	key := string(interaction.Kind) + "|" + domain.NormalizeName(interaction.With)
+	if existing, ok := s[key]; ok && severityRank[existing.Severity] >= severityRank[interaction.Severity] {
+		return
+	}
+	s[key] = interaction
+}
+
+func (s interactionSet) sorted() []domain.Interaction {
+	interactions := make([]domain.Interaction, 0, len(s))
+	for _, interaction := range s {
+		interactions = append(interactions, interaction)
+	}
+	sort.Slice(interactions, func(i, j int) bool {
+		a, b := interactions[i], interactions[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return domain.NormalizeName(a.With) < domain.NormalizeName(b.With)
+	})
+	return interactions
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadExampleRules loads the example rules shipped with the backend
+func loadExampleRules(t *testing.T) *RulesChecker {
+	checker, err := LoadRulesChecker(filepath.Join("..", "..", "..", "..", "interaction-rules.example.json"))
+	require.NoError(t, err)
+	return checker
+}
+
+func TestRulesCheckerDrugInteractions(t *testing.T) {
+	checker := loadExampleRules(t)
+
+	interactions, err := checker.Check(context.Background(), domain.InteractionCheck{
+		Drug:        "Ibuprofen",
+		ActiveDrugs: []string{"Warfarin", "Metformin", "Naproxen", "ibuprofen"},
+	})
+	require.NoError(t, err)
+	require.Len(t, interactions, 3)
+
+	assert.Equal(t, domain.Interaction{
+		Kind:        domain.InteractionDrug,
+		Severity:    domain.SeverityMajor,
+		Drug:        "Ibuprofen",
+		With:        "Warfarin",
+		Description: "Increased risk of bleeding",
+	}, interactions[0])
+	assert.Equal(t, "ibuprofen", interactions[1].With, "Prescribing a drug the patient already takes is reported")
+	assert.Equal(t, "Naproxen", interactions[2].With)
+	assert.Equal(t, domain.SeverityModerate, interactions[2].Severity)
+}
+
+func TestRulesCheckerAllergyInteractions(t *testing.T) {
+	checker := loadExampleRules(t)
+	ctx := context.Background()
+
+	interactions, err := checker.Check(ctx, domain.InteractionCheck{Drug: "Ampicillin", Allergens: []string{"Amoxicillin", "Latex"}})
+	require.NoError(t, err)
+	require.Len(t, interactions, 1)
+	assert.Equal(t, domain.InteractionAllergy, interactions[0].Kind)
+	assert.Equal(t, domain.SeverityContraindicated, interactions[0].Severity)
+	assert.Equal(t, "Amoxicillin", interactions[0].With)
+
+	interactions, err = checker.Check(ctx, domain.InteractionCheck{Drug: "Cefalexin", Allergens: []string{"Penicillins"}})
+	require.NoError(t, err)
+	require.Len(t, interactions, 1)
+	assert.Equal(t, domain.SeverityModerate, interactions[0].Severity)
+
+	interactions, err = checker.Check(ctx, domain.InteractionCheck{Drug: "Paracetamol", ActiveDrugs: []string{"Warfarin"}, Allergens: []string{"Penicillin"}})
+	require.NoError(t, err)
+	assert.Empty(t, interactions)
+}
+
+func TestRulesCheckerWithoutRules(t *testing.T) {
+	checker, err := LoadRulesChecker("")
+	require.NoError(t, err)
+
+	interactions, err := checker.Check(context.Background(), domain.InteractionCheck{
+		Drug:        "Penicillin",
+		ActiveDrugs: []string{"Warfarin"},
+		Allergens:   []string{"penicillin "},
+	})
+	require.NoError(t, err)
+	require.Len(t, interactions, 1, "An allergy to the drug itself is reported without rules")
+	assert.Equal(t, domain.InteractionAllergy, interactions[0].Kind)
+}
+
+func TestLoadRulesCheckerRejectsInvalidRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	for _, rules := range []string{
+		`{"drugInteractions": [{"drugs": ["warfarin", ""], "severity": "major"}]}`,
+		`{"drugInteractions": [{"drugs": ["warfarin", "aspirin"], "severity": "fatal"}]}`,
+		`{"allergyInteractions": [{"allergen": "penicillins", "severity": "major"}]}`,
+		`{"drugInteraction": []}`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+		_, err := LoadRulesChecker(path)
+		assert.Error(t, err, rules)
+	}
+
+	_, err := LoadRulesChecker(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
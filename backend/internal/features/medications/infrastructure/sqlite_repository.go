@@ -0,0 +1,276 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the medications feature, in order.
+// A prescription cannot be changed once written; only stopping it is
+// recorded, and a stopped medication stays as it is. Only while a patient is
+// listed in medication_transfers can their medications be handed to another
+// patient, which MoveToPatient does within a single transaction.
+var sqliteMigrations = []string{
+	`CREATE TABLE medications (
+		id            TEXT PRIMARY KEY,
+		patient_id    TEXT NOT NULL,
+		drug          TEXT NOT NULL,
+		dose          TEXT NOT NULL,
+		route         TEXT NOT NULL,
+		frequency     TEXT NOT NULL,
+		duration_days INTEGER NOT NULL DEFAULT 0,
+		instructions  TEXT NOT NULL DEFAULT '',
+		status        TEXT NOT NULL,
+		interactions  TEXT NOT NULL DEFAULT '[]',
+		prescribed_by TEXT NOT NULL DEFAULT '',
+		prescribed_at TEXT NOT NULL,
+		stopped_by    TEXT NOT NULL DEFAULT '',
+		stopped_at    TEXT,
+		stop_reason   TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_medications_patient ON medications (patient_id, prescribed_at);
+	CREATE TRIGGER medications_prescription_unchanged BEFORE UPDATE OF
+		id, patient_id, drug, dose, route, frequency, duration_days, instructions,
+		interactions, prescribed_by, prescribed_at ON medications
+	BEGIN SELECT RAISE(ABORT, 'prescriptions cannot be changed'); END;
+	CREATE TRIGGER medications_locked_when_stopped BEFORE UPDATE ON medications
+	WHEN OLD.status = 'stopped'
+	BEGIN SELECT RAISE(ABORT, 'medication is stopped'); END;`,
+	`CREATE TABLE medication_transfers (
+		patient_id TEXT PRIMARY KEY
+	);
+	DROP TRIGGER medications_prescription_unchanged;
+	CREATE TRIGGER medications_prescription_unchanged BEFORE UPDATE OF
+		id, drug, dose, route, frequency, duration_days, instructions,
+		interactions, prescribed_by, prescribed_at ON medications
+	BEGIN SELECT RAISE(ABORT, 'prescriptions cannot be changed'); END;
+	CREATE TRIGGER medications_patient_unchanged BEFORE UPDATE OF patient_id ON medications
+	WHEN OLD.patient_id NOT IN (SELECT patient_id FROM medication_transfers)
+	BEGIN SELECT RAISE(ABORT, 'prescriptions cannot be changed'); END;
+	DROP TRIGGER medications_locked_when_stopped;
+	CREATE TRIGGER medications_locked_when_stopped BEFORE UPDATE ON medications
+	WHEN OLD.status = 'stopped'
+		AND OLD.patient_id NOT IN (SELECT patient_id FROM medication_transfers)
+	BEGIN SELECT RAISE(ABORT, 'medication is stopped'); END;`,
+}
+
+// medicationColumns lists the medication columns in the order scanMedication expects
+const medicationColumns = `id, patient_id, drug, dose, route, frequency, duration_days, instructions,
+	status, interactions, prescribed_by, prescribed_at, stopped_by, stopped_at, stop_reason`
+
+// MigrateSQLite applies the medications schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "medications", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the medication Repository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite medication repository. The
+// schema must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new medication to the repository
+func (r *SQLiteRepository) Create(ctx context.Context, medication *domain.Medication) error {
+	interactions, err := json.Marshal(medication.Interactions)
+	if err != nil {
+		return fmt.Errorf("failed to encode interactions of medication %s: %w", medication.ID, err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO medications (`+medicationColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		medication.ID.String(),
+		medication.PatientID.String(),
+		medication.Drug,
+		medication.Dose,
+		string(medication.Route),
+		medication.Frequency,
+		medication.DurationDays,
+		medication.Instructions,
+		string(medication.Status),
+		string(interactions),
+		medication.PrescribedBy,
+		formatTime(medication.PrescribedAt),
+		medication.StoppedBy,
+		formatNullTime(medication.StoppedAt),
+		medication.StopReason,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create medication %s: %w", medication.ID, err)
+	}
+	return nil
+}
+
+// Update stores the status of a changed medication; the prescription
+// itself cannot change
+func (r *SQLiteRepository) Update(ctx context.Context, medication *domain.Medication) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE medications SET
+		status = ?, stopped_by = ?, stopped_at = ?, stop_reason = ?
+		WHERE id = ?`,
+		string(medication.Status),
+		medication.StoppedBy,
+		formatNullTime(medication.StoppedAt),
+		medication.StopReason,
+		medication.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update medication %s: %w", medication.ID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update medication %s: %w", medication.ID, err)
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a medication by its ID
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*domain.Medication, error) {
+	medication, err := scanMedication(r.db.QueryRowContext(ctx,
+		`SELECT `+medicationColumns+` FROM medications WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return medication, err
+}
+
+// ListByPatient returns the patient's medications, the latest prescribed first
+func (r *SQLiteRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Medication, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+medicationColumns+` FROM medications
+		WHERE patient_id = ? ORDER BY prescribed_at DESC, id DESC`, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list medications: %w", err)
+	}
+	defer rows.Close()
+
+	medications := []*domain.Medication{}
+	for rows.Next() {
+		medication, err := scanMedication(rows)
+		if err != nil {
+			return nil, err
+		}
+		medications = append(medications, medication)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list medications: %w", err)
+	}
+	return medications, nil
+}
+
+// DeleteByPatient removes all medications of the patient
+func (r *SQLiteRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	_, err := database.ConnFrom(ctx, r.db).ExecContext(ctx, `DELETE FROM medications WHERE patient_id = ?`, patientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete medications of patient %s: %w", patientID, err)
+	}
+	return nil
+}
+
+// MoveToPatient hands all medications of one patient to another. The
+// patient is listed in medication_transfers meanwhile, so that the triggers
+// let the prescriptions change hands.
+func (r *SQLiteRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		if _, err := conn.ExecContext(ctx, `INSERT INTO medication_transfers (patient_id) VALUES (?)`, fromID); err != nil {
+			return fmt.Errorf("failed to move medications of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `UPDATE medications SET patient_id = ? WHERE patient_id = ?`, toID, fromID); err != nil {
+			return fmt.Errorf("failed to move medications of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM medication_transfers WHERE patient_id = ?`, fromID); err != nil {
+			return fmt.Errorf("failed to move medications of patient %s: %w", fromID, err)
+		}
+		return nil
+	})
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMedication reads a medication selected with medicationColumns
+func scanMedication(row rowScanner) (*domain.Medication, error) {
+	var (
+		medication                               domain.Medication
+		id, patientID, route, status, prescribed string
+		interactions                             string
+		stoppedAt                                sql.NullString
+	)
+	err := row.Scan(
+		&id,
+		&patientID,
+		&medication.Drug,
+		&medication.Dose,
+		&route,
+		&medication.Frequency,
+		&medication.DurationDays,
+		&medication.Instructions,
+		&status,
+		&interactions,
+		&medication.PrescribedBy,
+		&prescribed,
+		&medication.StoppedBy,
+		&stoppedAt,
+		&medication.StopReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if medication.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid medication ID %q: %w", id, err)
+	}
+	if medication.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID of medication %s: %w", id, err)
+	}
+	if medication.PrescribedAt, err = time.Parse(timestampLayout, prescribed); err != nil {
+		return nil, fmt.Errorf("invalid prescription time of medication %s: %w", id, err)
+	}
+	if stoppedAt.Valid {
+		stopped, err := time.Parse(timestampLayout, stoppedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stopping time of medication %s: %w", id, err)
+		}
+		medication.StoppedAt = &stopped
+	}
+	medication.Interactions = []domain.Interaction{}
+	if err := json.Unmarshal([]byte(interactions), &medication.Interactions); err != nil {
+		return nil, fmt.Errorf("invalid interactions of medication %s: %w", id, err)
+	}
+	medication.Route = domain.Route(route)
+	medication.Status = domain.Status(status)
+	return &medication, nil
+}
+
+// formatTime formats a timestamp for storage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// formatNullTime formats an optional timestamp for storage
+func formatNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
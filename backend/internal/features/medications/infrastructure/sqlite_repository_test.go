@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) *SQLiteRepository {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "medications.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db)
+}
+
+func TestSQLiteRepositoryMedications(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patientID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	first := domain.NewMedication(patientID, domain.Prescription{
+		Drug: "Warfarin", Dose: "5 mg", Route: domain.RouteOral, Frequency: "once daily",
+	}, nil, "dr-1", now.Add(-time.Hour))
+	second := domain.NewMedication(patientID, domain.Prescription{
+		Drug: "Ibuprofen", Dose: "400 mg", Route: domain.RouteOral, Frequency: "as needed",
+		DurationDays: 5, Instructions: "Take with food",
+	}, []domain.Interaction{{
+		Kind: domain.InteractionDrug, Severity: domain.SeverityMajor, Drug: "Ibuprofen", With: "Warfarin",
+	}}, "dr-1", now)
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+	require.NoError(t, repo.Create(ctx, domain.NewMedication(uuid.New(), domain.Prescription{Drug: "Other"}, nil, "dr-1", now)))
+
+	found, err := repo.GetByID(ctx, second.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, second, found)
+
+	_, err = repo.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	medications, err := repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	require.Len(t, medications, 2)
+	assert.Equal(t, second.ID, medications[0].ID, "The latest prescription should be listed first")
+	assert.Equal(t, first.ID, medications[1].ID)
+
+	require.NoError(t, first.Stop("Bleeding", "dr-2", now))
+	require.NoError(t, repo.Update(ctx, first))
+	found, err = repo.GetByID(ctx, first.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, first, found)
+
+	assert.Error(t, repo.Update(ctx, first), "A stopped medication should not change")
+	assert.ErrorIs(t, repo.Update(ctx, domain.NewMedication(patientID, domain.Prescription{Drug: "Missing"}, nil, "dr-1", now)), domain.ErrNotFound)
+
+	_, err = repo.db.ExecContext(ctx, `UPDATE medications SET dose = '10 mg' WHERE id = ?`, second.ID.String())
+	assert.Error(t, err, "A prescription should not be changed")
+
+	// Merging the patient into another hands over stopped and active
+	// medications alike
+	keptID := uuid.New()
+	require.NoError(t, repo.MoveToPatient(ctx, patientID.String(), keptID.String()))
+	medications, err = repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	assert.Empty(t, medications)
+	medications, err = repo.ListByPatient(ctx, keptID.String())
+	require.NoError(t, err)
+	require.Len(t, medications, 2)
+	assert.Equal(t, keptID, medications[0].PatientID)
+
+	_, err = repo.db.ExecContext(ctx, `UPDATE medications SET patient_id = ? WHERE id = ?`, patientID.String(), second.ID.String())
+	assert.Error(t, err, "A prescription should not be changed")
+
+	// Purging the patient removes stopped and active medications alike
+	require.NoError(t, repo.DeleteByPatient(ctx, keptID.String()))
+	medications, err = repo.ListByPatient(ctx, keptID.String())
+	require.NoError(t, err)
+	assert.Empty(t, medications)
+}
@@ -0,0 +1,62 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// Medication list filters
+const (
+	FilterActive = "active"
+	FilterPast   = "past"
+)
+
+// GetMedicationsQuery represents the query for the medications of a
+// patient. Status narrows the list to the active or the past medications.
+type GetMedicationsQuery struct {
+	PatientID string
+	Status    string
+}
+
+// GetMedicationsHandler handles the get medications query
+type GetMedicationsHandler interface {
+	Handle(ctx context.Context, query GetMedicationsQuery) ([]*domain.Medication, error)
+}
+
+type getMedicationsHandler struct {
+	repo domain.GetMedicationsRepository
+}
+
+// NewGetMedicationsHandler creates a new get medications handler
+func NewGetMedicationsHandler(repo domain.GetMedicationsRepository) GetMedicationsHandler {
+	return &getMedicationsHandler{repo: repo}
+}
+
+// Handle processes the get medications query. Medications are listed the
+// latest prescribed first.
+func (h *getMedicationsHandler) Handle(ctx context.Context, query GetMedicationsQuery) ([]*domain.Medication, error) {
+	if query.Status != "" && query.Status != FilterActive && query.Status != FilterPast {
+		return nil, errors.NewAPIError(errors.ErrValidation, "Status must be active or past")
+	}
+
+	medications, err := h.repo.ListByPatient(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	listed := []*domain.Medication{}
+	for _, medication := range medications {
+		medication.Refresh(now)
+		switch {
+		case query.Status == FilterActive && medication.Status.IsPast(),
+			query.Status == FilterPast && !medication.Status.IsPast():
+			continue
+		}
+		listed = append(listed, medication)
+	}
+	return listed, nil
+}
@@ -0,0 +1,70 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/medications/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// GetPrescriptionQuery represents the query for the printable prescription
+// of a medication
+type GetPrescriptionQuery struct {
+	PatientID    string
+	MedicationID string
+}
+
+// PrescriptionDocument is a medication with everything a printed
+// prescription shows about the patient and the prescriber
+type PrescriptionDocument struct {
+	Medication     *domain.Medication
+	Patient        domain.PatientSummary
+	PrescriberName string
+}
+
+// GetPrescriptionHandler handles the get prescription query
+type GetPrescriptionHandler interface {
+	Handle(ctx context.Context, query GetPrescriptionQuery) (*PrescriptionDocument, error)
+}
+
+type getPrescriptionHandler struct {
+	repo     domain.GetMedicationsRepository
+	patients domain.PatientDirectory
+	users    domain.UserDirectory
+}
+
+// NewGetPrescriptionHandler creates a new get prescription handler
+func NewGetPrescriptionHandler(
+	repo domain.GetMedicationsRepository,
+	patients domain.PatientDirectory,
+	users domain.UserDirectory,
+) GetPrescriptionHandler {
+	return &getPrescriptionHandler{repo: repo, patients: patients, users: users}
+}
+
+// Handle processes the get prescription query
+func (h *getPrescriptionHandler) Handle(ctx context.Context, query GetPrescriptionQuery) (*PrescriptionDocument, error) {
+	medication, err := h.repo.GetByID(ctx, query.MedicationID)
+	if err == domain.ErrNotFound || (err == nil && !medication.BelongsTo(query.PatientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Medication not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	medication.Refresh(time.Now())
+
+	patient, err := h.patients.PatientSummary(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	}
+
+	return &PrescriptionDocument{
+		Medication:     medication,
+		Patient:        *patient,
+		PrescriberName: h.users.UserName(ctx, medication.PrescribedBy),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// ArchivePatientCommand represents the command to archive a patient. The
+// record is kept and can be restored.
+type ArchivePatientCommand struct {
+	ID       string `json:"-"`
+	AuthorID string `json:"-"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// ArchivePatientHandler handles the archive patient command
+type ArchivePatientHandler interface {
+	Handle(ctx context.Context, cmd ArchivePatientCommand) (*domain.Patient, error)
+}
+
+type archivePatientHandler struct {
+	repo domain.PatientRepository
+}
+
+// NewArchivePatientHandler creates a new archive patient handler
+func NewArchivePatientHandler(repo domain.PatientRepository) ArchivePatientHandler {
+	return &archivePatientHandler{repo: repo}
+}
+
+// Handle processes the archive patient command
+func (h *archivePatientHandler) Handle(ctx context.Context, cmd ArchivePatientCommand) (*domain.Patient, error) {
+	reason := strings.TrimSpace(cmd.Reason)
+	if reason == "" {
+		return nil, errors.NewAPIError(errors.ErrValidation, "A reason is required to archive a patient")
+	}
+
+	patient, err := findPatient(ctx, h.repo, cmd.ID, "Patient not found")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := patient.Archive(cmd.AuthorID, reason); err != nil {
+		return nil, errors.NewAPIError(errors.ErrConflict, "Patient is already archived")
+	}
+	if err := h.repo.Update(ctx, patient); err != nil {
+		if err == domain.ErrVersionConflict {
+			return nil, staleVersion(ctx, h.repo, cmd.ID)
+		}
+		return nil, err
+	}
+
+	return patient, nil
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestArchiveAndRestorePatient(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	require.NoError(t, repo.Create(ctx, patient))
+	id := patient.ID.String()
+
+	archive := NewArchivePatientHandler(repo)
+	restore := NewRestorePatientHandler(repo)
+
+	_, err := archive.Handle(ctx, ArchivePatientCommand{ID: id, Reason: "  "})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	archived, err := archive.Handle(ctx, ArchivePatientCommand{ID: id, AuthorID: "user-1", Reason: "Deceased"})
+	require.NoError(t, err)
+	assert.True(t, archived.IsArchived())
+
+	_, err = archive.Handle(ctx, ArchivePatientCommand{ID: id, Reason: "Deceased"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	// Archived patients are hidden from listings unless requested
+	patients, _, err := repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, patients)
+	patients, _, err = repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Len(t, patients, 1)
+
+	// and cannot be edited
+	_, err = NewPatchPatientHandler(repo).Handle(ctx, PatchPatientCommand{
+		ID:              id,
+		ExpectedVersion: archived.Version,
+		Format:          PatchFormatMerge,
+		Patch:           []byte(`{"phoneNumber":"555"}`),
+	})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	restored, err := restore.Handle(ctx, RestorePatientCommand{ID: id, AuthorID: "user-2", Reason: "Archived by mistake"})
+	require.NoError(t, err)
+	assert.False(t, restored.IsArchived())
+
+	_, err = restore.Handle(ctx, RestorePatientCommand{ID: id, Reason: "Again"})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	revisions, err := repo.ListRevisions(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, "Deceased", revisions[1].Reason)
+	assert.Equal(t, "Archived by mistake", revisions[2].Reason)
+}
+
+func TestPurgePatient(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	require.NoError(t, repo.Create(ctx, patient))
+	id := patient.ID.String()
+
+	records := &stubPatientRecords{}
+	retained := NewPurgePatientHandler(repo, domain.RetentionPolicy{Years: 10, AgeOfMajority: 18}, records)
+	expired := NewPurgePatientHandler(repo, domain.RetentionPolicy{}, records)
+
+	// Active patients cannot be purged
+	assertAPIError(t, expired.Handle(ctx, PurgePatientCommand{ID: id}), errors.ErrConflict)
+
+	_, err := NewArchivePatientHandler(repo).Handle(ctx, ArchivePatientCommand{ID: id, Reason: "Duplicate"})
+	require.NoError(t, err)
+
+	// nor can archived ones within the retention period
+	assertAPIError(t, retained.Handle(ctx, PurgePatientCommand{ID: id}), errors.ErrConflict)
+
+	assert.Empty(t, records.deleted)
+
+	// A patient whose records cannot be deleted is kept
+	failing := NewPurgePatientHandler(repo, domain.RetentionPolicy{}, &stubPatientRecords{err: fmt.Errorf("database error")}, records)
+	assert.Error(t, failing.Handle(ctx, PurgePatientCommand{ID: id}))
+	_, err = repo.GetByID(ctx, id)
+	require.NoError(t, err)
+
+	require.NoError(t, expired.Handle(ctx, PurgePatientCommand{ID: id}))
+	_, err = repo.GetByID(ctx, id)
+	assert.Error(t, err)
+	revisions, err := repo.ListRevisions(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+	assert.Equal(t, []string{id}, records.deleted, "The records of other features should be purged with the patient")
+}
+
+// stubPatientRecords records the patients whose records were deleted or
+// moved
+type stubPatientRecords struct {
+	deleted []string
+	moved   [][2]string
+	err     error
+}
+
+func (s *stubPatientRecords) DeleteByPatient(ctx context.Context, patientID string) error {
+	s.deleted = append(s.deleted, patientID)
+	return s.err
+}
+
+func (s *stubPatientRecords) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	s.moved = append(s.moved, [2]string{fromID, toID})
+	return s.err
+}
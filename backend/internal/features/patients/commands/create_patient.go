@@ -2,22 +2,83 @@ package commands
 
 import (
 	"context"
+	"net/mail"
+	"strings"
+	"time"
 
 	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
 )
 
-// CreatePatientCommand represents the command to create a new patient
+// CreatePatientCommand represents the command to create a new patient. It
+// also describes the full patient record a partial update is merged into.
+// Unless AllowDuplicate is set, a patient resembling an existing one is
+// rejected with a DuplicatePatientError.
 type CreatePatientCommand struct {
-	FirstName   string         `json:"firstName" binding:"required"`
-	LastName    string         `json:"lastName" binding:"required"`
-	MiddleName  string         `json:"middleName"`
-	DateOfBirth domain.Date    `json:"dateOfBirth" binding:"required"`
-	Gender      domain.Gender  `json:"gender" binding:"required"`
-	Email       string         `json:"email"`
-	PhoneNumber string         `json:"phoneNumber"`
-	Height      float64        `json:"height,omitempty"`
-	Weight      float64        `json:"weight,omitempty"`
-	Address     domain.Address `json:"address"`
+	AuthorID       string         `json:"-"`
+	AllowDuplicate bool           `json:"-"`
+	FirstName      string         `json:"firstName" binding:"required"`
+	LastName       string         `json:"lastName" binding:"required"`
+	MiddleName     string         `json:"middleName"`
+	DateOfBirth    domain.Date    `json:"dateOfBirth" binding:"required"`
+	Gender         domain.Gender  `json:"gender" binding:"required"`
+	Email          string         `json:"email"`
+	PhoneNumber    string         `json:"phoneNumber"`
+	Height         float64        `json:"height"`
+	Weight         float64        `json:"weight"`
+	Address        domain.Address `json:"address"`
+}
+
+// Validate checks the record the command describes
+func (cmd CreatePatientCommand) Validate() error {
+	if strings.TrimSpace(cmd.FirstName) == "" {
+		return errors.NewAPIError(errors.ErrValidation, "First name is required")
+	}
+	if strings.TrimSpace(cmd.LastName) == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Last name is required")
+	}
+	if cmd.DateOfBirth.Time().IsZero() {
+		return errors.NewAPIError(errors.ErrValidation, "Date of birth is required")
+	}
+	if cmd.DateOfBirth.Time().After(time.Now()) {
+		return errors.NewAPIError(errors.ErrValidation, "Date of birth must not be in the future")
+	}
+	if !cmd.Gender.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Gender must be one of male, female, other or unknown")
+	}
+	if cmd.Email != "" {
+		if address, err := mail.ParseAddress(cmd.Email); err != nil || address.Address != cmd.Email {
+			return errors.NewAPIError(errors.ErrValidation, "Invalid email address")
+		}
+	}
+	if cmd.Height < 0 || cmd.Weight < 0 {
+		return errors.NewAPIError(errors.ErrValidation, "Height and weight must not be negative")
+	}
+	return nil
+}
+
+// applyTo copies the record the command describes onto the patient
+func (cmd CreatePatientCommand) applyTo(patient *domain.Patient) {
+	patient.FirstName = cmd.FirstName
+	patient.LastName = cmd.LastName
+	patient.MiddleName = cmd.MiddleName
+	patient.DateOfBirth = cmd.DateOfBirth
+	patient.Gender = cmd.Gender
+	patient.Email = cmd.Email
+	patient.PhoneNumber = cmd.PhoneNumber
+	patient.Height = cmd.Height
+	patient.Weight = cmd.Weight
+	patient.Address = cmd.Address
+}
+
+// DuplicatePatientError reports existing patients that likely describe the
+// person a new patient was created for
+type DuplicatePatientError struct {
+	Candidates []domain.DuplicateCandidate
+}
+
+func (e *DuplicatePatientError) Error() string {
+	return "patient resembles an existing patient"
 }
 
 // CreatePatientHandler handles the creation of a new patient
@@ -38,13 +99,23 @@ func NewCreatePatientHandler(repo domain.CreatePatientRepository) CreatePatientH
 
 // Handle processes the create patient command
 func (h *createPatientHandler) Handle(ctx context.Context, cmd CreatePatientCommand) (*domain.Patient, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+
 	patient := domain.NewPatient(cmd.FirstName, cmd.LastName, cmd.DateOfBirth.Time(), cmd.Gender)
-	patient.MiddleName = cmd.MiddleName
-	patient.Email = cmd.Email
-	patient.PhoneNumber = cmd.PhoneNumber
-	patient.Height = cmd.Height
-	patient.Weight = cmd.Weight
-	patient.Address = cmd.Address
+	cmd.applyTo(patient)
+	patient.UpdatedBy = cmd.AuthorID
+
+	if !cmd.AllowDuplicate {
+		existing, err := h.patientRepository.ListDuplicateCandidates(ctx, patient)
+		if err != nil {
+			return nil, err
+		}
+		if candidates := domain.FindDuplicates(patient, existing); len(candidates) > 0 {
+			return nil, &DuplicatePatientError{Candidates: candidates}
+		}
+	}
 
 	err := h.patientRepository.Create(ctx, patient)
 	if err != nil {
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// MergePatientsCommand represents the command to fold a duplicate patient
+// into the record that is kept. The source is archived and redirects to the
+// target, which takes over its clinical records.
+type MergePatientsCommand struct {
+	SourceID string `json:"sourceId" binding:"required"`
+	TargetID string `json:"-"`
+	AuthorID string `json:"-"`
+}
+
+// MergePatientsHandler handles the merge patients command
+type MergePatientsHandler interface {
+	Handle(ctx context.Context, cmd MergePatientsCommand) (*domain.Patient, error)
+}
+
+type mergePatientsHandler struct {
+	repo    domain.MergePatientsRepository
+	records []domain.PatientRecords
+}
+
+// NewMergePatientsHandler creates a new merge patients handler. The
+// source's records kept by other features are moved to the target along
+// with the merge.
+func NewMergePatientsHandler(repo domain.MergePatientsRepository, records ...domain.PatientRecords) MergePatientsHandler {
+	return &mergePatientsHandler{repo: repo, records: records}
+}
+
+// Handle processes the merge patients command and returns the kept patient
+func (h *mergePatientsHandler) Handle(ctx context.Context, cmd MergePatientsCommand) (*domain.Patient, error) {
+	if cmd.SourceID == cmd.TargetID {
+		return nil, errors.NewAPIError(errors.ErrValidation, "A patient cannot be merged into itself")
+	}
+
+	source, err := findPatient(ctx, h.repo, cmd.SourceID, "Patient to merge not found")
+	if err != nil {
+		return nil, err
+	}
+	target, err := findPatient(ctx, h.repo, cmd.TargetID, "Patient not found")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := target.Merge(source, cmd.AuthorID); err != nil {
+		switch err {
+		case domain.ErrMergeIntoSelf:
+			return nil, errors.NewAPIError(errors.ErrValidation, "A patient cannot be merged into itself")
+		case domain.ErrAlreadyArchived:
+			return nil, errors.NewAPIError(errors.ErrConflict, "Archived patients cannot be merged")
+		}
+		return nil, err
+	}
+	err = h.repo.Transaction(ctx, func(ctx context.Context) error {
+		if err := h.repo.Merge(ctx, source, target); err != nil {
+			return err
+		}
+		for _, records := range h.records {
+			if err := records.MoveToPatient(ctx, source.ID.String(), target.ID.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if err == domain.ErrVersionConflict {
+			return nil, errors.NewAPIError(errors.ErrConflict, "One of the patients was changed during the merge, please retry")
+		}
+		return nil, err
+	}
+
+	return target, nil
+}
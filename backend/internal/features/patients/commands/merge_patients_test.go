@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePatientWarnsAboutDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	handler := NewCreatePatientHandler(repo)
+
+	cmd := CreatePatientCommand{
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		DateOfBirth: domain.Date(time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)),
+		Gender:      domain.GenderFemale,
+		PhoneNumber: "555-0100-100",
+	}
+	existing, err := handler.Handle(ctx, cmd)
+	require.NoError(t, err)
+
+	cmd.FirstName = "Janie"
+	_, err = handler.Handle(ctx, cmd)
+	duplicate, ok := err.(*DuplicatePatientError)
+	require.True(t, ok, "Expected a DuplicatePatientError, got %v", err)
+	require.Len(t, duplicate.Candidates, 1)
+	assert.Equal(t, existing.ID, duplicate.Candidates[0].Patient.ID)
+
+	cmd.AllowDuplicate = true
+	_, err = handler.Handle(ctx, cmd)
+	require.NoError(t, err)
+
+	patients, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, patients, 2)
+}
+
+func TestMergePatients(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	target := domain.NewPatient("Jane", "Doe", dob, domain.GenderFemale)
+	source := domain.NewPatient("Jane", "Doe", dob, domain.GenderFemale)
+	source.Email = "jane@example.com"
+	require.NoError(t, repo.Create(ctx, target))
+	require.NoError(t, repo.Create(ctx, source))
+
+	records := &stubPatientRecords{}
+	handler := NewMergePatientsHandler(repo, records)
+
+	_, err := handler.Handle(ctx, MergePatientsCommand{SourceID: target.ID.String(), TargetID: target.ID.String()})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	merged, err := handler.Handle(ctx, MergePatientsCommand{
+		SourceID: source.ID.String(),
+		TargetID: target.ID.String(),
+		AuthorID: "admin-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", merged.Email)
+	assert.Equal(t, 2, merged.Version)
+	assert.Equal(t, [][2]string{{source.ID.String(), target.ID.String()}}, records.moved, "The records of other features should move to the kept patient")
+
+	retired, err := repo.GetByID(ctx, source.ID.String())
+	require.NoError(t, err)
+	assert.True(t, retired.IsArchived())
+	require.NotNil(t, retired.MergedInto)
+	assert.Equal(t, target.ID, *retired.MergedInto)
+
+	// A merged record stays retired
+	_, err = handler.Handle(ctx, MergePatientsCommand{SourceID: source.ID.String(), TargetID: target.ID.String()})
+	assertAPIError(t, err, errors.ErrConflict)
+	_, err = NewRestorePatientHandler(repo).Handle(ctx, RestorePatientCommand{ID: source.ID.String(), Reason: "Undo"})
+	assertAPIError(t, err, errors.ErrConflict)
+}
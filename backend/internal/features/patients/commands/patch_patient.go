@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/jsonpatch"
+)
+
+// PatchFormat identifies how a partial update is encoded
+type PatchFormat string
+
+const (
+	// PatchFormatMerge is an RFC 7396 JSON Merge Patch
+	PatchFormatMerge PatchFormat = jsonpatch.MergePatchMediaType
+	// PatchFormatJSONPatch is an RFC 6902 JSON Patch
+	PatchFormatJSONPatch PatchFormat = jsonpatch.JSONPatchMediaType
+)
+
+// PatchPatientCommand represents the command to partially update a patient.
+// The patch applies to the record in the shape of CreatePatientCommand and
+// the result is validated like a new patient.
+type PatchPatientCommand struct {
+	ID              string
+	AuthorID        string
+	ExpectedVersion int
+	Format          PatchFormat
+	Patch           []byte
+}
+
+// PatchPatientHandler handles the patch patient command
+type PatchPatientHandler interface {
+	Handle(ctx context.Context, cmd PatchPatientCommand) (*domain.Patient, error)
+}
+
+type patchPatientHandler struct {
+	repo domain.PatientRepository
+}
+
+// NewPatchPatientHandler creates a new patch patient handler
+func NewPatchPatientHandler(repo domain.PatientRepository) PatchPatientHandler {
+	return &patchPatientHandler{repo: repo}
+}
+
+// Handle processes the patch patient command
+func (h *patchPatientHandler) Handle(ctx context.Context, cmd PatchPatientCommand) (*domain.Patient, error) {
+	patient, err := findPatient(ctx, h.repo, cmd.ID, "Patient not found")
+	if err != nil {
+		return nil, err
+	}
+	if patient.Version != cmd.ExpectedVersion {
+		return nil, &StaleVersionError{Current: patient}
+	}
+	if patient.IsArchived() {
+		return nil, errors.NewAPIError(errors.ErrConflict, "Archived patients must be restored before they can be edited")
+	}
+
+	record, err := patchRecord(recordOf(patient), cmd.Format, cmd.Patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+
+	record.applyTo(patient)
+	patient.UpdateBy(cmd.AuthorID)
+
+	if err := h.repo.Update(ctx, patient); err != nil {
+		if err == domain.ErrVersionConflict {
+			return nil, staleVersion(ctx, h.repo, cmd.ID)
+		}
+		return nil, err
+	}
+
+	return patient, nil
+}
+
+// recordOf describes the editable part of a patient record
+func recordOf(patient *domain.Patient) CreatePatientCommand {
+	return CreatePatientCommand{
+		FirstName:   patient.FirstName,
+		LastName:    patient.LastName,
+		MiddleName:  patient.MiddleName,
+		DateOfBirth: patient.DateOfBirth,
+		Gender:      patient.Gender,
+		Email:       patient.Email,
+		PhoneNumber: patient.PhoneNumber,
+		Height:      patient.Height,
+		Weight:      patient.Weight,
+		Address:     patient.Address,
+	}
+}
+
+// patchRecord applies the patch to the record. Members the record does not
+// have, such as the ID or the version, cannot be patched.
+func patchRecord(record CreatePatientCommand, format PatchFormat, patch []byte) (CreatePatientCommand, error) {
+	doc, err := json.Marshal(record)
+	if err != nil {
+		return record, err
+	}
+
+	switch format {
+	case PatchFormatMerge:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case PatchFormatJSONPatch:
+		doc, err = jsonpatch.Apply(doc, patch)
+	default:
+		return record, errors.NewAPIError(errors.ErrValidation, "Unsupported patch format")
+	}
+	if err != nil {
+		return record, errors.NewAPIError(errors.ErrValidation, "Invalid patch: "+err.Error())
+	}
+
+	var patched CreatePatientCommand
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return record, errors.NewAPIError(errors.ErrValidation, "Invalid patched record: "+err.Error())
+	}
+	return patched, nil
+}
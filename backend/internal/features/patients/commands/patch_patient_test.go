@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPatchTest(t *testing.T) (*infrastructure.MemoryRepository, *domain.Patient) {
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	patient.PhoneNumber = "555-0100"
+	patient.Height = 170
+	patient.Address = domain.Address{Street: "1 Main St", City: "Springfield"}
+	require.NoError(t, repo.Create(context.Background(), patient))
+	return repo, patient
+}
+
+func TestPatchPatientHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    PatchFormat
+		patch     string
+		errorCode string
+		check     func(*testing.T, *domain.Patient)
+	}{
+		{
+			name:   "merge patch changes only the given fields",
+			format: PatchFormatMerge,
+			patch:  `{"phoneNumber":"555-0199","address":{"city":"Shelbyville"}}`,
+			check: func(t *testing.T, p *domain.Patient) {
+				assert.Equal(t, "555-0199", p.PhoneNumber)
+				assert.Equal(t, "Shelbyville", p.Address.City)
+				assert.Equal(t, "1 Main St", p.Address.Street)
+				assert.Equal(t, "Doe", p.LastName)
+				assert.Equal(t, 170.0, p.Height)
+			},
+		},
+		{
+			name:   "merge patch null clears an optional field",
+			format: PatchFormatMerge,
+			patch:  `{"phoneNumber":null}`,
+			check: func(t *testing.T, p *domain.Patient) {
+				assert.Empty(t, p.PhoneNumber)
+			},
+		},
+		{
+			name:   "json patch",
+			format: PatchFormatJSONPatch,
+			patch: `[{"op":"test","path":"/lastName","value":"Doe"},
+				{"op":"replace","path":"/lastName","value":"Smith"},
+				{"op":"replace","path":"/weight","value":65.5}]`,
+			check: func(t *testing.T, p *domain.Patient) {
+				assert.Equal(t, "Smith", p.LastName)
+				assert.Equal(t, 65.5, p.Weight)
+			},
+		},
+		{
+			name:      "merged record is validated",
+			format:    PatchFormatMerge,
+			patch:     `{"lastName":null}`,
+			errorCode: errors.ErrValidation,
+		},
+		{
+			name:      "invalid gender",
+			format:    PatchFormatMerge,
+			patch:     `{"gender":"robot"}`,
+			errorCode: errors.ErrValidation,
+		},
+		{
+			name:      "fields outside the record cannot be patched",
+			format:    PatchFormatMerge,
+			patch:     `{"version":7}`,
+			errorCode: errors.ErrValidation,
+		},
+		{
+			name:      "failed json patch test",
+			format:    PatchFormatJSONPatch,
+			patch:     `[{"op":"test","path":"/lastName","value":"Smith"}]`,
+			errorCode: errors.ErrValidation,
+		},
+		{
+			name:      "malformed patch",
+			format:    PatchFormatMerge,
+			patch:     `{"lastName":`,
+			errorCode: errors.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, patient := setupPatchTest(t)
+			handler := NewPatchPatientHandler(repo)
+
+			patched, err := handler.Handle(context.Background(), PatchPatientCommand{
+				ID:              patient.ID.String(),
+				AuthorID:        "author-1",
+				ExpectedVersion: 1,
+				Format:          tt.format,
+				Patch:           []byte(tt.patch),
+			})
+
+			stored, getErr := repo.GetByID(context.Background(), patient.ID.String())
+			require.NoError(t, getErr)
+
+			if tt.errorCode != "" {
+				apiErr, ok := err.(*errors.APIError)
+				require.True(t, ok, "Expected an APIError, got %v", err)
+				assert.Equal(t, tt.errorCode, apiErr.Code)
+				assert.Equal(t, 1, stored.Version)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 2, patched.Version)
+			assert.Equal(t, "author-1", patched.UpdatedBy)
+			tt.check(t, stored)
+		})
+	}
+}
+
+func TestPatchPatientHandlerRejectsStaleVersion(t *testing.T) {
+	repo, patient := setupPatchTest(t)
+	handler := NewPatchPatientHandler(repo)
+
+	_, err := handler.Handle(context.Background(), PatchPatientCommand{
+		ID:              patient.ID.String(),
+		ExpectedVersion: 2,
+		Format:          PatchFormatMerge,
+		Patch:           []byte(`{"phoneNumber":"555-0199"}`),
+	})
+	stale, ok := err.(*StaleVersionError)
+	require.True(t, ok, "Expected a StaleVersionError, got %v", err)
+	assert.Equal(t, 1, stale.Current.Version)
+	assert.Equal(t, "555-0100", stale.Current.PhoneNumber)
+}
+
+func TestCreatePatientCommandValidate(t *testing.T) {
+	valid := CreatePatientCommand{
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		DateOfBirth: domain.Date(time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Gender:      domain.GenderFemale,
+		Email:       "jane@example.com",
+	}
+	assert.NoError(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*CreatePatientCommand)
+	}{
+		{"blank first name", func(c *CreatePatientCommand) { c.FirstName = " " }},
+		{"missing last name", func(c *CreatePatientCommand) { c.LastName = "" }},
+		{"missing date of birth", func(c *CreatePatientCommand) { c.DateOfBirth = domain.Date{} }},
+		{"future date of birth", func(c *CreatePatientCommand) { c.DateOfBirth = domain.Date(time.Now().AddDate(1, 0, 0)) }},
+		{"unknown gender", func(c *CreatePatientCommand) { c.Gender = "robot" }},
+		{"invalid email", func(c *CreatePatientCommand) { c.Email = "Jane <jane@example.com>" }},
+		{"negative weight", func(c *CreatePatientCommand) { c.Weight = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := valid
+			tt.modify(&cmd)
+			err := cmd.Validate()
+			apiErr, ok := err.(*errors.APIError)
+			require.True(t, ok, "Expected an APIError, got %v", err)
+			assert.Equal(t, errors.ErrValidation, apiErr.Code)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// PurgePatientCommand represents the command to permanently remove an
+// archived patient, its history and its clinical records
+type PurgePatientCommand struct {
+	ID string `json:"-"`
+}
+
+// PurgePatientHandler handles the purge patient command
+type PurgePatientHandler interface {
+	Handle(ctx context.Context, cmd PurgePatientCommand) error
+}
+
+type purgePatientHandler struct {
+	repo    domain.PurgePatientRepository
+	policy  domain.RetentionPolicy
+	records []domain.PatientRecords
+}
+
+// NewPurgePatientHandler creates a new purge patient handler that enforces
+// the given retention policy. The patient's records kept by other features
+// are deleted along with it.
+func NewPurgePatientHandler(repo domain.PurgePatientRepository, policy domain.RetentionPolicy, records ...domain.PatientRecords) PurgePatientHandler {
+	return &purgePatientHandler{repo: repo, policy: policy, records: records}
+}
+
+// Handle processes the purge patient command
+func (h *purgePatientHandler) Handle(ctx context.Context, cmd PurgePatientCommand) error {
+	patient, err := findPatient(ctx, h.repo, cmd.ID, "Patient not found")
+	if err != nil {
+		return err
+	}
+
+	if !patient.IsArchived() {
+		return errors.NewAPIError(errors.ErrConflict, "Only archived patients can be purged")
+	}
+	if !h.policy.CanPurge(patient, time.Now()) {
+		return errors.NewAPIError(errors.ErrConflict, fmt.Sprintf(
+			"Patient must be retained until %s", h.policy.PurgeableFrom(patient).Format("2006-01-02")))
+	}
+
+	return h.repo.Transaction(ctx, func(ctx context.Context) error {
+		for _, records := range h.records {
+			if err := records.DeleteByPatient(ctx, cmd.ID); err != nil {
+				return err
+			}
+		}
+		return h.repo.Delete(ctx, cmd.ID)
+	})
+}
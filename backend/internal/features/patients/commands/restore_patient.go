@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// RestorePatientCommand represents the command to return an archived
+// patient to the active records
+type RestorePatientCommand struct {
+	ID       string `json:"-"`
+	AuthorID string `json:"-"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// RestorePatientHandler handles the restore patient command
+type RestorePatientHandler interface {
+	Handle(ctx context.Context, cmd RestorePatientCommand) (*domain.Patient, error)
+}
+
+type restorePatientHandler struct {
+	repo domain.PatientRepository
+}
+
+// NewRestorePatientHandler creates a new restore patient handler
+func NewRestorePatientHandler(repo domain.PatientRepository) RestorePatientHandler {
+	return &restorePatientHandler{repo: repo}
+}
+
+// Handle processes the restore patient command
+func (h *restorePatientHandler) Handle(ctx context.Context, cmd RestorePatientCommand) (*domain.Patient, error) {
+	reason := strings.TrimSpace(cmd.Reason)
+	if reason == "" {
+		return nil, errors.NewAPIError(errors.ErrValidation, "A reason is required to restore a patient")
+	}
+
+	patient, err := findPatient(ctx, h.repo, cmd.ID, "Patient not found")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := patient.Restore(cmd.AuthorID, reason); err != nil {
+		if err == domain.ErrMerged {
+			return nil, errors.NewAPIError(errors.ErrConflict, "Merged patients cannot be restored")
+		}
+		return nil, errors.NewAPIError(errors.ErrConflict, "Patient is not archived")
+	}
+	if err := h.repo.Update(ctx, patient); err != nil {
+		if err == domain.ErrVersionConflict {
+			return nil, staleVersion(ctx, h.repo, cmd.ID)
+		}
+		return nil, err
+	}
+
+	return patient, nil
+}
@@ -2,23 +2,28 @@ package commands
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	"time"
 
 	"github.com/dksch/pococlinic/internal/features/patients/domain"
 	"github.com/dksch/pococlinic/internal/pkg/errors"
 )
 
-// UpdatePatientCommand represents the command to update a patient
+// UpdatePatientCommand represents the command to update a patient.
+// ExpectedVersion is the version of the record the change was made on.
 type UpdatePatientCommand struct {
-	ID          string  `json:"-"`
-	FirstName   string  `json:"firstName" binding:"required"`
-	LastName    string  `json:"lastName" binding:"required"`
-	MiddleName  *string `json:"middleName,omitempty"`
-	DateOfBirth string  `json:"dateOfBirth" binding:"required"`
-	Gender      string  `json:"gender" binding:"required,oneof=male female other unknown"`
-	Email       string  `json:"email" binding:"required,email"`
-	PhoneNumber string  `json:"phoneNumber" binding:"required"`
-	Address     *struct {
+	ID              string  `json:"-"`
+	AuthorID        string  `json:"-"`
+	ExpectedVersion int     `json:"-"`
+	FirstName       string  `json:"firstName" binding:"required"`
+	LastName        string  `json:"lastName" binding:"required"`
+	MiddleName      *string `json:"middleName,omitempty"`
+	DateOfBirth     string  `json:"dateOfBirth" binding:"required"`
+	Gender          string  `json:"gender" binding:"required,oneof=male female other unknown"`
+	Email           string  `json:"email" binding:"required,email"`
+	PhoneNumber     string  `json:"phoneNumber" binding:"required"`
+	Address         *struct {
 		Street     string `json:"street" binding:"required"`
 		City       string `json:"city" binding:"required"`
 		State      string `json:"state" binding:"required"`
@@ -29,6 +34,17 @@ type UpdatePatientCommand struct {
 	Weight *float64 `json:"weight,omitempty"`
 }
 
+// StaleVersionError rejects an update that was made on an outdated version
+// of the patient. It carries the record as currently stored.
+type StaleVersionError struct {
+	Current *domain.Patient
+}
+
+// Error implements the error interface
+func (e *StaleVersionError) Error() string {
+	return fmt.Sprintf("patient %s is at version %d", e.Current.ID, e.Current.Version)
+}
+
 // UpdatePatientHandler handles the update patient command
 type UpdatePatientHandler interface {
 	Handle(ctx context.Context, cmd UpdatePatientCommand) (*domain.Patient, error)
@@ -46,12 +62,15 @@ func NewUpdatePatientHandler(repo domain.PatientRepository) UpdatePatientHandler
 // Handle processes the update patient command
 func (h *updatePatientHandler) Handle(ctx context.Context, cmd UpdatePatientCommand) (*domain.Patient, error) {
 	// Get the existing patient
-	patient, err := h.repo.GetByID(ctx, cmd.ID)
+	patient, err := findPatient(ctx, h.repo, cmd.ID, "Patient not found")
 	if err != nil {
 		return nil, err
 	}
-	if patient == nil {
-		return nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	if patient.Version != cmd.ExpectedVersion {
+		return nil, &StaleVersionError{Current: patient}
+	}
+	if patient.IsArchived() {
+		return nil, errors.NewAPIError(errors.ErrConflict, "Archived patients must be restored before they can be edited")
 	}
 
 	// Parse the date of birth
@@ -87,10 +106,45 @@ func (h *updatePatientHandler) Handle(ctx context.Context, cmd UpdatePatientComm
 		}
 	}
 
+	patient.UpdateBy(cmd.AuthorID)
+
 	// Save the updated patient
 	if err := h.repo.Update(ctx, patient); err != nil {
+		if err == domain.ErrVersionConflict {
+			return nil, staleVersion(ctx, h.repo, cmd.ID)
+		}
 		return nil, err
 	}
 
 	return patient, nil
 }
+
+// staleVersion reports an update that lost the race against another one,
+// with the record that won
+func staleVersion(ctx context.Context, repo domain.PatientRepository, id string) error {
+	current, err := findPatient(ctx, repo, id, "Patient not found")
+	if err != nil {
+		return err
+	}
+	return &StaleVersionError{Current: current}
+}
+
+// patientReader is the part of the patient repositories the commands read
+// patients through
+type patientReader interface {
+	GetByID(ctx context.Context, id string) (*domain.Patient, error)
+}
+
+// findPatient reads the patient a command works on. A patient that does not
+// exist, whether the repository reports ErrPatientNotFound or no patient at
+// all, is reported as not found with the given message.
+func findPatient(ctx context.Context, repo patientReader, id, notFound string) (*domain.Patient, error) {
+	patient, err := repo.GetByID(ctx, id)
+	if stderrors.Is(err, domain.ErrPatientNotFound) || (err == nil && patient == nil) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, notFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
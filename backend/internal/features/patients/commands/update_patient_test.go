@@ -52,8 +52,8 @@ func (m *MockPatientRepository) List(ctx context.Context) ([]*domain.Patient, er
 	return args.Get(0).([]*domain.Patient), args.Error(1)
 }
 
-func (m *MockPatientRepository) ListPaginated(ctx context.Context, page, pageSize int, search string) ([]*domain.Patient, int64, error) {
-	args := m.Called(ctx, page, pageSize, search)
+func (m *MockPatientRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.PatientFilter) ([]*domain.Patient, int64, error) {
+	args := m.Called(ctx, page, pageSize, filter)
 	if args.Get(0) == nil {
 		return nil, 0, args.Error(1)
 	}
@@ -121,6 +121,16 @@ func TestUpdatePatientHandler_Handle(t *testing.T) {
 			expectedError: errors.NewAPIError(errors.ErrNotFound, "Patient not found"),
 			checkAPIError: true,
 		},
+		{
+			name: "patient not found error",
+			setupMock: func(mockRepo *MockPatientRepository) {
+				notFound := fmt.Errorf("%w: %s", domain.ErrPatientNotFound, existingPatient.ID)
+				mockRepo.On("GetByID", mock.Anything, existingPatient.ID.String()).Return(nil, notFound)
+			},
+			cmd:           updateCmd,
+			expectedError: errors.NewAPIError(errors.ErrNotFound, "Patient not found"),
+			checkAPIError: true,
+		},
 		{
 			name: "error from repository",
 			setupMock: func(mockRepo *MockPatientRepository) {
@@ -131,6 +141,34 @@ func TestUpdatePatientHandler_Handle(t *testing.T) {
 			expectedError: fmt.Errorf("database error"),
 			checkAPIError: false,
 		},
+		{
+			name: "stale version",
+			setupMock: func(mockRepo *MockPatientRepository) {
+				mockRepo.On("GetByID", mock.Anything, existingPatient.ID.String()).Return(existingPatient, nil)
+			},
+			cmd: UpdatePatientCommand{
+				ID:              existingPatient.ID.String(),
+				ExpectedVersion: 3,
+				FirstName:       "John",
+				LastName:        "Doe",
+				DateOfBirth:     "1990-01-01",
+				Gender:          "male",
+				Email:           "john@example.com",
+				PhoneNumber:     "1234567890",
+			},
+			expectedError: &StaleVersionError{Current: existingPatient},
+			checkAPIError: false,
+		},
+		{
+			name: "concurrent update",
+			setupMock: func(mockRepo *MockPatientRepository) {
+				mockRepo.On("GetByID", mock.Anything, existingPatient.ID.String()).Return(existingPatient, nil)
+				mockRepo.On("Update", mock.Anything, mock.Anything).Return(domain.ErrVersionConflict)
+			},
+			cmd:           updateCmd,
+			expectedError: &StaleVersionError{Current: existingPatient},
+			checkAPIError: false,
+		},
 		{
 			name: "invalid date format",
 			setupMock: func(mockRepo *MockPatientRepository) {
@@ -177,6 +215,7 @@ func TestUpdatePatientHandler_Handle(t *testing.T) {
 						assert.Equal(t, expectedAPIErr.Message, apiErr.Message)
 					}
 				} else {
+					assert.IsType(t, tt.expectedError, err)
 					assert.Equal(t, tt.expectedError.Error(), err.Error())
 				}
 				return
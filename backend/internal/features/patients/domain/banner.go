@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+// Banner is a safety notice about a patient, such as an allergy, shown
+// prominently with the patient's record to everyone allowed to see it. Other
+// features contribute banners through a BannerSource.
+type Banner struct {
+	Kind    string `json:"kind"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// BannerSource provides the banners of a patient
+type BannerSource interface {
+	Banners(ctx context.Context, patientID string) ([]Banner, error)
+}
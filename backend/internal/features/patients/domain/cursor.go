@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a cursor that cannot be decoded
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageCursor marks a position in a patient listing: the sort values and ID
+// of the patient a page starts after, or ends before when Backward is set.
+// It keeps the values the patient had when the cursor was issued, so pages
+// continue from the same place when patients are added or removed in
+// between, the cursor's own patient included. A patient whose sort values
+// change in between, such as any edited patient in a listing by update
+// time, moves to its new place and may be listed twice or not at all. A
+// cursor only applies to the order it was issued for.
+type PageCursor struct {
+	PatientID uuid.UUID   `json:"id"`
+	Backward  bool        `json:"back,omitempty"`
+	Sort      PatientSort `json:"sort"`
+
+	// The values of the patient the sort orders by, the others are nil
+	LastName    *string    `json:"lastName,omitempty"`
+	FirstName   *string    `json:"firstName,omitempty"`
+	DateOfBirth *Date      `json:"dateOfBirth,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// NewPageCursor returns the cursor marking the patient's position in a
+// listing in the given order
+func NewPageCursor(patient *Patient, sort PatientSort, backward bool) PageCursor {
+	cursor := PageCursor{PatientID: patient.ID, Backward: backward, Sort: sort}
+	switch sort.Field {
+	case SortByName:
+		lastName, firstName := patient.LastName, patient.FirstName
+		cursor.LastName, cursor.FirstName = &lastName, &firstName
+	case SortByDateOfBirth:
+		dateOfBirth := patient.DateOfBirth
+		cursor.DateOfBirth = &dateOfBirth
+	case SortByUpdatedAt:
+		updatedAt := patient.UpdatedAt.UTC()
+		cursor.UpdatedAt = &updatedAt
+	default:
+		createdAt := patient.CreatedAt.UTC()
+		cursor.CreatedAt = &createdAt
+	}
+	return cursor
+}
+
+// Pivot returns a patient with the ID and sort values of the cursor, to be
+// compared with the patients of the listing
+func (c PageCursor) Pivot() *Patient {
+	pivot := &Patient{ID: c.PatientID}
+	if c.LastName != nil {
+		pivot.LastName = *c.LastName
+	}
+	if c.FirstName != nil {
+		pivot.FirstName = *c.FirstName
+	}
+	if c.DateOfBirth != nil {
+		pivot.DateOfBirth = *c.DateOfBirth
+	}
+	if c.CreatedAt != nil {
+		pivot.CreatedAt = *c.CreatedAt
+	}
+	if c.UpdatedAt != nil {
+		pivot.UpdatedAt = *c.UpdatedAt
+	}
+	return pivot
+}
+
+// hasSortValues reports whether the cursor holds the values its sort orders by
+func (c PageCursor) hasSortValues() bool {
+	switch c.Sort.Field {
+	case SortByName:
+		return c.LastName != nil && c.FirstName != nil
+	case SortByDateOfBirth:
+		return c.DateOfBirth != nil
+	case SortByUpdatedAt:
+		return c.UpdatedAt != nil
+	case SortByCreatedAt:
+		return c.CreatedAt != nil
+	}
+	return false
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor parses a cursor returned by Encode
+func DecodePageCursor(encoded string) (PageCursor, error) {
+	var cursor PageCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.PatientID == uuid.Nil || !cursor.hasSortValues() {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// PatientPage is one page of a listing paged by cursor
+type PatientPage struct {
+	Patients   []*Patient
+	TotalCount int64
+	HasNext    bool
+	HasPrev    bool
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCursor(t *testing.T) {
+	patient := NewPatient("Jane", "Doe", time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC), GenderFemale)
+	cursor := NewPageCursor(patient, PatientSort{Field: SortByName, Descending: true}, true)
+
+	decoded, err := DecodePageCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	// The cursor keeps the values it was issued with
+	patient.LastName = "Smith"
+	pivot := decoded.Pivot()
+	assert.Equal(t, patient.ID, pivot.ID)
+	assert.Equal(t, "Doe", pivot.LastName)
+	assert.Equal(t, "Jane", pivot.FirstName)
+
+	for _, sort := range []SortField{SortByCreatedAt, SortByUpdatedAt, SortByDateOfBirth} {
+		cursor := NewPageCursor(patient, PatientSort{Field: sort}, false)
+		decoded, err := DecodePageCursor(cursor.Encode())
+		require.NoError(t, err, sort)
+		assert.False(t, PatientSort{Field: sort}.Less(decoded.Pivot(), patient), sort)
+		assert.False(t, PatientSort{Field: sort}.Less(patient, decoded.Pivot()), sort)
+	}
+
+	id := uuid.New()
+	for _, encoded := range []string{"", "not a cursor", PageCursor{Sort: PatientSort{Field: SortByName}}.Encode(),
+		PageCursor{PatientID: id, Sort: PatientSort{Field: "age"}}.Encode(),
+		PageCursor{PatientID: id, Sort: PatientSort{Field: SortByName}}.Encode()} {
+		_, err := DecodePageCursor(encoded)
+		assert.ErrorIs(t, err, ErrInvalidCursor, encoded)
+	}
+}
@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DuplicateThreshold is the score from which two records are considered
+// likely to describe the same person
+const DuplicateThreshold = 0.7
+
+// Weights of the compared fields in a duplicate score, they add up to 1
+const (
+	weightFirstName   = 0.2
+	weightLastName    = 0.25
+	weightDateOfBirth = 0.3
+	weightPhone       = 0.125
+	weightEmail       = 0.125
+)
+
+// DuplicateCandidate is an existing patient that resembles a new one
+type DuplicateCandidate struct {
+	Patient *Patient `json:"patient"`
+	Score   float64  `json:"score"`
+	Matched []string `json:"matched"`
+}
+
+// DuplicateScore rates from 0 to 1 how likely two records describe the same
+// person. Names are compared by similarity so that spelling variants still
+// score; a date of birth with day and month swapped counts half. Phone and
+// email only count when both records have them and they are equal. The
+// matched fields are returned in a fixed order.
+func DuplicateScore(a, b *Patient) (float64, []string) {
+	score := 0.0
+	matched := []string{}
+
+	if similarity := nameSimilarity(a.FirstName, b.FirstName); similarity > 0.85 {
+		score += weightFirstName * similarity
+		matched = append(matched, "firstName")
+	}
+	if similarity := nameSimilarity(a.LastName, b.LastName); similarity > 0.85 {
+		score += weightLastName * similarity
+		matched = append(matched, "lastName")
+	}
+
+	dobA, dobB := a.DateOfBirth.Time(), b.DateOfBirth.Time()
+	switch {
+	case sameDate(dobA, dobB):
+		score += weightDateOfBirth
+		matched = append(matched, "dateOfBirth")
+	case dobA.Year() == dobB.Year() && int(dobA.Month()) == dobB.Day() && dobA.Day() == int(dobB.Month()):
+		score += weightDateOfBirth / 2
+		matched = append(matched, "dateOfBirth")
+	}
+
+	if phone := Digits(a.PhoneNumber); len(phone) >= 7 && phone == Digits(b.PhoneNumber) {
+		score += weightPhone
+		matched = append(matched, "phoneNumber")
+	}
+	if email := NormalizeEmail(a.Email); email != "" && email == NormalizeEmail(b.Email) {
+		score += weightEmail
+		matched = append(matched, "email")
+	}
+
+	return score, matched
+}
+
+// MayDuplicate reports whether other shares a field without which
+// DuplicateScore stays below DuplicateThreshold: the date of birth, also with
+// day and month swapped, the phone number or the email. Repositories select
+// the patients FindDuplicates scores by these fields.
+func MayDuplicate(patient, other *Patient) bool {
+	dob, otherDOB := patient.DateOfBirth.Time(), other.DateOfBirth.Time()
+	if swapped, ok := SwappedDayMonth(dob); sameDate(dob, otherDOB) || ok && sameDate(swapped, otherDOB) {
+		return true
+	}
+	if phone := Digits(patient.PhoneNumber); len(phone) >= 7 && phone == Digits(other.PhoneNumber) {
+		return true
+	}
+	email := NormalizeEmail(patient.Email)
+	return email != "" && email == NormalizeEmail(other.Email)
+}
+
+// FindDuplicates returns the existing patients that likely describe the same
+// person as patient, best match first. Archived records are skipped.
+func FindDuplicates(patient *Patient, existing []*Patient) []DuplicateCandidate {
+	candidates := []DuplicateCandidate{}
+	for _, other := range existing {
+		if other.ID == patient.ID || other.IsArchived() {
+			continue
+		}
+		if score, matched := DuplicateScore(patient, other); score >= DuplicateThreshold {
+			candidates = append(candidates, DuplicateCandidate{Patient: other, Score: score, Matched: matched})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// nameSimilarity compares two names with the Jaro-Winkler similarity after
+// ignoring case, spaces and punctuation
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	return jaroWinkler([]rune(a), []rune(b))
+}
+
+// normalizeName keeps only the lower-cased letters of a name
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, 1 meaning
+// equal. A common prefix of up to four characters raises the score.
+func jaroWinkler(a, b []rune) float64 {
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		for j := max(0, i-window); j < min(len(b), i+window+1); j++ {
+			if !matchedB[j] && a[i] == b[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range a {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(a), len(b)) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// sameDate reports whether two times fall on the same calendar day
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+// SwappedDayMonth returns the date with day and month exchanged, or false if
+// the day cannot be a month
+func SwappedDayMonth(date time.Time) (time.Time, bool) {
+	if date.Day() > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(date.Year(), time.Month(date.Day()), int(date.Month()), 0, 0, 0, 0, time.UTC), true
+}
+
+// NormalizeEmail lower-cases an email address for comparison
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Digits keeps only the digits of a phone number
+func Digits(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
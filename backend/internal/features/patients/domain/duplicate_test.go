@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDuplicateTestPatient(firstName, lastName string, dob time.Time) *Patient {
+	patient := NewPatient(firstName, lastName, dob, GenderFemale)
+	patient.PhoneNumber = "+1 (555) 010-0100"
+	patient.Email = "jane@example.com"
+	return patient
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.Equal(t, 1.0, jaroWinkler([]rune("martha"), []rune("martha")))
+	assert.InDelta(t, 0.961, jaroWinkler([]rune("martha"), []rune("marhta")), 0.001)
+	assert.InDelta(t, 0.840, jaroWinkler([]rune("dwayne"), []rune("duane")), 0.001)
+	assert.Equal(t, 0.0, jaroWinkler([]rune("abc"), []rune("xyz")))
+}
+
+func TestDuplicateScore(t *testing.T) {
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	existing := newDuplicateTestPatient("Jane", "Doe", dob)
+
+	tests := []struct {
+		name      string
+		candidate *Patient
+		duplicate bool
+		matched   []string
+	}{
+		{
+			name:      "identical record",
+			candidate: newDuplicateTestPatient("Jane", "Doe", dob),
+			duplicate: true,
+			matched:   []string{"firstName", "lastName", "dateOfBirth", "phoneNumber", "email"},
+		},
+		{
+			name: "spelling variant without contact details",
+			candidate: func() *Patient {
+				p := NewPatient("JANE", "Dhoe", dob, GenderFemale)
+				p.PhoneNumber = "555 0100 0100"
+				return p
+			}(),
+			duplicate: true,
+			matched:   []string{"firstName", "lastName", "dateOfBirth"},
+		},
+		{
+			name:      "day and month swapped",
+			candidate: newDuplicateTestPatient("Jane", "Doe", time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)),
+			duplicate: true,
+			matched:   []string{"firstName", "lastName", "dateOfBirth", "phoneNumber", "email"},
+		},
+		{
+			name:      "same name, different birthday",
+			candidate: NewPatient("Jane", "Doe", time.Date(1985, 7, 9, 0, 0, 0, 0, time.UTC), GenderFemale),
+			duplicate: false,
+			matched:   []string{"firstName", "lastName"},
+		},
+		{
+			name:      "relative sharing contact details",
+			candidate: newDuplicateTestPatient("Robert", "Doe", time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)),
+			duplicate: false,
+			matched:   []string{"lastName", "phoneNumber", "email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, matched := DuplicateScore(tt.candidate, existing)
+			assert.Equal(t, tt.duplicate, score >= DuplicateThreshold, "score %.3f", score)
+			assert.Equal(t, tt.matched, matched)
+			if tt.duplicate {
+				assert.True(t, MayDuplicate(tt.candidate, existing))
+			}
+		})
+	}
+}
+
+func TestMayDuplicate(t *testing.T) {
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	existing := NewPatient("Jane", "Doe", dob, GenderFemale)
+	existing.PhoneNumber = "+1 (555) 010-0100"
+	existing.Email = "Jane@Example.com"
+
+	tests := []struct {
+		name      string
+		candidate func(p *Patient)
+		want      bool
+	}{
+		{"same date of birth", func(p *Patient) { p.DateOfBirth = Date(dob) }, true},
+		{"day and month swapped", func(p *Patient) { p.DateOfBirth = Date(time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)) }, true},
+		{"same phone digits", func(p *Patient) { p.PhoneNumber = "15550100100" }, true},
+		{"same email", func(p *Patient) { p.Email = " jane@example.com" }, true},
+		{"other phone number", func(p *Patient) { p.PhoneNumber = "+1 (555) 010-0199" }, false},
+		{"nothing shared", func(p *Patient) {}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := NewPatient("Jane", "Doe", time.Date(1985, 7, 19, 0, 0, 0, 0, time.UTC), GenderFemale)
+			tt.candidate(candidate)
+			assert.Equal(t, tt.want, MayDuplicate(candidate, existing))
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	exact := newDuplicateTestPatient("Jane", "Doe", dob)
+	close := NewPatient("Jane", "Doe", dob, GenderFemale)
+	archived := newDuplicateTestPatient("Jane", "Doe", dob)
+	require.NoError(t, archived.Archive("user-1", "Moved away"))
+	other := newDuplicateTestPatient("John", "Smith", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	patient := newDuplicateTestPatient("Jane", "Doe", dob)
+	candidates := FindDuplicates(patient, []*Patient{close, archived, other, exact, patient})
+
+	require.Len(t, candidates, 2)
+	assert.Equal(t, exact.ID, candidates[0].Patient.ID)
+	assert.Equal(t, close.ID, candidates[1].Patient.ID)
+	assert.Greater(t, candidates[0].Score, candidates[1].Score)
+}
+
+func TestMerge(t *testing.T) {
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	target := NewPatient("Jane", "Doe", dob, GenderFemale)
+	target.PhoneNumber = "555-0100"
+	source := newDuplicateTestPatient("Jane", "Doe", dob)
+	source.PhoneNumber = "555-0199"
+	source.Height = 170
+
+	require.NoError(t, target.Merge(source, "admin-1"))
+
+	// Details the kept record lacks are taken over, its own are kept
+	assert.Equal(t, "jane@example.com", target.Email)
+	assert.Equal(t, "555-0100", target.PhoneNumber)
+	assert.Equal(t, 170.0, target.Height)
+	assert.Equal(t, "admin-1", target.UpdatedBy)
+	assert.Equal(t, "Merged from "+source.ID.String(), target.ChangeReason)
+
+	assert.True(t, source.IsArchived())
+	require.NotNil(t, source.MergedInto)
+	assert.Equal(t, target.ID, *source.MergedInto)
+	assert.ErrorIs(t, source.Restore("admin-1", "Undo"), ErrMerged)
+
+	assert.ErrorIs(t, target.Merge(target, "admin-1"), ErrMergeIntoSelf)
+	assert.ErrorIs(t, target.Merge(source, "admin-1"), ErrAlreadyArchived)
+}
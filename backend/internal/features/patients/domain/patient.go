@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 	"time"
 
@@ -50,6 +51,15 @@ const (
 	GenderUnknown Gender = "unknown"
 )
 
+// IsValid reports whether the gender is one of the known values
+func (g Gender) IsValid() bool {
+	switch g {
+	case GenderMale, GenderFemale, GenderOther, GenderUnknown:
+		return true
+	}
+	return false
+}
+
 // Patient represents the core patient domain model
 type Patient struct {
 	ID          uuid.UUID `json:"id"`
@@ -63,10 +73,30 @@ type Patient struct {
 	Height      float64   `json:"height,omitempty"`
 	Weight      float64   `json:"weight,omitempty"`
 	Address     Address   `json:"address,omitempty"`
+	Version     int       `json:"version"`
+	UpdatedBy   string    `json:"updatedBy,omitempty"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// An archived patient is kept but hidden from listings by default. A
+	// patient merged into another record is archived and redirects to it.
+	ArchivedAt    *time.Time `json:"archivedAt,omitempty"`
+	ArchiveReason string     `json:"archiveReason,omitempty"`
+	MergedInto    *uuid.UUID `json:"mergedInto,omitempty"`
+
+	// ChangeReason explains the latest change and is recorded with its
+	// revision. It is not part of the record itself.
+	ChangeReason string `json:"-"`
 }
 
+// Errors returned when archiving, restoring or merging a patient
+var (
+	ErrAlreadyArchived = errors.New("patient is already archived")
+	ErrNotArchived     = errors.New("patient is not archived")
+	ErrMerged          = errors.New("patient has been merged into another record")
+	ErrMergeIntoSelf   = errors.New("a patient cannot be merged into itself")
+)
+
 // Address represents a physical address
 type Address struct {
 	Street     string `json:"street"`
@@ -95,6 +125,88 @@ func (p *Patient) Update() {
 	p.UpdatedAt = time.Now()
 }
 
+// UpdateBy updates the patient's updatedAt timestamp and records who made
+// the change, which becomes the author of the next revision
+func (p *Patient) UpdateBy(authorID string) {
+	p.Update()
+	p.UpdatedBy = authorID
+	p.ChangeReason = ""
+}
+
+// IsArchived reports whether the patient has been archived
+func (p *Patient) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
+// Archive hides the patient from listings for the given reason. The record
+// is kept until it is purged.
+func (p *Patient) Archive(authorID, reason string) error {
+	if p.IsArchived() {
+		return ErrAlreadyArchived
+	}
+	p.UpdateBy(authorID)
+	archivedAt := p.UpdatedAt
+	p.ArchivedAt = &archivedAt
+	p.ArchiveReason = reason
+	p.ChangeReason = reason
+	return nil
+}
+
+// Restore returns an archived patient to the active records. Merged
+// records cannot be restored.
+func (p *Patient) Restore(authorID, reason string) error {
+	if p.MergedInto != nil {
+		return ErrMerged
+	}
+	if !p.IsArchived() {
+		return ErrNotArchived
+	}
+	p.UpdateBy(authorID)
+	p.ArchivedAt = nil
+	p.ArchiveReason = ""
+	p.ChangeReason = reason
+	return nil
+}
+
+// Merge folds source into p, the record that is kept. Fields p lacks are
+// taken from source, which is then archived with a redirect to p. Both
+// records must be active.
+func (p *Patient) Merge(source *Patient, authorID string) error {
+	if source.ID == p.ID {
+		return ErrMergeIntoSelf
+	}
+	if p.IsArchived() || source.IsArchived() {
+		return ErrAlreadyArchived
+	}
+
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&p.MiddleName, source.MiddleName)
+	fill(&p.Email, source.Email)
+	fill(&p.PhoneNumber, source.PhoneNumber)
+	if p.Address == (Address{}) {
+		p.Address = source.Address
+	}
+	if p.Height == 0 {
+		p.Height = source.Height
+	}
+	if p.Weight == 0 {
+		p.Weight = source.Weight
+	}
+	p.UpdateBy(authorID)
+	p.ChangeReason = "Merged from " + source.ID.String()
+
+	if err := source.Archive(authorID, "Merged into "+p.ID.String()); err != nil {
+		return err
+	}
+	mergedInto := p.ID
+	source.MergedInto = &mergedInto
+	return nil
+}
+
 // FullName returns the patient's full name
 func (p *Patient) FullName() string {
 	if p.MiddleName != "" {
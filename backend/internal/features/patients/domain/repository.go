@@ -2,24 +2,77 @@ package domain
 
 import (
 	"context"
+	"errors"
 )
 
-// PatientRepository defines the interface for patient persistence
+// ErrPatientNotFound is returned, possibly wrapped, when a patient to read
+// or write does not exist
+var ErrPatientNotFound = errors.New("patient not found")
+
+// ErrVersionConflict is returned by Update when the stored patient is no
+// longer at the version the update started from
+var ErrVersionConflict = errors.New("patient was modified by another update")
+
+// PatientRepository defines the interface for patient persistence. Create
+// and Update also store the new state as a revision, see NewRevision.
+// Update fails with ErrVersionConflict unless the patient's version matches
+// the stored one. Delete removes the patient permanently, including its
+// revisions.
 type PatientRepository interface {
 	Create(ctx context.Context, patient *Patient) error
 	Update(ctx context.Context, patient *Patient) error
 	Delete(ctx context.Context, id string) error
 	GetByID(ctx context.Context, id string) (*Patient, error)
 	List(ctx context.Context) ([]*Patient, error)
-	ListPaginated(ctx context.Context, page, pageSize int, search string) ([]*Patient, int64, error)
+	ListPaginated(ctx context.Context, page, pageSize int, filter PatientFilter) ([]*Patient, int64, error)
 }
 
-// CreatePatientRepository defines the minimal interface for patient creation
+// CreatePatientRepository defines the minimal interface for patient creation.
+// ListDuplicateCandidates returns the unarchived patients that MayDuplicate
+// the new one, which are then scored for duplicates.
 type CreatePatientRepository interface {
 	Create(ctx context.Context, patient *Patient) error
+	ListDuplicateCandidates(ctx context.Context, patient *Patient) ([]*Patient, error)
 }
 
-// GetPatientsRepository defines the minimal interface for patient retrieval
+// MergePatientsRepository defines the minimal interface for merging patients.
+// Merge stores both records atomically and fails with ErrVersionConflict if
+// either has changed since it was read. Transaction runs fn so that the
+// records other features keep of the source move to the target together
+// with the merge.
+type MergePatientsRepository interface {
+	GetByID(ctx context.Context, id string) (*Patient, error)
+	Merge(ctx context.Context, source, target *Patient) error
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// GetPatientsRepository defines the minimal interface for patient retrieval.
+// ListByCursor returns up to limit patients following the cursor's sort
+// values in the order of the filter, or the first ones without a cursor.
 type GetPatientsRepository interface {
-	ListPaginated(ctx context.Context, page, pageSize int, search string) ([]*Patient, int64, error)
+	ListPaginated(ctx context.Context, page, pageSize int, filter PatientFilter) ([]*Patient, int64, error)
+	ListByCursor(ctx context.Context, filter PatientFilter, cursor *PageCursor, limit int) (*PatientPage, error)
+}
+
+// PurgePatientRepository defines the minimal interface for purging a patient.
+// Transaction runs fn so that the patient and the records other features
+// keep of it, changed with the context passed to fn, are deleted together.
+type PurgePatientRepository interface {
+	GetByID(ctx context.Context, id string) (*Patient, error)
+	Delete(ctx context.Context, id string) error
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// PatientRecords are the records another feature keeps of each patient, such
+// as encounters or allergies. DeleteByPatient removes all of them when the
+// patient is purged; MoveToPatient hands them from a duplicate to the
+// patient it is merged into.
+type PatientRecords interface {
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// RevisionRepository defines the interface for reading patient revisions
+type RevisionRepository interface {
+	ListRevisions(ctx context.Context, patientID string) ([]*Revision, error)
 }
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// RetentionPolicy decides when an archived patient record may be purged.
+// Records are retained for Years after their last change. Records of minors
+// are in addition retained for Years after the patient came of age.
+type RetentionPolicy struct {
+	Years         int
+	AgeOfMajority int
+}
+
+// PurgeableFrom returns the earliest time the patient may be purged
+func (p RetentionPolicy) PurgeableFrom(patient *Patient) time.Time {
+	from := patient.UpdatedAt
+	if cameOfAge := patient.DateOfBirth.Time().AddDate(p.AgeOfMajority, 0, 0); cameOfAge.After(from) {
+		from = cameOfAge
+	}
+	return from.AddDate(p.Years, 0, 0)
+}
+
+// CanPurge reports whether the patient may be purged at the given time. Only
+// archived patients can be purged.
+func (p RetentionPolicy) CanPurge(patient *Patient, at time.Time) bool {
+	return patient.IsArchived() && !at.Before(p.PurgeableFrom(patient))
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveAndRestore(t *testing.T) {
+	patient := setupPatientTest().defaultPatient
+
+	require.NoError(t, patient.Archive("user-1", "Moved away"))
+	assert.True(t, patient.IsArchived())
+	assert.Equal(t, "Moved away", patient.ArchiveReason)
+	assert.Equal(t, "Moved away", patient.ChangeReason)
+	assert.Equal(t, "user-1", patient.UpdatedBy)
+	assert.ErrorIs(t, patient.Archive("user-1", "Again"), ErrAlreadyArchived)
+
+	require.NoError(t, patient.Restore("user-2", "Returned"))
+	assert.False(t, patient.IsArchived())
+	assert.Empty(t, patient.ArchiveReason)
+	assert.Equal(t, "Returned", patient.ChangeReason)
+	assert.ErrorIs(t, patient.Restore("user-2", "Again"), ErrNotArchived)
+
+	// An ordinary update carries no reason
+	patient.UpdateBy("user-3")
+	assert.Empty(t, patient.ChangeReason)
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	policy := RetentionPolicy{Years: 10, AgeOfMajority: 18}
+	archivedAt := time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	adult := &Patient{DateOfBirth: Date(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)), UpdatedAt: archivedAt, ArchivedAt: &archivedAt}
+	assert.Equal(t, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), policy.PurgeableFrom(adult))
+	assert.False(t, policy.CanPurge(adult, time.Date(2020, 5, 31, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, policy.CanPurge(adult, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Records of minors are kept until ten years after they turn 18
+	minor := &Patient{DateOfBirth: Date(time.Date(2005, 3, 1, 0, 0, 0, 0, time.UTC)), UpdatedAt: archivedAt, ArchivedAt: &archivedAt}
+	assert.Equal(t, time.Date(2033, 3, 1, 0, 0, 0, 0, time.UTC), policy.PurgeableFrom(minor))
+
+	// Active records are never purged
+	active := &Patient{DateOfBirth: adult.DateOfBirth, UpdatedAt: archivedAt}
+	assert.False(t, policy.CanPurge(active, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revision is an immutable snapshot of a patient record, taken on every
+// create and update. Versions count up from 1 for each patient.
+type Revision struct {
+	PatientID     uuid.UUID `json:"patientId"`
+	Version       int       `json:"version"`
+	AuthorID      string    `json:"authorId"`
+	ChangedFields []string  `json:"changedFields"`
+	Reason        string    `json:"reason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Patient       Patient   `json:"patient"`
+}
+
+// FieldChange describes how one field differs between two revisions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// NewRevision snapshots the patient as the revision following prev, the
+// record as it was stored before. prev is nil when the patient is created.
+// The patient's version is advanced to the new revision.
+func NewRevision(prev, patient *Patient) *Revision {
+	base := &Patient{}
+	patient.Version = 1
+	if prev != nil {
+		base = prev
+		patient.Version = prev.Version + 1
+	}
+
+	changes := DiffPatients(base, patient)
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+
+	return &Revision{
+		PatientID:     patient.ID,
+		Version:       patient.Version,
+		AuthorID:      patient.UpdatedBy,
+		ChangedFields: fields,
+		Reason:        patient.ChangeReason,
+		CreatedAt:     patient.UpdatedAt,
+		Patient:       *patient,
+	}
+}
+
+// DiffPatients lists the record fields that differ between two versions of
+// a patient, in a fixed order. Bookkeeping fields such as timestamps are
+// not compared.
+func DiffPatients(from, to *Patient) []FieldChange {
+	fromFields, toFields := recordFields(from), recordFields(to)
+
+	changes := []FieldChange{}
+	for i, field := range fromFields {
+		if field.value != toFields[i].value {
+			changes = append(changes, FieldChange{Field: field.name, From: field.value, To: toFields[i].value})
+		}
+	}
+	return changes
+}
+
+// RevisionAt returns the revision that was current at the given time, or
+// nil if the patient did not exist yet. Revisions must be ordered by version.
+func RevisionAt(revisions []*Revision, at time.Time) *Revision {
+	var current *Revision
+	for _, revision := range revisions {
+		if revision.CreatedAt.After(at) {
+			break
+		}
+		current = revision
+	}
+	return current
+}
+
+// recordField is a named, comparable field value of a patient record
+type recordField struct {
+	name  string
+	value any
+}
+
+// recordFields returns the fields of the patient record under their JSON names
+func recordFields(p *Patient) []recordField {
+	mergedInto := ""
+	if p.MergedInto != nil {
+		mergedInto = p.MergedInto.String()
+	}
+	dateOfBirth := ""
+	if !p.DateOfBirth.Time().IsZero() {
+		dateOfBirth = p.DateOfBirth.Time().Format("2006-01-02")
+	}
+
+	return []recordField{
+		{"firstName", p.FirstName},
+		{"lastName", p.LastName},
+		{"middleName", p.MiddleName},
+		{"dateOfBirth", dateOfBirth},
+		{"gender", string(p.Gender)},
+		{"email", p.Email},
+		{"phoneNumber", p.PhoneNumber},
+		{"height", p.Height},
+		{"weight", p.Weight},
+		{"address.street", p.Address.Street},
+		{"address.city", p.Address.City},
+		{"address.state", p.Address.State},
+		{"address.postalCode", p.Address.PostalCode},
+		{"address.country", p.Address.Country},
+		{"archived", p.IsArchived()},
+		{"archiveReason", p.ArchiveReason},
+		{"mergedInto", mergedInto},
+	}
+}
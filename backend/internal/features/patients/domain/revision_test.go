@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRevision(t *testing.T) {
+	suite := setupPatientTest()
+	patient := suite.defaultPatient
+	patient.UpdatedBy = "user-1"
+
+	first := NewRevision(nil, patient)
+	assert.Equal(t, 1, first.Version)
+	assert.Equal(t, 1, patient.Version)
+	assert.Equal(t, "user-1", first.AuthorID)
+	assert.Contains(t, first.ChangedFields, "firstName")
+	assert.Contains(t, first.ChangedFields, "address.city")
+	assert.NotContains(t, first.ChangedFields, "height")
+
+	prev := *patient
+	patient.PhoneNumber = "555-0199"
+	patient.Address.City = "Elsewhere"
+	patient.UpdateBy("user-2")
+
+	second := NewRevision(&prev, patient)
+	assert.Equal(t, 2, second.Version)
+	assert.Equal(t, 2, patient.Version)
+	assert.Equal(t, "user-2", second.AuthorID)
+	assert.Equal(t, []string{"phoneNumber", "address.city"}, second.ChangedFields)
+	assert.Equal(t, "Elsewhere", second.Patient.Address.City)
+	assert.Equal(t, "Healthcare City", first.Patient.Address.City)
+}
+
+func TestDiffPatients(t *testing.T) {
+	suite := setupPatientTest()
+	from := *suite.defaultPatient
+	to := from
+	to.Weight = 72.5
+	to.DateOfBirth = Date(time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC))
+	to.UpdatedAt = to.UpdatedAt.Add(time.Hour)
+
+	changes := DiffPatients(&from, &to)
+	require.Len(t, changes, 2)
+	assert.Equal(t, FieldChange{Field: "dateOfBirth", From: "1990-01-01", To: "1990-01-02"}, changes[0])
+	assert.Equal(t, FieldChange{Field: "weight", From: 0.0, To: 72.5}, changes[1])
+
+	assert.Empty(t, DiffPatients(&from, &from))
+}
+
+func TestRevisionAt(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	revisions := []*Revision{
+		{Version: 1, CreatedAt: start},
+		{Version: 2, CreatedAt: start.Add(time.Hour)},
+		{Version: 3, CreatedAt: start.Add(2 * time.Hour)},
+	}
+
+	assert.Nil(t, RevisionAt(revisions, start.Add(-time.Second)))
+	assert.Equal(t, 1, RevisionAt(revisions, start).Version)
+	assert.Equal(t, 2, RevisionAt(revisions, start.Add(90*time.Minute)).Version)
+	assert.Equal(t, 3, RevisionAt(revisions, start.Add(24*time.Hour)).Version)
+	assert.Nil(t, RevisionAt(nil, start))
+}
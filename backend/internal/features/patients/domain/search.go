@@ -0,0 +1,283 @@
+package domain
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SortField names the patient attribute a listing is ordered by
+type SortField string
+
+const (
+	SortByCreatedAt   SortField = "createdAt"
+	SortByUpdatedAt   SortField = "updatedAt"
+	SortByName        SortField = "name"
+	SortByDateOfBirth SortField = "dateOfBirth"
+)
+
+// IsValid reports whether the field is one a listing can be ordered by
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByCreatedAt, SortByUpdatedAt, SortByName, SortByDateOfBirth:
+		return true
+	}
+	return false
+}
+
+// PatientSort orders a patient listing. Sorting by name compares the last
+// name, then the first name. Ties are broken by ID so that pages are stable;
+// the zero value orders by creation time.
+type PatientSort struct {
+	Field      SortField `json:"field"`
+	Descending bool      `json:"desc,omitempty"`
+}
+
+// Less reports whether a is listed before b
+func (s PatientSort) Less(a, b *Patient) bool {
+	var cmp int
+	switch s.Field {
+	case SortByName:
+		if cmp = strings.Compare(strings.ToLower(a.LastName), strings.ToLower(b.LastName)); cmp == 0 {
+			cmp = strings.Compare(strings.ToLower(a.FirstName), strings.ToLower(b.FirstName))
+		}
+	case SortByDateOfBirth:
+		cmp = a.DateOfBirth.Time().Compare(b.DateOfBirth.Time())
+	case SortByUpdatedAt:
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if s.Descending {
+		cmp = -cmp
+	}
+	if cmp == 0 {
+		return a.ID.String() < b.ID.String()
+	}
+	return cmp < 0
+}
+
+// Sort orders the patients in place
+func (s PatientSort) Sort(patients []*Patient) {
+	sort.Slice(patients, func(i, j int) bool {
+		return s.Less(patients[i], patients[j])
+	})
+}
+
+// PatientFilter narrows down and orders a patient listing. Empty criteria
+// match every patient; archived patients are left out unless
+// IncludeArchived is set.
+//
+// Search is matched against the names tolerating typos and spelling
+// variants, see MatchesName. City, postal code and email compare without
+// regard to case, phone numbers by their digits. BornFrom and BornTo bound
+// the date of birth, both inclusive.
+type PatientFilter struct {
+	Search          string
+	Gender          Gender
+	BornFrom        time.Time
+	BornTo          time.Time
+	City            string
+	PostalCode      string
+	Phone           string
+	Email           string
+	IncludeArchived bool
+	Sort            PatientSort
+}
+
+// Matches reports whether the patient passes the filter
+func (f PatientFilter) Matches(patient *Patient) bool {
+	return f.MatchesAttributes(patient) && f.MatchesName(patient)
+}
+
+// MatchesAttributes reports whether the patient passes every criterion of
+// the filter except the name search
+func (f PatientFilter) MatchesAttributes(patient *Patient) bool {
+	if patient.IsArchived() && !f.IncludeArchived {
+		return false
+	}
+	if f.Gender != "" && patient.Gender != f.Gender {
+		return false
+	}
+
+	dob := patient.DateOfBirth.Time()
+	if !f.BornFrom.IsZero() && dob.Before(f.BornFrom) {
+		return false
+	}
+	if !f.BornTo.IsZero() && dob.After(f.BornTo) {
+		return false
+	}
+
+	if f.City != "" && !strings.EqualFold(patient.Address.City, strings.TrimSpace(f.City)) {
+		return false
+	}
+	if f.PostalCode != "" && !strings.EqualFold(NormalizePostalCode(patient.Address.PostalCode), NormalizePostalCode(f.PostalCode)) {
+		return false
+	}
+	if f.Email != "" && !strings.EqualFold(patient.Email, strings.TrimSpace(f.Email)) {
+		return false
+	}
+	if phone := Digits(f.Phone); phone != "" && !strings.Contains(Digits(patient.PhoneNumber), phone) {
+		return false
+	}
+	return true
+}
+
+// MatchesName reports whether the patient's names match the search. Every
+// word searched for must match one of the names by prefix, by similar
+// spelling or by sounding alike, so that "Jon Smyth" finds John Smith.
+func (f PatientFilter) MatchesName(patient *Patient) bool {
+	if f.Search == "" {
+		return true
+	}
+
+	words := nameWords(f.Search)
+	if len(words) == 0 {
+		return false
+	}
+	names := nameWords(patient.FullName())
+	for _, word := range words {
+		if !matchesAnyName(word, names) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizePostalCode drops the spaces and dashes of a postal code
+func NormalizePostalCode(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+}
+
+// SearchKeys returns for every word of the search the keys of which one of
+// the names must have one to match it, see NameKeys. A search without words
+// has none and matches no names.
+func (f PatientFilter) SearchKeys() [][]string {
+	keys := [][]string{}
+	for _, word := range nameWords(f.Search) {
+		keys = append(keys, wordKeys(word))
+	}
+	return keys
+}
+
+// NameKeys returns the keys the words of the names are looked up by: the
+// first one, two and three letters of each word and its Soundex code. A name
+// only matches a searched word if they share a key, so repositories can
+// index the keys to narrow down a search.
+func NameKeys(names ...string) []string {
+	keys := []string{}
+	for _, name := range names {
+		for _, word := range nameWords(name) {
+			letters := []rune(word)
+			for n := 1; n <= min(3, len(letters)); n++ {
+				keys = append(keys, string(letters[:n]))
+			}
+			if code := Soundex(word); code != "" {
+				keys = append(keys, code)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// wordKeys returns the keys of which a name must have one to match the
+// searched word: the word itself if shorter than three letters, as it only
+// matches as a prefix, otherwise its first three letters and its Soundex
+// code. Soundex codes are upper case and contain digits, so they never equal
+// a prefix.
+func wordKeys(word string) []string {
+	letters := []rune(word)
+	if len(letters) < 3 {
+		return []string{word}
+	}
+	keys := []string{string(letters[:3])}
+	if code := Soundex(word); code != "" {
+		keys = append(keys, code)
+	}
+	return keys
+}
+
+// matchesAnyName reports whether a searched word matches one of the names.
+// Words shorter than three letters only match as a prefix. Longer ones must
+// share a key with the name, see wordKeys. Soundex alone is too coarse, it
+// equates Jane and John, so names that sound alike must also be spelled
+// somewhat alike.
+func matchesAnyName(word string, names []string) bool {
+	keys := wordKeys(word)
+	for _, name := range names {
+		if strings.HasPrefix(name, word) {
+			return true
+		}
+		if len([]rune(word)) < 3 || !slices.ContainsFunc(NameKeys(name), func(key string) bool { return slices.Contains(keys, key) }) {
+			continue
+		}
+		similarity := jaroWinkler([]rune(word), []rune(name))
+		if similarity >= 0.85 || (similarity >= 0.75 && Soundex(word) == Soundex(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameWords splits a name into its normalized words, see normalizeName
+func nameWords(name string) []string {
+	words := []string{}
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	}) {
+		if word = normalizeName(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// soundexCodes maps letters to their Soundex digit, vowels and the letters
+// h, w and y have none
+var soundexCodes = map[rune]byte{
+	'b': '1', 'f': '1', 'p': '1', 'v': '1',
+	'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+	'd': '3', 't': '3',
+	'l': '4',
+	'm': '5', 'n': '5',
+	'r': '6',
+}
+
+// Soundex returns the American Soundex code of a name, a letter followed by
+// three digits, so that names that sound alike share a code. Letters outside
+// a to z are ignored; a name without any yields an empty code.
+func Soundex(name string) string {
+	code := make([]byte, 0, 4)
+	var last byte
+	for _, r := range strings.ToLower(name) {
+		if r < 'a' || r > 'z' {
+			continue
+		}
+		digit := soundexCodes[r]
+		if len(code) == 0 {
+			code = append(code, byte(unicode.ToUpper(r)))
+			last = digit
+			continue
+		}
+		if digit != 0 && digit != last {
+			code = append(code, digit)
+			if len(code) == 4 {
+				break
+			}
+		}
+		// h and w do not separate letters with the same code, vowels do
+		if r != 'h' && r != 'w' {
+			last = digit
+		}
+	}
+	if len(code) == 0 {
+		return ""
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
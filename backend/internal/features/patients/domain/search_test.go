@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Rubin":    "R150",
+		"Ashcraft": "A261",
+		"Tymczak":  "T522",
+		"Pfister":  "P236",
+		"Lee":      "L000",
+		"Smith":    "S530",
+		"Smyth":    "S530",
+		"O'Hara":   "O600",
+		"":         "",
+	}
+	for name, code := range tests {
+		assert.Equal(t, code, Soundex(name), name)
+	}
+}
+
+func TestPatientFilterMatchesName(t *testing.T) {
+	patient := NewPatient("John", "Smith", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), GenderMale)
+	patient.MiddleName = "Robert"
+
+	tests := []struct {
+		search  string
+		matches bool
+	}{
+		{"", true},
+		{"joh rob", true},
+		{"ohn", false},
+		{"Jon Smyth", true},
+		{"smiht", true},
+		{"J Smith", true},
+		{"Rupert", true},
+		{"Meier", false},
+		{"Jane Smith", false},
+		{"Jon Miller", false},
+		{"%", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.matches, PatientFilter{Search: tt.search}.MatchesName(patient), tt.search)
+	}
+}
+
+func TestNameKeys(t *testing.T) {
+	assert.Equal(t, []string{"J000", "J500", "R163", "S530", "j", "jo", "joh", "r", "ro", "rob", "s", "sm", "smi"},
+		NameKeys("John", "Robert", "Smith", "Jo"))
+	assert.Empty(t, NameKeys("", "%"))
+}
+
+func TestPatientFilterSearchKeys(t *testing.T) {
+	assert.Equal(t, [][]string{{"jo"}, {"smy", "S530"}}, PatientFilter{Search: "Jo Smyth"}.SearchKeys())
+	assert.Empty(t, PatientFilter{Search: "%"}.SearchKeys())
+
+	// Every name a search matches shares a key with each of its words
+	patient := NewPatient("John", "Smith", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), GenderMale)
+	for _, search := range []string{"Jon Smyth", "smiht", "J Smith"} {
+		filter := PatientFilter{Search: search}
+		require.True(t, filter.MatchesName(patient), search)
+		for _, keys := range filter.SearchKeys() {
+			assert.True(t, slices.ContainsFunc(keys, func(key string) bool {
+				return slices.Contains(NameKeys(patient.FirstName, patient.LastName), key)
+			}), search)
+		}
+	}
+}
+
+func TestPatientFilterMatchesAttributes(t *testing.T) {
+	patient := NewPatient("Jane", "Doe", time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC), GenderFemale)
+	patient.PhoneNumber = "+1 (555) 010-0100"
+	patient.Email = "Jane@Example.com"
+	patient.Address = Address{City: "Springfield", PostalCode: "AB1 2CD"}
+
+	tests := []struct {
+		name    string
+		filter  PatientFilter
+		matches bool
+	}{
+		{"no criteria", PatientFilter{}, true},
+		{"gender", PatientFilter{Gender: GenderFemale}, true},
+		{"other gender", PatientFilter{Gender: GenderMale}, false},
+		{"born on the day", PatientFilter{BornFrom: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC), BornTo: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)}, true},
+		{"born before the range", PatientFilter{BornFrom: time.Date(1990, 3, 5, 0, 0, 0, 0, time.UTC)}, false},
+		{"born after the range", PatientFilter{BornTo: time.Date(1990, 3, 3, 0, 0, 0, 0, time.UTC)}, false},
+		{"city ignoring case", PatientFilter{City: "springfield"}, true},
+		{"postal code ignoring spaces", PatientFilter{PostalCode: "ab12cd"}, true},
+		{"email ignoring case", PatientFilter{Email: "jane@example.com"}, true},
+		{"part of the phone number", PatientFilter{Phone: "010-0100"}, true},
+		{"other phone number", PatientFilter{Phone: "555 0199"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, tt.filter.MatchesAttributes(patient))
+		})
+	}
+
+	assert.NoError(t, patient.Archive("user-1", "Moved away"))
+	assert.False(t, PatientFilter{}.MatchesAttributes(patient))
+	assert.True(t, PatientFilter{IncludeArchived: true}.MatchesAttributes(patient))
+}
+
+func TestPatientSort(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPatient := func(first, last string, dob time.Time, created time.Duration) *Patient {
+		p := NewPatient(first, last, dob, GenderFemale)
+		p.CreatedAt = base.Add(created)
+		p.UpdatedAt = base.Add(-created)
+		return p
+	}
+	amy := newPatient("Amy", "brown", time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), 2*time.Hour)
+	bea := newPatient("Bea", "Adams", time.Date(1995, 1, 1, 0, 0, 0, 0, time.UTC), time.Hour)
+	cat := newPatient("Cat", "Brown", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), 3*time.Hour)
+
+	order := func(sort PatientSort) []string {
+		patients := []*Patient{cat, amy, bea}
+		sort.Sort(patients)
+		names := []string{}
+		for _, p := range patients {
+			names = append(names, p.FirstName)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Bea", "Amy", "Cat"}, order(PatientSort{}))
+	assert.Equal(t, []string{"Cat", "Amy", "Bea"}, order(PatientSort{Field: SortByCreatedAt, Descending: true}))
+	assert.Equal(t, []string{"Cat", "Amy", "Bea"}, order(PatientSort{Field: SortByUpdatedAt}))
+	assert.Equal(t, []string{"Bea", "Amy", "Cat"}, order(PatientSort{Field: SortByName}))
+	assert.Equal(t, []string{"Cat", "Amy", "Bea"}, order(PatientSort{Field: SortByDateOfBirth}))
+	assert.Equal(t, []string{"Bea", "Amy", "Cat"}, order(PatientSort{Field: SortByDateOfBirth, Descending: true}))
+}
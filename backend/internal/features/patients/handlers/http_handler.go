@@ -1,23 +1,53 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dksch/pococlinic/internal/features/patients/commands"
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
 	"github.com/dksch/pococlinic/internal/features/patients/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
 	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/jsonpatch"
 	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
 	"github.com/gin-gonic/gin"
 )
 
+// Access identifies the permission a patient route requires. The patient
+// record holds demographics and contact details, not clinical data.
+type Access string
+
+const (
+	AccessReadDemographics  Access = "patients:read:demographics"
+	AccessWriteDemographics Access = "patients:write:demographics"
+	AccessPurge             Access = "patients:purge"
+	AccessMerge             Access = "patients:merge"
+)
+
+// Guard checks the caller's access to patient records on each route
+type Guard = authz.Guard[Access]
+
 // PatientHandler handles HTTP requests for patient operations
 type PatientHandler struct {
-	createPatientHandler commands.CreatePatientHandler
-	getPatientsHandler   queries.GetPatientsHandler
-	getPatientHandler    queries.GetPatientHandler
-	updatePatientHandler commands.UpdatePatientHandler
-	logger               *logging.Logger
+	createPatientHandler  commands.CreatePatientHandler
+	getPatientsHandler    queries.GetPatientsHandler
+	getPatientHandler     queries.GetPatientHandler
+	updatePatientHandler  commands.UpdatePatientHandler
+	patchPatientHandler   commands.PatchPatientHandler
+	archivePatientHandler commands.ArchivePatientHandler
+	restorePatientHandler commands.RestorePatientHandler
+	purgePatientHandler   commands.PurgePatientHandler
+	mergePatientsHandler  commands.MergePatientsHandler
+	listRevisionsHandler  queries.ListRevisionsHandler
+	diffRevisionsHandler  queries.DiffRevisionsHandler
+	getRevisionAtHandler  queries.GetRevisionAtHandler
+	bannerSources         []bannerSource
+	logger                *logging.Logger
 }
 
 // NewPatientHandler creates a new patient handler
@@ -26,25 +56,73 @@ func NewPatientHandler(
 	getHandler queries.GetPatientsHandler,
 	getPatientHandler queries.GetPatientHandler,
 	updateHandler commands.UpdatePatientHandler,
+	patchHandler commands.PatchPatientHandler,
+	archiveHandler commands.ArchivePatientHandler,
+	restoreHandler commands.RestorePatientHandler,
+	purgeHandler commands.PurgePatientHandler,
+	mergeHandler commands.MergePatientsHandler,
+	listRevisionsHandler queries.ListRevisionsHandler,
+	diffRevisionsHandler queries.DiffRevisionsHandler,
+	getRevisionAtHandler queries.GetRevisionAtHandler,
 	logger *logging.Logger,
 ) *PatientHandler {
 	return &PatientHandler{
-		createPatientHandler: createHandler,
-		getPatientsHandler:   getHandler,
-		getPatientHandler:    getPatientHandler,
-		updatePatientHandler: updateHandler,
-		logger:               logger,
+		createPatientHandler:  createHandler,
+		getPatientsHandler:    getHandler,
+		getPatientHandler:     getPatientHandler,
+		updatePatientHandler:  updateHandler,
+		patchPatientHandler:   patchHandler,
+		archivePatientHandler: archiveHandler,
+		restorePatientHandler: restoreHandler,
+		purgePatientHandler:   purgeHandler,
+		mergePatientsHandler:  mergeHandler,
+		listRevisionsHandler:  listRevisionsHandler,
+		diffRevisionsHandler:  diffRevisionsHandler,
+		getRevisionAtHandler:  getRevisionAtHandler,
+		logger:                logger,
+	}
+}
+
+// BannerAccess reports whether the caller of a request may see the banners
+// of a source, which can reveal clinical data the patient record does not
+type BannerAccess func(c *gin.Context) bool
+
+// bannerSource is a source of banners with the access it requires
+type bannerSource struct {
+	source  domain.BannerSource
+	allowed BannerAccess
+}
+
+// AddBannerSource adds a source of the banners shown with a patient's
+// record. Its banners are only shown to callers the access allows; adding a
+// source without one panics, like registering routes without a guard.
+func (h *PatientHandler) AddBannerSource(source domain.BannerSource, allowed BannerAccess) {
+	if allowed == nil {
+		panic("patients: banner source added without an access check")
 	}
+	h.bannerSources = append(h.bannerSources, bannerSource{source: source, allowed: allowed})
 }
 
-// RegisterRoutes registers the patient routes with the given router group
-func (h *PatientHandler) RegisterRoutes(router *gin.RouterGroup) {
+// RegisterRoutes registers the patient routes with the given router group.
+// Each route is wrapped with the middleware the guard returns for its access
+// level; registering them without a guard panics.
+func (h *PatientHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
 	patients := router.Group("/patients")
 	{
-		patients.POST("", h.CreatePatient)
-		patients.GET("", h.ListPatients)
-		patients.GET("/:id", h.GetPatient)
-		patients.PUT("/:id", h.UpdatePatient)
+		patients.POST("", guard(AccessWriteDemographics), h.CreatePatient)
+		patients.GET("", guard(AccessReadDemographics), h.ListPatients)
+		patients.GET("/:id", guard(AccessReadDemographics), h.GetPatient)
+		patients.PUT("/:id", guard(AccessWriteDemographics), h.UpdatePatient)
+		patients.PATCH("/:id", guard(AccessWriteDemographics), h.PatchPatient)
+		patients.DELETE("/:id", guard(AccessPurge), h.PurgePatient)
+		patients.POST("/:id/archive", guard(AccessWriteDemographics), h.ArchivePatient)
+		patients.POST("/:id/restore", guard(AccessWriteDemographics), h.RestorePatient)
+		patients.POST("/:id/merge", guard(AccessMerge), h.MergePatients)
+		patients.GET("/:id/revisions", guard(AccessReadDemographics), h.ListRevisions)
+		patients.GET("/:id/revisions/diff", guard(AccessReadDemographics), h.DiffRevisions)
+		patients.GET("/:id/revisions/at", guard(AccessReadDemographics), h.GetRevisionAt)
 		// Add more routes as needed
 	}
 }
@@ -57,6 +135,14 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
 		return
 	}
+	cmd.AuthorID = c.GetString("userID")
+
+	allowDuplicate, err := strconv.ParseBool(c.DefaultQuery("allowDuplicate", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid allowDuplicate flag"))
+		return
+	}
+	cmd.AllowDuplicate = allowDuplicate
 
 	// Log the received command
 	h.logger.Info("Received create patient command",
@@ -72,15 +158,23 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 		h.logger.Error("Failed to create patient", err)
 		// Check for specific error types and return appropriate status codes
 		switch err.(type) {
+		case *commands.DuplicatePatientError:
+			c.JSON(http.StatusConflict, duplicatePatientResponse{
+				APIError:   errors.NewAPIError(errors.ErrConflict, "Patient resembles existing patients, confirm with allowDuplicate=true to create anyway"),
+				Candidates: err.(*commands.DuplicatePatientError).Candidates,
+			})
 		case *errors.APIError:
 			apiErr := err.(*errors.APIError)
-			c.JSON(getStatusCodeForError(apiErr.Code), apiErr)
+			c.JSON(errors.StatusCode(apiErr.Code), apiErr)
 		default:
 			c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, "Failed to create patient"))
 		}
 		return
 	}
 
+	// Name the new patient for the audit log, the route has no patient ID
+	c.Set(patientref.ContextKey, patient.ID.String())
+
 	// Log the created patient
 	h.logger.Info("Created patient",
 		"id", patient.ID,
@@ -91,6 +185,7 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 		"weight", patient.Weight,
 	)
 
+	c.Header("ETag", etag(patient))
 	c.JSON(http.StatusCreated, patient)
 }
 
@@ -110,10 +205,37 @@ func (h *PatientHandler) ListPatients(c *gin.Context) {
 
 	search := c.Query("search")
 
+	includeArchived, err := strconv.ParseBool(c.DefaultQuery("includeArchived", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid includeArchived flag"))
+		return
+	}
+
 	query := queries.GetPatientsQuery{
-		Page:     page,
-		PageSize: pageSize,
-		Search:   search,
+		Page:            page,
+		PageSize:        pageSize,
+		Cursor:          c.Query("cursor"),
+		Search:          search,
+		Gender:          domain.Gender(c.Query("gender")),
+		City:            c.Query("city"),
+		PostalCode:      c.Query("postalCode"),
+		Phone:           c.Query("phone"),
+		Email:           c.Query("email"),
+		Sort:            domain.SortField(c.Query("sort")),
+		Order:           c.Query("order"),
+		IncludeArchived: includeArchived,
+	}
+	for name, date := range map[string]*time.Time{
+		"dateOfBirth": &query.DateOfBirth,
+		"bornFrom":    &query.BornFrom,
+		"bornTo":      &query.BornTo,
+	} {
+		if value := c.Query(name); value != "" {
+			if *date, err = time.Parse("2006-01-02", value); err != nil {
+				c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid "+name+", expected YYYY-MM-DD"))
+				return
+			}
+		}
 	}
 
 	result, err := h.getPatientsHandler.Handle(c.Request.Context(), query)
@@ -122,7 +244,7 @@ func (h *PatientHandler) ListPatients(c *gin.Context) {
 		switch err.(type) {
 		case *errors.APIError:
 			apiErr := err.(*errors.APIError)
-			c.JSON(getStatusCodeForError(apiErr.Code), apiErr)
+			c.JSON(errors.StatusCode(apiErr.Code), apiErr)
 		default:
 			c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, "Failed to retrieve patients"))
 		}
@@ -143,19 +265,57 @@ func (h *PatientHandler) GetPatient(c *gin.Context) {
 		switch err.(type) {
 		case *errors.APIError:
 			apiErr := err.(*errors.APIError)
-			c.JSON(getStatusCodeForError(apiErr.Code), apiErr)
+			c.JSON(errors.StatusCode(apiErr.Code), apiErr)
 		default:
 			c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, "Failed to fetch patient"))
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, patient)
+	// A merged record only points to the patient it was merged into
+	if patient.MergedInto != nil {
+		target := patient.MergedInto.String()
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, id)+target)
+		c.JSON(http.StatusPermanentRedirect, gin.H{"mergedInto": target})
+		return
+	}
+
+	banners := []domain.Banner{}
+	for _, source := range h.bannerSources {
+		if !source.allowed(c) {
+			continue
+		}
+		found, err := source.source.Banners(c.Request.Context(), patient.ID.String())
+		if err != nil {
+			h.logger.Error("Failed to fetch patient banners", err)
+			c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, "Failed to fetch patient"))
+			return
+		}
+		banners = append(banners, found...)
+	}
+
+	c.Header("ETag", etag(patient))
+	c.JSON(http.StatusOK, patientDetails{Patient: patient, Banners: banners})
 }
 
-// UpdatePatient handles the request to update a patient
+// patientDetails is a patient as fetched on its own, along with the banners
+// to show with it
+type patientDetails struct {
+	*domain.Patient
+	Banners []domain.Banner `json:"banners"`
+}
+
+// UpdatePatient handles the request to update a patient. The If-Match header
+// must carry the ETag of the version the changes were made on, so that
+// concurrent edits cannot silently overwrite each other.
 func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	id := c.Param("id")
+	version, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, errors.NewAPIError(errors.ErrPreconditionRequired, "If-Match header with the patient's ETag is required"))
+		return
+	}
+
 	var cmd commands.UpdatePatientCommand
 	if err := c.ShouldBindJSON(&cmd); err != nil {
 		h.logger.Error("Invalid request body", err)
@@ -164,6 +324,8 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	}
 
 	cmd.ID = id
+	cmd.AuthorID = c.GetString("userID")
+	cmd.ExpectedVersion = version
 
 	// Log the received command
 	h.logger.Info("Received update patient command",
@@ -179,9 +341,11 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	if err != nil {
 		h.logger.Error("Failed to update patient", err)
 		switch err.(type) {
+		case *commands.StaleVersionError:
+			h.respondWithStaleVersion(c, err.(*commands.StaleVersionError))
 		case *errors.APIError:
 			apiErr := err.(*errors.APIError)
-			c.JSON(getStatusCodeForError(apiErr.Code), apiErr)
+			c.JSON(errors.StatusCode(apiErr.Code), apiErr)
 		default:
 			c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, "Failed to update patient"))
 		}
@@ -198,23 +362,266 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 		"weight", patient.Weight,
 	)
 
+	c.Header("ETag", etag(patient))
 	c.JSON(http.StatusOK, patient)
 }
 
-// getStatusCodeForError returns the appropriate HTTP status code for an error code
-func getStatusCodeForError(code string) int {
-	switch code {
-	case errors.ErrValidation:
-		return http.StatusBadRequest
-	case errors.ErrNotFound:
-		return http.StatusNotFound
-	case errors.ErrUnauthorized:
-		return http.StatusUnauthorized
-	case errors.ErrForbidden:
-		return http.StatusForbidden
-	case errors.ErrRateLimit:
-		return http.StatusTooManyRequests
+// PatchPatient handles the request to partially update a patient. The body
+// is a JSON Merge Patch, or a JSON Patch when sent as such. Like updates,
+// patches must name the version they apply to in If-Match.
+func (h *PatientHandler) PatchPatient(c *gin.Context) {
+	id := c.Param("id")
+	version, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, errors.NewAPIError(errors.ErrPreconditionRequired, "If-Match header with the patient's ETag is required"))
+		return
+	}
+
+	var format commands.PatchFormat
+	switch c.ContentType() {
+	case jsonpatch.MergePatchMediaType, "application/json":
+		format = commands.PatchFormatMerge
+	case jsonpatch.JSONPatchMediaType:
+		format = commands.PatchFormatJSONPatch
 	default:
-		return http.StatusInternalServerError
+		c.JSON(http.StatusUnsupportedMediaType, errors.NewAPIError(errors.ErrUnsupportedMediaType,
+			"Patches must be sent as "+jsonpatch.MergePatchMediaType+" or "+jsonpatch.JSONPatchMediaType))
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+
+	cmd := commands.PatchPatientCommand{
+		ID:              id,
+		AuthorID:        c.GetString("userID"),
+		ExpectedVersion: version,
+		Format:          format,
+		Patch:           patch,
+	}
+	h.logger.Info("Received patch patient command", "id", id, "format", format)
+
+	patient, err := h.patchPatientHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.logger.Error("Failed to patch patient", err)
+		if stale, ok := err.(*commands.StaleVersionError); ok {
+			h.respondWithStaleVersion(c, stale)
+			return
+		}
+		h.respondWithError(c, err, "Failed to patch patient")
+		return
+	}
+
+	h.logger.Info("Patched patient", "id", patient.ID)
+
+	c.Header("ETag", etag(patient))
+	c.JSON(http.StatusOK, patient)
+}
+
+// ArchivePatient handles the request to archive a patient with a reason
+func (h *PatientHandler) ArchivePatient(c *gin.Context) {
+	var cmd commands.ArchivePatientCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "A reason is required"))
+		return
+	}
+	cmd.ID = c.Param("id")
+	cmd.AuthorID = c.GetString("userID")
+
+	patient, err := h.archivePatientHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.logger.Error("Failed to archive patient", err)
+		if stale, ok := err.(*commands.StaleVersionError); ok {
+			h.respondWithStaleVersion(c, stale)
+			return
+		}
+		h.respondWithError(c, err, "Failed to archive patient")
+		return
+	}
+
+	h.logger.Info("Archived patient", "id", patient.ID)
+
+	c.Header("ETag", etag(patient))
+	c.JSON(http.StatusOK, patient)
+}
+
+// RestorePatient handles the request to restore an archived patient with a reason
+func (h *PatientHandler) RestorePatient(c *gin.Context) {
+	var cmd commands.RestorePatientCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "A reason is required"))
+		return
+	}
+	cmd.ID = c.Param("id")
+	cmd.AuthorID = c.GetString("userID")
+
+	patient, err := h.restorePatientHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.logger.Error("Failed to restore patient", err)
+		if stale, ok := err.(*commands.StaleVersionError); ok {
+			h.respondWithStaleVersion(c, stale)
+			return
+		}
+		h.respondWithError(c, err, "Failed to restore patient")
+		return
+	}
+
+	h.logger.Info("Restored patient", "id", patient.ID)
+
+	c.Header("ETag", etag(patient))
+	c.JSON(http.StatusOK, patient)
+}
+
+// PurgePatient handles the request to permanently remove an archived patient
+// whose retention period has passed
+func (h *PatientHandler) PurgePatient(c *gin.Context) {
+	cmd := commands.PurgePatientCommand{ID: c.Param("id")}
+
+	if err := h.purgePatientHandler.Handle(c.Request.Context(), cmd); err != nil {
+		h.logger.Error("Failed to purge patient", err)
+		h.respondWithError(c, err, "Failed to purge patient")
+		return
+	}
+
+	h.logger.Warn("Purged patient", "id", cmd.ID, "by", c.GetString("userID"))
+
+	c.Status(http.StatusNoContent)
+}
+
+// MergePatients handles the request to merge a duplicate patient into the
+// patient in the path. The duplicate is archived and redirects to the kept
+// record from then on.
+func (h *PatientHandler) MergePatients(c *gin.Context) {
+	var cmd commands.MergePatientsCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "The ID of the patient to merge is required"))
+		return
+	}
+	cmd.TargetID = c.Param("id")
+	cmd.AuthorID = c.GetString("userID")
+
+	patient, err := h.mergePatientsHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.logger.Error("Failed to merge patients", err)
+		h.respondWithError(c, err, "Failed to merge patients")
+		return
+	}
+
+	h.logger.Warn("Merged patients", "source", cmd.SourceID, "target", patient.ID, "by", cmd.AuthorID)
+
+	c.Header("ETag", etag(patient))
+	c.JSON(http.StatusOK, patient)
+}
+
+// respondWithStaleVersion rejects a change made on an outdated version with
+// the record as currently stored
+func (h *PatientHandler) respondWithStaleVersion(c *gin.Context, err *commands.StaleVersionError) {
+	c.Header("ETag", etag(err.Current))
+	c.JSON(http.StatusPreconditionFailed, staleVersionResponse{
+		APIError: errors.NewAPIError(errors.ErrPreconditionFailed, "Patient was modified since it was read"),
+		Current:  err.Current,
+	})
+}
+
+// staleVersionResponse is the body of a rejected update, it shows the
+// record as currently stored
+type staleVersionResponse struct {
+	*errors.APIError
+	Current *domain.Patient `json:"current"`
+}
+
+// duplicatePatientResponse is the body of a rejected create, it lists the
+// existing patients the new one resembles
+type duplicatePatientResponse struct {
+	*errors.APIError
+	Candidates []domain.DuplicateCandidate `json:"candidates"`
+}
+
+// etag returns the entity tag of a patient record, its quoted version
+func etag(patient *domain.Patient) string {
+	return fmt.Sprintf(`"%d"`, patient.Version)
+}
+
+// parseIfMatch returns the version named by an If-Match header. A missing
+// header or "*" does not name a version. Tags that are not a version, such
+// as weak ones, match no version at all.
+func parseIfMatch(header string) (int, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return -1, true
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil {
+		return -1, true
+	}
+	return version, true
+}
+
+// ListRevisions handles the request to fetch the change history of a patient
+func (h *PatientHandler) ListRevisions(c *gin.Context) {
+	query := queries.ListRevisionsQuery{PatientID: c.Param("id")}
+
+	revisions, err := h.listRevisionsHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.logger.Error("Failed to fetch patient revisions", err)
+		h.respondWithError(c, err, "Failed to fetch patient revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// DiffRevisions handles the request to compare two revisions of a patient
+func (h *PatientHandler) DiffRevisions(c *gin.Context) {
+	var query queries.DiffRevisionsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid revision numbers"))
+		return
+	}
+	query.PatientID = c.Param("id")
+
+	diff, err := h.diffRevisionsHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.logger.Error("Failed to compare patient revisions", err)
+		h.respondWithError(c, err, "Failed to compare patient revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// GetRevisionAt handles the request to fetch a patient record as it was at
+// the time given in RFC 3339 format
+func (h *PatientHandler) GetRevisionAt(c *gin.Context) {
+	var query queries.GetRevisionAtQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid time, expected RFC 3339"))
+		return
+	}
+	query.PatientID = c.Param("id")
+
+	revision, err := h.getRevisionAtHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.logger.Error("Failed to fetch patient revision", err)
+		h.respondWithError(c, err, "Failed to fetch patient revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// respondWithError writes an API error as is and hides any other error
+// behind the given message
+func (h *PatientHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		c.JSON(errors.StatusCode(apiErr.Code), apiErr)
+		return
 	}
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
 }
@@ -5,18 +5,28 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dksch/pococlinic/internal/features/patients/commands"
 	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/features/patients/infrastructure"
 	"github.com/dksch/pococlinic/internal/features/patients/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
 	"github.com/dksch/pococlinic/internal/pkg/errors"
 	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// allowAll lets every request through to the routes under test
+var allowAll = authz.AllowAll[Access]()
+
 // MockGetPatientHandler is a mock implementation of the GetPatientHandler interface
 type MockGetPatientHandler struct {
 	mock.Mock
@@ -89,12 +99,12 @@ func TestGetPatient(t *testing.T) {
 			tt.setupMock(mockHandler)
 
 			// Create handler with mock
-			handler := NewPatientHandler(nil, nil, mockHandler, nil, logger)
+			handler := NewPatientHandler(nil, nil, mockHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
 
 			// Setup router
 			router := gin.New()
 			api := router.Group("/api")
-			handler.RegisterRoutes(api)
+			handler.RegisterRoutes(api, allowAll)
 
 			// Create request
 			w := httptest.NewRecorder()
@@ -119,3 +129,355 @@ func TestGetPatient(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterRoutesAppliesGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	testID := uuid.New()
+	mockHandler := new(MockGetPatientHandler)
+	mockHandler.On("Handle", mock.Anything, queries.GetPatientQuery{ID: testID.String()}).Return(
+		&domain.Patient{ID: testID}, nil)
+
+	handler := NewPatientHandler(nil, nil, mockHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+
+	var requested []Access
+	guard := func(access Access) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			requested = append(requested, access)
+			if c.GetHeader("X-Deny") != "" {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+		}
+	}
+
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), guard)
+
+	// Allowed request reaches the handler
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/patients/"+testID.String(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []Access{AccessReadDemographics}, requested)
+
+	// Denied request never reaches the handler
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/patients/"+testID.String(), nil)
+	req.Header.Set("X-Deny", "1")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockHandler.AssertNumberOfCalls(t, "Handle", 1)
+}
+
+// anyone lets every caller see the banners of a source
+func anyone(c *gin.Context) bool {
+	return true
+}
+
+// stubBannerSource returns fixed banners, or fails if err is set
+type stubBannerSource struct {
+	banners []domain.Banner
+	err     error
+}
+
+func (s stubBannerSource) Banners(ctx context.Context, patientID string) ([]domain.Banner, error) {
+	return s.banners, s.err
+}
+
+func TestGetPatientIncludesBanners(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	testID := uuid.New()
+	mockHandler := new(MockGetPatientHandler)
+	mockHandler.On("Handle", mock.Anything, queries.GetPatientQuery{ID: testID.String()}).Return(
+		&domain.Patient{ID: testID, FirstName: "Jane"}, nil)
+
+	handler := NewPatientHandler(nil, nil, mockHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), allowAll)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/patients/"+testID.String(), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without sources the banners are empty, not missing
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, string(mustField(t, w.Body.Bytes(), "banners")))
+
+	allergy := domain.Banner{Kind: "allergies", Level: "critical", Message: "Penicillin allergy (severe)"}
+	handler.AddBannerSource(stubBannerSource{banners: []domain.Banner{allergy}}, anyone)
+	w = get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		FirstName string          `json:"firstName"`
+		Banners   []domain.Banner `json:"banners"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Jane", response.FirstName)
+	assert.Equal(t, []domain.Banner{allergy}, response.Banners)
+
+	// A failing source fails the request rather than hiding a banner
+	handler.AddBannerSource(stubBannerSource{err: assert.AnError}, anyone)
+	assert.Equal(t, http.StatusInternalServerError, get().Code)
+}
+
+func TestGetPatientShowsBannersOnlyWithAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	testID := uuid.New()
+	mockHandler := new(MockGetPatientHandler)
+	mockHandler.On("Handle", mock.Anything, queries.GetPatientQuery{ID: testID.String()}).Return(
+		&domain.Patient{ID: testID, FirstName: "Jane"}, nil)
+
+	// The caller's permissions are sent along with the request
+	holds := func(c *gin.Context, permission string) bool {
+		return slices.Contains(c.Request.Header.Values("X-Permission"), permission)
+	}
+	guard := func(access Access) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if !holds(c, string(access)) {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+		}
+	}
+
+	handler := NewPatientHandler(nil, nil, mockHandler, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+	allergy := domain.Banner{Kind: "allergies", Level: "critical", Message: "Penicillin allergy (severe)"}
+	handler.AddBannerSource(stubBannerSource{banners: []domain.Banner{allergy}}, func(c *gin.Context) bool {
+		return holds(c, "patients:read:allergies")
+	})
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), guard)
+
+	// A source without an access check is refused rather than shown to all
+	assert.Panics(t, func() { handler.AddBannerSource(stubBannerSource{}, nil) })
+
+	tests := []struct {
+		name         string
+		permissions  []string
+		expectedCode int
+		banners      string
+	}{
+		{name: "no access", expectedCode: http.StatusForbidden},
+		{name: "demographics only", permissions: []string{"patients:read:demographics"}, expectedCode: http.StatusOK, banners: `[]`},
+		{
+			name:         "demographics and allergies",
+			permissions:  []string{"patients:read:demographics", "patients:read:allergies"},
+			expectedCode: http.StatusOK,
+			banners:      `[{"kind": "allergies", "level": "critical", "message": "Penicillin allergy (severe)"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/patients/"+testID.String(), nil)
+			for _, permission := range tt.permissions {
+				req.Header.Add("X-Permission", permission)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.banners != "" {
+				assert.JSONEq(t, tt.banners, string(mustField(t, w.Body.Bytes(), "banners")))
+			}
+		})
+	}
+}
+
+// mustField returns the raw JSON of a field of a JSON object
+func mustField(t *testing.T, body []byte, name string) json.RawMessage {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(body, &fields))
+	return fields[name]
+}
+
+func TestUpdatePatientRequiresCurrentVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	assert.NoError(t, repo.Create(context.Background(), patient))
+
+	handler := NewPatientHandler(nil, nil, queries.NewGetPatientHandler(repo), commands.NewUpdatePatientHandler(repo), nil, nil, nil, nil, nil, nil, nil, nil, logger)
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), allowAll)
+
+	update := func(ifMatch, lastName string) *httptest.ResponseRecorder {
+		body := `{"firstName":"Jane","lastName":"` + lastName + `","dateOfBirth":"1990-01-01",
+			"gender":"female","email":"jane@example.com","phoneNumber":"555-0100"}`
+		req, _ := http.NewRequest("PUT", "/api/patients/"+patient.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Reading the record yields its version as ETag
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/patients/"+patient.ID.String(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusPreconditionRequired, update("", "Smith").Code)
+	assert.Equal(t, http.StatusPreconditionRequired, update("*", "Smith").Code)
+
+	w = update(`"1"`, "Smith")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// A second edit based on the first version is rejected with the current state
+	w = update(`"1"`, "Jones")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	var response struct {
+		Code    string         `json:"code"`
+		Current domain.Patient `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, errors.ErrPreconditionFailed, response.Code)
+	assert.Equal(t, "Smith", response.Current.LastName)
+	assert.Equal(t, 2, response.Current.Version)
+
+	assert.Equal(t, http.StatusPreconditionFailed, update(`W/"2"`, "Jones").Code)
+	assert.Equal(t, http.StatusOK, update(`"2"`, "Jones").Code)
+}
+
+func TestPatchPatientContentTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	assert.NoError(t, repo.Create(context.Background(), patient))
+
+	handler := NewPatientHandler(nil, nil, nil, nil, commands.NewPatchPatientHandler(repo), nil, nil, nil, nil, nil, nil, nil, logger)
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), allowAll)
+
+	patch := func(contentType, ifMatch, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/api/patients/"+patient.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusPreconditionRequired, patch("application/merge-patch+json", "", `{"phoneNumber":"555"}`).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, patch("text/plain", `"1"`, `{"phoneNumber":"555"}`).Code)
+
+	w := patch("application/merge-patch+json", `"1"`, `{"phoneNumber":"555-0199"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = patch("application/json-patch+json", `"2"`, `[{"op":"replace","path":"/firstName","value":""}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = patch("application/json-patch+json", `"2"`, `[{"op":"replace","path":"/firstName","value":"Janet"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var patched domain.Patient
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &patched))
+	assert.Equal(t, "Janet", patched.FirstName)
+	assert.Equal(t, "555-0199", patched.PhoneNumber)
+
+	assert.Equal(t, http.StatusPreconditionFailed, patch("application/merge-patch+json", `"2"`, `{"lastName":"Smith"}`).Code)
+}
+
+func TestUnknownPatientIsNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+
+	repo := infrastructure.NewMemoryRepository()
+	patient := domain.NewPatient("Jane", "Doe", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	assert.NoError(t, repo.Create(context.Background(), patient))
+
+	handler := NewPatientHandler(nil, nil,
+		queries.NewGetPatientHandler(repo),
+		commands.NewUpdatePatientHandler(repo),
+		commands.NewPatchPatientHandler(repo),
+		commands.NewArchivePatientHandler(repo),
+		commands.NewRestorePatientHandler(repo),
+		commands.NewPurgePatientHandler(repo, domain.RetentionPolicy{Years: 10, AgeOfMajority: 18}),
+		commands.NewMergePatientsHandler(repo),
+		nil, nil, nil, logger)
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), allowAll)
+
+	unknown := "/api/patients/" + uuid.New().String()
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+	}{
+		{"Get", "GET", unknown, "", ""},
+		{"Update", "PUT", unknown, "application/json", `{"firstName":"Jane","lastName":"Doe","dateOfBirth":"1990-01-01","gender":"female","email":"jane@example.com","phoneNumber":"555-0100"}`},
+		{"Patch", "PATCH", unknown, "application/merge-patch+json", `{"phoneNumber":"555"}`},
+		{"Archive", "POST", unknown + "/archive", "application/json", `{"reason":"Duplicate"}`},
+		{"Restore", "POST", unknown + "/restore", "application/json", `{"reason":"Archived by mistake"}`},
+		{"Purge", "DELETE", unknown, "", ""},
+		{"Merge into unknown", "POST", unknown + "/merge", "application/json", `{"sourceId":"` + patient.ID.String() + `"}`},
+		{"Merge unknown", "POST", "/api/patients/" + patient.ID.String() + "/merge", "application/json", `{"sourceId":"` + uuid.New().String() + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			req.Header.Set("If-Match", `"1"`)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestCreatePatientNamesPatientForAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := infrastructure.NewMemoryRepository()
+	handler := NewPatientHandler(commands.NewCreatePatientHandler(repo), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, logging.NewLogger())
+
+	// Stands in for the audit recorder, which reads the key after the handler
+	var audited string
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		audited = c.GetString(patientref.ContextKey)
+	})
+	handler.RegisterRoutes(router.Group("/api"), allowAll)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/patients", strings.NewReader(`{
+		"firstName": "Jane", "lastName": "Doe", "dateOfBirth": "1990-03-04", "gender": "female",
+		"email": "jane@example.com", "phoneNumber": "555-0100-100"
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	created := string(mustField(t, w.Body.Bytes(), "id"))
+	assert.Equal(t, `"`+audited+`"`, created)
+}
@@ -3,22 +3,24 @@ package infrastructure
 import (
 	"context"
 	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/dksch/pococlinic/internal/features/patients/domain"
 )
 
-// MemoryRepository is a simple in-memory implementation of the PatientRepository interface
+// MemoryRepository is a simple in-memory implementation of the PatientRepository interface.
+// It stores and hands out copies, so that a change is only kept once it is saved.
 type MemoryRepository struct {
-	patients map[string]*domain.Patient
-	mu       sync.RWMutex
+	patients  map[string]*domain.Patient
+	revisions map[string][]*domain.Revision // key: patient ID, ordered by version
+	mu        sync.RWMutex
 }
 
 // NewMemoryRepository creates a new in-memory patient repository
 func NewMemoryRepository() *MemoryRepository {
 	return &MemoryRepository{
-		patients: make(map[string]*domain.Patient),
+		patients:  make(map[string]*domain.Patient),
+		revisions: make(map[string][]*domain.Revision),
 	}
 }
 
@@ -31,7 +33,7 @@ func (r *MemoryRepository) Create(ctx context.Context, patient *domain.Patient)
 		return fmt.Errorf("patient with ID %s already exists", patient.ID)
 	}
 
-	r.patients[patient.ID.String()] = patient
+	r.save(nil, patient)
 	return nil
 }
 
@@ -40,27 +42,66 @@ func (r *MemoryRepository) Update(ctx context.Context, patient *domain.Patient)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.patients[patient.ID.String()]; !exists {
-		return fmt.Errorf("patient with ID %s not found", patient.ID)
+	prev, exists := r.patients[patient.ID.String()]
+	if !exists {
+		return fmt.Errorf("%w: %s", domain.ErrPatientNotFound, patient.ID)
+	}
+	if prev.Version != patient.Version {
+		return domain.ErrVersionConflict
+	}
+
+	r.save(prev, patient)
+	return nil
+}
+
+// Merge stores both records of a merge at once, see domain.Patient.Merge
+func (r *MemoryRepository) Merge(ctx context.Context, source, target *domain.Patient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prevSource, prevTarget := r.patients[source.ID.String()], r.patients[target.ID.String()]
+	if prevSource == nil || prevTarget == nil {
+		return domain.ErrPatientNotFound
+	}
+	if prevSource.Version != source.Version || prevTarget.Version != target.Version {
+		return domain.ErrVersionConflict
 	}
 
-	r.patients[patient.ID.String()] = patient
+	r.save(prevSource, source)
+	r.save(prevTarget, target)
 	return nil
 }
 
-// Delete removes a patient from the repository
+// save stores a copy of the patient together with its next revision
+func (r *MemoryRepository) save(prev, patient *domain.Patient) {
+	revision := domain.NewRevision(prev, patient)
+	id := patient.ID.String()
+
+	stored := *patient
+	r.patients[id] = &stored
+	r.revisions[id] = append(r.revisions[id], revision)
+}
+
+// Delete permanently removes a patient and its revisions from the repository
 func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.patients[id]; !exists {
-		return fmt.Errorf("patient with ID %s not found", id)
+		return fmt.Errorf("%w: %s", domain.ErrPatientNotFound, id)
 	}
 
 	delete(r.patients, id)
+	delete(r.revisions, id)
 	return nil
 }
 
+// Transaction runs fn. Memory repositories have no transactions, so changes
+// made before a failure are kept.
+func (r *MemoryRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 // GetByID retrieves a patient by their ID
 func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Patient, error) {
 	r.mu.RLock()
@@ -68,10 +109,11 @@ func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Pati
 
 	patient, exists := r.patients[id]
 	if !exists {
-		return nil, fmt.Errorf("patient with ID %s not found", id)
+		return nil, fmt.Errorf("%w: %s", domain.ErrPatientNotFound, id)
 	}
 
-	return patient, nil
+	found := *patient
+	return &found, nil
 }
 
 // List returns all patients in the repository
@@ -81,48 +123,80 @@ func (r *MemoryRepository) List(ctx context.Context) ([]*domain.Patient, error)
 
 	patients := make([]*domain.Patient, 0, len(r.patients))
 	for _, patient := range r.patients {
-		patients = append(patients, patient)
+		found := *patient
+		patients = append(patients, &found)
 	}
 
 	return patients, nil
 }
 
-// ListPaginated returns a paginated list of patients with optional search
-func (r *MemoryRepository) ListPaginated(ctx context.Context, page, pageSize int, search string) ([]*domain.Patient, int64, error) {
+// ListDuplicateCandidates returns the unarchived patients that may duplicate
+// the given one
+func (r *MemoryRepository) ListDuplicateCandidates(ctx context.Context, patient *domain.Patient) ([]*domain.Patient, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Filter patients by search term if provided
-	var filteredPatients []*domain.Patient
-	for _, patient := range r.patients {
-		if search == "" || strings.Contains(strings.ToLower(patient.FullName()), strings.ToLower(search)) {
-			filteredPatients = append(filteredPatients, patient)
+	candidates := []*domain.Patient{}
+	for _, other := range r.patients {
+		if !other.IsArchived() && domain.MayDuplicate(patient, other) {
+			found := *other
+			candidates = append(candidates, &found)
 		}
 	}
+	return candidates, nil
+}
 
-	// Calculate pagination
-	totalCount := int64(len(filteredPatients))
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if start >= len(filteredPatients) {
-		return []*domain.Patient{}, totalCount, nil
-	}
-	if end > len(filteredPatients) {
-		end = len(filteredPatients)
+// ListPaginated returns a paginated list of the patients matching the filter
+func (r *MemoryRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.PatientFilter) ([]*domain.Patient, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filteredPatients := r.list(filter)
+	return paginate(filteredPatients, page, pageSize), int64(len(filteredPatients)), nil
+}
+
+// ListByCursor returns the page of patients matching the filter that the
+// cursor points to
+func (r *MemoryRepository) ListByCursor(ctx context.Context, filter domain.PatientFilter, cursor *domain.PageCursor, limit int) (*domain.PatientPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var pivot *domain.Patient
+	backward := false
+	if cursor != nil {
+		pivot, backward = cursor.Pivot(), cursor.Backward
 	}
+	return pageByCursor(r.list(filter), filter.Sort, pivot, backward, limit), nil
+}
 
-	return filteredPatients[start:end], totalCount, nil
+// list returns copies of the patients matching the filter in its order. The
+// caller must hold the lock.
+func (r *MemoryRepository) list(filter domain.PatientFilter) []*domain.Patient {
+	filteredPatients := []*domain.Patient{}
+	for _, patient := range r.patients {
+		if filter.Matches(patient) {
+			found := *patient
+			filteredPatients = append(filteredPatients, &found)
+		}
+	}
+	filter.Sort.Sort(filteredPatients)
+	return filteredPatients
 }
 
 // GetPatientByID retrieves a patient by their ID
 func (r *MemoryRepository) GetPatientByID(ctx context.Context, id string) (*domain.Patient, error) {
+	return r.GetByID(ctx, id)
+}
+
+// ListRevisions returns the revisions of a patient, oldest first
+func (r *MemoryRepository) ListRevisions(ctx context.Context, patientID string) ([]*domain.Revision, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	patient, exists := r.patients[id]
-	if !exists {
-		return nil, fmt.Errorf("patient with ID %s not found", id)
+	revisions := make([]*domain.Revision, 0, len(r.revisions[patientID]))
+	for _, revision := range r.revisions[patientID] {
+		found := *revision
+		revisions = append(revisions, &found)
 	}
-
-	return patient, nil
+	return revisions, nil
 }
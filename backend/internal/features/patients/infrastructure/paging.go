@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"sort"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+)
+
+// paginate returns the given page of the patients
+func paginate(patients []*domain.Patient, page, pageSize int) []*domain.Patient {
+	start := (page - 1) * pageSize
+	if start >= len(patients) {
+		return []*domain.Patient{}
+	}
+	return patients[start:min(start+pageSize, len(patients))]
+}
+
+// pageByCursor returns up to limit of the patients, which are in the given
+// order, that follow the pivot, or precede it when backward is set. A nil
+// pivot starts from the beginning. The pivot need not be among the patients.
+func pageByCursor(patients []*domain.Patient, order domain.PatientSort, pivot *domain.Patient, backward bool, limit int) *domain.PatientPage {
+	start, end := 0, len(patients)
+	if pivot != nil {
+		if backward {
+			end = sort.Search(len(patients), func(i int) bool { return !order.Less(patients[i], pivot) })
+		} else {
+			start = sort.Search(len(patients), func(i int) bool { return order.Less(pivot, patients[i]) })
+		}
+	}
+	if backward {
+		start = max(start, end-limit)
+	} else {
+		end = min(end, start+limit)
+	}
+
+	return &domain.PatientPage{
+		Patients:   patients[start:end],
+		TotalCount: int64(len(patients)),
+		HasPrev:    start > 0,
+		HasNext:    end < len(patients),
+	}
+}
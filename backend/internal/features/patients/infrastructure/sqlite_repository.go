@@ -0,0 +1,824 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// dateLayout is the storage format of calendar dates such as the date of birth
+const dateLayout = "2006-01-02"
+
+// sqliteMigrations holds the schema of the patients feature, in order
+var sqliteMigrations = []string{
+	`CREATE TABLE patients (
+		id           TEXT PRIMARY KEY,
+		first_name   TEXT NOT NULL,
+		last_name    TEXT NOT NULL,
+		middle_name  TEXT NOT NULL DEFAULT '',
+		date_of_birth TEXT NOT NULL,
+		gender       TEXT NOT NULL,
+		email        TEXT NOT NULL DEFAULT '',
+		phone_number TEXT NOT NULL DEFAULT '',
+		height       REAL NOT NULL DEFAULT 0,
+		weight       REAL NOT NULL DEFAULT 0,
+		street       TEXT NOT NULL DEFAULT '',
+		city         TEXT NOT NULL DEFAULT '',
+		state        TEXT NOT NULL DEFAULT '',
+		postal_code  TEXT NOT NULL DEFAULT '',
+		country      TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMP NOT NULL,
+		updated_at   TIMESTAMP NOT NULL
+	);
+	CREATE INDEX idx_patients_name ON patients (last_name, first_name);`,
+	// Each revision keeps the full record as JSON. Existing patients get a
+	// first revision without an author; timestamps are stored in UTC.
+	`ALTER TABLE patients ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE patients ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+	CREATE TABLE patient_revisions (
+		patient_id     TEXT NOT NULL,
+		version        INTEGER NOT NULL,
+		author_id      TEXT NOT NULL DEFAULT '',
+		changed_fields TEXT NOT NULL DEFAULT '',
+		created_at     TIMESTAMP NOT NULL,
+		snapshot       TEXT NOT NULL,
+		PRIMARY KEY (patient_id, version)
+	);
+	INSERT INTO patient_revisions (patient_id, version, created_at, snapshot)
+	SELECT id, 1, updated_at, json_object(
+		'id', id, 'firstName', first_name, 'lastName', last_name, 'middleName', middle_name,
+		'dateOfBirth', date_of_birth, 'gender', gender, 'email', email, 'phoneNumber', phone_number,
+		'height', height, 'weight', weight,
+		'address', json_object('street', street, 'city', city, 'state', state,
+			'postalCode', postal_code, 'country', country),
+		'version', 1,
+		'createdAt', replace(replace(created_at, ' +0000 UTC', 'Z'), ' ', 'T'),
+		'updatedAt', replace(replace(updated_at, ' +0000 UTC', 'Z'), ' ', 'T'))
+	FROM patients;`,
+	`ALTER TABLE patients ADD COLUMN archived_at TIMESTAMP;
+	ALTER TABLE patients ADD COLUMN archive_reason TEXT NOT NULL DEFAULT '';
+	ALTER TABLE patient_revisions ADD COLUMN reason TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE patients ADD COLUMN merged_into TEXT;`,
+	// Duplicate candidates are looked up by these fields on every create, the
+	// expressions must stay those of ListDuplicateCandidates
+	`CREATE INDEX idx_patients_date_of_birth ON patients (date_of_birth);
+	CREATE INDEX idx_patients_email ON patients (lower(trim(email)));
+	CREATE INDEX idx_patients_phone_digits ON patients (` + phoneDigitsExpr + `);`,
+	// A name search looks patients up by the keys of their names, see
+	// domain.NameKeys. MigrateSQLite adds the keys of existing patients.
+	`CREATE TABLE patient_name_keys (
+		name_key   TEXT NOT NULL,
+		patient_id TEXT NOT NULL,
+		PRIMARY KEY (name_key, patient_id)
+	);
+	CREATE INDEX idx_patient_name_keys_patient ON patient_name_keys (patient_id);`,
+}
+
+// patientColumns lists the patient columns in the order scanPatient expects
+const patientColumns = `id, first_name, last_name, middle_name, date_of_birth, gender,
+	email, phone_number, height, weight, street, city, state, postal_code, country,
+	version, updated_by, created_at, updated_at, archived_at, archive_reason, merged_into`
+
+// fullNameExpr builds the same full name as domain.Patient.FullName
+const fullNameExpr = `first_name || ' ' ||
+	CASE WHEN middle_name != '' THEN middle_name || ' ' ELSE '' END || last_name`
+
+// MigrateSQLite applies the patients schema to the database and indexes the
+// names of patients stored without their keys
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	if err := database.Migrate(ctx, db, "patients", sqliteMigrations); err != nil {
+		return err
+	}
+	return indexMissingNames(ctx, db)
+}
+
+// indexMissingNames stores the name keys of the patients that have none
+func indexMissingNames(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT `+patientColumns+` FROM patients
+		WHERE NOT EXISTS (SELECT 1 FROM patient_name_keys WHERE patient_id = patients.id)`)
+	if err != nil {
+		return fmt.Errorf("failed to list patients to index: %w", err)
+	}
+	patients, err := scanPatients(rows)
+	if err != nil || len(patients) == 0 {
+		return err
+	}
+
+	return database.InTx(ctx, db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, db)
+		for _, patient := range patients {
+			if err := writeNameKeys(ctx, conn, patient); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SQLiteRepository is a SQLite implementation of the PatientRepository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite patient repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new patient to the repository together with its first revision
+func (r *SQLiteRepository) Create(ctx context.Context, patient *domain.Patient) error {
+	return r.save(ctx, patient, false)
+}
+
+// Update modifies an existing patient in the repository and records the
+// change as a new revision
+func (r *SQLiteRepository) Update(ctx context.Context, patient *domain.Patient) error {
+	return r.save(ctx, patient, true)
+}
+
+// Merge stores both records of a merge in one transaction, see
+// domain.Patient.Merge. The repositories of other features join it through
+// the context when run within Transaction.
+func (r *SQLiteRepository) Merge(ctx context.Context, source, target *domain.Patient) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		for _, patient := range []*domain.Patient{source, target} {
+			if err := r.saveTx(ctx, conn, patient, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// save writes the patient and its next revision in one transaction
+func (r *SQLiteRepository) save(ctx context.Context, patient *domain.Patient, update bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save patient %s: %w", patient.ID, err)
+	}
+	defer tx.Rollback()
+
+	if err := r.saveTx(ctx, tx, patient, update); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveTx writes the patient and its next revision. For an update the stored
+// record is read first to determine what changed.
+func (r *SQLiteRepository) saveTx(ctx context.Context, tx database.Conn, patient *domain.Patient, update bool) error {
+	var (
+		prev *domain.Patient
+		err  error
+	)
+	if update {
+		row := tx.QueryRowContext(ctx, `SELECT `+patientColumns+` FROM patients WHERE id = ?`, patient.ID.String())
+		prev, err = scanPatient(row)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", domain.ErrPatientNotFound, patient.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read patient %s: %w", patient.ID, err)
+		}
+		if prev.Version != patient.Version {
+			return domain.ErrVersionConflict
+		}
+	}
+	revision := domain.NewRevision(prev, patient)
+
+	if update {
+		err = r.update(ctx, tx, patient)
+	} else {
+		err = r.insert(ctx, tx, patient)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeNameKeys(ctx, tx, patient); err != nil {
+		return err
+	}
+	return insertRevision(ctx, tx, revision)
+}
+
+// insert writes a new patient row
+func (r *SQLiteRepository) insert(ctx context.Context, tx database.Conn, patient *domain.Patient) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO patients (`+patientColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		patient.ID.String(),
+		patient.FirstName,
+		patient.LastName,
+		patient.MiddleName,
+		patient.DateOfBirth.Time().Format(dateLayout),
+		string(patient.Gender),
+		patient.Email,
+		patient.PhoneNumber,
+		patient.Height,
+		patient.Weight,
+		patient.Address.Street,
+		patient.Address.City,
+		patient.Address.State,
+		patient.Address.PostalCode,
+		patient.Address.Country,
+		patient.Version,
+		patient.UpdatedBy,
+		patient.CreatedAt.UTC(),
+		patient.UpdatedAt.UTC(),
+		nullTime(patient.ArchivedAt),
+		patient.ArchiveReason,
+		nullUUID(patient.MergedInto),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create patient %s: %w", patient.ID, err)
+	}
+	return nil
+}
+
+// update overwrites an existing patient row
+func (r *SQLiteRepository) update(ctx context.Context, tx database.Conn, patient *domain.Patient) error {
+	result, err := tx.ExecContext(ctx, `UPDATE patients SET
+		first_name = ?, last_name = ?, middle_name = ?, date_of_birth = ?, gender = ?,
+		email = ?, phone_number = ?, height = ?, weight = ?,
+		street = ?, city = ?, state = ?, postal_code = ?, country = ?,
+		version = ?, updated_by = ?, updated_at = ?, archived_at = ?, archive_reason = ?, merged_into = ?
+		WHERE id = ?`,
+		patient.FirstName,
+		patient.LastName,
+		patient.MiddleName,
+		patient.DateOfBirth.Time().Format(dateLayout),
+		string(patient.Gender),
+		patient.Email,
+		patient.PhoneNumber,
+		patient.Height,
+		patient.Weight,
+		patient.Address.Street,
+		patient.Address.City,
+		patient.Address.State,
+		patient.Address.PostalCode,
+		patient.Address.Country,
+		patient.Version,
+		patient.UpdatedBy,
+		patient.UpdatedAt.UTC(),
+		nullTime(patient.ArchivedAt),
+		patient.ArchiveReason,
+		nullUUID(patient.MergedInto),
+		patient.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update patient %s: %w", patient.ID, err)
+	}
+	return expectOneRow(result, patient.ID.String())
+}
+
+// writeNameKeys replaces the keys the patient's names are looked up by
+func writeNameKeys(ctx context.Context, tx database.Conn, patient *domain.Patient) error {
+	id := patient.ID.String()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM patient_name_keys WHERE patient_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to index names of patient %s: %w", id, err)
+	}
+	for _, key := range domain.NameKeys(patient.FirstName, patient.MiddleName, patient.LastName) {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO patient_name_keys (name_key, patient_id) VALUES (?, ?)`, key, id); err != nil {
+			return fmt.Errorf("failed to index names of patient %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// insertRevision stores a revision with its snapshot encoded as JSON
+func insertRevision(ctx context.Context, tx database.Conn, revision *domain.Revision) error {
+	snapshot, err := json.Marshal(revision.Patient)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision of patient %s: %w", revision.PatientID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO patient_revisions
+		(patient_id, version, author_id, changed_fields, reason, created_at, snapshot)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		revision.PatientID.String(),
+		revision.Version,
+		revision.AuthorID,
+		strings.Join(revision.ChangedFields, ","),
+		revision.Reason,
+		revision.CreatedAt.UTC(),
+		string(snapshot),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record revision of patient %s: %w", revision.PatientID, err)
+	}
+	return nil
+}
+
+// Delete permanently removes a patient and its revisions from the repository
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		result, err := conn.ExecContext(ctx, `DELETE FROM patients WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete patient %s: %w", id, err)
+		}
+		if err := expectOneRow(result, id); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM patient_revisions WHERE patient_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete revisions of patient %s: %w", id, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM patient_name_keys WHERE patient_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete name keys of patient %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// Transaction runs fn in a transaction that Delete, Merge and the
+// repositories of other features join through the context
+func (r *SQLiteRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return database.InTx(ctx, r.db, fn)
+}
+
+// GetByID retrieves a patient by their ID
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*domain.Patient, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+patientColumns+` FROM patients WHERE id = ?`, id)
+	patient, err := scanPatient(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrPatientNotFound, id)
+	}
+	return patient, err
+}
+
+// List returns all patients in the repository
+func (r *SQLiteRepository) List(ctx context.Context) ([]*domain.Patient, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+patientColumns+` FROM patients ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patients: %w", err)
+	}
+	return scanPatients(rows)
+}
+
+// ListDuplicateCandidates returns the unarchived patients that may duplicate
+// the given one, see domain.MayDuplicate. Each criterion is backed by an
+// index, so only those patients are read.
+func (r *SQLiteRepository) ListDuplicateCandidates(ctx context.Context, patient *domain.Patient) ([]*domain.Patient, error) {
+	dob := patient.DateOfBirth.Time()
+	conditions := []string{"date_of_birth = ?"}
+	args := []any{dob.Format(dateLayout)}
+	if swapped, ok := domain.SwappedDayMonth(dob); ok {
+		conditions = append(conditions, "date_of_birth = ?")
+		args = append(args, swapped.Format(dateLayout))
+	}
+	if phone := domain.Digits(patient.PhoneNumber); len(phone) >= 7 {
+		conditions = append(conditions, phoneDigitsExpr+" = ?")
+		args = append(args, phone)
+	}
+	if email := domain.NormalizeEmail(patient.Email); email != "" {
+		conditions = append(conditions, "lower(trim(email)) = ?")
+		args = append(args, email)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+patientColumns+` FROM patients
+		WHERE archived_at IS NULL AND (`+strings.Join(conditions, " OR ")+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate candidates: %w", err)
+	}
+	return scanPatients(rows)
+}
+
+// ListPaginated returns a paginated list of the patients matching the filter.
+// A name search is paged in memory, see listMatching.
+func (r *SQLiteRepository) ListPaginated(ctx context.Context, page, pageSize int, filter domain.PatientFilter) ([]*domain.Patient, int64, error) {
+	where, args := patientConditions(filter)
+
+	if filter.Search != "" {
+		matched, err := r.listMatching(ctx, filter, where, args)
+		if err != nil {
+			return nil, 0, err
+		}
+		return paginate(matched, page, pageSize), int64(len(matched)), nil
+	}
+
+	totalCount, err := r.count(ctx, where, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+patientColumns+` FROM patients `+where+patientOrder(filter.Sort, false)+` LIMIT ? OFFSET ?`,
+		append(args, pageSize, (page-1)*pageSize)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list patients: %w", err)
+	}
+
+	patients, err := scanPatients(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return patients, totalCount, nil
+}
+
+// ListByCursor returns the page of patients matching the filter that the
+// cursor points to. Pages are selected by the sort values the cursor holds,
+// so their cost does not grow with the position in the listing.
+// A name search is the exception: all its matches are read to find the page,
+// see listMatching.
+func (r *SQLiteRepository) ListByCursor(ctx context.Context, filter domain.PatientFilter, cursor *domain.PageCursor, limit int) (*domain.PatientPage, error) {
+	var pivot *domain.Patient
+	backward := false
+	if cursor != nil {
+		pivot, backward = cursor.Pivot(), cursor.Backward
+	}
+
+	where, args := patientConditions(filter)
+	if filter.Search != "" {
+		matched, err := r.listMatching(ctx, filter, where, args)
+		if err != nil {
+			return nil, err
+		}
+		return pageByCursor(matched, filter.Sort, pivot, backward, limit), nil
+	}
+
+	totalCount, err := r.count(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.PatientPage{TotalCount: totalCount}
+
+	pageWhere, pageArgs := where, args
+	if pivot != nil {
+		condition, conditionArgs := keysetCondition(filter.Sort, pivot, backward)
+		pageWhere, pageArgs = andWhere(where, condition), append(append([]any{}, args...), conditionArgs...)
+
+		// Whether anything lies on the other side of the cursor, counting
+		// a patient with the cursor's own values
+		condition, conditionArgs = keysetCondition(filter.Sort, pivot, backward)
+		var beyond bool
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM patients `+andWhere(where, "NOT "+condition)+`)`,
+			append(append([]any{}, args...), conditionArgs...)...,
+		).Scan(&beyond); err != nil {
+			return nil, fmt.Errorf("failed to list patients: %w", err)
+		}
+		page.HasNext, page.HasPrev = backward && beyond, !backward && beyond
+	}
+
+	// One more row than needed tells whether the listing continues
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+patientColumns+` FROM patients `+pageWhere+patientOrder(filter.Sort, backward)+` LIMIT ?`,
+		append(pageArgs, limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patients: %w", err)
+	}
+	patients, err := scanPatients(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(patients) > limit
+	if more {
+		patients = patients[:limit]
+	}
+	if backward {
+		slices.Reverse(patients)
+		page.HasPrev = more
+	} else {
+		page.HasNext = more
+	}
+	page.Patients = patients
+	return page, nil
+}
+
+// listMatching reads the patients passing the conditions in the order of the
+// filter and keeps those matching its name search. The index of name keys
+// narrows the rows read down to the patients that share a key with every
+// searched word; these are still read in full and matched in memory, so a
+// search for a common name costs as many rows as there are patients called
+// alike, whichever page is asked for.
+func (r *SQLiteRepository) listMatching(ctx context.Context, filter domain.PatientFilter, where string, args []any) ([]*domain.Patient, error) {
+	searchKeys := filter.SearchKeys()
+	if len(searchKeys) == 0 {
+		return []*domain.Patient{}, nil
+	}
+	args = slices.Clone(args)
+	for _, keys := range searchKeys {
+		where = andWhere(where, `id IN (SELECT patient_id FROM patient_name_keys
+			WHERE name_key IN (?`+strings.Repeat(", ?", len(keys)-1)+`))`)
+		for _, key := range keys {
+			args = append(args, key)
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+patientColumns+` FROM patients `+where+patientOrder(filter.Sort, false), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patients: %w", err)
+	}
+	patients, err := scanPatients(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*domain.Patient{}
+	for _, patient := range patients {
+		if filter.MatchesName(patient) {
+			matched = append(matched, patient)
+		}
+	}
+	return matched, nil
+}
+
+// count returns the number of patients passing the conditions
+func (r *SQLiteRepository) count(ctx context.Context, where string, args []any) (int64, error) {
+	var totalCount int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM patients `+where, args...).Scan(&totalCount); err != nil {
+		return 0, fmt.Errorf("failed to count patients: %w", err)
+	}
+	return totalCount, nil
+}
+
+// phoneDigitsExpr strips the usual separators from the phone number, like
+// domain.Digits does
+const phoneDigitsExpr = `replace(replace(replace(replace(replace(replace(replace(
+	phone_number, ' ', ''), '-', ''), '(', ''), ')', ''), '+', ''), '.', ''), '/', '')`
+
+// patientConditions translates the filter, apart from the name search, into
+// a WHERE clause and its arguments
+func patientConditions(filter domain.PatientFilter) (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	add := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if !filter.IncludeArchived {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+	if filter.Gender != "" {
+		add("gender = ?", string(filter.Gender))
+	}
+	if !filter.BornFrom.IsZero() {
+		add("date_of_birth >= ?", filter.BornFrom.Format(dateLayout))
+	}
+	if !filter.BornTo.IsZero() {
+		add("date_of_birth <= ?", filter.BornTo.Format(dateLayout))
+	}
+	if filter.City != "" {
+		add("lower(city) = lower(?)", strings.TrimSpace(filter.City))
+	}
+	if filter.PostalCode != "" {
+		add("lower(replace(replace(postal_code, ' ', ''), '-', '')) = lower(?)", domain.NormalizePostalCode(filter.PostalCode))
+	}
+	if filter.Email != "" {
+		add("lower(email) = lower(?)", strings.TrimSpace(filter.Email))
+	}
+	if phone := domain.Digits(filter.Phone); phone != "" {
+		add(phoneDigitsExpr+" LIKE '%' || ? || '%'", phone)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// sortColumn is an expression a listing is ordered by together with the
+// value it has for a patient
+type sortColumn struct {
+	expr  string
+	value func(*domain.Patient) any
+}
+
+// patientSortColumns lists the columns each sort field orders by. Names
+// compare without regard to case like in domain.PatientSort.
+var patientSortColumns = map[domain.SortField][]sortColumn{
+	domain.SortByCreatedAt: {
+		{"created_at", func(p *domain.Patient) any { return p.CreatedAt.UTC() }},
+	},
+	domain.SortByUpdatedAt: {
+		{"updated_at", func(p *domain.Patient) any { return p.UpdatedAt.UTC() }},
+	},
+	domain.SortByName: {
+		{"last_name COLLATE NOCASE", func(p *domain.Patient) any { return p.LastName }},
+		{"first_name COLLATE NOCASE", func(p *domain.Patient) any { return p.FirstName }},
+	},
+	domain.SortByDateOfBirth: {
+		{"date_of_birth", func(p *domain.Patient) any { return p.DateOfBirth.Time().Format(dateLayout) }},
+	},
+}
+
+// idColumn breaks ties between equal sort values, always in ascending order
+var idColumn = sortColumn{"id", func(p *domain.Patient) any { return p.ID.String() }}
+
+// sortColumnsOf returns the columns a sort orders by, the ID last
+func sortColumnsOf(sort domain.PatientSort) []sortColumn {
+	columns, ok := patientSortColumns[sort.Field]
+	if !ok {
+		columns = patientSortColumns[domain.SortByCreatedAt]
+	}
+	return append(append([]sortColumn{}, columns...), idColumn)
+}
+
+// patientOrder builds the ORDER BY clause of a listing in the order of
+// domain.PatientSort, or in the opposite order if reverse is set
+func patientOrder(sort domain.PatientSort, reverse bool) string {
+	columns := sortColumnsOf(sort)
+	order := make([]string, 0, len(columns))
+	for i, column := range columns {
+		descending := sort.Descending && i < len(columns)-1
+		if descending != reverse {
+			column.expr += " DESC"
+		}
+		order = append(order, column.expr)
+	}
+	return " ORDER BY " + strings.Join(order, ", ")
+}
+
+// keysetCondition selects the rows listed after the pivot in the order of
+// the sort, or before it when backward is set
+func keysetCondition(sort domain.PatientSort, pivot *domain.Patient, backward bool) (string, []any) {
+	columns := sortColumnsOf(sort)
+	alternatives := make([]string, 0, len(columns))
+	args := []any{}
+	for i, column := range columns {
+		parts := []string{}
+		for _, equal := range columns[:i] {
+			parts = append(parts, equal.expr+" = ?")
+			args = append(args, equal.value(pivot))
+		}
+
+		descending := sort.Descending && i < len(columns)-1
+		op := ">"
+		if descending != backward {
+			op = "<"
+		}
+		parts = append(parts, column.expr+" "+op+" ?")
+		args = append(args, column.value(pivot))
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// andWhere adds a condition to a WHERE clause built by patientConditions
+func andWhere(where, condition string) string {
+	if where == "" {
+		return "WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+// GetPatientByID retrieves a patient by their ID
+func (r *SQLiteRepository) GetPatientByID(ctx context.Context, id string) (*domain.Patient, error) {
+	return r.GetByID(ctx, id)
+}
+
+// ListRevisions returns the revisions of a patient, oldest first
+func (r *SQLiteRepository) ListRevisions(ctx context.Context, patientID string) ([]*domain.Revision, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT version, author_id, changed_fields, reason, created_at, snapshot
+		FROM patient_revisions WHERE patient_id = ? ORDER BY version`,
+		patientID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions of patient %s: %w", patientID, err)
+	}
+	defer rows.Close()
+
+	revisions := []*domain.Revision{}
+	for rows.Next() {
+		var (
+			revision      domain.Revision
+			changedFields string
+			snapshot      string
+		)
+		if err := rows.Scan(&revision.Version, &revision.AuthorID, &changedFields, &revision.Reason, &revision.CreatedAt, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to read revision of patient %s: %w", patientID, err)
+		}
+		if err := json.Unmarshal([]byte(snapshot), &revision.Patient); err != nil {
+			return nil, fmt.Errorf("invalid revision %d of patient %s: %w", revision.Version, patientID, err)
+		}
+		revision.PatientID = revision.Patient.ID
+		revision.ChangedFields = []string{}
+		if changedFields != "" {
+			revision.ChangedFields = strings.Split(changedFields, ",")
+		}
+		revisions = append(revisions, &revision)
+	}
+	return revisions, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPatient reads a patient selected with patientColumns
+func scanPatient(row rowScanner) (*domain.Patient, error) {
+	var (
+		patient     domain.Patient
+		id          string
+		dateOfBirth string
+		gender      string
+		archivedAt  sql.NullTime
+		mergedInto  sql.NullString
+	)
+	err := row.Scan(
+		&id,
+		&patient.FirstName,
+		&patient.LastName,
+		&patient.MiddleName,
+		&dateOfBirth,
+		&gender,
+		&patient.Email,
+		&patient.PhoneNumber,
+		&patient.Height,
+		&patient.Weight,
+		&patient.Address.Street,
+		&patient.Address.City,
+		&patient.Address.State,
+		&patient.Address.PostalCode,
+		&patient.Address.Country,
+		&patient.Version,
+		&patient.UpdatedBy,
+		&patient.CreatedAt,
+		&patient.UpdatedAt,
+		&archivedAt,
+		&patient.ArchiveReason,
+		&mergedInto,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if archivedAt.Valid {
+		patient.ArchivedAt = &archivedAt.Time
+	}
+	if mergedInto.Valid {
+		target, err := uuid.Parse(mergedInto.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge target for patient %s: %w", id, err)
+		}
+		patient.MergedInto = &target
+	}
+
+	if patient.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid patient ID %q: %w", id, err)
+	}
+	dob, err := time.Parse(dateLayout, dateOfBirth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date of birth for patient %s: %w", id, err)
+	}
+	patient.DateOfBirth = domain.Date(dob)
+	patient.Gender = domain.Gender(gender)
+
+	return &patient, nil
+}
+
+// scanPatients reads all patients from rows and closes them
+func scanPatients(rows *sql.Rows) ([]*domain.Patient, error) {
+	defer rows.Close()
+
+	patients := []*domain.Patient{}
+	for rows.Next() {
+		patient, err := scanPatient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patient: %w", err)
+		}
+		patients = append(patients, patient)
+	}
+	return patients, rows.Err()
+}
+
+// expectOneRow reports a not found error when a write matched no patient
+func expectOneRow(result sql.Result, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrPatientNotFound, id)
+	}
+	return nil
+}
+
+// nullTime converts an optional time into a nullable column value
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// nullUUID converts an optional ID into a nullable column value
+func nullUUID(id *uuid.UUID) sql.NullString {
+	if id == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.String(), Valid: true}
+}
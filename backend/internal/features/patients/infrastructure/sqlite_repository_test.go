@@ -0,0 +1,551 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) *SQLiteRepository {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "patients.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db)
+}
+
+func newTestPatient(firstName, lastName string) *domain.Patient {
+	patient := domain.NewPatient(firstName, lastName, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+	patient.Email = "jane@example.com"
+	patient.Address = domain.Address{
+		Street:     "123 Medical Drive",
+		City:       "Healthcare City",
+		State:      "HC",
+		PostalCode: "12345",
+		Country:    "Medical Land",
+	}
+	return patient
+}
+
+func TestSQLiteRepositoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	patient := newTestPatient("Jane", "Doe")
+	patient.MiddleName = "Ann"
+	patient.Height = 170.5
+	require.NoError(t, repo.Create(ctx, patient))
+
+	stored, err := repo.GetByID(ctx, patient.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, patient.ID, stored.ID)
+	assert.Equal(t, patient.FullName(), stored.FullName())
+	assert.Equal(t, patient.DateOfBirth.Time(), stored.DateOfBirth.Time())
+	assert.Equal(t, patient.Address, stored.Address)
+	assert.Equal(t, 170.5, stored.Height)
+	assert.True(t, patient.CreatedAt.Equal(stored.CreatedAt))
+
+	stored.PhoneNumber = "555-0199"
+	stored.Update()
+	require.NoError(t, repo.Update(ctx, stored))
+
+	updated, err := repo.GetPatientByID(ctx, patient.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "555-0199", updated.PhoneNumber)
+
+	require.NoError(t, repo.Delete(ctx, patient.ID.String()))
+	_, err = repo.GetByID(ctx, patient.ID.String())
+	assert.Error(t, err)
+	assert.Error(t, repo.Delete(ctx, patient.ID.String()))
+}
+
+func TestSQLiteRepositoryListPaginated(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	for _, name := range []string{"Alice", "Bob", "Carol", "Alicia"} {
+		require.NoError(t, repo.Create(ctx, newTestPatient(name, "Smith")))
+	}
+
+	patients, total, err := repo.ListPaginated(ctx, 1, 2, domain.PatientFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, patients, 2)
+
+	patients, total, err = repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{Search: "ALI"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, patients, 2)
+
+	// LIKE wildcards in the search term are matched literally
+	_, total, err = repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{Search: "%"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+// TestListPaginatedFilters checks that both repositories filter and order
+// listings alike
+func TestListPaginatedFilters(t *testing.T) {
+	type repository interface {
+		Create(ctx context.Context, patient *domain.Patient) error
+		ListPaginated(ctx context.Context, page, pageSize int, filter domain.PatientFilter) ([]*domain.Patient, int64, error)
+	}
+	repositories := map[string]func(t *testing.T) repository{
+		"memory": func(t *testing.T) repository { return NewMemoryRepository() },
+		"sqlite": func(t *testing.T) repository { return setupSQLiteRepository(t) },
+	}
+
+	born := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPatient := func(first, last string, dob time.Time, gender domain.Gender, city, phone string) *domain.Patient {
+		patient := domain.NewPatient(first, last, dob, gender)
+		patient.Address = domain.Address{City: city, PostalCode: "12 345"}
+		patient.PhoneNumber = phone
+		patient.Email = strings.ToLower(first) + "@example.com"
+		created = created.Add(time.Hour)
+		patient.CreatedAt, patient.UpdatedAt = created, created
+		return patient
+	}
+
+	tests := []struct {
+		name     string
+		filter   domain.PatientFilter
+		expected []string
+	}{
+		{"created order by default", domain.PatientFilter{}, []string{"John", "Jane", "Carol", "Dave"}},
+		{"phonetic name search", domain.PatientFilter{Search: "Jon Smyth"}, []string{"John"}},
+		{"gender", domain.PatientFilter{Gender: domain.GenderFemale}, []string{"Jane", "Carol"}},
+		{"birth range", domain.PatientFilter{BornFrom: born(1980, 1, 1), BornTo: born(1990, 3, 4)}, []string{"Jane", "Carol", "Dave"}},
+		{"city", domain.PatientFilter{City: "SHELBYVILLE"}, []string{"Carol", "Dave"}},
+		{"postal code", domain.PatientFilter{PostalCode: "12345", Gender: domain.GenderMale}, []string{"John", "Dave"}},
+		{"phone digits", domain.PatientFilter{Phone: "0100"}, []string{"John", "Carol"}},
+		{"email", domain.PatientFilter{Email: "DAVE@example.com"}, []string{"Dave"}},
+		{"sorted by name", domain.PatientFilter{Sort: domain.PatientSort{Field: domain.SortByName}}, []string{"Jane", "Carol", "Dave", "John"}},
+		{"sorted by birth, newest first", domain.PatientFilter{Sort: domain.PatientSort{Field: domain.SortByDateOfBirth, Descending: true}}, []string{"John", "Jane", "Dave", "Carol"}},
+		{"search and sort", domain.PatientFilter{Search: "smith", Sort: domain.PatientSort{Field: domain.SortByCreatedAt, Descending: true}}, []string{"Dave", "John"}},
+	}
+
+	for repoName, setup := range repositories {
+		t.Run(repoName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := setup(t)
+			for _, patient := range []*domain.Patient{
+				newPatient("John", "Smith", born(1995, 6, 1), domain.GenderMale, "Springfield", "+1 (555) 010-0100"),
+				newPatient("Jane", "Adams", born(1990, 3, 4), domain.GenderFemale, "Springfield", "555-0200"),
+				newPatient("Carol", "Baker", born(1980, 1, 1), domain.GenderFemale, "Shelbyville", "555 0100"),
+				newPatient("Dave", "Baker-Smith", born(1985, 7, 9), domain.GenderMale, "Shelbyville", ""),
+			} {
+				require.NoError(t, repo.Create(ctx, patient))
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					patients, total, err := repo.ListPaginated(ctx, 1, 10, tt.filter)
+					require.NoError(t, err)
+					names := []string{}
+					for _, patient := range patients {
+						names = append(names, patient.FirstName)
+					}
+					assert.Equal(t, tt.expected, names)
+					assert.Equal(t, int64(len(tt.expected)), total)
+				})
+			}
+
+			// Searches are paged like any other listing
+			patients, total, err := repo.ListPaginated(ctx, 2, 1, domain.PatientFilter{Search: "smith"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), total)
+			require.Len(t, patients, 1)
+			assert.Equal(t, "Dave", patients[0].FirstName)
+		})
+	}
+}
+
+// TestListByCursor walks both repositories page by page in either direction
+func TestSQLiteRepositorySearchByNameKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patient := newTestPatient("John", "Smith")
+	require.NoError(t, repo.Create(ctx, patient))
+	require.NoError(t, repo.Create(ctx, newTestPatient("Jane", "Miller")))
+
+	search := func(term string) []*domain.Patient {
+		patients, total, err := repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{Search: term})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(patients)), total)
+		return patients
+	}
+	require.Len(t, search("Jon Smyth"), 1)
+
+	// The keys follow a change of name
+	patient.LastName = "Miller"
+	require.NoError(t, repo.Update(ctx, patient))
+	assert.Empty(t, search("Smyth"))
+	assert.Len(t, search("Miler"), 2)
+	assert.Empty(t, search("%"))
+
+	require.NoError(t, repo.Delete(ctx, patient.ID.String()))
+	var keys int
+	require.NoError(t, repo.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM patient_name_keys WHERE patient_id = ?`, patient.ID.String()).Scan(&keys))
+	assert.Zero(t, keys)
+}
+
+func TestListByCursor(t *testing.T) {
+	type repository interface {
+		Create(ctx context.Context, patient *domain.Patient) error
+		Update(ctx context.Context, patient *domain.Patient) error
+		Delete(ctx context.Context, id string) error
+		ListPaginated(ctx context.Context, page, pageSize int, filter domain.PatientFilter) ([]*domain.Patient, int64, error)
+		ListByCursor(ctx context.Context, filter domain.PatientFilter, cursor *domain.PageCursor, limit int) (*domain.PatientPage, error)
+	}
+	repositories := map[string]func(t *testing.T) repository{
+		"memory": func(t *testing.T) repository { return NewMemoryRepository() },
+		"sqlite": func(t *testing.T) repository { return setupSQLiteRepository(t) },
+	}
+
+	filters := map[string]domain.PatientFilter{
+		"created":          {},
+		"name descending":  {Sort: domain.PatientSort{Field: domain.SortByName, Descending: true}},
+		"date of birth":    {Sort: domain.PatientSort{Field: domain.SortByDateOfBirth}},
+		"search":           {Search: "Smyth"},
+		"updated filtered": {Gender: domain.GenderFemale, Sort: domain.PatientSort{Field: domain.SortByUpdatedAt}},
+	}
+
+	idsOf := func(patients []*domain.Patient) []string {
+		ids := []string{}
+		for _, patient := range patients {
+			ids = append(ids, patient.ID.String())
+		}
+		return ids
+	}
+
+	for repoName, setup := range repositories {
+		t.Run(repoName, func(t *testing.T) {
+			ctx := context.Background()
+			repo := setup(t)
+
+			// Equal names, birthdays and timestamps make the ID decide
+			created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, name := range []string{"Smith", "Jones", "Smith", "Brown", "Smith", "Jones", "Smith"} {
+				patient := domain.NewPatient("Ann", name, time.Date(1980+i%3, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+				if i%2 == 1 {
+					patient.Gender = domain.GenderMale
+				}
+				patient.CreatedAt = created.Add(time.Duration(i/2) * time.Hour)
+				patient.UpdatedAt = patient.CreatedAt
+				require.NoError(t, repo.Create(ctx, patient))
+			}
+
+			for filterName, filter := range filters {
+				t.Run(filterName, func(t *testing.T) {
+					if filter.Sort.Field == "" {
+						filter.Sort.Field = domain.SortByCreatedAt
+					}
+					all, total, err := repo.ListPaginated(ctx, 1, 100, filter)
+					require.NoError(t, err)
+
+					forward := []*domain.Patient{}
+					var cursor *domain.PageCursor
+					for pages := 0; ; pages++ {
+						require.Less(t, pages, 10)
+						page, err := repo.ListByCursor(ctx, filter, cursor, 2)
+						require.NoError(t, err)
+						assert.Equal(t, total, page.TotalCount)
+						assert.Equal(t, cursor != nil, page.HasPrev)
+						forward = append(forward, page.Patients...)
+						if !page.HasNext {
+							break
+						}
+						next := domain.NewPageCursor(page.Patients[len(page.Patients)-1], filter.Sort, false)
+						cursor = &next
+					}
+					assert.Equal(t, idsOf(all), idsOf(forward))
+
+					backward := []*domain.Patient{}
+					last := domain.NewPageCursor(all[len(all)-1], filter.Sort, true)
+					cursor = &last
+					backward = append(backward, all[len(all)-1])
+					for pages := 0; ; pages++ {
+						require.Less(t, pages, 10)
+						page, err := repo.ListByCursor(ctx, filter, cursor, 2)
+						require.NoError(t, err)
+						assert.True(t, page.HasNext)
+						backward = append(append([]*domain.Patient{}, page.Patients...), backward...)
+						if !page.HasPrev {
+							break
+						}
+						prev := domain.NewPageCursor(page.Patients[0], filter.Sort, true)
+						cursor = &prev
+					}
+					assert.Equal(t, idsOf(all), idsOf(backward))
+				})
+			}
+
+			// New patients ahead of the cursor neither shift nor repeat the next page
+			first, err := repo.ListByCursor(ctx, domain.PatientFilter{}, nil, 3)
+			require.NoError(t, err)
+			early := domain.NewPatient("Ann", "Early", time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), domain.GenderFemale)
+			early.CreatedAt = created.Add(-time.Hour)
+			require.NoError(t, repo.Create(ctx, early))
+			cursor := domain.NewPageCursor(first.Patients[2], domain.PatientSort{}, false)
+			next, err := repo.ListByCursor(ctx, domain.PatientFilter{}, &cursor, 3)
+			require.NoError(t, err)
+			all, _, err := repo.ListPaginated(ctx, 1, 100, domain.PatientFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, idsOf(all[4:7]), idsOf(next.Patients))
+
+			// Editing the cursor's patient moves it, not the page after the
+			// cursor; purging it leaves the cursor usable
+			byUpdate := domain.PatientFilter{Sort: domain.PatientSort{Field: domain.SortByUpdatedAt}}
+			before, _, err := repo.ListPaginated(ctx, 1, 100, byUpdate)
+			require.NoError(t, err)
+			pivot := before[2]
+			cursor = domain.NewPageCursor(pivot, byUpdate.Sort, false)
+			pivot.LastName = "Edited"
+			pivot.UpdatedAt = pivot.UpdatedAt.Add(24 * time.Hour)
+			require.NoError(t, repo.Update(ctx, pivot))
+
+			next, err = repo.ListByCursor(ctx, byUpdate, &cursor, 3)
+			require.NoError(t, err)
+			assert.Equal(t, idsOf(before[3:6]), idsOf(next.Patients))
+			assert.True(t, next.HasPrev)
+			assert.True(t, next.HasNext)
+
+			require.NoError(t, repo.Delete(ctx, pivot.ID.String()))
+			next, err = repo.ListByCursor(ctx, byUpdate, &cursor, 3)
+			require.NoError(t, err)
+			assert.Equal(t, idsOf(before[3:6]), idsOf(next.Patients))
+			assert.True(t, next.HasPrev)
+		})
+	}
+}
+
+func TestSQLiteRepositoryRevisions(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	patient := newTestPatient("Jane", "Doe")
+	patient.UpdatedBy = "author-1"
+	require.NoError(t, repo.Create(ctx, patient))
+	assert.Equal(t, 1, patient.Version)
+
+	stored, err := repo.GetByID(ctx, patient.ID.String())
+	require.NoError(t, err)
+	stored.Address.City = "Elsewhere"
+	stored.UpdateBy("author-2")
+	require.NoError(t, repo.Update(ctx, stored))
+	assert.Equal(t, 2, stored.Version)
+
+	updated, err := repo.GetByID(ctx, patient.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+	assert.Equal(t, "author-2", updated.UpdatedBy)
+
+	revisions, err := repo.ListRevisions(ctx, patient.ID.String())
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Version)
+	assert.Equal(t, "author-1", revisions[0].AuthorID)
+	assert.Equal(t, "Healthcare City", revisions[0].Patient.Address.City)
+	assert.Equal(t, 2, revisions[1].Version)
+	assert.Equal(t, "author-2", revisions[1].AuthorID)
+	assert.Equal(t, []string{"address.city"}, revisions[1].ChangedFields)
+	assert.Equal(t, "Elsewhere", revisions[1].Patient.Address.City)
+	assert.True(t, stored.UpdatedAt.Equal(revisions[1].CreatedAt))
+
+	// Deleting purges the history along with the record
+	require.NoError(t, repo.Delete(ctx, patient.ID.String()))
+	revisions, err = repo.ListRevisions(ctx, patient.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestSQLiteRepositoryArchive(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	active := newTestPatient("Alice", "Smith")
+	archived := newTestPatient("Bob", "Smith")
+	require.NoError(t, repo.Create(ctx, active))
+	require.NoError(t, repo.Create(ctx, archived))
+
+	require.NoError(t, archived.Archive("author-1", "Duplicate registration"))
+	require.NoError(t, repo.Update(ctx, archived))
+
+	stored, err := repo.GetByID(ctx, archived.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, stored.ArchivedAt)
+	assert.True(t, archived.ArchivedAt.Equal(*stored.ArchivedAt))
+	assert.Equal(t, "Duplicate registration", stored.ArchiveReason)
+
+	patients, total, err := repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, patients, 1)
+	assert.Equal(t, active.ID, patients[0].ID)
+
+	_, total, err = repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	require.NoError(t, stored.Restore("author-2", "Registered in error"))
+	require.NoError(t, repo.Update(ctx, stored))
+	restored, err := repo.GetByID(ctx, archived.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, restored.ArchivedAt)
+
+	revisions, err := repo.ListRevisions(ctx, archived.ID.String())
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, "Duplicate registration", revisions[1].Reason)
+	assert.Contains(t, revisions[1].ChangedFields, "archived")
+	assert.Equal(t, "Registered in error", revisions[2].Reason)
+	assert.NotNil(t, revisions[1].Patient.ArchivedAt)
+}
+
+func TestSQLiteRepositoryListDuplicateCandidates(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	dob := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	create := func(firstName string, dob time.Time, edit func(p *domain.Patient)) *domain.Patient {
+		patient := domain.NewPatient(firstName, "Doe", dob, domain.GenderFemale)
+		edit(patient)
+		require.NoError(t, repo.Create(ctx, patient))
+		return patient
+	}
+	sameBirthday := create("Jane", dob, func(p *domain.Patient) {})
+	swapped := create("Jane", time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC), func(p *domain.Patient) {})
+	samePhone := create("Janet", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), func(p *domain.Patient) { p.PhoneNumber = "555.010.0100" })
+	sameEmail := create("Joan", time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), func(p *domain.Patient) { p.Email = "JANE@example.com " })
+	create("John", time.Date(1980, 5, 6, 0, 0, 0, 0, time.UTC), func(p *domain.Patient) { p.Email = "john@example.com" })
+	archived := create("Jane", dob, func(p *domain.Patient) {})
+	require.NoError(t, archived.Archive("author-1", "Moved away"))
+	require.NoError(t, repo.Update(ctx, archived))
+
+	patient := domain.NewPatient("Jane", "Doe", dob, domain.GenderFemale)
+	patient.PhoneNumber = "(555) 010-0100"
+	patient.Email = "jane@example.com"
+	candidates, err := repo.ListDuplicateCandidates(ctx, patient)
+	require.NoError(t, err)
+
+	ids := []string{}
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ID.String())
+	}
+	assert.ElementsMatch(t, []string{
+		sameBirthday.ID.String(), swapped.ID.String(), samePhone.ID.String(), sameEmail.ID.String(),
+	}, ids)
+}
+
+func TestSQLiteRepositoryDeleteInTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patient := newTestPatient("Alice", "Smith")
+	require.NoError(t, repo.Create(ctx, patient))
+	id := patient.ID.String()
+
+	// A step of the purge that fails after the delete restores the patient
+	err := repo.Transaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.Delete(ctx, id))
+		return errors.New("failed to delete records")
+	})
+	assert.Error(t, err)
+	_, err = repo.GetByID(ctx, id)
+	require.NoError(t, err)
+	revisions, err := repo.ListRevisions(ctx, id)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1)
+
+	require.NoError(t, repo.Transaction(ctx, func(ctx context.Context) error {
+		return repo.Delete(ctx, id)
+	}))
+	_, err = repo.GetByID(ctx, id)
+	assert.ErrorIs(t, err, domain.ErrPatientNotFound)
+}
+
+func TestSQLiteRepositoryMerge(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	target := newTestPatient("Jane", "Doe")
+	source := newTestPatient("Jane", "Doe")
+	source.PhoneNumber = "555-0100"
+	require.NoError(t, repo.Create(ctx, target))
+	require.NoError(t, repo.Create(ctx, source))
+	outdated := *target
+
+	require.NoError(t, target.Merge(source, "admin-1"))
+	require.NoError(t, repo.Merge(ctx, source, target))
+
+	stored, err := repo.GetByID(ctx, source.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, stored.MergedInto)
+	assert.Equal(t, target.ID, *stored.MergedInto)
+	assert.True(t, stored.IsArchived())
+
+	kept, err := repo.GetByID(ctx, target.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, kept.MergedInto)
+	assert.Equal(t, "555-0100", kept.PhoneNumber)
+	assert.Equal(t, 2, kept.Version)
+
+	revisions, err := repo.ListRevisions(ctx, target.ID.String())
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "Merged from "+source.ID.String(), revisions[1].Reason)
+
+	// Nothing is written when either record is outdated
+	outdated.FirstName = "Janet"
+	assert.ErrorIs(t, repo.Merge(ctx, stored, &outdated), domain.ErrVersionConflict)
+	kept, err = repo.GetByID(ctx, target.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", kept.FirstName)
+}
+
+func TestSQLiteMigrationBackfillsRevisions(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "patients.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// A patient stored before revisions were introduced
+	require.NoError(t, database.Migrate(ctx, db, "patients", sqliteMigrations[:1]))
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	_, err = db.ExecContext(ctx, `INSERT INTO patients
+		(id, first_name, last_name, date_of_birth, gender, city, created_at, updated_at)
+		VALUES ('6f9619ff-8b86-d011-b42d-00c04fc964ff', 'Jane', 'Doe', '1990-01-01', 'female', 'Springfield', ?, ?)`,
+		created, created)
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateSQLite(ctx, db))
+	repo := NewSQLiteRepository(db)
+
+	revisions, err := repo.ListRevisions(ctx, "6f9619ff-8b86-d011-b42d-00c04fc964ff")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, 1, revisions[0].Version)
+	assert.Equal(t, "Jane", revisions[0].Patient.FirstName)
+	assert.Equal(t, "Springfield", revisions[0].Patient.Address.City)
+	assert.Equal(t, "1990-01-01", revisions[0].Patient.DateOfBirth.Time().Format("2006-01-02"))
+	assert.True(t, created.Equal(revisions[0].Patient.CreatedAt))
+	assert.True(t, created.Equal(revisions[0].CreatedAt))
+
+	// Its names are indexed for searches
+	patients, _, err := repo.ListPaginated(ctx, 1, 10, domain.PatientFilter{Search: "Jayne"})
+	require.NoError(t, err)
+	require.Len(t, patients, 1)
+	assert.Equal(t, "6f9619ff-8b86-d011-b42d-00c04fc964ff", patients[0].ID.String())
+}
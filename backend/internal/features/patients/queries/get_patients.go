@@ -2,24 +2,110 @@ package queries
 
 import (
 	"context"
+	stderrors "errors"
+	"time"
 
 	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
 )
 
-// GetPatientsQuery represents the query to retrieve patients
+// GetPatientsQuery represents the query to retrieve patients. DateOfBirth
+// finds patients born on one day, BornFrom and BornTo a range of days. Sort
+// names a domain.SortField and Order is asc, the default, or desc.
+//
+// A Cursor from a previous result selects the page next to it instead of
+// Page; it must be used with the same criteria it was issued for.
 type GetPatientsQuery struct {
 	Page     int    `form:"page,default=1"`
 	PageSize int    `form:"pageSize,default=20"`
+	Cursor   string `form:"cursor"`
 	Search   string `form:"search"`
+
+	Gender      domain.Gender `form:"gender"`
+	DateOfBirth time.Time     `form:"dateOfBirth" time_format:"2006-01-02"`
+	BornFrom    time.Time     `form:"bornFrom" time_format:"2006-01-02"`
+	BornTo      time.Time     `form:"bornTo" time_format:"2006-01-02"`
+	City        string        `form:"city"`
+	PostalCode  string        `form:"postalCode"`
+	Phone       string        `form:"phone"`
+	Email       string        `form:"email"`
+
+	Sort  domain.SortField `form:"sort"`
+	Order string           `form:"order"`
+
+	IncludeArchived bool `form:"includeArchived"`
+}
+
+// filter validates the search criteria of the query and translates them
+// into a patient filter
+func (q GetPatientsQuery) filter() (domain.PatientFilter, error) {
+	filter := domain.PatientFilter{
+		Search:          q.Search,
+		Gender:          q.Gender,
+		BornFrom:        q.BornFrom,
+		BornTo:          q.BornTo,
+		City:            q.City,
+		PostalCode:      q.PostalCode,
+		Phone:           q.Phone,
+		Email:           q.Email,
+		IncludeArchived: q.IncludeArchived,
+		Sort:            domain.PatientSort{Field: q.Sort},
+	}
+
+	if q.Gender != "" && !q.Gender.IsValid() {
+		return filter, errors.NewAPIError(errors.ErrValidation, "Gender must be one of male, female, other or unknown")
+	}
+	if !q.DateOfBirth.IsZero() {
+		if !q.BornFrom.IsZero() || !q.BornTo.IsZero() {
+			return filter, errors.NewAPIError(errors.ErrValidation, "Filter by either a date of birth or a birth range")
+		}
+		filter.BornFrom, filter.BornTo = q.DateOfBirth, q.DateOfBirth
+	}
+	if !q.BornFrom.IsZero() && !q.BornTo.IsZero() && q.BornFrom.After(q.BornTo) {
+		return filter, errors.NewAPIError(errors.ErrValidation, "Birth range must not end before it starts")
+	}
+
+	if q.Sort == "" {
+		filter.Sort.Field = domain.SortByCreatedAt
+	}
+	if !filter.Sort.Field.IsValid() {
+		return filter, errors.NewAPIError(errors.ErrValidation, "Sort must be one of name, dateOfBirth, createdAt or updatedAt")
+	}
+	switch q.Order {
+	case "", "asc":
+	case "desc":
+		filter.Sort.Descending = true
+	default:
+		return filter, errors.NewAPIError(errors.ErrValidation, "Order must be asc or desc")
+	}
+
+	return filter, nil
 }
 
-// PaginatedPatients represents a paginated list of patients
+// PaginatedPatients represents a paginated list of patients. The cursors
+// select the following and the preceding page if there is one; the current
+// page number is unknown, and thus 0, for pages selected by cursor.
 type PaginatedPatients struct {
 	Patients    []*domain.Patient `json:"patients"`
 	TotalCount  int64             `json:"totalCount"`
 	CurrentPage int               `json:"currentPage"`
 	PageSize    int               `json:"pageSize"`
 	TotalPages  int               `json:"totalPages"`
+	NextCursor  string            `json:"nextCursor,omitempty"`
+	PrevCursor  string            `json:"prevCursor,omitempty"`
+}
+
+// setCursors adds the cursors pointing away from the first and last patient
+func (p *PaginatedPatients) setCursors(sort domain.PatientSort, hasPrev, hasNext bool) {
+	if len(p.Patients) == 0 {
+		return
+	}
+	if hasNext {
+		p.NextCursor = domain.NewPageCursor(p.Patients[len(p.Patients)-1], sort, false).Encode()
+	}
+	if hasPrev {
+		p.PrevCursor = domain.NewPageCursor(p.Patients[0], sort, true).Encode()
+	}
 }
 
 // GetPatientsHandler handles the retrieval of patients
@@ -48,25 +134,60 @@ func (h *getPatientsHandler) Handle(ctx context.Context, query GetPatientsQuery)
 		query.PageSize = 20
 	}
 
-	// Get patients with pagination
-	patients, totalCount, err := h.patientRepository.ListPaginated(ctx, query.Page, query.PageSize, query.Search)
+	filter, err := query.filter()
 	if err != nil {
 		return nil, err
 	}
+	if query.Cursor != "" {
+		return h.handleCursor(ctx, query, filter)
+	}
 
-	// Calculate total pages
-	totalPages := int(totalCount) / query.PageSize
-	if int(totalCount)%query.PageSize > 0 {
-		totalPages++
+	// Get patients with pagination
+	patients, totalCount, err := h.patientRepository.ListPaginated(ctx, query.Page, query.PageSize, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return &PaginatedPatients{
+	result := &PaginatedPatients{
 		Patients:    patients,
 		TotalCount:  totalCount,
 		CurrentPage: query.Page,
 		PageSize:    query.PageSize,
-		TotalPages:  totalPages,
-	}, nil
+		TotalPages:  totalPages(totalCount, query.PageSize),
+	}
+	result.setCursors(filter.Sort, query.Page > 1, query.Page < result.TotalPages)
+	return result, nil
+}
+
+// handleCursor returns the page the query's cursor points to
+func (h *getPatientsHandler) handleCursor(ctx context.Context, query GetPatientsQuery, filter domain.PatientFilter) (*PaginatedPatients, error) {
+	cursor, err := domain.DecodePageCursor(query.Cursor)
+	if err != nil || cursor.Sort != filter.Sort {
+		return nil, errors.NewAPIError(errors.ErrValidation, "Invalid cursor")
+	}
+
+	page, err := h.patientRepository.ListByCursor(ctx, filter, &cursor, query.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaginatedPatients{
+		Patients:   page.Patients,
+		TotalCount: page.TotalCount,
+		PageSize:   query.PageSize,
+		TotalPages: totalPages(page.TotalCount, query.PageSize),
+	}
+	result.setCursors(filter.Sort, page.HasPrev, page.HasNext)
+	return result, nil
+}
+
+// totalPages returns the number of pages needed for the given count
+func totalPages(totalCount int64, pageSize int) int {
+	pages := int(totalCount) / pageSize
+	if int(totalCount)%pageSize > 0 {
+		pages++
+	}
+	return pages
 }
 
 // GetPatientQuery represents the query to retrieve a single patient by ID
@@ -90,6 +211,11 @@ func NewGetPatientHandler(repo domain.GetPatientRepository) GetPatientHandler {
 	}
 }
 
+// Handle processes the get patient query
 func (h *getPatientHandler) Handle(ctx context.Context, query GetPatientQuery) (*domain.Patient, error) {
-	return h.patientRepository.GetPatientByID(ctx, query.ID)
+	patient, err := h.patientRepository.GetPatientByID(ctx, query.ID)
+	if stderrors.Is(err, domain.ErrPatientNotFound) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	}
+	return patient, err
 }
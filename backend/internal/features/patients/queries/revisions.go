@@ -0,0 +1,143 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/patients/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// ListRevisionsQuery represents the query to retrieve the change history of a patient
+type ListRevisionsQuery struct {
+	PatientID string `json:"-"`
+}
+
+// ListRevisionsHandler handles the retrieval of a patient's revisions
+type ListRevisionsHandler interface {
+	Handle(ctx context.Context, query ListRevisionsQuery) ([]*domain.Revision, error)
+}
+
+// listRevisionsHandler implements ListRevisionsHandler
+type listRevisionsHandler struct {
+	revisionRepository domain.RevisionRepository
+}
+
+// NewListRevisionsHandler creates a new handler for retrieving a patient's revisions
+func NewListRevisionsHandler(repo domain.RevisionRepository) ListRevisionsHandler {
+	return &listRevisionsHandler{
+		revisionRepository: repo,
+	}
+}
+
+// Handle processes the list revisions query. Revisions are ordered oldest first.
+func (h *listRevisionsHandler) Handle(ctx context.Context, query ListRevisionsQuery) ([]*domain.Revision, error) {
+	revisions, err := h.revisionRepository.ListRevisions(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	}
+	return revisions, nil
+}
+
+// DiffRevisionsQuery represents the query to compare two revisions of a patient
+type DiffRevisionsQuery struct {
+	PatientID string `json:"-"`
+	From      int    `form:"from" binding:"required,min=1"`
+	To        int    `form:"to" binding:"required,min=1"`
+}
+
+// RevisionDiff lists the fields that changed between two revisions
+type RevisionDiff struct {
+	PatientID string               `json:"patientId"`
+	From      int                  `json:"from"`
+	To        int                  `json:"to"`
+	Changes   []domain.FieldChange `json:"changes"`
+}
+
+// DiffRevisionsHandler handles the comparison of two patient revisions
+type DiffRevisionsHandler interface {
+	Handle(ctx context.Context, query DiffRevisionsQuery) (*RevisionDiff, error)
+}
+
+// diffRevisionsHandler implements DiffRevisionsHandler
+type diffRevisionsHandler struct {
+	revisionRepository domain.RevisionRepository
+}
+
+// NewDiffRevisionsHandler creates a new handler for comparing patient revisions
+func NewDiffRevisionsHandler(repo domain.RevisionRepository) DiffRevisionsHandler {
+	return &diffRevisionsHandler{
+		revisionRepository: repo,
+	}
+}
+
+// Handle processes the diff revisions query
+func (h *diffRevisionsHandler) Handle(ctx context.Context, query DiffRevisionsQuery) (*RevisionDiff, error) {
+	revisions, err := h.revisionRepository.ListRevisions(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := findRevision(revisions, query.From), findRevision(revisions, query.To)
+	if from == nil || to == nil {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Revision not found")
+	}
+
+	return &RevisionDiff{
+		PatientID: query.PatientID,
+		From:      from.Version,
+		To:        to.Version,
+		Changes:   domain.DiffPatients(&from.Patient, &to.Patient),
+	}, nil
+}
+
+// findRevision returns the revision with the given version, if any
+func findRevision(revisions []*domain.Revision, version int) *domain.Revision {
+	for _, revision := range revisions {
+		if revision.Version == version {
+			return revision
+		}
+	}
+	return nil
+}
+
+// GetRevisionAtQuery represents the query to retrieve a patient record as it
+// was at a point in time
+type GetRevisionAtQuery struct {
+	PatientID string    `json:"-"`
+	At        time.Time `form:"at" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// GetRevisionAtHandler handles point-in-time retrieval of a patient record
+type GetRevisionAtHandler interface {
+	Handle(ctx context.Context, query GetRevisionAtQuery) (*domain.Revision, error)
+}
+
+// getRevisionAtHandler implements GetRevisionAtHandler
+type getRevisionAtHandler struct {
+	revisionRepository domain.RevisionRepository
+}
+
+// NewGetRevisionAtHandler creates a new handler for point-in-time retrieval
+func NewGetRevisionAtHandler(repo domain.RevisionRepository) GetRevisionAtHandler {
+	return &getRevisionAtHandler{
+		revisionRepository: repo,
+	}
+}
+
+// Handle processes the get revision at query
+func (h *getRevisionAtHandler) Handle(ctx context.Context, query GetRevisionAtQuery) (*domain.Revision, error) {
+	revisions, err := h.revisionRepository.ListRevisions(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := domain.RevisionAt(revisions, query.At)
+	if revision == nil {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Patient did not exist at the given time")
+	}
+	return revision, nil
+}
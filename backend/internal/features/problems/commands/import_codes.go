@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// ImportCodesCommand represents the command to replace the ICD-10 code set
+// with the codes read from a file. Source names the file and Checksum
+// identifies its content.
+type ImportCodesCommand struct {
+	Source   string
+	Checksum string
+	Codes    []domain.Code
+}
+
+// ImportCodesResult describes the code set in use after an import.
+// Unchanged tells that the file had already been imported.
+type ImportCodesResult struct {
+	domain.CodeImport
+	Unchanged bool `json:"unchanged"`
+}
+
+// ImportCodesHandler handles the import codes command
+type ImportCodesHandler interface {
+	Handle(ctx context.Context, cmd ImportCodesCommand) (*ImportCodesResult, error)
+}
+
+type importCodesHandler struct {
+	repo    domain.ImportCodesRepository
+	catalog domain.CodeCatalog
+}
+
+// NewImportCodesHandler creates a new import codes handler. Imported codes
+// are stored and replace those of the catalog.
+func NewImportCodesHandler(repo domain.ImportCodesRepository, catalog domain.CodeCatalog) ImportCodesHandler {
+	return &importCodesHandler{repo: repo, catalog: catalog}
+}
+
+// Handle processes the import codes command. A file with the checksum of
+// the last import is not imported again.
+func (h *importCodesHandler) Handle(ctx context.Context, cmd ImportCodesCommand) (*ImportCodesResult, error) {
+	if len(cmd.Codes) == 0 {
+		return nil, errors.NewAPIError(errors.ErrValidation, "The code set holds no codes")
+	}
+	seen := make(map[string]bool, len(cmd.Codes))
+	for _, code := range cmd.Codes {
+		if seen[code.Code] {
+			return nil, errors.NewAPIError(errors.ErrValidation, fmt.Sprintf("Duplicate ICD-10 code %s", code.Code))
+		}
+		seen[code.Code] = true
+	}
+
+	current, err := h.repo.GetCodeImport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Checksum == cmd.Checksum {
+		return &ImportCodesResult{CodeImport: *current, Unchanged: true}, nil
+	}
+
+	codeImport := domain.CodeImport{
+		Source:     cmd.Source,
+		Checksum:   cmd.Checksum,
+		Codes:      len(cmd.Codes),
+		ImportedAt: time.Now().UTC(),
+	}
+	if err := h.repo.ReplaceCodes(ctx, cmd.Codes, &codeImport); err != nil {
+		return nil, err
+	}
+	h.catalog.Replace(cmd.Codes)
+	return &ImportCodesResult{CodeImport: codeImport}, nil
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// RecordProblemCommand represents the command to add a problem to the
+// problem list of a patient
+type RecordProblemCommand struct {
+	PatientID  string `json:"-"`
+	RecordedBy string `json:"-"`
+	domain.Details
+}
+
+// RecordProblemHandler handles the record problem command
+type RecordProblemHandler interface {
+	Handle(ctx context.Context, cmd RecordProblemCommand) (*domain.Problem, error)
+}
+
+type recordProblemHandler struct {
+	repo     domain.RecordProblemRepository
+	patients patientref.Directory
+	codes    domain.CodeCatalog
+}
+
+// NewRecordProblemHandler creates a new record problem handler
+func NewRecordProblemHandler(repo domain.RecordProblemRepository, patients patientref.Directory, codes domain.CodeCatalog) RecordProblemHandler {
+	return &recordProblemHandler{repo: repo, patients: patients, codes: codes}
+}
+
+// Handle processes the record problem command
+func (h *recordProblemHandler) Handle(ctx context.Context, cmd RecordProblemCommand) (*domain.Problem, error) {
+	details := cmd.Details.Normalize()
+	if err := validateDetails(details); err != nil {
+		return nil, err
+	}
+	code, err := lookupCode(h.codes, details.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	problem := domain.NewProblem(patientID, details, code, cmd.RecordedBy)
+	if err := h.repo.Create(ctx, problem); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
+
+// validateDetails checks the normalized details of a problem
+func validateDetails(details domain.Details) error {
+	if details.Code == "" {
+		return errors.NewAPIError(errors.ErrValidation, "Code is required")
+	}
+	if !details.Status.IsValid() {
+		return errors.NewAPIError(errors.ErrValidation, "Status must be active or resolved")
+	}
+
+	now := time.Now()
+	if details.Onset != "" {
+		if err := domain.ValidateDate(details.Onset, now); err != nil {
+			return errors.NewAPIError(errors.ErrValidation, "Onset must be a past year, month or day (YYYY, YYYY-MM or YYYY-MM-DD)")
+		}
+	}
+	if details.ResolvedOn != "" {
+		if err := domain.ValidateDate(details.ResolvedOn, now); err != nil {
+			return errors.NewAPIError(errors.ErrValidation, "Resolution must be a past year, month or day (YYYY, YYYY-MM or YYYY-MM-DD)")
+		}
+	}
+
+	switch {
+	case details.Status == domain.StatusActive && details.ResolvedOn != "":
+		return errors.NewAPIError(errors.ErrValidation, "An active problem cannot have a resolution date")
+	case details.Status == domain.StatusResolved && details.ResolvedOn == "":
+		return errors.NewAPIError(errors.ErrValidation, "A resolved problem needs a resolution date")
+	case details.Onset != "" && details.ResolvedOn != "" && domain.ResolvedBeforeOnset(details.Onset, details.ResolvedOn):
+		return errors.NewAPIError(errors.ErrValidation, "Resolution cannot be before onset")
+	}
+	return nil
+}
+
+// lookupCode finds a code in the imported code set
+func lookupCode(codes domain.CodeCatalog, code string) (domain.Code, error) {
+	if codes.Len() == 0 {
+		return domain.Code{}, errors.NewAPIError(errors.ErrConflict, "No ICD-10 code set has been imported")
+	}
+	found, ok := codes.Lookup(code)
+	if !ok {
+		return domain.Code{}, errors.NewAPIError(errors.ErrValidation, "Unknown ICD-10 code")
+	}
+	return found, nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/features/problems/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of active patients
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestProblemList(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	index := infrastructure.NewCodeIndex()
+	patientID := uuid.NewString()
+	record := NewRecordProblemHandler(repo, patientDirectory{patientID: true}, index)
+	update := NewUpdateProblemHandler(repo, index)
+	importCodes := NewImportCodesHandler(repo, index)
+
+	_, err := record.Handle(ctx, RecordProblemCommand{PatientID: patientID, Details: domain.Details{Code: "I10"}})
+	assertAPIError(t, err, errors.ErrConflict)
+
+	_, err = importCodes.Handle(ctx, ImportCodesCommand{Source: "codes.txt", Checksum: "v1", Codes: []domain.Code{
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+		{Code: "I10", Title: "Hypertension"},
+	}})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	imported, err := importCodes.Handle(ctx, ImportCodesCommand{Source: "codes.txt", Checksum: "v1", Codes: []domain.Code{
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+		{Code: "J06.9", Title: "Acute upper respiratory infection, unspecified"},
+	}})
+	require.NoError(t, err)
+	assert.False(t, imported.Unchanged)
+	assert.Equal(t, 2, imported.Codes)
+	assert.Equal(t, 2, index.Len())
+
+	_, err = record.Handle(ctx, RecordProblemCommand{PatientID: uuid.NewString(), Details: domain.Details{Code: "I10"}})
+	assertAPIError(t, err, errors.ErrNotFound)
+	_, err = record.Handle(ctx, RecordProblemCommand{PatientID: patientID, Details: domain.Details{Code: "I11"}})
+	assertAPIError(t, err, errors.ErrValidation)
+	_, err = record.Handle(ctx, RecordProblemCommand{PatientID: patientID, Details: domain.Details{Code: "I10", Status: domain.StatusResolved}})
+	assertAPIError(t, err, errors.ErrValidation)
+	_, err = record.Handle(ctx, RecordProblemCommand{PatientID: patientID, Details: domain.Details{Code: "I10", Onset: "2020-05", ResolvedOn: "2020-04"}})
+	assertAPIError(t, err, errors.ErrValidation)
+
+	problem, err := record.Handle(ctx, RecordProblemCommand{
+		PatientID:  patientID,
+		RecordedBy: "dr-1",
+		Details:    domain.Details{Code: "j069", Onset: "2024-01-05"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "J06.9", problem.Code)
+	assert.Equal(t, "Acute upper respiratory infection, unspecified", problem.Title)
+	assert.Equal(t, domain.StatusActive, problem.Status)
+
+	// A new edition of the code set words the code differently
+	_, err = importCodes.Handle(ctx, ImportCodesCommand{Source: "codes.txt", Checksum: "v2", Codes: []domain.Code{
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+		{Code: "J06.9", Title: "Acute upper respiratory infection"},
+	}})
+	require.NoError(t, err)
+
+	resolved, err := update.Handle(ctx, UpdateProblemCommand{
+		PatientID: patientID,
+		ProblemID: problem.ID.String(),
+		UpdatedBy: "dr-2",
+		Details:   domain.Details{Code: "J06.9", Onset: "2024-01-05", ResolvedOn: "2024-01-20"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusResolved, resolved.Status)
+	assert.Equal(t, "Acute upper respiratory infection, unspecified", resolved.Title, "A problem keeping its code keeps its title")
+	assert.Equal(t, "dr-2", resolved.UpdatedBy)
+
+	recoded, err := update.Handle(ctx, UpdateProblemCommand{PatientID: patientID, ProblemID: problem.ID.String(), Details: domain.Details{Code: "I10"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Essential (primary) hypertension", recoded.Title)
+
+	_, err = update.Handle(ctx, UpdateProblemCommand{PatientID: uuid.NewString(), ProblemID: problem.ID.String(), Details: domain.Details{Code: "I10"}})
+	assertAPIError(t, err, errors.ErrNotFound)
+}
+
+func TestImportCodesSkipsUnchangedFile(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	index := infrastructure.NewCodeIndex()
+	importCodes := NewImportCodesHandler(repo, index)
+	codes := []domain.Code{{Code: "I10", Title: "Essential (primary) hypertension"}}
+
+	first, err := importCodes.Handle(ctx, ImportCodesCommand{Source: "codes.txt", Checksum: "abc", Codes: codes})
+	require.NoError(t, err)
+
+	again, err := importCodes.Handle(ctx, ImportCodesCommand{Source: "codes.txt", Checksum: "abc", Codes: codes})
+	require.NoError(t, err)
+	assert.True(t, again.Unchanged)
+	assert.Equal(t, first.ImportedAt, again.ImportedAt)
+
+	_, err = importCodes.Handle(ctx, ImportCodesCommand{Source: "empty.txt", Checksum: "def"})
+	assertAPIError(t, err, errors.ErrValidation)
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// UpdateProblemCommand represents the command to change a problem, for
+// instance to mark it resolved. A problem that keeps its code keeps the
+// title it was coded with, even if a later code set words it differently.
+type UpdateProblemCommand struct {
+	PatientID string `json:"-"`
+	ProblemID string `json:"-"`
+	UpdatedBy string `json:"-"`
+	domain.Details
+}
+
+// UpdateProblemHandler handles the update problem command
+type UpdateProblemHandler interface {
+	Handle(ctx context.Context, cmd UpdateProblemCommand) (*domain.Problem, error)
+}
+
+type updateProblemHandler struct {
+	repo  domain.UpdateProblemRepository
+	codes domain.CodeCatalog
+}
+
+// NewUpdateProblemHandler creates a new update problem handler
+func NewUpdateProblemHandler(repo domain.UpdateProblemRepository, codes domain.CodeCatalog) UpdateProblemHandler {
+	return &updateProblemHandler{repo: repo, codes: codes}
+}
+
+// Handle processes the update problem command
+func (h *updateProblemHandler) Handle(ctx context.Context, cmd UpdateProblemCommand) (*domain.Problem, error) {
+	details := cmd.Details.Normalize()
+	if err := validateDetails(details); err != nil {
+		return nil, err
+	}
+
+	problem, err := h.repo.GetByID(ctx, cmd.ProblemID)
+	if err == domain.ErrNotFound || (err == nil && !problem.BelongsTo(cmd.PatientID)) {
+		return nil, errors.NewAPIError(errors.ErrNotFound, "Problem not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	code := domain.Code{Code: problem.Code, Title: problem.Title}
+	if details.Code != problem.Code {
+		if code, err = lookupCode(h.codes, details.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	problem.Edit(details, code, cmd.UpdatedBy)
+	if err := h.repo.Update(ctx, problem); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
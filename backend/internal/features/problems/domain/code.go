@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Code is an entry of the ICD-10 code set
+type Code struct {
+	Code  string `json:"code"`
+	Title string `json:"title"`
+}
+
+// CodeImport describes the code set currently in use: the file it was
+// imported from and its checksum, which tells whether a file has changed
+// since it was imported
+type CodeImport struct {
+	Source     string    `json:"source"`
+	Checksum   string    `json:"checksum"`
+	Codes      int       `json:"codes"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// CodeCatalog looks up and searches the ICD-10 code set in use
+type CodeCatalog interface {
+	Lookup(code string) (Code, bool)
+	Search(query string, limit int) []Code
+	Replace(codes []Code)
+	Len() int
+}
+
+// NormalizeCode formats an ICD-10 code the way it is stored: upper case,
+// with a dot after the category, so that "j459" becomes "J45.9". It
+// reports false for text that is not shaped like a code.
+func NormalizeCode(code string) (string, bool) {
+	compact := CompactCode(code)
+	if len(compact) < 3 || len(compact) > 7 {
+		return "", false
+	}
+	for i, r := range compact {
+		switch {
+		case i == 0 && (r < 'A' || r > 'Z'):
+			return "", false
+		case i == 1 && (r < '0' || r > '9'):
+			return "", false
+		case i > 1 && !(r >= '0' && r <= '9') && !(r >= 'A' && r <= 'Z'):
+			return "", false
+		}
+	}
+	if len(compact) == 3 {
+		return compact, true
+	}
+	return compact[:3] + "." + compact[3:], true
+}
+
+// CompactCode returns a code, or the start of one, in upper case without
+// dots and spaces
+func CompactCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == ' ' || r == '\t' {
+			return -1
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is whether a problem still affects the patient
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusResolved Status = "resolved"
+)
+
+// IsValid reports whether the status is a known one
+func (s Status) IsValid() bool {
+	return s == StatusActive || s == StatusResolved
+}
+
+// ErrInvalidDate is returned for an onset or resolution that is not a past date
+var ErrInvalidDate = errors.New("invalid date")
+
+// Problem is a diagnosis on a patient's problem list, coded with ICD-10.
+// The title of the code is kept as it read when the problem was coded.
+// Onset and resolution may be given as a year, a month or a day, as they
+// are often only roughly known.
+type Problem struct {
+	ID         uuid.UUID `json:"id"`
+	PatientID  uuid.UUID `json:"patientId"`
+	Code       string    `json:"code"`
+	Title      string    `json:"title"`
+	Notes      string    `json:"notes,omitempty"`
+	Status     Status    `json:"status"`
+	Onset      string    `json:"onset,omitempty"`
+	ResolvedOn string    `json:"resolvedOn,omitempty"`
+	RecordedBy string    `json:"recordedBy"`
+	RecordedAt time.Time `json:"recordedAt"`
+	UpdatedBy  string    `json:"updatedBy"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Details are the clinical details of a problem
+type Details struct {
+	Code       string `json:"code" binding:"required"`
+	Notes      string `json:"notes"`
+	Status     Status `json:"status"`
+	Onset      string `json:"onset"`
+	ResolvedOn string `json:"resolvedOn"`
+}
+
+// Normalize trims the details and fills in the defaults: a problem is
+// resolved if it has a resolution date and active otherwise
+func (d Details) Normalize() Details {
+	if code, ok := NormalizeCode(d.Code); ok {
+		d.Code = code
+	} else {
+		d.Code = strings.TrimSpace(d.Code)
+	}
+	d.Notes = strings.TrimSpace(d.Notes)
+	d.Onset = strings.TrimSpace(d.Onset)
+	d.ResolvedOn = strings.TrimSpace(d.ResolvedOn)
+	if d.Status == "" {
+		d.Status = StatusActive
+		if d.ResolvedOn != "" {
+			d.Status = StatusResolved
+		}
+	}
+	return d
+}
+
+// NewProblem records a problem of a patient, coded with the given code
+func NewProblem(patientID uuid.UUID, details Details, code Code, recordedBy string) *Problem {
+	details = details.Normalize()
+	now := time.Now()
+	return &Problem{
+		ID:         uuid.New(),
+		PatientID:  patientID,
+		Code:       code.Code,
+		Title:      code.Title,
+		Notes:      details.Notes,
+		Status:     details.Status,
+		Onset:      details.Onset,
+		ResolvedOn: details.ResolvedOn,
+		RecordedBy: recordedBy,
+		RecordedAt: now,
+		UpdatedBy:  recordedBy,
+		UpdatedAt:  now,
+	}
+}
+
+// Edit replaces the details of a problem, coded with the given code
+func (p *Problem) Edit(details Details, code Code, userID string) {
+	details = details.Normalize()
+
+	p.Code = code.Code
+	p.Title = code.Title
+	p.Notes = details.Notes
+	p.Status = details.Status
+	p.Onset = details.Onset
+	p.ResolvedOn = details.ResolvedOn
+	p.UpdatedBy = userID
+	p.UpdatedAt = time.Now()
+}
+
+// BelongsTo reports whether the problem is one of the patient's
+func (p *Problem) BelongsTo(patientID string) bool {
+	return p.PatientID.String() == patientID
+}
+
+// dateLayouts are the precisions an onset or resolution can be given in
+var dateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// ValidateDate checks that a date is a year (YYYY), month (YYYY-MM) or day
+// (YYYY-MM-DD) that has begun by now
+func ValidateDate(date string, now time.Time) error {
+	for _, layout := range dateLayouts {
+		if len(date) != len(layout) {
+			continue
+		}
+		start, err := time.Parse(layout, date)
+		if err != nil {
+			break
+		}
+		if start.After(now) {
+			return fmt.Errorf("%w: %s is in the future", ErrInvalidDate, date)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: expected YYYY, YYYY-MM or YYYY-MM-DD", ErrInvalidDate)
+}
+
+// ResolvedBeforeOnset reports whether a resolution certainly lies before
+// the onset. Dates of different precision are compared as far as both go,
+// so a problem that began in 2020-05 may be resolved in 2020.
+func ResolvedBeforeOnset(onset, resolvedOn string) bool {
+	n := min(len(onset), len(resolvedOn))
+	return resolvedOn[:n] < onset[:n]
+}
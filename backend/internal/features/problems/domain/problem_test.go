@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"J45.909", "J45.909", true},
+		{" j45909 ", "J45.909", true},
+		{"i10", "I10", true},
+		{"F32A", "F32.A", true},
+		{"S72.001A", "S72.001A", true},
+		{"J4", "", false},
+		{"145.9", "", false},
+		{"JA5.9", "", false},
+		{"S72.001AB", "", false},
+		{"J45-9", "", false},
+	}
+
+	for _, tt := range tests {
+		code, ok := NormalizeCode(tt.code)
+		assert.Equal(t, tt.ok, ok, tt.code)
+		assert.Equal(t, tt.want, code, tt.code)
+	}
+}
+
+func TestDetailsNormalize(t *testing.T) {
+	details := Details{Code: "e119", Notes: " Diet controlled ", Onset: " 2019 "}.Normalize()
+	assert.Equal(t, "E11.9", details.Code)
+	assert.Equal(t, "Diet controlled", details.Notes)
+	assert.Equal(t, "2019", details.Onset)
+	assert.Equal(t, StatusActive, details.Status)
+
+	details = Details{Code: "J06.9", ResolvedOn: "2024-02-10"}.Normalize()
+	assert.Equal(t, StatusResolved, details.Status, "A problem with a resolution date is resolved by default")
+}
+
+func TestValidateDate(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	for _, date := range []string{"2020", "2026-03", "2026-03-15"} {
+		assert.NoError(t, ValidateDate(date, now), date)
+	}
+	for _, date := range []string{"2027", "2026-04", "2026-03-16", "2026-3", "March 2020", "2020-13"} {
+		assert.ErrorIs(t, ValidateDate(date, now), ErrInvalidDate, date)
+	}
+}
+
+func TestResolvedBeforeOnset(t *testing.T) {
+	assert.False(t, ResolvedBeforeOnset("2020-05", "2020"), "Dates are compared as far as both go")
+	assert.False(t, ResolvedBeforeOnset("2020-05-01", "2020-05-01"))
+	assert.False(t, ResolvedBeforeOnset("2019", "2020-01-10"))
+	assert.True(t, ResolvedBeforeOnset("2020-05", "2020-04-30"))
+	assert.True(t, ResolvedBeforeOnset("2021", "2020-12"))
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when a problem does not exist
+var ErrNotFound = errors.New("problem not found")
+
+// Repository defines the interface for problem persistence.
+// DeleteByPatient removes the problem list of a purged patient and
+// MoveToPatient hands it from a duplicate to the patient it is merged into.
+type Repository interface {
+	Create(ctx context.Context, problem *Problem) error
+	Update(ctx context.Context, problem *Problem) error
+	GetByID(ctx context.Context, id string) (*Problem, error)
+	ListByPatient(ctx context.Context, patientID string) ([]*Problem, error)
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// CodeRepository defines the interface for storing the ICD-10 code set.
+// An import replaces the whole code set at once.
+type CodeRepository interface {
+	ListCodes(ctx context.Context) ([]Code, error)
+	GetCodeImport(ctx context.Context) (*CodeImport, error)
+	ReplaceCodes(ctx context.Context, codes []Code, codeImport *CodeImport) error
+}
+
+// RecordProblemRepository defines the minimal interface for recording problems
+type RecordProblemRepository interface {
+	Create(ctx context.Context, problem *Problem) error
+}
+
+// UpdateProblemRepository defines the minimal interface for changing a problem
+type UpdateProblemRepository interface {
+	GetByID(ctx context.Context, id string) (*Problem, error)
+	Update(ctx context.Context, problem *Problem) error
+}
+
+// GetProblemsRepository defines the minimal interface for reading the
+// problem list of a patient
+type GetProblemsRepository interface {
+	ListByPatient(ctx context.Context, patientID string) ([]*Problem, error)
+}
+
+// ImportCodesRepository defines the minimal interface for importing the code set
+type ImportCodesRepository interface {
+	GetCodeImport(ctx context.Context) (*CodeImport, error)
+	ReplaceCodes(ctx context.Context, codes []Code, codeImport *CodeImport) error
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dksch/pococlinic/internal/features/problems/commands"
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/features/problems/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission a problem route requires
+type Access string
+
+const (
+	AccessReadProblems  Access = "patients:read:problems"
+	AccessWriteProblems Access = "patients:write:problems"
+)
+
+// Guard checks whether the caller may read or change problem lists and
+// search the code set
+type Guard = authz.Guard[Access]
+
+// ProblemHandler handles HTTP requests for problem list operations
+type ProblemHandler struct {
+	recordProblemHandler commands.RecordProblemHandler
+	updateProblemHandler commands.UpdateProblemHandler
+	getProblemsHandler   queries.GetProblemsHandler
+	searchCodesHandler   queries.SearchCodesHandler
+	logger               *logging.Logger
+}
+
+// NewProblemHandler creates a new problem handler
+func NewProblemHandler(
+	recordHandler commands.RecordProblemHandler,
+	updateHandler commands.UpdateProblemHandler,
+	getHandler queries.GetProblemsHandler,
+	searchHandler queries.SearchCodesHandler,
+	logger *logging.Logger,
+) *ProblemHandler {
+	return &ProblemHandler{
+		recordProblemHandler: recordHandler,
+		updateProblemHandler: updateHandler,
+		getProblemsHandler:   getHandler,
+		searchCodesHandler:   searchHandler,
+		logger:               logger,
+	}
+}
+
+// RegisterRoutes registers the problem list routes below the patient they
+// belong to
+func (h *ProblemHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	patient := router.Group("/patients/:id")
+	{
+		patient.GET("/problems", guard(AccessReadProblems), h.GetProblems)
+		patient.POST("/problems", guard(AccessWriteProblems), h.RecordProblem)
+		patient.PUT("/problems/:problemId", guard(AccessWriteProblems), h.UpdateProblem)
+	}
+}
+
+// RegisterCodeRoutes registers the ICD-10 code search. It holds no patient
+// data, so it belongs outside the audited routes.
+func (h *ProblemHandler) RegisterCodeRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	router.GET("/icd10/codes", guard(AccessReadProblems), h.SearchCodes)
+}
+
+// GetProblems handles the request for the problem list of a patient
+func (h *ProblemHandler) GetProblems(c *gin.Context) {
+	problems, err := h.getProblemsHandler.Handle(c.Request.Context(), queries.GetProblemsQuery{
+		PatientID: c.Param("id"),
+		Status:    domain.Status(c.Query("status")),
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to fetch problems")
+		return
+	}
+
+	c.JSON(http.StatusOK, problems)
+}
+
+// RecordProblem handles the request to add a problem to a patient's list
+func (h *ProblemHandler) RecordProblem(c *gin.Context) {
+	var cmd commands.RecordProblemCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.RecordedBy = c.GetString("userID")
+
+	problem, err := h.recordProblemHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to record problem")
+		return
+	}
+
+	c.JSON(http.StatusCreated, problem)
+}
+
+// UpdateProblem handles the request to change a problem
+func (h *ProblemHandler) UpdateProblem(c *gin.Context) {
+	var cmd commands.UpdateProblemCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.ProblemID = c.Param("problemId")
+	cmd.UpdatedBy = c.GetString("userID")
+
+	problem, err := h.updateProblemHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to update problem")
+		return
+	}
+
+	c.JSON(http.StatusOK, problem)
+}
+
+// SearchCodes handles the autocomplete request for ICD-10 codes matching
+// the term q
+func (h *ProblemHandler) SearchCodes(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid limit"))
+			return
+		}
+	}
+
+	codes, err := h.searchCodesHandler.Handle(c.Request.Context(), queries.SearchCodesQuery{
+		Query: c.Query("q"),
+		Limit: limit,
+	})
+	if err != nil {
+		h.respondWithError(c, err, "Failed to search codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// respondWithError writes an API error with its status and hides any other
+// error behind the given message
+func (h *ProblemHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		c.JSON(errors.StatusCode(apiErr.Code), apiErr)
+		return
+	}
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+}
@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+)
+
+// CodeFile is an ICD-10 code table read from a local file
+type CodeFile struct {
+	Codes    []domain.Code
+	Checksum string
+}
+
+// ReadCodeFile reads an ICD-10 code table. Files ending in .csv hold the
+// code and its title in the first two columns, after an optional header
+// row. Other files hold one code per line followed by whitespace and its
+// title, as in the code files published for ICD-10-CM; blank lines and
+// lines starting with # are skipped. Codes may be given with or without
+// their dot.
+func ReadCodeFile(path string) (*CodeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICD-10 code file: %w", err)
+	}
+
+	// Spreadsheets tend to start their exports with a byte order mark
+	content := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	var codes []domain.Code
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		codes, err = parseCodeCSV(bytes.NewReader(content))
+	} else {
+		codes, err = parseCodeText(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid ICD-10 code file %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return &CodeFile{Codes: codes, Checksum: hex.EncodeToString(sum[:])}, nil
+}
+
+// parseCodeText reads a code table with one code and its title per line
+func parseCodeText(r io.Reader) ([]domain.Code, error) {
+	codes := []domain.Code{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		code, title, _ := strings.Cut(strings.Join(strings.Fields(text), " "), " ")
+		entry, err := newCode(code, title)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		codes = append(codes, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// parseCodeCSV reads a code table with the code and its title in the first
+// two columns
+func parseCodeCSV(r io.Reader) ([]domain.Code, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := []domain.Code{}
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("row %d: expected a code and a title", i+1)
+		}
+		if _, ok := domain.NormalizeCode(record[0]); !ok && i == 0 {
+			continue // header row
+		}
+		entry, err := newCode(record[0], strings.Join(strings.Fields(record[1]), " "))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		codes = append(codes, entry)
+	}
+	return codes, nil
+}
+
+// newCode checks and normalizes an entry of a code table
+func newCode(code, title string) (domain.Code, error) {
+	normalized, ok := domain.NormalizeCode(code)
+	if !ok {
+		return domain.Code{}, fmt.Errorf("invalid ICD-10 code %q", code)
+	}
+	if title == "" {
+		return domain.Code{}, fmt.Errorf("code %s has no title", normalized)
+	}
+	return domain.Code{Code: normalized, Title: title}, nil
+}
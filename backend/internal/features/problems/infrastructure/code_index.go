@@ -0,0 +1,159 @@
+package infrastructure
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+)
+
+// CodeIndex keeps the ICD-10 code set in memory for lookups and
+// autocomplete. Codes are searched by their start and titles by the start
+// of their words, so that "ast" finds asthma and "j45" its subcodes.
+type CodeIndex struct {
+	mu sync.RWMutex
+	// codes is sorted by code, so that codes sharing a start are adjacent
+	codes []domain.Code
+	// compact holds the codes without dots, in the same order
+	compact []string
+	// words is the sorted list of distinct words of all titles
+	words []string
+	// postings lists for each word the codes whose title contains it, by
+	// their position in codes
+	postings map[string][]int
+}
+
+// NewCodeIndex creates an empty code index
+func NewCodeIndex() *CodeIndex {
+	return &CodeIndex{postings: make(map[string][]int)}
+}
+
+// Replace swaps the indexed code set for the given one
+func (x *CodeIndex) Replace(codes []domain.Code) {
+	sorted := append([]domain.Code{}, codes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Code < sorted[j].Code })
+
+	compact := make([]string, len(sorted))
+	postings := make(map[string][]int)
+	for i, code := range sorted {
+		compact[i] = domain.CompactCode(code.Code)
+		seen := map[string]bool{}
+		for _, word := range titleWords(code.Title) {
+			if !seen[word] {
+				seen[word] = true
+				postings[word] = append(postings[word], i)
+			}
+		}
+	}
+	words := make([]string, 0, len(postings))
+	for word := range postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.codes = sorted
+	x.compact = compact
+	x.words = words
+	x.postings = postings
+}
+
+// Len returns the number of indexed codes
+func (x *CodeIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.codes)
+}
+
+// Lookup returns the entry of a code, given with or without its dot
+func (x *CodeIndex) Lookup(code string) (domain.Code, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	compact := domain.CompactCode(code)
+	i := sort.SearchStrings(x.compact, compact)
+	if i < len(x.compact) && x.compact[i] == compact {
+		return x.codes[i], true
+	}
+	return domain.Code{}, false
+}
+
+// Search returns up to limit codes for an autocomplete query. Codes that
+// start with the query come first, in code order, followed by codes with
+// a title word starting with each word of the query. Among those,
+// categories come before their subcodes, so that "asthma" lists J45
+// before J45.9.
+func (x *CodeIndex) Search(query string, limit int) []domain.Code {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	results := []domain.Code{}
+	if limit <= 0 {
+		return results
+	}
+	found := map[int]bool{}
+
+	if prefix := domain.CompactCode(strings.TrimSpace(query)); prefix != "" {
+		for i := sort.SearchStrings(x.compact, prefix); i < len(x.compact) && strings.HasPrefix(x.compact[i], prefix); i++ {
+			if len(results) == limit {
+				return results
+			}
+			found[i] = true
+			results = append(results, x.codes[i])
+		}
+	}
+
+	// Categories are shorter than their subcodes, so listing the matches
+	// by length puts them first
+	matches := x.matchWords(titleWords(query))
+	for length := 3; length <= 7; length++ {
+		for _, i := range matches {
+			if len(results) == limit {
+				return results
+			}
+			if len(x.compact[i]) == length && !found[i] {
+				results = append(results, x.codes[i])
+			}
+		}
+	}
+	return results
+}
+
+// matchWords returns the positions of the codes whose titles have a word
+// starting with each of the given words, in code order; the caller must
+// hold the lock
+func (x *CodeIndex) matchWords(words []string) []int {
+	if len(words) == 0 {
+		return nil
+	}
+
+	// hits counts for each code the leading query words its title matched
+	hits := make([]int, len(x.codes))
+	for n, word := range words {
+		for i := sort.SearchStrings(x.words, word); i < len(x.words) && strings.HasPrefix(x.words[i], word); i++ {
+			for _, code := range x.postings[x.words[i]] {
+				if hits[code] == n {
+					hits[code] = n + 1
+				}
+			}
+		}
+	}
+
+	matches := []int{}
+	for code, count := range hits {
+		if count == len(words) {
+			matches = append(matches, code)
+		}
+	}
+	return matches
+}
+
+// titleWords splits a title or query into lower case words
+func titleWords(title string) []string {
+	return strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
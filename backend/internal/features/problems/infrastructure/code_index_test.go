@@ -0,0 +1,85 @@
+package infrastructure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleIndex indexes the example code file shipped with the backend
+func exampleIndex(t *testing.T) *CodeIndex {
+	file, err := ReadCodeFile(filepath.Join("..", "..", "..", "..", "icd10-codes.example.txt"))
+	require.NoError(t, err)
+	index := NewCodeIndex()
+	index.Replace(file.Codes)
+	return index
+}
+
+// codesOf returns the codes of search results
+func codesOf(codes []domain.Code) []string {
+	found := []string{}
+	for _, code := range codes {
+		found = append(found, code.Code)
+	}
+	return found
+}
+
+func TestCodeIndexLookup(t *testing.T) {
+	index := exampleIndex(t)
+
+	code, ok := index.Lookup("J45.909")
+	require.True(t, ok)
+	assert.Equal(t, "Unspecified asthma, uncomplicated", code.Title)
+
+	code, ok = index.Lookup("e119")
+	require.True(t, ok, "Codes can be looked up without their dot")
+	assert.Equal(t, "E11.9", code.Code)
+
+	_, ok = index.Lookup("J45.9")
+	assert.False(t, ok)
+}
+
+func TestCodeIndexSearch(t *testing.T) {
+	index := exampleIndex(t)
+
+	assert.Equal(t, []string{"J45", "J45.20", "J45.40", "J45.909"}, codesOf(index.Search("j45", 10)))
+	assert.Equal(t, []string{"J45.40"}, codesOf(index.Search("J454", 10)), "Dots should not matter")
+	assert.Equal(t, []string{"J45", "J45.20", "J45.40", "J45.909"}, codesOf(index.Search("asth", 10)),
+		"Categories should come before their subcodes")
+	assert.Equal(t, []string{"J45.20"}, codesOf(index.Search("mild asth", 10)))
+	assert.Equal(t, []string{"E11.65"}, codesOf(index.Search("diabetes hyperglyc", 10)))
+	assert.Equal(t, []string{"J45", "J45.20"}, codesOf(index.Search("asthma", 2)))
+	assert.Empty(t, index.Search("asthma fracture", 10))
+	assert.Empty(t, index.Search("asthma", 0))
+
+	index.Replace([]domain.Code{{Code: "A00.0", Title: "Cholera due to Vibrio cholerae 01, biovar cholerae"}})
+	assert.Empty(t, index.Search("asthma", 10), "Replacing the code set should drop the old codes")
+	assert.Equal(t, 1, index.Len())
+}
+
+func TestReadCodeFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "codes.csv")
+	require.NoError(t, os.WriteFile(path, []byte("\xef\xbb\xbfcode,title\nJ45.909,\"Unspecified asthma, uncomplicated\"\nI10,Essential (primary) hypertension\n"), 0o600))
+	file, err := ReadCodeFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Code{
+		{Code: "J45.909", Title: "Unspecified asthma, uncomplicated"},
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+	}, file.Codes)
+	assert.Len(t, file.Checksum, 64)
+
+	path = filepath.Join(dir, "codes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("I10\tEssential (primary) hypertension\nnot-a-code Something\n"), 0o600))
+	_, err = ReadCodeFile(path)
+	assert.ErrorContains(t, err, "line 2")
+
+	require.NoError(t, os.WriteFile(path, []byte("I10\n"), 0o600))
+	_, err = ReadCodeFile(path)
+	assert.ErrorContains(t, err, "no title")
+}
@@ -0,0 +1,148 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryRepository is a simple in-memory implementation of the problem
+// Repository and CodeRepository interfaces
+type MemoryRepository struct {
+	problems   map[string]*domain.Problem
+	codes      []domain.Code
+	codeImport *domain.CodeImport
+	mu         sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory problem repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		problems: make(map[string]*domain.Problem),
+		codes:    []domain.Code{},
+	}
+}
+
+// Create adds a new problem to the repository
+func (r *MemoryRepository) Create(ctx context.Context, problem *domain.Problem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *problem
+	r.problems[problem.ID.String()] = &stored
+	return nil
+}
+
+// Update stores the changed problem
+func (r *MemoryRepository) Update(ctx context.Context, problem *domain.Problem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.problems[problem.ID.String()]; !exists {
+		return domain.ErrNotFound
+	}
+	stored := *problem
+	r.problems[problem.ID.String()] = &stored
+	return nil
+}
+
+// GetByID retrieves a problem by its ID
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*domain.Problem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	problem, exists := r.problems[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	found := *problem
+	return &found, nil
+}
+
+// ListByPatient returns the patient's problems in the order they were recorded
+func (r *MemoryRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Problem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	problems := []*domain.Problem{}
+	for _, problem := range r.problems {
+		if problem.BelongsTo(patientID) {
+			found := *problem
+			problems = append(problems, &found)
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if !problems[i].RecordedAt.Equal(problems[j].RecordedAt) {
+			return problems[i].RecordedAt.Before(problems[j].RecordedAt)
+		}
+		return problems[i].ID.String() < problems[j].ID.String()
+	})
+	return problems, nil
+}
+
+// DeleteByPatient removes the patient's problem list
+func (r *MemoryRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, problem := range r.problems {
+		if problem.BelongsTo(patientID) {
+			delete(r.problems, id)
+		}
+	}
+	return nil
+}
+
+// MoveToPatient adds the problem list of one patient to another's
+func (r *MemoryRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	target, err := uuid.Parse(toID)
+	if err != nil {
+		return fmt.Errorf("invalid patient ID %q: %w", toID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, problem := range r.problems {
+		if problem.BelongsTo(fromID) {
+			problem.PatientID = target
+		}
+	}
+	return nil
+}
+
+// ListCodes returns the imported code set
+func (r *MemoryRepository) ListCodes(ctx context.Context) ([]domain.Code, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.Code{}, r.codes...), nil
+}
+
+// GetCodeImport describes the imported code set, or returns nil if none
+// has been imported
+func (r *MemoryRepository) GetCodeImport(ctx context.Context) (*domain.CodeImport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.codeImport == nil {
+		return nil, nil
+	}
+	found := *r.codeImport
+	return &found, nil
+}
+
+// ReplaceCodes replaces the code set with an imported one
+func (r *MemoryRepository) ReplaceCodes(ctx context.Context, codes []domain.Code, codeImport *domain.CodeImport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *codeImport
+	r.codes = append([]domain.Code{}, codes...)
+	r.codeImport = &stored
+	return nil
+}
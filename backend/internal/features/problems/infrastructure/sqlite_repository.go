@@ -0,0 +1,305 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the problems feature, in order. The
+// code set is kept with the problems so that it survives restarts; the
+// single row of icd10_import describes where it came from.
+var sqliteMigrations = []string{
+	`CREATE TABLE problems (
+		id          TEXT PRIMARY KEY,
+		patient_id  TEXT NOT NULL,
+		code        TEXT NOT NULL,
+		title       TEXT NOT NULL,
+		notes       TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL,
+		onset       TEXT NOT NULL DEFAULT '',
+		resolved_on TEXT NOT NULL DEFAULT '',
+		recorded_by TEXT NOT NULL DEFAULT '',
+		recorded_at TEXT NOT NULL,
+		updated_by  TEXT NOT NULL DEFAULT '',
+		updated_at  TEXT NOT NULL
+	);
+	CREATE INDEX idx_problems_patient ON problems (patient_id, recorded_at);
+	CREATE TABLE icd10_codes (
+		code  TEXT PRIMARY KEY,
+		title TEXT NOT NULL
+	);
+	CREATE TABLE icd10_import (
+		id          INTEGER PRIMARY KEY CHECK (id = 1),
+		source      TEXT NOT NULL,
+		checksum    TEXT NOT NULL,
+		codes       INTEGER NOT NULL,
+		imported_at TEXT NOT NULL
+	);`,
+}
+
+// problemColumns lists the problem columns in the order scanProblem expects
+const problemColumns = `id, patient_id, code, title, notes, status, onset, resolved_on,
+	recorded_by, recorded_at, updated_by, updated_at`
+
+// MigrateSQLite applies the problems schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "problems", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the problem Repository
+// and CodeRepository interfaces
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite problem repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new problem to the repository
+func (r *SQLiteRepository) Create(ctx context.Context, problem *domain.Problem) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO problems (`+problemColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		problem.ID.String(),
+		problem.PatientID.String(),
+		problem.Code,
+		problem.Title,
+		problem.Notes,
+		string(problem.Status),
+		problem.Onset,
+		problem.ResolvedOn,
+		problem.RecordedBy,
+		formatTime(problem.RecordedAt),
+		problem.UpdatedBy,
+		formatTime(problem.UpdatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create problem %s: %w", problem.ID, err)
+	}
+	return nil
+}
+
+// Update stores the changed problem
+func (r *SQLiteRepository) Update(ctx context.Context, problem *domain.Problem) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE problems SET
+		code = ?, title = ?, notes = ?, status = ?, onset = ?, resolved_on = ?,
+		updated_by = ?, updated_at = ?
+		WHERE id = ?`,
+		problem.Code,
+		problem.Title,
+		problem.Notes,
+		string(problem.Status),
+		problem.Onset,
+		problem.ResolvedOn,
+		problem.UpdatedBy,
+		formatTime(problem.UpdatedAt),
+		problem.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update problem %s: %w", problem.ID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update problem %s: %w", problem.ID, err)
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a problem by its ID
+func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*domain.Problem, error) {
+	problem, err := scanProblem(r.db.QueryRowContext(ctx,
+		`SELECT `+problemColumns+` FROM problems WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return problem, err
+}
+
+// ListByPatient returns the patient's problems in the order they were recorded
+func (r *SQLiteRepository) ListByPatient(ctx context.Context, patientID string) ([]*domain.Problem, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+problemColumns+` FROM problems
+		WHERE patient_id = ? ORDER BY recorded_at, id`, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list problems: %w", err)
+	}
+	defer rows.Close()
+
+	problems := []*domain.Problem{}
+	for rows.Next() {
+		problem, err := scanProblem(rows)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, problem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list problems: %w", err)
+	}
+	return problems, nil
+}
+
+// DeleteByPatient removes the patient's problem list
+func (r *SQLiteRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	_, err := database.ConnFrom(ctx, r.db).ExecContext(ctx, `DELETE FROM problems WHERE patient_id = ?`, patientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete problems of patient %s: %w", patientID, err)
+	}
+	return nil
+}
+
+// MoveToPatient adds the problem list of one patient to another's
+func (r *SQLiteRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	_, err := database.ConnFrom(ctx, r.db).ExecContext(ctx, `UPDATE problems SET patient_id = ? WHERE patient_id = ?`, toID, fromID)
+	if err != nil {
+		return fmt.Errorf("failed to move problems of patient %s: %w", fromID, err)
+	}
+	return nil
+}
+
+// ListCodes returns the imported code set
+func (r *SQLiteRepository) ListCodes(ctx context.Context) ([]domain.Code, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT code, title FROM icd10_codes ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ICD-10 codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []domain.Code{}
+	for rows.Next() {
+		var code domain.Code
+		if err := rows.Scan(&code.Code, &code.Title); err != nil {
+			return nil, fmt.Errorf("failed to list ICD-10 codes: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list ICD-10 codes: %w", err)
+	}
+	return codes, nil
+}
+
+// GetCodeImport describes the imported code set, or returns nil if none
+// has been imported
+func (r *SQLiteRepository) GetCodeImport(ctx context.Context) (*domain.CodeImport, error) {
+	var (
+		codeImport domain.CodeImport
+		importedAt string
+	)
+	err := r.db.QueryRowContext(ctx, `SELECT source, checksum, codes, imported_at FROM icd10_import`).
+		Scan(&codeImport.Source, &codeImport.Checksum, &codeImport.Codes, &importedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICD-10 import: %w", err)
+	}
+	if codeImport.ImportedAt, err = time.Parse(timestampLayout, importedAt); err != nil {
+		return nil, fmt.Errorf("invalid time of ICD-10 import: %w", err)
+	}
+	return &codeImport, nil
+}
+
+// ReplaceCodes replaces the code set with an imported one in a single
+// transaction, so that searches never see a partial code set
+func (r *SQLiteRepository) ReplaceCodes(ctx context.Context, codes []domain.Code, codeImport *domain.CodeImport) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to import ICD-10 codes: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM icd10_codes`); err != nil {
+		return fmt.Errorf("failed to import ICD-10 codes: %w", err)
+	}
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO icd10_codes (code, title) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to import ICD-10 codes: %w", err)
+	}
+	defer insert.Close()
+	for _, code := range codes {
+		if _, err := insert.ExecContext(ctx, code.Code, code.Title); err != nil {
+			return fmt.Errorf("failed to import ICD-10 code %s: %w", code.Code, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO icd10_import (id, source, checksum, codes, imported_at)
+		VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET source = excluded.source, checksum = excluded.checksum,
+			codes = excluded.codes, imported_at = excluded.imported_at`,
+		codeImport.Source,
+		codeImport.Checksum,
+		codeImport.Codes,
+		formatTime(codeImport.ImportedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record ICD-10 import: %w", err)
+	}
+	return tx.Commit()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProblem reads a problem selected with problemColumns
+func scanProblem(row rowScanner) (*domain.Problem, error) {
+	var (
+		problem                              domain.Problem
+		id, patientID, recordedAt, updatedAt string
+		status                               string
+	)
+	err := row.Scan(
+		&id,
+		&patientID,
+		&problem.Code,
+		&problem.Title,
+		&problem.Notes,
+		&status,
+		&problem.Onset,
+		&problem.ResolvedOn,
+		&problem.RecordedBy,
+		&recordedAt,
+		&problem.UpdatedBy,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if problem.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid problem ID %q: %w", id, err)
+	}
+	if problem.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID of problem %s: %w", id, err)
+	}
+	if problem.RecordedAt, err = time.Parse(timestampLayout, recordedAt); err != nil {
+		return nil, fmt.Errorf("invalid recording time of problem %s: %w", id, err)
+	}
+	if problem.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("invalid update time of problem %s: %w", id, err)
+	}
+	problem.Status = domain.Status(status)
+	return &problem, nil
+}
+
+// formatTime formats a timestamp for storage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
@@ -0,0 +1,100 @@
+package infrastructure
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) *SQLiteRepository {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "problems.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db)
+}
+
+func TestSQLiteRepositoryProblems(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+	patientID := uuid.New()
+
+	problem := domain.NewProblem(patientID, domain.Details{Code: "I10", Notes: "Home readings high", Onset: "2018"},
+		domain.Code{Code: "I10", Title: "Essential (primary) hypertension"}, "dr-1")
+	require.NoError(t, repo.Create(ctx, problem))
+
+	found, err := repo.GetByID(ctx, problem.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, problem.Code, found.Code)
+	assert.Equal(t, problem.Title, found.Title)
+	assert.Equal(t, "Home readings high", found.Notes)
+	assert.Equal(t, "2018", found.Onset)
+	assert.Equal(t, domain.StatusActive, found.Status)
+
+	_, err = repo.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	problem.Edit(domain.Details{Code: "I10", Onset: "2018", ResolvedOn: "2025-06"}, domain.Code{Code: "I10", Title: problem.Title}, "dr-2")
+	require.NoError(t, repo.Update(ctx, problem))
+
+	problems, err := repo.ListByPatient(ctx, patientID.String())
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, domain.StatusResolved, problems[0].Status)
+	assert.Equal(t, "2025-06", problems[0].ResolvedOn)
+	assert.Equal(t, "dr-2", problems[0].UpdatedBy)
+
+	missing := domain.NewProblem(patientID, domain.Details{Code: "I10"}, domain.Code{Code: "I10"}, "dr-1")
+	assert.ErrorIs(t, repo.Update(ctx, missing), domain.ErrNotFound)
+
+	keptID := uuid.New()
+	require.NoError(t, repo.MoveToPatient(ctx, patientID.String(), keptID.String()))
+	problems, err = repo.ListByPatient(ctx, keptID.String())
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, keptID, problems[0].PatientID)
+
+	require.NoError(t, repo.DeleteByPatient(ctx, keptID.String()))
+	problems, err = repo.ListByPatient(ctx, keptID.String())
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestSQLiteRepositoryCodes(t *testing.T) {
+	ctx := context.Background()
+	repo := setupSQLiteRepository(t)
+
+	codeImport, err := repo.GetCodeImport(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, codeImport)
+
+	importedAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.ReplaceCodes(ctx, []domain.Code{
+		{Code: "J45.909", Title: "Unspecified asthma, uncomplicated"},
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+	}, &domain.CodeImport{Source: "v1.txt", Checksum: "v1", Codes: 2, ImportedAt: importedAt}))
+
+	require.NoError(t, repo.ReplaceCodes(ctx, []domain.Code{
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+		{Code: "J45", Title: "Asthma"},
+	}, &domain.CodeImport{Source: "v2.txt", Checksum: "v2", Codes: 2, ImportedAt: importedAt}))
+
+	codes, err := repo.ListCodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Code{
+		{Code: "I10", Title: "Essential (primary) hypertension"},
+		{Code: "J45", Title: "Asthma"},
+	}, codes)
+
+	codeImport, err = repo.GetCodeImport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.CodeImport{Source: "v2.txt", Checksum: "v2", Codes: 2, ImportedAt: importedAt}, codeImport)
+}
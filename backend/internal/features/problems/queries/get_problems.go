@@ -0,0 +1,52 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// GetProblemsQuery represents the query for the problem list of a patient.
+// Status narrows the list to the active or the resolved problems.
+type GetProblemsQuery struct {
+	PatientID string
+	Status    domain.Status
+}
+
+// GetProblemsHandler handles the get problems query
+type GetProblemsHandler interface {
+	Handle(ctx context.Context, query GetProblemsQuery) ([]*domain.Problem, error)
+}
+
+type getProblemsHandler struct {
+	repo domain.GetProblemsRepository
+}
+
+// NewGetProblemsHandler creates a new get problems handler
+func NewGetProblemsHandler(repo domain.GetProblemsRepository) GetProblemsHandler {
+	return &getProblemsHandler{repo: repo}
+}
+
+// Handle processes the get problems query
+func (h *getProblemsHandler) Handle(ctx context.Context, query GetProblemsQuery) ([]*domain.Problem, error) {
+	if query.Status != "" && !query.Status.IsValid() {
+		return nil, errors.NewAPIError(errors.ErrValidation, "Status must be active or resolved")
+	}
+
+	problems, err := h.repo.ListByPatient(ctx, query.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	if query.Status == "" {
+		return problems, nil
+	}
+
+	listed := []*domain.Problem{}
+	for _, problem := range problems {
+		if problem.Status == query.Status {
+			listed = append(listed, problem)
+		}
+	}
+	return listed, nil
+}
@@ -0,0 +1,52 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dksch/pococlinic/internal/features/problems/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// Limits of a code search
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchCodesQuery represents the query for ICD-10 codes matching the text
+// typed so far, by code or by title
+type SearchCodesQuery struct {
+	Query string
+	Limit int
+}
+
+// SearchCodesHandler handles the search codes query
+type SearchCodesHandler interface {
+	Handle(ctx context.Context, query SearchCodesQuery) ([]domain.Code, error)
+}
+
+type searchCodesHandler struct {
+	codes domain.CodeCatalog
+}
+
+// NewSearchCodesHandler creates a new search codes handler
+func NewSearchCodesHandler(codes domain.CodeCatalog) SearchCodesHandler {
+	return &searchCodesHandler{codes: codes}
+}
+
+// Handle processes the search codes query. A zero limit returns the default
+// number of codes.
+func (h *searchCodesHandler) Handle(ctx context.Context, query SearchCodesQuery) ([]domain.Code, error) {
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, errors.NewAPIError(errors.ErrValidation, "A search term is required")
+	}
+	if query.Limit < 0 || query.Limit > MaxSearchLimit {
+		return nil, errors.NewAPIError(errors.ErrValidation, fmt.Sprintf("Limit must be between 1 and %d", MaxSearchLimit))
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultSearchLimit
+	}
+	return h.codes.Search(query.Query, query.Limit), nil
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/patientref"
+)
+
+// RecordVitalsCommand represents the command to record vital signs of a
+// patient. Every value carries its unit; the reading is taken now unless
+// TakenAt says otherwise. The recorded reading is returned in metric units.
+type RecordVitalsCommand struct {
+	PatientID  string     `json:"-"`
+	RecordedBy string     `json:"-"`
+	TakenAt    *time.Time `json:"takenAt"`
+	domain.Values
+}
+
+// RecordVitalsHandler handles the record vitals command
+type RecordVitalsHandler interface {
+	Handle(ctx context.Context, cmd RecordVitalsCommand) (*domain.Reading, error)
+}
+
+type recordVitalsHandler struct {
+	repo     domain.RecordVitalsRepository
+	patients patientref.Directory
+}
+
+// NewRecordVitalsHandler creates a new record vitals handler
+func NewRecordVitalsHandler(repo domain.RecordVitalsRepository, patients patientref.Directory) RecordVitalsHandler {
+	return &recordVitalsHandler{repo: repo, patients: patients}
+}
+
+// Handle processes the record vitals command
+func (h *recordVitalsHandler) Handle(ctx context.Context, cmd RecordVitalsCommand) (*domain.Reading, error) {
+	patientID, err := patientref.RequireActive(ctx, h.patients, cmd.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	takenAt := time.Now()
+	if cmd.TakenAt != nil {
+		takenAt = *cmd.TakenAt
+	}
+	reading, err := domain.NewReading(patientID, takenAt, cmd.Values, cmd.RecordedBy)
+	if err != nil {
+		return nil, readingError(err)
+	}
+
+	if err := h.repo.Create(ctx, reading); err != nil {
+		return nil, err
+	}
+
+	lastHeight, err := h.repo.LastHeight(ctx, cmd.PatientID, reading.TakenAt)
+	if err != nil {
+		return nil, err
+	}
+	reading.DeriveBMI(lastHeight)
+	return reading.InUnits(domain.UnitSystemMetric), nil
+}
+
+// readingError translates an invalid reading into a validation error
+func readingError(err error) error {
+	switch err := err.(type) {
+	case *domain.MeasurementError:
+		return errors.NewAPIError(errors.ErrValidation, fmt.Sprintf("Invalid %s: %s", err.Kind, err.Reason))
+	}
+	switch err {
+	case domain.ErrNoMeasurements:
+		return errors.NewAPIError(errors.ErrValidation, "At least one vital sign is required")
+	case domain.ErrTakenInFuture:
+		return errors.NewAPIError(errors.ErrValidation, "Vital signs cannot be taken in the future")
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/features/vitals/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of patients and whether they are active
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func assertAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	apiErr, ok := err.(*errors.APIError)
+	require.True(t, ok, "Expected an APIError, got %v", err)
+	assert.Equal(t, code, apiErr.Code)
+}
+
+func TestRecordVitals(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patientID, archivedID := uuid.NewString(), uuid.NewString()
+	handler := NewRecordVitalsHandler(repo, patientDirectory{patientID: true, archivedID: false})
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	height, err := handler.Handle(ctx, RecordVitalsCommand{
+		PatientID:  patientID,
+		RecordedBy: "nurse-1",
+		TakenAt:    &yesterday,
+		Values:     domain.Values{Height: &domain.Measurement{Value: 175, Unit: domain.UnitCentimeter}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "nurse-1", height.RecordedBy)
+	assert.True(t, yesterday.Equal(height.TakenAt))
+	assert.Nil(t, height.BMI)
+
+	// A weight in pounds is returned in kilograms with the BMI derived from
+	// the last height
+	weight, err := handler.Handle(ctx, RecordVitalsCommand{
+		PatientID:  patientID,
+		RecordedBy: "nurse-1",
+		Values:     domain.Values{Weight: &domain.Measurement{Value: 154.3, Unit: domain.UnitPound}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Measurement{Value: 70, Unit: domain.UnitKilogram}, weight.Weight)
+	require.NotNil(t, weight.BMI)
+	assert.Equal(t, 22.9, *weight.BMI)
+
+	readings, err := repo.ListByPatient(ctx, patientID, domain.ReadingFilter{})
+	require.NoError(t, err)
+	assert.Len(t, readings, 2)
+}
+
+func TestRecordVitalsRejectsInvalidReadings(t *testing.T) {
+	ctx := context.Background()
+	patientID, archivedID := uuid.NewString(), uuid.NewString()
+	handler := NewRecordVitalsHandler(infrastructure.NewMemoryRepository(), patientDirectory{patientID: true, archivedID: false})
+	pulse := domain.Values{Pulse: &domain.Measurement{Value: 72, Unit: domain.UnitPerMinute}}
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name string
+		cmd  RecordVitalsCommand
+		code string
+	}{
+		{"Malformed patient ID", RecordVitalsCommand{PatientID: "not-a-uuid", Values: pulse}, errors.ErrNotFound},
+		{"Unknown patient", RecordVitalsCommand{PatientID: uuid.NewString(), Values: pulse}, errors.ErrNotFound},
+		{"Archived patient", RecordVitalsCommand{PatientID: archivedID, Values: pulse}, errors.ErrNotFound},
+		{"No measurements", RecordVitalsCommand{PatientID: patientID}, errors.ErrValidation},
+		{"Taken in the future", RecordVitalsCommand{PatientID: patientID, TakenAt: &tomorrow, Values: pulse}, errors.ErrValidation},
+		{"Wrong unit", RecordVitalsCommand{PatientID: patientID, Values: domain.Values{Weight: &domain.Measurement{Value: 70, Unit: domain.UnitCentimeter}}}, errors.ErrValidation},
+		{"Implausible value", RecordVitalsCommand{PatientID: patientID, Values: domain.Values{Pulse: &domain.Measurement{Value: 900, Unit: domain.UnitPerMinute}}}, errors.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handler.Handle(ctx, tt.cmd)
+			assertAPIError(t, err, tt.code)
+		})
+	}
+}
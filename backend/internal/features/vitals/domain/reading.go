@@ -0,0 +1,289 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kind is a vital sign that can be measured
+type Kind string
+
+const (
+	KindHeight          Kind = "height"
+	KindWeight          Kind = "weight"
+	KindBloodPressure   Kind = "bloodPressure"
+	KindPulse           Kind = "pulse"
+	KindTemperature     Kind = "temperature"
+	KindSpO2            Kind = "spo2"
+	KindRespiratoryRate Kind = "respiratoryRate"
+)
+
+// kindSpec describes how a vital sign is reported and which values are
+// plausible, in its metric unit
+type kindSpec struct {
+	metric   Unit
+	imperial Unit
+	min, max float64
+}
+
+var kinds = map[Kind]kindSpec{
+	KindHeight:          {UnitCentimeter, UnitInch, 20, 280},
+	KindWeight:          {UnitKilogram, UnitPound, 0.2, 650},
+	KindBloodPressure:   {UnitMmHg, UnitMmHg, 20, 300},
+	KindPulse:           {UnitPerMinute, UnitPerMinute, 20, 300},
+	KindTemperature:     {UnitCelsius, UnitFahrenheit, 25, 45},
+	KindSpO2:            {UnitPercent, UnitPercent, 50, 100},
+	KindRespiratoryRate: {UnitPerMinute, UnitPerMinute, 4, 80},
+}
+
+// IsValid reports whether the kind is a known vital sign
+func (k Kind) IsValid() bool {
+	_, ok := kinds[k]
+	return ok
+}
+
+// Unit returns the unit the vital sign is reported in by a unit system
+func (k Kind) Unit(system UnitSystem) Unit {
+	if system == UnitSystemImperial {
+		return kinds[k].imperial
+	}
+	return kinds[k].metric
+}
+
+// clockSkew is how far in the future a reading may be taken, to allow for
+// clocks that are slightly off
+const clockSkew = 5 * time.Minute
+
+var (
+	// ErrNoMeasurements is returned for a reading without any value
+	ErrNoMeasurements = errors.New("reading has no measurements")
+	// ErrTakenInFuture is returned for a reading taken after the current time
+	ErrTakenInFuture = errors.New("reading is taken in the future")
+)
+
+// MeasurementError reports a measurement that is given in the wrong unit or
+// out of the plausible range
+type MeasurementError struct {
+	Kind   Kind
+	Reason string
+}
+
+func (e *MeasurementError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Kind, e.Reason)
+}
+
+// Measurement is a value with its unit
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  Unit    `json:"unit"`
+}
+
+// BloodPressure is a blood pressure measurement
+type BloodPressure struct {
+	Systolic  float64 `json:"systolic"`
+	Diastolic float64 `json:"diastolic"`
+	Unit      Unit    `json:"unit"`
+}
+
+// Values holds the vital signs measured at one time, each of them optional
+type Values struct {
+	Height          *Measurement   `json:"height,omitempty"`
+	Weight          *Measurement   `json:"weight,omitempty"`
+	BloodPressure   *BloodPressure `json:"bloodPressure,omitempty"`
+	Pulse           *Measurement   `json:"pulse,omitempty"`
+	Temperature     *Measurement   `json:"temperature,omitempty"`
+	SpO2            *Measurement   `json:"spo2,omitempty"`
+	RespiratoryRate *Measurement   `json:"respiratoryRate,omitempty"`
+}
+
+// Reading is a set of vital signs of a patient taken at one time. Values
+// are stored in metric units without rounding; BMI is derived and not
+// stored.
+type Reading struct {
+	ID        uuid.UUID `json:"id"`
+	PatientID uuid.UUID `json:"patientId"`
+	TakenAt   time.Time `json:"takenAt"`
+	Values
+	BMI        *float64  `json:"bmi,omitempty"`
+	RecordedBy string    `json:"recordedBy"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// NewReading creates a reading of the patient's vital signs. The values may
+// be given in any unit of their kind and are converted to metric ones.
+func NewReading(patientID uuid.UUID, takenAt time.Time, values Values, recordedBy string) (*Reading, error) {
+	now := time.Now()
+	if takenAt.After(now.Add(clockSkew)) {
+		return nil, ErrTakenInFuture
+	}
+	if values.IsEmpty() {
+		return nil, ErrNoMeasurements
+	}
+
+	metric, err := values.normalize()
+	if err != nil {
+		return nil, err
+	}
+	return &Reading{
+		ID:         uuid.New(),
+		PatientID:  patientID,
+		TakenAt:    takenAt,
+		Values:     metric,
+		RecordedBy: recordedBy,
+		RecordedAt: now,
+	}, nil
+}
+
+// IsEmpty reports whether no vital sign was measured
+func (v Values) IsEmpty() bool {
+	if v.BloodPressure != nil {
+		return false
+	}
+	for _, kind := range singleValued {
+		if v.Has(kind) {
+			return false
+		}
+	}
+	return true
+}
+
+// Has reports whether the vital sign was measured
+func (v Values) Has(kind Kind) bool {
+	if kind == KindBloodPressure {
+		return v.BloodPressure != nil
+	}
+	field := v.measurement(kind)
+	return field != nil && *field != nil
+}
+
+// measurement returns the field holding a single valued vital sign
+func (v *Values) measurement(kind Kind) **Measurement {
+	switch kind {
+	case KindHeight:
+		return &v.Height
+	case KindWeight:
+		return &v.Weight
+	case KindPulse:
+		return &v.Pulse
+	case KindTemperature:
+		return &v.Temperature
+	case KindSpO2:
+		return &v.SpO2
+	case KindRespiratoryRate:
+		return &v.RespiratoryRate
+	}
+	return nil
+}
+
+// singleValued lists the vital signs measured by a single value
+var singleValued = []Kind{KindHeight, KindWeight, KindPulse, KindTemperature, KindSpO2, KindRespiratoryRate}
+
+// normalize checks the values and converts them to metric units
+func (v Values) normalize() (Values, error) {
+	var metric Values
+	for _, kind := range singleValued {
+		m := *v.measurement(kind)
+		if m == nil {
+			continue
+		}
+		value, err := toMetric(kind, m.Value, m.Unit)
+		if err != nil {
+			return Values{}, err
+		}
+		*metric.measurement(kind) = &Measurement{Value: value, Unit: kind.Unit(UnitSystemMetric)}
+	}
+
+	if bp := v.BloodPressure; bp != nil {
+		systolic, err := toMetric(KindBloodPressure, bp.Systolic, bp.Unit)
+		if err != nil {
+			return Values{}, err
+		}
+		diastolic, err := toMetric(KindBloodPressure, bp.Diastolic, bp.Unit)
+		if err != nil {
+			return Values{}, err
+		}
+		if diastolic >= systolic {
+			return Values{}, &MeasurementError{Kind: KindBloodPressure, Reason: "diastolic must be below systolic"}
+		}
+		metric.BloodPressure = &BloodPressure{Systolic: systolic, Diastolic: diastolic, Unit: UnitMmHg}
+	}
+	return metric, nil
+}
+
+// toMetric converts a value of a vital sign to its metric unit and checks
+// that it is plausible
+func toMetric(kind Kind, value float64, unit Unit) (float64, error) {
+	spec := kinds[kind]
+	metric, err := convert(value, unit, spec.metric)
+	if err != nil {
+		return 0, &MeasurementError{Kind: kind, Reason: fmt.Sprintf("unit %q is not a unit of %s", unit, kind)}
+	}
+	if metric < spec.min || metric > spec.max {
+		return 0, &MeasurementError{Kind: kind, Reason: fmt.Sprintf("must be between %g and %g %s", spec.min, spec.max, spec.metric)}
+	}
+	return metric, nil
+}
+
+// InUnits returns the values in the units of the given system, rounded to
+// one decimal
+func (v Values) InUnits(system UnitSystem) Values {
+	converted := v
+	for _, kind := range singleValued {
+		m := *v.measurement(kind)
+		if m == nil {
+			continue
+		}
+		unit := kind.Unit(system)
+		value, err := Convert(m.Value, m.Unit, unit)
+		if err != nil {
+			continue
+		}
+		*converted.measurement(kind) = &Measurement{Value: value, Unit: unit}
+	}
+	if bp := v.BloodPressure; bp != nil {
+		converted.BloodPressure = &BloodPressure{Systolic: round(bp.Systolic), Diastolic: round(bp.Diastolic), Unit: bp.Unit}
+	}
+	return converted
+}
+
+// DeriveBMI sets the body mass index of a reading with a weight. The
+// reading's own height is used, or else the last height measured before.
+func (r *Reading) DeriveBMI(lastHeight *Measurement) {
+	height := r.Height
+	if height == nil {
+		height = lastHeight
+	}
+	if r.Weight == nil || height == nil {
+		r.BMI = nil
+		return
+	}
+
+	bmi := BMI(*height, *r.Weight)
+	r.BMI = &bmi
+}
+
+// BMI returns the body mass index for a height and weight in any units,
+// rounded to one decimal
+func BMI(height, weight Measurement) float64 {
+	cm, err := convert(height.Value, height.Unit, UnitCentimeter)
+	if err != nil || cm <= 0 {
+		return 0
+	}
+	kg, err := convert(weight.Value, weight.Unit, UnitKilogram)
+	if err != nil {
+		return 0
+	}
+	meters := cm / 100
+	return round(kg / (meters * meters))
+}
+
+// InUnits returns a copy of the reading with its values in the units of the
+// given system
+func (r *Reading) InUnits(system UnitSystem) *Reading {
+	converted := *r
+	converted.Values = r.Values.InUnits(system)
+	return &converted
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReadingConvertsToMetric(t *testing.T) {
+	takenAt := time.Now().Add(-time.Hour)
+	reading, err := NewReading(uuid.New(), takenAt, Values{
+		Height:        &Measurement{Value: 70, Unit: UnitInch},
+		Weight:        &Measurement{Value: 154, Unit: UnitPound},
+		Temperature:   &Measurement{Value: 98.6, Unit: UnitFahrenheit},
+		BloodPressure: &BloodPressure{Systolic: 120, Diastolic: 80, Unit: UnitMmHg},
+	}, "nurse-1")
+	require.NoError(t, err)
+	assert.InDelta(t, 69.853, reading.Weight.Value, 0.001, "Stored values should not be rounded")
+
+	metric := reading.InUnits(UnitSystemMetric)
+	assert.Equal(t, &Measurement{Value: 177.8, Unit: UnitCentimeter}, metric.Height)
+	assert.Equal(t, &Measurement{Value: 69.9, Unit: UnitKilogram}, metric.Weight)
+	assert.Equal(t, &Measurement{Value: 37, Unit: UnitCelsius}, metric.Temperature)
+	assert.Nil(t, metric.Pulse)
+	assert.True(t, reading.Has(KindBloodPressure))
+	assert.False(t, reading.Has(KindSpO2))
+
+	imperial := reading.InUnits(UnitSystemImperial)
+	assert.Equal(t, &Measurement{Value: 70, Unit: UnitInch}, imperial.Height)
+	assert.Equal(t, &Measurement{Value: 154, Unit: UnitPound}, imperial.Weight)
+	assert.Equal(t, UnitCentimeter, reading.Height.Unit, "Converting should not change the reading")
+}
+
+func TestNewReadingValidation(t *testing.T) {
+	patientID := uuid.New()
+	now := time.Now()
+
+	_, err := NewReading(patientID, now, Values{}, "nurse-1")
+	assert.ErrorIs(t, err, ErrNoMeasurements)
+
+	_, err = NewReading(patientID, now.Add(time.Hour), Values{Pulse: &Measurement{Value: 70, Unit: UnitPerMinute}}, "nurse-1")
+	assert.ErrorIs(t, err, ErrTakenInFuture)
+
+	invalid := []Values{
+		{Weight: &Measurement{Value: 70, Unit: UnitCentimeter}},
+		{Temperature: &Measurement{Value: 37, Unit: UnitFahrenheit}},
+		{SpO2: &Measurement{Value: 101, Unit: UnitPercent}},
+		{Pulse: &Measurement{Value: 70}},
+		{BloodPressure: &BloodPressure{Systolic: 80, Diastolic: 120, Unit: UnitMmHg}},
+	}
+	for _, values := range invalid {
+		_, err := NewReading(patientID, now, values, "nurse-1")
+		var measurementErr *MeasurementError
+		assert.ErrorAs(t, err, &measurementErr)
+	}
+}
+
+func TestDeriveBMI(t *testing.T) {
+	assert.Equal(t, 22.9, BMI(Measurement{Value: 175, Unit: UnitCentimeter}, Measurement{Value: 70, Unit: UnitKilogram}))
+
+	reading := &Reading{Values: Values{Weight: &Measurement{Value: 70, Unit: UnitKilogram}}}
+	reading.DeriveBMI(nil)
+	assert.Nil(t, reading.BMI, "BMI needs a height")
+
+	reading.DeriveBMI(&Measurement{Value: 175, Unit: UnitCentimeter})
+	require.NotNil(t, reading.BMI)
+	assert.Equal(t, 22.9, *reading.BMI)
+
+	reading.Height = &Measurement{Value: 160, Unit: UnitCentimeter}
+	reading.DeriveBMI(&Measurement{Value: 175, Unit: UnitCentimeter})
+	assert.Equal(t, 27.3, *reading.BMI, "The reading's own height should be preferred")
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ReadingFilter selects the readings of a patient taken within a time
+// range. Zero times leave the range open.
+type ReadingFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// Matches reports whether a reading is selected by the filter
+func (f ReadingFilter) Matches(reading *Reading) bool {
+	if !f.From.IsZero() && reading.TakenAt.Before(f.From) {
+		return false
+	}
+	return f.To.IsZero() || !reading.TakenAt.After(f.To)
+}
+
+// Repository defines the interface for vital sign persistence. Readings are
+// only ever added, and only removed all at once by DeleteByPatient when
+// their patient is purged or handed over by MoveToPatient when it is merged
+// into another. LastHeight returns the height of the most recent
+// reading taken before the given time, or nil if there is none.
+type Repository interface {
+	Create(ctx context.Context, reading *Reading) error
+	ListByPatient(ctx context.Context, patientID string, filter ReadingFilter) ([]*Reading, error)
+	LastHeight(ctx context.Context, patientID string, before time.Time) (*Measurement, error)
+	DeleteByPatient(ctx context.Context, patientID string) error
+	MoveToPatient(ctx context.Context, fromID, toID string) error
+}
+
+// RecordVitalsRepository defines the minimal interface for recording vital signs
+type RecordVitalsRepository interface {
+	Create(ctx context.Context, reading *Reading) error
+	LastHeight(ctx context.Context, patientID string, before time.Time) (*Measurement, error)
+}
+
+// VitalsHistoryRepository defines the minimal interface for reading the
+// vital signs of a patient, oldest first
+type VitalsHistoryRepository interface {
+	ListByPatient(ctx context.Context, patientID string, filter ReadingFilter) ([]*Reading, error)
+	LastHeight(ctx context.Context, patientID string, before time.Time) (*Measurement, error)
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"errors"
+	"math"
+)
+
+// Unit is the unit a measurement is given in
+type Unit string
+
+const (
+	UnitCentimeter Unit = "cm"
+	UnitInch       Unit = "in"
+	UnitKilogram   Unit = "kg"
+	UnitPound      Unit = "lb"
+	UnitMmHg       Unit = "mmHg"
+	UnitKilopascal Unit = "kPa"
+	UnitCelsius    Unit = "C"
+	UnitFahrenheit Unit = "F"
+	UnitPerMinute  Unit = "/min"
+	UnitPercent    Unit = "%"
+)
+
+// UnitSystem selects the units measurements are reported in
+type UnitSystem string
+
+const (
+	UnitSystemMetric   UnitSystem = "metric"
+	UnitSystemImperial UnitSystem = "imperial"
+)
+
+// IsValid reports whether the unit system is a known one
+func (s UnitSystem) IsValid() bool {
+	return s == UnitSystemMetric || s == UnitSystemImperial
+}
+
+// ErrIncompatibleUnit is returned when a value is converted between units
+// of different quantities, such as kilograms and centimeters
+var ErrIncompatibleUnit = errors.New("incompatible unit")
+
+// quantity is what a unit measures. Only units of the same quantity can be
+// converted into each other.
+type quantity int
+
+const (
+	quantityLength quantity = iota + 1
+	quantityMass
+	quantityPressure
+	quantityTemperature
+	quantityRate
+	quantityFraction
+)
+
+// scale converts a unit linearly to the base unit of its quantity:
+// base = value*factor + offset
+type scale struct {
+	quantity quantity
+	factor   float64
+	offset   float64
+}
+
+// units holds the scale of every known unit. The base units are the metric
+// ones, which are also the units measurements are stored in.
+var units = map[Unit]scale{
+	UnitCentimeter: {quantityLength, 1, 0},
+	UnitInch:       {quantityLength, 2.54, 0},
+	UnitKilogram:   {quantityMass, 1, 0},
+	UnitPound:      {quantityMass, 0.45359237, 0},
+	UnitMmHg:       {quantityPressure, 1, 0},
+	UnitKilopascal: {quantityPressure, 7.50061683, 0},
+	UnitCelsius:    {quantityTemperature, 1, 0},
+	UnitFahrenheit: {quantityTemperature, 5.0 / 9, -32 * 5.0 / 9},
+	UnitPerMinute:  {quantityRate, 1, 0},
+	UnitPercent:    {quantityFraction, 1, 0},
+}
+
+// IsValid reports whether the unit is a known one
+func (u Unit) IsValid() bool {
+	_, ok := units[u]
+	return ok
+}
+
+// Convert converts a value from one unit to another of the same quantity,
+// rounded to one decimal for reporting
+func Convert(value float64, from, to Unit) (float64, error) {
+	converted, err := convert(value, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return round(converted), nil
+}
+
+// convert converts a value from one unit to another of the same quantity
+// without rounding it
+func convert(value float64, from, to Unit) (float64, error) {
+	source, ok := units[from]
+	if !ok {
+		return 0, ErrIncompatibleUnit
+	}
+	target, ok := units[to]
+	if !ok || source.quantity != target.quantity {
+		return 0, ErrIncompatibleUnit
+	}
+	if from == to {
+		return value, nil
+	}
+
+	base := value*source.factor + source.offset
+	return (base - target.offset) / target.factor, nil
+}
+
+// round rounds a value to one decimal
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to Unit
+		want     float64
+	}{
+		{70, UnitInch, UnitCentimeter, 177.8},
+		{177.8, UnitCentimeter, UnitInch, 70},
+		{154, UnitPound, UnitKilogram, 69.9},
+		{70, UnitKilogram, UnitPound, 154.3},
+		{98.6, UnitFahrenheit, UnitCelsius, 37},
+		{37, UnitCelsius, UnitFahrenheit, 98.6},
+		{16, UnitKilopascal, UnitMmHg, 120},
+		{36.64, UnitCelsius, UnitCelsius, 36.6},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%g %s in %s", tt.value, tt.from, tt.to)
+	}
+
+	_, err := Convert(70, UnitKilogram, UnitCentimeter)
+	assert.ErrorIs(t, err, ErrIncompatibleUnit)
+	_, err = Convert(70, Unit("stone"), UnitKilogram)
+	assert.ErrorIs(t, err, ErrIncompatibleUnit)
+}
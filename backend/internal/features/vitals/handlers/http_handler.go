@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/commands"
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/features/vitals/queries"
+	"github.com/dksch/pococlinic/internal/pkg/authz"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Access identifies the permission a vitals route requires. Recording vital
+// signs is granted on its own, so that nurses can do it without being able
+// to change anything else.
+type Access string
+
+const (
+	AccessReadVitals  Access = "patients:read:vitals"
+	AccessWriteVitals Access = "patients:write:vitals"
+)
+
+// Guard checks whether the caller may read or record vital signs
+type Guard = authz.Guard[Access]
+
+// VitalsHandler handles HTTP requests for vital signs
+type VitalsHandler struct {
+	recordVitalsHandler     commands.RecordVitalsHandler
+	getVitalsHistoryHandler queries.GetVitalsHistoryHandler
+	logger                  *logging.Logger
+}
+
+// NewVitalsHandler creates a new vitals handler
+func NewVitalsHandler(
+	recordHandler commands.RecordVitalsHandler,
+	historyHandler queries.GetVitalsHistoryHandler,
+	logger *logging.Logger,
+) *VitalsHandler {
+	return &VitalsHandler{
+		recordVitalsHandler:     recordHandler,
+		getVitalsHistoryHandler: historyHandler,
+		logger:                  logger,
+	}
+}
+
+// RegisterRoutes registers the vitals routes below the patient they belong
+// to, recording guarded apart from reading
+func (h *VitalsHandler) RegisterRoutes(router *gin.RouterGroup, guard Guard) {
+	guard = guard.Required()
+
+	vitals := router.Group("/patients/:id/vitals")
+	{
+		vitals.POST("", guard(AccessWriteVitals), h.RecordVitals)
+		vitals.GET("", guard(AccessReadVitals), h.GetVitalsHistory)
+	}
+}
+
+// RecordVitals handles the request to record vital signs of a patient
+func (h *VitalsHandler) RecordVitals(c *gin.Context) {
+	var cmd commands.RecordVitalsCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid request body"))
+		return
+	}
+	cmd.PatientID = c.Param("id")
+	cmd.RecordedBy = c.GetString("userID")
+
+	reading, err := h.recordVitalsHandler.Handle(c.Request.Context(), cmd)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to record vital signs")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reading)
+}
+
+// GetVitalsHistory handles the request for the vital signs of a patient.
+// The range is given by from and to, as timestamps or dates; a date as the
+// end of the range includes that whole day.
+func (h *VitalsHandler) GetVitalsHistory(c *gin.Context) {
+	query := queries.GetVitalsHistoryQuery{
+		PatientID: c.Param("id"),
+		Kind:      domain.Kind(c.Query("kind")),
+		Units:     domain.UnitSystem(c.Query("units")),
+	}
+
+	for name, bound := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			*bound = t
+			continue
+		}
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewAPIError(errors.ErrValidation, "Invalid "+name+", expected an RFC 3339 timestamp or YYYY-MM-DD"))
+			return
+		}
+		if name == "to" {
+			day = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		*bound = day
+	}
+
+	history, err := h.getVitalsHistoryHandler.Handle(c.Request.Context(), query)
+	if err != nil {
+		h.respondWithError(c, err, "Failed to fetch vital signs")
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// respondWithError writes an API error with its status and hides any other
+// error behind the given message
+func (h *VitalsHandler) respondWithError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		c.JSON(errors.StatusCode(apiErr.Code), apiErr)
+		return
+	}
+	h.logger.Error(message, err)
+	c.JSON(http.StatusInternalServerError, errors.NewAPIError(errors.ErrInternalServer, message))
+}
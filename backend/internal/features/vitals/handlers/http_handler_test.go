@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/commands"
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/features/vitals/infrastructure"
+	"github.com/dksch/pococlinic/internal/features/vitals/queries"
+	"github.com/dksch/pococlinic/internal/pkg/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patientDirectory knows a fixed set of active patients
+type patientDirectory map[string]bool
+
+func (d patientDirectory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+// setupRouter serves the vitals routes with a guard that records the access
+// each request requires and denies requests with an X-Deny header
+func setupRouter(patientID string) (*gin.Engine, *[]Access) {
+	gin.SetMode(gin.TestMode)
+	repo := infrastructure.NewMemoryRepository()
+	handler := NewVitalsHandler(
+		commands.NewRecordVitalsHandler(repo, patientDirectory{patientID: true}),
+		queries.NewGetVitalsHistoryHandler(repo),
+		logging.NewLogger(),
+	)
+
+	requested := &[]Access{}
+	guard := func(access Access) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			*requested = append(*requested, access)
+			if c.GetHeader("X-Deny") != "" {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+		}
+	}
+
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"), guard)
+	return router, requested
+}
+
+func serve(router *gin.Engine, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRecordVitals(t *testing.T) {
+	patientID := uuid.NewString()
+	router, requested := setupRouter(patientID)
+	path := "/api/patients/" + patientID + "/vitals"
+
+	w := serve(router, "POST", path, `{"weight": {"value": 154.3, "unit": "lb"}, "pulse": {"value": 72, "unit": "/min"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reading domain.Reading
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reading))
+	assert.Equal(t, &domain.Measurement{Value: 70, Unit: domain.UnitKilogram}, reading.Weight)
+	assert.Equal(t, []Access{AccessWriteVitals}, *requested)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		header []string
+		status int
+	}{
+		{"Malformed body", path, `{"weight": 70}`, nil, http.StatusBadRequest},
+		{"Invalid reading", path, `{}`, nil, http.StatusBadRequest},
+		{"Unknown patient", "/api/patients/" + uuid.NewString() + "/vitals", `{"pulse": {"value": 72, "unit": "/min"}}`, nil, http.StatusNotFound},
+		{"Denied", path, `{"pulse": {"value": 72, "unit": "/min"}}`, []string{"X-Deny", "1"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, serve(router, "POST", tt.path, tt.body, tt.header...).Code)
+		})
+	}
+}
+
+func TestGetVitalsHistory(t *testing.T) {
+	patientID := uuid.NewString()
+	router, requested := setupRouter(patientID)
+	path := "/api/patients/" + patientID + "/vitals"
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	lateYesterday := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 23, 0, 0, 0, time.UTC)
+	body := `{"takenAt": "` + lateYesterday.Format(time.RFC3339) + `", "weight": {"value": 70, "unit": "kg"}}`
+	require.Equal(t, http.StatusCreated, serve(router, "POST", path, body).Code)
+	require.Equal(t, http.StatusCreated, serve(router, "POST", path, `{"pulse": {"value": 72, "unit": "/min"}}`).Code)
+	*requested = nil
+
+	history := func(query string) queries.VitalsHistory {
+		t.Helper()
+		w := serve(router, "GET", path+query, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var history queries.VitalsHistory
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+		return history
+	}
+
+	assert.Len(t, history("").Readings, 2)
+	assert.Equal(t, []Access{AccessReadVitals}, *requested)
+
+	// A date as the end of the range includes that whole day
+	day := lateYesterday.Format("2006-01-02")
+	readings := history("?to=" + day).Readings
+	require.Len(t, readings, 1)
+	assert.NotNil(t, readings[0].Weight)
+	assert.Len(t, history("?from="+day+"&kind=pulse").Readings, 1)
+
+	imperial := history("?units=imperial&kind=weight")
+	assert.Equal(t, domain.UnitSystemImperial, imperial.Units)
+	require.Len(t, imperial.Readings, 1)
+	assert.Equal(t, &domain.Measurement{Value: 154.3, Unit: domain.UnitPound}, imperial.Readings[0].Weight)
+
+	assert.Equal(t, http.StatusBadRequest, serve(router, "GET", path+"?from=yesterday", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, "GET", path+"?units=nautical", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(router, "GET", path, "", "X-Deny", "1").Code)
+}
@@ -0,0 +1,110 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryRepository is a simple in-memory implementation of the vitals Repository interface
+type MemoryRepository struct {
+	readings map[string][]*domain.Reading
+	mu       sync.RWMutex
+}
+
+// NewMemoryRepository creates a new in-memory vitals repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		readings: make(map[string][]*domain.Reading),
+	}
+}
+
+// Create adds a new reading to the repository, keeping the readings of the
+// patient ordered by the time they were taken
+func (r *MemoryRepository) Create(ctx context.Context, reading *domain.Reading) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *reading
+	stored.BMI = nil
+	patientID := reading.PatientID.String()
+	readings := append(r.readings[patientID], &stored)
+	sort.SliceStable(readings, func(i, j int) bool {
+		return readings[i].TakenAt.Before(readings[j].TakenAt)
+	})
+	r.readings[patientID] = readings
+	return nil
+}
+
+// ListByPatient returns the patient's readings within the filter's range,
+// oldest first
+func (r *MemoryRepository) ListByPatient(ctx context.Context, patientID string, filter domain.ReadingFilter) ([]*domain.Reading, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	readings := []*domain.Reading{}
+	for _, reading := range r.readings[patientID] {
+		if filter.Matches(reading) {
+			found := *reading
+			readings = append(readings, &found)
+		}
+	}
+	return readings, nil
+}
+
+// LastHeight returns the height of the patient's most recent reading taken
+// before the given time
+func (r *MemoryRepository) LastHeight(ctx context.Context, patientID string, before time.Time) (*domain.Measurement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	readings := r.readings[patientID]
+	for i := len(readings) - 1; i >= 0; i-- {
+		if readings[i].TakenAt.Before(before) && readings[i].Height != nil {
+			height := *readings[i].Height
+			return &height, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteByPatient removes all readings of the patient
+func (r *MemoryRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.readings, patientID)
+	return nil
+}
+
+// MoveToPatient hands all readings of one patient to another, keeping the
+// readings of the latter ordered by the time they were taken
+func (r *MemoryRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	target, err := uuid.Parse(toID)
+	if err != nil {
+		return fmt.Errorf("invalid patient ID %q: %w", toID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	moved := r.readings[fromID]
+	if len(moved) == 0 {
+		return nil
+	}
+	for _, reading := range moved {
+		reading.PatientID = target
+	}
+	readings := append(r.readings[toID], moved...)
+	sort.SliceStable(readings, func(i, j int) bool {
+		return readings[i].TakenAt.Before(readings[j].TakenAt)
+	})
+	r.readings[toID] = readings
+	delete(r.readings, fromID)
+	return nil
+}
@@ -0,0 +1,264 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+)
+
+// timestampLayout stores timestamps as fixed-width UTC text, so that they
+// sort and compare correctly as strings
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteMigrations holds the schema of the vitals feature, in order. Every
+// value is stored in the metric unit its column is named after; readings
+// are never changed once recorded. Only while a patient is listed in
+// vital_transfers can their readings be handed to another patient, which
+// MoveToPatient does within a single transaction.
+var sqliteMigrations = []string{
+	`CREATE TABLE vital_readings (
+		id                   TEXT PRIMARY KEY,
+		patient_id           TEXT NOT NULL,
+		taken_at             TEXT NOT NULL,
+		height_cm            REAL,
+		weight_kg            REAL,
+		systolic_mmhg        REAL,
+		diastolic_mmhg       REAL,
+		pulse_per_min        REAL,
+		temperature_c        REAL,
+		spo2_percent         REAL,
+		respiratory_per_min  REAL,
+		recorded_by          TEXT NOT NULL DEFAULT '',
+		recorded_at          TEXT NOT NULL
+	);
+	CREATE INDEX idx_vital_readings_patient_taken ON vital_readings (patient_id, taken_at);
+	CREATE TRIGGER vital_readings_append_only_update BEFORE UPDATE ON vital_readings
+	BEGIN SELECT RAISE(ABORT, 'vital readings cannot be changed'); END;`,
+	`CREATE TABLE vital_transfers (
+		patient_id TEXT PRIMARY KEY
+	);
+	DROP TRIGGER vital_readings_append_only_update;
+	CREATE TRIGGER vital_readings_append_only_update BEFORE UPDATE ON vital_readings
+	WHEN OLD.patient_id NOT IN (SELECT patient_id FROM vital_transfers)
+	BEGIN SELECT RAISE(ABORT, 'vital readings cannot be changed'); END;`,
+}
+
+// readingColumns lists the reading columns in the order scanReading expects
+const readingColumns = `id, patient_id, taken_at, height_cm, weight_kg, systolic_mmhg, diastolic_mmhg,
+	pulse_per_min, temperature_c, spo2_percent, respiratory_per_min, recorded_by, recorded_at`
+
+// MigrateSQLite applies the vitals schema to the database
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return database.Migrate(ctx, db, "vitals", sqliteMigrations)
+}
+
+// SQLiteRepository is a SQLite implementation of the vitals Repository interface
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite vitals repository. The schema
+// must have been migrated with MigrateSQLite.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// Create adds a new reading to the repository
+func (r *SQLiteRepository) Create(ctx context.Context, reading *domain.Reading) error {
+	var systolic, diastolic sql.NullFloat64
+	if bp := reading.BloodPressure; bp != nil {
+		systolic = sql.NullFloat64{Float64: bp.Systolic, Valid: true}
+		diastolic = sql.NullFloat64{Float64: bp.Diastolic, Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO vital_readings (`+readingColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reading.ID.String(),
+		reading.PatientID.String(),
+		formatTime(reading.TakenAt),
+		nullValue(reading.Height),
+		nullValue(reading.Weight),
+		systolic,
+		diastolic,
+		nullValue(reading.Pulse),
+		nullValue(reading.Temperature),
+		nullValue(reading.SpO2),
+		nullValue(reading.RespiratoryRate),
+		reading.RecordedBy,
+		formatTime(reading.RecordedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record vital signs: %w", err)
+	}
+	return nil
+}
+
+// ListByPatient returns the patient's readings within the filter's range,
+// oldest first
+func (r *SQLiteRepository) ListByPatient(ctx context.Context, patientID string, filter domain.ReadingFilter) ([]*domain.Reading, error) {
+	conditions := []string{"patient_id = ?"}
+	args := []any{patientID}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "taken_at >= ?")
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "taken_at <= ?")
+		args = append(args, formatTime(filter.To))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+readingColumns+` FROM vital_readings
+		WHERE `+strings.Join(conditions, " AND ")+` ORDER BY taken_at, recorded_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vital signs: %w", err)
+	}
+	defer rows.Close()
+
+	readings := []*domain.Reading{}
+	for rows.Next() {
+		reading, err := scanReading(rows)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, reading)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list vital signs: %w", err)
+	}
+	return readings, nil
+}
+
+// LastHeight returns the height of the patient's most recent reading taken
+// before the given time
+func (r *SQLiteRepository) LastHeight(ctx context.Context, patientID string, before time.Time) (*domain.Measurement, error) {
+	var height float64
+	err := r.db.QueryRowContext(ctx, `SELECT height_cm FROM vital_readings
+		WHERE patient_id = ? AND taken_at < ? AND height_cm IS NOT NULL
+		ORDER BY taken_at DESC, recorded_at DESC, id DESC LIMIT 1`,
+		patientID, formatTime(before),
+	).Scan(&height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last height: %w", err)
+	}
+	return &domain.Measurement{Value: height, Unit: domain.UnitCentimeter}, nil
+}
+
+// DeleteByPatient removes all readings of the patient
+func (r *SQLiteRepository) DeleteByPatient(ctx context.Context, patientID string) error {
+	_, err := database.ConnFrom(ctx, r.db).ExecContext(ctx, `DELETE FROM vital_readings WHERE patient_id = ?`, patientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete readings of patient %s: %w", patientID, err)
+	}
+	return nil
+}
+
+// MoveToPatient hands all readings of one patient to another. The patient
+// is listed in vital_transfers meanwhile, so that the trigger lets the
+// readings change hands.
+func (r *SQLiteRepository) MoveToPatient(ctx context.Context, fromID, toID string) error {
+	return database.InTx(ctx, r.db, func(ctx context.Context) error {
+		conn := database.ConnFrom(ctx, r.db)
+		if _, err := conn.ExecContext(ctx, `INSERT INTO vital_transfers (patient_id) VALUES (?)`, fromID); err != nil {
+			return fmt.Errorf("failed to move readings of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `UPDATE vital_readings SET patient_id = ? WHERE patient_id = ?`, toID, fromID); err != nil {
+			return fmt.Errorf("failed to move readings of patient %s: %w", fromID, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM vital_transfers WHERE patient_id = ?`, fromID); err != nil {
+			return fmt.Errorf("failed to move readings of patient %s: %w", fromID, err)
+		}
+		return nil
+	})
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanReading reads a reading selected with readingColumns
+func scanReading(row rowScanner) (*domain.Reading, error) {
+	var (
+		reading                                   domain.Reading
+		id, patientID, takenAt, recordedAt        string
+		height, weight, systolic, diastolic       sql.NullFloat64
+		pulse, temperature, spo2, respiratoryRate sql.NullFloat64
+	)
+	err := row.Scan(
+		&id,
+		&patientID,
+		&takenAt,
+		&height,
+		&weight,
+		&systolic,
+		&diastolic,
+		&pulse,
+		&temperature,
+		&spo2,
+		&respiratoryRate,
+		&reading.RecordedBy,
+		&recordedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if reading.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid reading ID %q: %w", id, err)
+	}
+	if reading.PatientID, err = uuid.Parse(patientID); err != nil {
+		return nil, fmt.Errorf("invalid patient ID of reading %s: %w", id, err)
+	}
+	if reading.TakenAt, err = time.Parse(timestampLayout, takenAt); err != nil {
+		return nil, fmt.Errorf("invalid time of reading %s: %w", id, err)
+	}
+	if reading.RecordedAt, err = time.Parse(timestampLayout, recordedAt); err != nil {
+		return nil, fmt.Errorf("invalid recording time of reading %s: %w", id, err)
+	}
+
+	reading.Height = measurement(height, domain.UnitCentimeter)
+	reading.Weight = measurement(weight, domain.UnitKilogram)
+	reading.Pulse = measurement(pulse, domain.UnitPerMinute)
+	reading.Temperature = measurement(temperature, domain.UnitCelsius)
+	reading.SpO2 = measurement(spo2, domain.UnitPercent)
+	reading.RespiratoryRate = measurement(respiratoryRate, domain.UnitPerMinute)
+	if systolic.Valid && diastolic.Valid {
+		reading.BloodPressure = &domain.BloodPressure{
+			Systolic:  systolic.Float64,
+			Diastolic: diastolic.Float64,
+			Unit:      domain.UnitMmHg,
+		}
+	}
+	return &reading, nil
+}
+
+// measurement returns a stored value in its unit, or nil if it is missing
+func measurement(value sql.NullFloat64, unit domain.Unit) *domain.Measurement {
+	if !value.Valid {
+		return nil
+	}
+	return &domain.Measurement{Value: value.Float64, Unit: unit}
+}
+
+// nullValue returns the value of an optional measurement for storage
+func nullValue(m *domain.Measurement) sql.NullFloat64 {
+	if m == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: m.Value, Valid: true}
+}
+
+// formatTime formats a timestamp for storage
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
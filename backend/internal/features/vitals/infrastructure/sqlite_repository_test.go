@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteRepository(t *testing.T) (*SQLiteRepository, *sql.DB) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "vitals.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, MigrateSQLite(context.Background(), db))
+	return NewSQLiteRepository(db), db
+}
+
+func TestSQLiteRepositoryReadings(t *testing.T) {
+	ctx := context.Background()
+	repo, db := setupSQLiteRepository(t)
+	patientID := uuid.New()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	first, err := domain.NewReading(patientID, start, domain.Values{
+		Height:        &domain.Measurement{Value: 175, Unit: domain.UnitCentimeter},
+		Weight:        &domain.Measurement{Value: 70, Unit: domain.UnitKilogram},
+		BloodPressure: &domain.BloodPressure{Systolic: 120, Diastolic: 80, Unit: domain.UnitMmHg},
+	}, "nurse-1")
+	require.NoError(t, err)
+	second, err := domain.NewReading(patientID, start.AddDate(0, 1, 0), domain.Values{
+		Temperature: &domain.Measurement{Value: 37.2, Unit: domain.UnitCelsius},
+		SpO2:        &domain.Measurement{Value: 98, Unit: domain.UnitPercent},
+	}, "nurse-1")
+	require.NoError(t, err)
+	other, err := domain.NewReading(uuid.New(), start, domain.Values{
+		Pulse: &domain.Measurement{Value: 60, Unit: domain.UnitPerMinute},
+	}, "nurse-1")
+	require.NoError(t, err)
+
+	// Stored out of order, listed by the time they were taken
+	for _, reading := range []*domain.Reading{second, first, other} {
+		require.NoError(t, repo.Create(ctx, reading))
+	}
+
+	readings, err := repo.ListByPatient(ctx, patientID.String(), domain.ReadingFilter{})
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, first.ID, readings[0].ID)
+	assert.True(t, start.Equal(readings[0].TakenAt))
+	assert.Equal(t, first.Values, readings[0].Values)
+	assert.Equal(t, second.Values, readings[1].Values)
+
+	readings, err = repo.ListByPatient(ctx, patientID.String(), domain.ReadingFilter{From: start.Add(time.Second)})
+	require.NoError(t, err)
+	require.Len(t, readings, 1)
+	assert.Equal(t, second.ID, readings[0].ID)
+
+	height, err := repo.LastHeight(ctx, patientID.String(), second.TakenAt)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Measurement{Value: 175, Unit: domain.UnitCentimeter}, height)
+
+	height, err = repo.LastHeight(ctx, patientID.String(), start)
+	require.NoError(t, err)
+	assert.Nil(t, height)
+
+	_, err = db.ExecContext(ctx, `UPDATE vital_readings SET weight_kg = 80`)
+	assert.Error(t, err, "Readings should not be changeable")
+
+	// Merging the patient into another hands over the readings
+	keptID := uuid.New()
+	require.NoError(t, repo.MoveToPatient(ctx, patientID.String(), keptID.String()))
+	readings, err = repo.ListByPatient(ctx, patientID.String(), domain.ReadingFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+	readings, err = repo.ListByPatient(ctx, keptID.String(), domain.ReadingFilter{})
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, keptID, readings[0].PatientID)
+
+	_, err = db.ExecContext(ctx, `UPDATE vital_readings SET patient_id = ?`, patientID.String())
+	assert.Error(t, err, "Readings should not be changeable")
+
+	// Purging the patient removes all readings
+	require.NoError(t, repo.DeleteByPatient(ctx, keptID.String()))
+	readings, err = repo.ListByPatient(ctx, keptID.String(), domain.ReadingFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+}
@@ -0,0 +1,85 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+)
+
+// GetVitalsHistoryQuery represents the query for the vital signs of a
+// patient taken within a time range, in the units of a unit system
+type GetVitalsHistoryQuery struct {
+	PatientID string
+	From      time.Time
+	To        time.Time
+	Kind      domain.Kind
+	Units     domain.UnitSystem
+}
+
+// VitalsHistory represents the readings of a patient, oldest first, so
+// that they can be charted as they are
+type VitalsHistory struct {
+	Readings []*domain.Reading `json:"readings"`
+	Units    domain.UnitSystem `json:"units"`
+}
+
+// GetVitalsHistoryHandler handles the get vitals history query
+type GetVitalsHistoryHandler interface {
+	Handle(ctx context.Context, query GetVitalsHistoryQuery) (*VitalsHistory, error)
+}
+
+type getVitalsHistoryHandler struct {
+	repo domain.VitalsHistoryRepository
+}
+
+// NewGetVitalsHistoryHandler creates a new get vitals history handler
+func NewGetVitalsHistoryHandler(repo domain.VitalsHistoryRepository) GetVitalsHistoryHandler {
+	return &getVitalsHistoryHandler{repo: repo}
+}
+
+// Handle processes the get vitals history query. Each reading with a
+// weight gets its BMI from the latest height known at the time.
+func (h *getVitalsHistoryHandler) Handle(ctx context.Context, query GetVitalsHistoryQuery) (*VitalsHistory, error) {
+	if query.Units == "" {
+		query.Units = domain.UnitSystemMetric
+	}
+	if !query.Units.IsValid() {
+		return nil, errors.NewAPIError(errors.ErrValidation, "Units must be metric or imperial")
+	}
+	if query.Kind != "" && !query.Kind.IsValid() {
+		return nil, errors.NewAPIError(errors.ErrValidation,
+			"Kind must be one of height, weight, bloodPressure, pulse, temperature, spo2 or respiratoryRate")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, errors.NewAPIError(errors.ErrValidation, "The range must not end before it starts")
+	}
+
+	// The kind is applied below, as the BMI needs the heights of readings
+	// that may not be of that kind
+	filter := domain.ReadingFilter{From: query.From, To: query.To}
+	readings, err := h.repo.ListByPatient(ctx, query.PatientID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastHeight *domain.Measurement
+	if len(readings) > 0 {
+		if lastHeight, err = h.repo.LastHeight(ctx, query.PatientID, readings[0].TakenAt); err != nil {
+			return nil, err
+		}
+	}
+
+	history := &VitalsHistory{Readings: []*domain.Reading{}, Units: query.Units}
+	for _, reading := range readings {
+		reading.DeriveBMI(lastHeight)
+		if reading.Height != nil {
+			lastHeight = reading.Height
+		}
+		if query.Kind == "" || reading.Has(query.Kind) {
+			history.Readings = append(history.Readings, reading.InUnits(query.Units))
+		}
+	}
+	return history, nil
+}
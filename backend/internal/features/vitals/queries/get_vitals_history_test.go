@@ -0,0 +1,63 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dksch/pococlinic/internal/features/vitals/domain"
+	"github.com/dksch/pococlinic/internal/features/vitals/infrastructure"
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVitalsHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := infrastructure.NewMemoryRepository()
+	patientID := uuid.New()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	record := func(days int, values domain.Values) {
+		reading, err := domain.NewReading(patientID, start.AddDate(0, 0, days), values, "nurse-1")
+		require.NoError(t, err)
+		require.NoError(t, repo.Create(ctx, reading))
+	}
+	record(0, domain.Values{Height: &domain.Measurement{Value: 175, Unit: domain.UnitCentimeter}})
+	record(10, domain.Values{Weight: &domain.Measurement{Value: 70, Unit: domain.UnitKilogram}})
+	record(20, domain.Values{Pulse: &domain.Measurement{Value: 72, Unit: domain.UnitPerMinute}})
+	record(30, domain.Values{Weight: &domain.Measurement{Value: 176.4, Unit: domain.UnitPound}})
+
+	handler := NewGetVitalsHistoryHandler(repo)
+	history, err := handler.Handle(ctx, GetVitalsHistoryQuery{
+		PatientID: patientID.String(),
+		From:      start.AddDate(0, 0, 5),
+		Kind:      domain.KindWeight,
+		Units:     domain.UnitSystemImperial,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, history.Readings, 2)
+	assert.Equal(t, domain.UnitSystemImperial, history.Units)
+	assert.Equal(t, &domain.Measurement{Value: 154.3, Unit: domain.UnitPound}, history.Readings[0].Weight)
+	require.NotNil(t, history.Readings[0].BMI, "BMI should use the height measured before the range")
+	assert.Equal(t, 22.9, *history.Readings[0].BMI)
+	assert.Equal(t, 26.1, *history.Readings[1].BMI)
+
+	all, err := handler.Handle(ctx, GetVitalsHistoryQuery{PatientID: patientID.String(), To: start.AddDate(0, 0, 20)})
+	require.NoError(t, err)
+	assert.Len(t, all.Readings, 3)
+	assert.Equal(t, domain.UnitSystemMetric, all.Units)
+
+	for _, query := range []GetVitalsHistoryQuery{
+		{PatientID: patientID.String(), Units: "nautical"},
+		{PatientID: patientID.String(), Kind: "mood"},
+		{PatientID: patientID.String(), From: start, To: start.AddDate(0, 0, -1)},
+	} {
+		_, err := handler.Handle(ctx, query)
+		apiErr, ok := err.(*errors.APIError)
+		require.True(t, ok, "Expected an APIError, got %v", err)
+		assert.Equal(t, errors.ErrValidation, apiErr.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package authz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type access string
+
+func TestRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	deny := Guard[access](func(access) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	})
+
+	serve := func(guard Guard[access]) int {
+		router := gin.New()
+		router.GET("/", guard.Required()("read"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve(deny))
+	assert.Equal(t, http.StatusOK, serve(AllowAll[access]()))
+
+	// A missing guard fails closed when the routes are registered
+	assert.PanicsWithValue(t, "authz: routes registered without a guard", func() { serve(nil) })
+}
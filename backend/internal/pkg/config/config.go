@@ -1,9 +1,13 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -11,8 +15,13 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Security SecurityConfig
+	Server      ServerConfig
+	Security    SecurityConfig
+	Auth        AuthConfig
+	Database    DatabaseConfig
+	Patients    PatientsConfig
+	Medications MedicationsConfig
+	Problems    ProblemsConfig
 }
 
 // ServerConfig holds all server-related configuration
@@ -33,6 +42,92 @@ type RateLimitConfig struct {
 	BurstSize         int
 }
 
+// AuthConfig holds authentication-related configuration
+type AuthConfig struct {
+	AccessTokenSecret   string
+	RefreshTokenSecret  string
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	Issuer              string
+	BootstrapAdminEmail string
+	BootstrapAdminName  string
+	// BootstrapAdminCredentialsFile receives the bootstrap administrator's
+	// generated credentials; it must not exist yet
+	BootstrapAdminCredentialsFile string
+	Hashing                       HashingConfig
+	Lockout                       LockoutConfig
+	Permissions                   map[string][]string
+	EmergencyAccess               EmergencyAccessConfig
+}
+
+// HashingConfig holds the Argon2id cost parameters for credential hashing.
+// Raising them upgrades existing credentials on each user's next login.
+type HashingConfig struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// LockoutConfig holds the account lockout policy: after MaxAttempts failed
+// attempts in a row an account is locked for Duration.
+type LockoutConfig struct {
+	MaxAttempts int
+	Duration    time.Duration
+}
+
+// EmergencyAccessConfig holds the break-the-glass policy: a grant lasts TTL
+// and adds Permissions on top of the caller's role. By default it only adds
+// reading patient records; writing, such as prescribing, has to be granted
+// explicitly.
+type EmergencyAccessConfig struct {
+	TTL         time.Duration
+	Permissions []string
+}
+
+// DefaultPermissions is the role to permission matrix used when no
+// PERMISSIONS_FILE is configured. A permission also grants every more
+// specific permission below it, and "*" grants everything, including
+// managing users (users:manage) and reviewing emergency access
+// (emergency:review).
+var DefaultPermissions = map[string][]string{
+	"admin":  {"*"},
+	"doctor": {"patients:read", "patients:write", "emergency:request"},
+	"nurse":  {"patients:read", "patients:write:vitals", "patients:write:allergies", "emergency:request"},
+	"staff":  {"patients:read:demographics", "patients:write:demographics", "emergency:request"},
+}
+
+// PatientsConfig holds the retention rules for patient records. An archived
+// record may be purged RetentionYears after its last change, and for minors
+// no earlier than RetentionYears after they reach AgeOfMajority.
+type PatientsConfig struct {
+	RetentionYears int
+	AgeOfMajority  int
+}
+
+// MedicationsConfig holds the prescribing settings. Prescriptions are
+// checked for interactions against the rules in InteractionRulesFile.
+type MedicationsConfig struct {
+	InteractionRulesFile string
+}
+
+// ProblemsConfig holds the problem list settings. The ICD-10 code set is
+// imported from ICD10CodesFile on startup whenever the file has changed.
+type ProblemsConfig struct {
+	ICD10CodesFile string
+}
+
+// Supported storage drivers
+const (
+	DriverMemory = "memory"
+	DriverSQLite = "sqlite"
+)
+
+// DatabaseConfig holds storage configuration
+type DatabaseConfig struct {
+	Driver string
+	Path   string
+}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	config := &Config{}
@@ -64,15 +159,159 @@ func LoadConfig() (*Config, error) {
 		BurstSize:         burst,
 	}
 
+	// Auth configuration
+	if err := loadAuthConfig(&config.Auth); err != nil {
+		return nil, err
+	}
+
+	// Database configuration
+	config.Database.Driver = getEnvOrDefault("DB_DRIVER", DriverMemory)
+	if config.Database.Driver != DriverMemory && config.Database.Driver != DriverSQLite {
+		return nil, fmt.Errorf("invalid DB_DRIVER %q: must be %q or %q", config.Database.Driver, DriverMemory, DriverSQLite)
+	}
+	config.Database.Path = getEnvOrDefault("DB_PATH", "pococlinic.db")
+
+	// Patient record retention
+	retentionYears, err := strconv.Atoi(getEnvOrDefault("PATIENT_RETENTION_YEARS", "10"))
+	if err != nil || retentionYears < 0 {
+		return nil, fmt.Errorf("invalid PATIENT_RETENTION_YEARS: must be a non-negative integer")
+	}
+	ageOfMajority, err := strconv.Atoi(getEnvOrDefault("PATIENT_AGE_OF_MAJORITY", "18"))
+	if err != nil || ageOfMajority < 0 {
+		return nil, fmt.Errorf("invalid PATIENT_AGE_OF_MAJORITY: must be a non-negative integer")
+	}
+	config.Patients = PatientsConfig{
+		RetentionYears: retentionYears,
+		AgeOfMajority:  ageOfMajority,
+	}
+
+	// Prescribing
+	config.Medications.InteractionRulesFile = getEnvOrDefault("INTERACTION_RULES_FILE", "")
+
+	// Problem list coding
+	config.Problems.ICD10CodesFile = getEnvOrDefault("ICD10_CODES_FILE", "")
+
 	return config, nil
 }
 
+// loadAuthConfig loads token and bootstrap settings. Outside production a
+// missing token secret is replaced with a random one, which invalidates all
+// tokens on restart.
+func loadAuthConfig(auth *AuthConfig) error {
+	var err error
+	auth.AccessTokenSecret, err = getSecret("JWT_ACCESS_SECRET")
+	if err != nil {
+		return err
+	}
+	auth.RefreshTokenSecret, err = getSecret("JWT_REFRESH_SECRET")
+	if err != nil {
+		return err
+	}
+
+	auth.AccessTokenTTL, err = time.ParseDuration(getEnvOrDefault("JWT_ACCESS_TTL", "15m"))
+	if err != nil {
+		return fmt.Errorf("invalid JWT_ACCESS_TTL: %w", err)
+	}
+	auth.RefreshTokenTTL, err = time.ParseDuration(getEnvOrDefault("JWT_REFRESH_TTL", "24h"))
+	if err != nil {
+		return fmt.Errorf("invalid JWT_REFRESH_TTL: %w", err)
+	}
+
+	auth.Issuer = getEnvOrDefault("JWT_ISSUER", "pococlinic")
+	auth.BootstrapAdminEmail = getEnvOrDefault("BOOTSTRAP_ADMIN_EMAIL", "")
+	auth.BootstrapAdminName = getEnvOrDefault("BOOTSTRAP_ADMIN_NAME", "Administrator")
+	auth.BootstrapAdminCredentialsFile = getEnvOrDefault("BOOTSTRAP_ADMIN_CREDENTIALS_FILE", "bootstrap-admin.txt")
+
+	hashTime, err := strconv.ParseUint(getEnvOrDefault("ARGON2_TIME", "1"), 10, 32)
+	if err != nil || hashTime == 0 {
+		return fmt.Errorf("invalid ARGON2_TIME: must be a positive integer")
+	}
+	hashMemory, err := strconv.ParseUint(getEnvOrDefault("ARGON2_MEMORY_KIB", "65536"), 10, 32)
+	if err != nil || hashMemory < 8*1024 {
+		return fmt.Errorf("invalid ARGON2_MEMORY_KIB: must be at least 8192")
+	}
+	hashThreads, err := strconv.ParseUint(getEnvOrDefault("ARGON2_THREADS", "4"), 10, 8)
+	if err != nil || hashThreads == 0 {
+		return fmt.Errorf("invalid ARGON2_THREADS: must be between 1 and 255")
+	}
+	auth.Hashing = HashingConfig{
+		Time:      uint32(hashTime),
+		MemoryKiB: uint32(hashMemory),
+		Threads:   uint8(hashThreads),
+	}
+
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("LOCKOUT_MAX_ATTEMPTS", "5"))
+	if err != nil || maxAttempts <= 0 {
+		return fmt.Errorf("invalid LOCKOUT_MAX_ATTEMPTS: must be a positive integer")
+	}
+	lockDuration, err := time.ParseDuration(getEnvOrDefault("LOCKOUT_DURATION", "15m"))
+	if err != nil || lockDuration <= 0 {
+		return fmt.Errorf("invalid LOCKOUT_DURATION: must be a positive duration")
+	}
+	auth.Lockout = LockoutConfig{
+		MaxAttempts: maxAttempts,
+		Duration:    lockDuration,
+	}
+
+	emergencyTTL, err := time.ParseDuration(getEnvOrDefault("EMERGENCY_ACCESS_TTL", "30m"))
+	if err != nil || emergencyTTL <= 0 {
+		return fmt.Errorf("invalid EMERGENCY_ACCESS_TTL: must be a positive duration")
+	}
+	auth.EmergencyAccess = EmergencyAccessConfig{
+		TTL:         emergencyTTL,
+		Permissions: splitList(getEnvOrDefault("EMERGENCY_ACCESS_PERMISSIONS", "patients:read")),
+	}
+
+	auth.Permissions, err = loadPermissions(getEnvOrDefault("PERMISSIONS_FILE", ""))
+	return err
+}
+
+// loadPermissions reads the role to permission matrix from a JSON file that
+// maps each role to its list of permissions. Without a file the defaults apply.
+func loadPermissions(path string) (map[string][]string, error) {
+	if path == "" {
+		permissions := make(map[string][]string, len(DefaultPermissions))
+		for role, granted := range DefaultPermissions {
+			permissions[role] = append([]string{}, granted...)
+		}
+		return permissions, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PERMISSIONS_FILE: %w", err)
+	}
+	var permissions map[string][]string
+	if err := json.Unmarshal(data, &permissions); err != nil {
+		return nil, fmt.Errorf("invalid PERMISSIONS_FILE %s: %w", path, err)
+	}
+	return permissions, nil
+}
+
+// getSecret returns the secret stored in the given environment variable.
+// Secrets are mandatory in production; elsewhere a random one is generated.
+func getSecret(key string) (string, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, nil
+	}
+	if os.Getenv("ENV") == "production" {
+		return "", fmt.Errorf("%s must be set in production", key)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", key, err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
 // ConfigureCORS returns CORS configuration based on the current environment
 func (c *Config) ConfigureCORS() cors.Config {
 	return cors.Config{
 		AllowOrigins:     c.Security.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 		// Don't use AllowOriginFunc as it causes 403s
@@ -87,3 +326,14 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList splits a comma-separated value, dropping blank entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -2,11 +2,13 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -42,16 +44,49 @@ func TestLoadConfig(t *testing.T) {
 				assert.Equal(t, []string{"http://localhost:3000"}, cfg.Security.AllowedOrigins)
 				assert.Equal(t, 10, cfg.Security.RateLimit.RequestsPerSecond)
 				assert.Equal(t, 20, cfg.Security.RateLimit.BurstSize)
+				assert.Equal(t, 15*time.Minute, cfg.Auth.AccessTokenTTL)
+				assert.Equal(t, 24*time.Hour, cfg.Auth.RefreshTokenTTL)
+				assert.Equal(t, "pococlinic", cfg.Auth.Issuer)
+				assert.NotEmpty(t, cfg.Auth.AccessTokenSecret)
+				assert.NotEmpty(t, cfg.Auth.RefreshTokenSecret)
+				assert.Equal(t, HashingConfig{Time: 1, MemoryKiB: 64 * 1024, Threads: 4}, cfg.Auth.Hashing)
+				assert.Equal(t, LockoutConfig{MaxAttempts: 5, Duration: 15 * time.Minute}, cfg.Auth.Lockout)
+				assert.Equal(t, DefaultPermissions, cfg.Auth.Permissions)
+				assert.Equal(t, EmergencyAccessConfig{
+					TTL:         30 * time.Minute,
+					Permissions: []string{"patients:read"},
+				}, cfg.Auth.EmergencyAccess)
+				assert.Equal(t, DriverMemory, cfg.Database.Driver)
+				assert.Equal(t, "pococlinic.db", cfg.Database.Path)
+				assert.Equal(t, PatientsConfig{RetentionYears: 10, AgeOfMajority: 18}, cfg.Patients)
+				assert.Empty(t, cfg.Medications.InteractionRulesFile)
+				assert.Empty(t, cfg.Problems.ICD10CodesFile)
 			},
 		},
 		{
 			name: "Custom configuration",
 			envVars: map[string]string{
-				"SERVER_PORT":      "9000",
-				"SERVER_HOST":      "0.0.0.0",
-				"ALLOWED_ORIGIN":   "https://example.com",
-				"RATE_LIMIT_RPS":   "100",
-				"RATE_LIMIT_BURST": "50",
+				"SERVER_PORT":                  "9000",
+				"SERVER_HOST":                  "0.0.0.0",
+				"ALLOWED_ORIGIN":               "https://example.com",
+				"RATE_LIMIT_RPS":               "100",
+				"RATE_LIMIT_BURST":             "50",
+				"JWT_ACCESS_SECRET":            "access-secret",
+				"JWT_REFRESH_SECRET":           "refresh-secret",
+				"JWT_ACCESS_TTL":               "5m",
+				"DB_DRIVER":                    "sqlite",
+				"DB_PATH":                      "/var/lib/pococlinic/clinic.db",
+				"ARGON2_TIME":                  "3",
+				"ARGON2_MEMORY_KIB":            "131072",
+				"ARGON2_THREADS":               "2",
+				"LOCKOUT_MAX_ATTEMPTS":         "3",
+				"LOCKOUT_DURATION":             "1h",
+				"EMERGENCY_ACCESS_TTL":         "10m",
+				"EMERGENCY_ACCESS_PERMISSIONS": "patients:read, ",
+				"PATIENT_RETENTION_YEARS":      "7",
+				"PATIENT_AGE_OF_MAJORITY":      "21",
+				"INTERACTION_RULES_FILE":       "/etc/pococlinic/interactions.json",
+				"ICD10_CODES_FILE":             "/etc/pococlinic/icd10cm_codes.txt",
 			},
 			wantError: false,
 			validate: func(t *testing.T, cfg *Config) {
@@ -60,6 +95,20 @@ func TestLoadConfig(t *testing.T) {
 				assert.Equal(t, []string{"https://example.com"}, cfg.Security.AllowedOrigins)
 				assert.Equal(t, 100, cfg.Security.RateLimit.RequestsPerSecond)
 				assert.Equal(t, 50, cfg.Security.RateLimit.BurstSize)
+				assert.Equal(t, "access-secret", cfg.Auth.AccessTokenSecret)
+				assert.Equal(t, "refresh-secret", cfg.Auth.RefreshTokenSecret)
+				assert.Equal(t, 5*time.Minute, cfg.Auth.AccessTokenTTL)
+				assert.Equal(t, DriverSQLite, cfg.Database.Driver)
+				assert.Equal(t, "/var/lib/pococlinic/clinic.db", cfg.Database.Path)
+				assert.Equal(t, HashingConfig{Time: 3, MemoryKiB: 128 * 1024, Threads: 2}, cfg.Auth.Hashing)
+				assert.Equal(t, LockoutConfig{MaxAttempts: 3, Duration: time.Hour}, cfg.Auth.Lockout)
+				assert.Equal(t, EmergencyAccessConfig{
+					TTL:         10 * time.Minute,
+					Permissions: []string{"patients:read"},
+				}, cfg.Auth.EmergencyAccess)
+				assert.Equal(t, PatientsConfig{RetentionYears: 7, AgeOfMajority: 21}, cfg.Patients)
+				assert.Equal(t, "/etc/pococlinic/interactions.json", cfg.Medications.InteractionRulesFile)
+				assert.Equal(t, "/etc/pococlinic/icd10cm_codes.txt", cfg.Problems.ICD10CodesFile)
 			},
 		},
 		{
@@ -76,13 +125,70 @@ func TestLoadConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "Invalid token TTL",
+			envVars: map[string]string{
+				"JWT_ACCESS_TTL": "invalid",
+			},
+			wantError: true,
+		},
+		{
+			name: "Hashing memory too low",
+			envVars: map[string]string{
+				"ARGON2_MEMORY_KIB": "1024",
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid lockout threshold",
+			envVars: map[string]string{
+				"LOCKOUT_MAX_ATTEMPTS": "0",
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid emergency access TTL",
+			envVars: map[string]string{
+				"EMERGENCY_ACCESS_TTL": "0s",
+			},
+			wantError: true,
+		},
+		{
+			name: "Missing permissions file",
+			envVars: map[string]string{
+				"PERMISSIONS_FILE": "does-not-exist.json",
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid database driver",
+			envVars: map[string]string{
+				"DB_DRIVER": "oracle",
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative retention period",
+			envVars: map[string]string{
+				"PATIENT_RETENTION_YEARS": "-1",
+			},
+			wantError: true,
+		},
+		{
+			name: "Missing secret in production",
+			envVars: map[string]string{
+				"ENV":               "production",
+				"JWT_ACCESS_SECRET": "",
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Set environment variables
+			// Set environment variables for this case only
 			for k, v := range tt.envVars {
-				os.Setenv(k, v)
+				t.Setenv(k, v)
 			}
 
 			cfg, err := LoadConfig()
@@ -97,6 +203,22 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadPermissionsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "permissions.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"admin": ["*"], "nurse": ["patients:read"]}`), 0o600))
+
+	permissions, err := loadPermissions(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"admin": {"*"},
+		"nurse": {"patients:read"},
+	}, permissions)
+
+	require.NoError(t, os.WriteFile(path, []byte(`["not", "a", "matrix"]`), 0o600))
+	_, err = loadPermissions(path)
+	assert.Error(t, err)
+}
+
 func TestConfigureCORS(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -113,7 +235,8 @@ func TestConfigureCORS(t *testing.T) {
 			validateConfig: func(t *testing.T, c cors.Config) {
 				assert.Equal(t, []string{"http://localhost:3000"}, c.AllowOrigins)
 				assert.ElementsMatch(t, []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}, c.AllowMethods)
-				assert.ElementsMatch(t, []string{"Origin", "Content-Length", "Content-Type", "Authorization"}, c.AllowHeaders)
+				assert.ElementsMatch(t, []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match"}, c.AllowHeaders)
+				assert.Equal(t, []string{"ETag"}, c.ExposeHeaders)
 				assert.True(t, c.AllowCredentials)
 				assert.Equal(t, 12*time.Hour, c.MaxAge)
 			},
@@ -128,7 +251,8 @@ func TestConfigureCORS(t *testing.T) {
 			validateConfig: func(t *testing.T, c cors.Config) {
 				assert.Equal(t, []string{"http://localhost:3000", "https://example.com"}, c.AllowOrigins)
 				assert.ElementsMatch(t, []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}, c.AllowMethods)
-				assert.ElementsMatch(t, []string{"Origin", "Content-Length", "Content-Type", "Authorization"}, c.AllowHeaders)
+				assert.ElementsMatch(t, []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match"}, c.AllowHeaders)
+				assert.Equal(t, []string{"ETag"}, c.ExposeHeaders)
 				assert.True(t, c.AllowCredentials)
 				assert.Equal(t, 12*time.Hour, c.MaxAge)
 			},
@@ -0,0 +1,133 @@
+// Package database provides the shared SQLite connection and a small schema
+// migration runner. Each feature owns its tables and registers its own ordered
+// list of migrations under a component name.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed
+func OpenSQLite(path string) (*sql.DB, error) {
+	pragmas := []string{
+		"_pragma=foreign_keys(1)",
+		"_pragma=journal_mode(WAL)",
+		"_pragma=busy_timeout(5000)",
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+strings.Join(pragmas, "&"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// Migrate applies the migrations of a component that have not run yet. The
+// position of a statement in the slice is its version, so migrations must
+// only ever be appended.
+func Migrate(ctx context.Context, db *sql.DB, component string, migrations []string) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		component  TEXT    NOT NULL,
+		version    INTEGER NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (component, version)
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	err := db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE component = ?`,
+		component,
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version of %s: %w", component, err)
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		if err := applyMigration(ctx, db, component, version, migrations[i]); err != nil {
+			return fmt.Errorf("failed to apply migration %d of %s: %w", version, component, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a single migration and records it in one transaction
+func applyMigration(ctx context.Context, db *sql.DB, component string, version int, statement string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (component, version, applied_at) VALUES (?, ?, ?)`,
+		component, version, time.Now().UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Conn runs statements, either directly on the database or within a
+// transaction
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// InTx runs fn in a transaction that is committed if fn succeeds. Statements
+// run on ConnFrom(ctx, db) with the context passed to fn take part in it, so
+// that several repositories can change their records together. Within an
+// enclosing InTx, fn simply joins its transaction.
+func InTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConnFrom returns the transaction InTx runs ctx in, or db outside of one.
+// As the database allows a single connection, statements within InTx must
+// not use db directly.
+func ConnFrom(ctx context.Context, db *sql.DB) Conn {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// EscapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
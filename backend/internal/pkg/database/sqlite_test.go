@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrations := []string{
+		`CREATE TABLE items (id TEXT PRIMARY KEY)`,
+	}
+	require.NoError(t, Migrate(ctx, db, "items", migrations))
+
+	// Running again is a no-op
+	require.NoError(t, Migrate(ctx, db, "items", migrations))
+
+	// Appended migrations are applied on the next run
+	migrations = append(migrations, `ALTER TABLE items ADD COLUMN name TEXT NOT NULL DEFAULT ''`)
+	require.NoError(t, Migrate(ctx, db, "items", migrations))
+
+	_, err = db.ExecContext(ctx, `INSERT INTO items (id, name) VALUES ('1', 'one')`)
+	assert.NoError(t, err)
+
+	var version int
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT MAX(version) FROM schema_migrations WHERE component = 'items'`,
+	).Scan(&version))
+	assert.Equal(t, 2, version)
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = Migrate(ctx, db, "broken", []string{`CREATE TABLE broken (`})
+	assert.Error(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM schema_migrations WHERE component = 'broken'`,
+	).Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(ctx, db, "items", []string{`CREATE TABLE items (id TEXT PRIMARY KEY)`}))
+
+	insert := func(ctx context.Context, id string) error {
+		_, err := ConnFrom(ctx, db).ExecContext(ctx, `INSERT INTO items (id) VALUES (?)`, id)
+		return err
+	}
+	count := func() int {
+		var count int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items`).Scan(&count))
+		return count
+	}
+
+	// A failure discards everything done in the transaction, including
+	// within nested calls
+	err = InTx(ctx, db, func(ctx context.Context) error {
+		require.NoError(t, InTx(ctx, db, func(ctx context.Context) error {
+			return insert(ctx, "1")
+		}))
+		return insert(ctx, "1")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, count())
+
+	require.NoError(t, InTx(ctx, db, func(ctx context.Context) error {
+		if err := insert(ctx, "1"); err != nil {
+			return err
+		}
+		return insert(ctx, "2")
+	}))
+	assert.Equal(t, 2, count())
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% \_done\\`, EscapeLike(`100% _done\`))
+}
@@ -1,5 +1,7 @@
 package errors
 
+import "net/http"
+
 // APIError represents a standardized API error response
 type APIError struct {
 	Code    string `json:"code"`
@@ -14,6 +16,11 @@ const (
 	ErrUnauthorized   = "UNAUTHORIZED"
 	ErrForbidden      = "FORBIDDEN"
 	ErrRateLimit      = "RATE_LIMIT_EXCEEDED"
+	ErrConflict       = "CONFLICT"
+
+	ErrPreconditionFailed   = "PRECONDITION_FAILED"
+	ErrPreconditionRequired = "PRECONDITION_REQUIRED"
+	ErrUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
 )
 
 // NewAPIError creates a new API error
@@ -28,3 +35,30 @@ func NewAPIError(code string, message string) *APIError {
 func (e *APIError) Error() string {
 	return e.Message
 }
+
+// StatusCode returns the HTTP status code an API error with the given code
+// is sent with
+func StatusCode(code string) int {
+	switch code {
+	case ErrValidation:
+		return http.StatusBadRequest
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrUnauthorized:
+		return http.StatusUnauthorized
+	case ErrForbidden:
+		return http.StatusForbidden
+	case ErrRateLimit:
+		return http.StatusTooManyRequests
+	case ErrConflict:
+		return http.StatusConflict
+	case ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrPreconditionRequired:
+		return http.StatusPreconditionRequired
+	case ErrUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,347 @@
+// Package jsonpatch applies partial updates to JSON documents, either as an
+// RFC 7396 JSON Merge Patch or as an RFC 6902 JSON Patch. Documents are
+// handled in their decoded form, so numbers compare as float64.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two patch formats
+const (
+	MergePatchMediaType = "application/merge-patch+json"
+	JSONPatchMediaType  = "application/json-patch+json"
+)
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to a JSON document. Members
+// set to null in the patch are removed, objects are merged recursively and
+// any other value replaces the target.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue merges patch into target as described in RFC 7396
+func mergeValue(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	merged, ok := target.(map[string]any)
+	if !ok {
+		merged = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergeValue(merged[key], value)
+	}
+	return merged
+}
+
+// Operation is a single step of an RFC 6902 JSON Patch
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies an RFC 6902 JSON Patch to a JSON document. The operations
+// run in order and the patch fails as a whole if any of them fails,
+// including a failed "test".
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		target, err = operation.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// apply runs the operation against the document and returns the result
+func (o Operation) apply(doc any) (any, error) {
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add", "replace", "test":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		switch o.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "copy" {
+			return add(doc, path, deepCopy(value))
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+// value decodes the operation's value, which add, replace and test require
+func (o Operation) value() (any, error) {
+	if o.Value == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	var value any
+	if err := json.Unmarshal(*o.Value, &value); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value at path
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path does not exist")
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("path does not exist")
+		}
+	}
+	return doc, nil
+}
+
+// add inserts value at path. Members of an object are set, elements of an
+// array are inserted before the index, "-" appends.
+func add(doc any, path []string, value any) (any, error) {
+	return edit(doc, path, value, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("path does not exist")
+		}
+	})
+}
+
+// replace sets the existing value at path
+func replace(doc any, path []string, value any) (any, error) {
+	return edit(doc, path, value, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("path does not exist")
+			}
+			node[token] = value
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("path does not exist")
+		}
+	})
+}
+
+// remove deletes the value at path
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return edit(doc, path, nil, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("path does not exist")
+			}
+			delete(node, token)
+			return node, nil
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path does not exist")
+		}
+	})
+}
+
+// edit walks to the parent of path and lets change modify it. Containers are
+// written back on the way up, since changing an array may reallocate it. An
+// empty path stands for the whole document, which becomes root.
+func edit(doc any, path []string, root any, change func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("path does not exist")
+		}
+		updated, err := edit(child, path[1:], root, change)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = updated
+		return node, nil
+	case []any:
+		index, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := edit(node[index], path[1:], root, change)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path does not exist")
+	}
+}
+
+// arrayIndex parses an array index token that must not exceed last
+func arrayIndex(token string, last int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > last {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// isPrefix reports whether prefix is a leading part of path
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// deepCopy duplicates a decoded JSON value so that copies do not share maps
+// or slices
+func deepCopy(value any) any {
+	switch node := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(node))
+		for key, child := range node {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(node))
+		for i, child := range node {
+			copied[i] = deepCopy(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
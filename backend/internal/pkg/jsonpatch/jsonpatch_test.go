@@ -0,0 +1,95 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested object", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":"g"}}`, `{"a":{"b":"c","f":"g"}}`},
+		{"object into scalar", `{"a":"b"}`, `{"a":{"b":"c"}}`, `{"a":{"b":"c"}}`},
+		{"non-object patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"empty patch", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append array element", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"test then replace", `{"a":1.5}`, `[{"op":"test","path":"/a","value":1.5},{"op":"replace","path":"/a","value":2}]`, `{"a":2}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"replace document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"failed test", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`},
+		{"add to missing parent", `{"a":1}`, `[{"op":"add","path":"/b/c","value":2}]`},
+		{"array index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":2}]`},
+		{"leading zero index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`},
+		{"move into child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`},
+		{"missing value", `{"a":1}`, `[{"op":"add","path":"/b"}]`},
+		{"unknown operation", `{"a":1}`, `[{"op":"merge","path":"/a","value":2}]`},
+		{"invalid pointer", `{"a":1}`, `[{"op":"remove","path":"a"}]`},
+		{"not a patch", `{"a":1}`, `{"op":"remove","path":"/a"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package patientref checks the patient a clinical record refers to. The
+// features keeping such records only see patients through a Directory,
+// which cmd implements on top of the patients feature.
+package patientref
+
+import (
+	"context"
+
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// ContextKey is the request context key under which a handler names the
+// patient a request concerned when the route has no patient ID parameter,
+// such as when a patient is created, so that the audit log records it
+const ContextKey = "patientID"
+
+// Directory tells whether a patient exists and is not archived
+type Directory interface {
+	IsActivePatient(ctx context.Context, patientID string) (bool, error)
+}
+
+// RequireActive parses the ID of the patient a new record is for. A
+// malformed ID, an unknown patient and an archived one are all reported as
+// not found, so that archived patients receive no further records.
+func RequireActive(ctx context.Context, patients Directory, patientID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(patientID)
+	if err != nil {
+		return uuid.Nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	}
+	active, err := patients.IsActivePatient(ctx, patientID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !active {
+		return uuid.Nil, errors.NewAPIError(errors.ErrNotFound, "Patient not found")
+	}
+	return id, nil
+}
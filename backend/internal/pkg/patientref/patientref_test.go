@@ -0,0 +1,35 @@
+package patientref
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dksch/pococlinic/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// directory knows a fixed set of patients and whether they are active
+type directory map[string]bool
+
+func (d directory) IsActivePatient(ctx context.Context, patientID string) (bool, error) {
+	return d[patientID], nil
+}
+
+func TestRequireActive(t *testing.T) {
+	ctx := context.Background()
+	active, archived := uuid.New(), uuid.New()
+	patients := directory{active.String(): true, archived.String(): false}
+
+	id, err := RequireActive(ctx, patients, active.String())
+	require.NoError(t, err)
+	assert.Equal(t, active, id)
+
+	for _, patientID := range []string{"not-a-uuid", uuid.NewString(), archived.String()} {
+		_, err := RequireActive(ctx, patients, patientID)
+		apiErr, ok := err.(*errors.APIError)
+		require.True(t, ok, "Expected an APIError for %s, got %v", patientID, err)
+		assert.Equal(t, errors.ErrNotFound, apiErr.Code)
+	}
+}
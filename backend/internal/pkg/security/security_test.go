@@ -179,6 +179,7 @@ func TestCORSConfiguration(t *testing.T) {
 			shouldAllowOrigin: true,
 			expectedHeaders: map[string]string{
 				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "Etag",
 			},
 		},
 		{
@@ -197,7 +198,7 @@ func TestCORSConfiguration(t *testing.T) {
 			expectedHeaders: map[string]string{
 				"Access-Control-Allow-Credentials": "true",
 				"Access-Control-Allow-Methods":     "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS",
-				"Access-Control-Allow-Headers":     "Origin,Content-Length,Content-Type,Authorization",
+				"Access-Control-Allow-Headers":     "Origin,Content-Length,Content-Type,Authorization,If-Match",
 			},
 		},
 		{
@@ -210,7 +211,7 @@ func TestCORSConfiguration(t *testing.T) {
 			expectedStatus:    http.StatusNoContent,
 			shouldAllowOrigin: true,
 			expectedHeaders: map[string]string{
-				"Access-Control-Allow-Headers": "Origin,Content-Length,Content-Type,Authorization",
+				"Access-Control-Allow-Headers": "Origin,Content-Length,Content-Type,Authorization,If-Match",
 			},
 		},
 		{
@@ -8,6 +8,15 @@ export interface Address {
   country: string;
 }
 
+export type BannerLevel = 'critical' | 'warning' | 'info';
+
+// Safety notice shown prominently with a patient, such as an allergy
+export interface PatientBanner {
+  kind: string;
+  level: BannerLevel;
+  message: string;
+}
+
 export interface Patient {
   id: string;
   firstName: string;
@@ -22,6 +31,7 @@ export interface Patient {
   weight?: number | null;  // Weight in kilograms
   createdAt: string;
   updatedAt: string;
+  banners?: PatientBanner[];  // Only when fetched on its own
 }
 
 export interface PaginatedPatients {
@@ -30,6 +40,8 @@ export interface PaginatedPatients {
   currentPage: number;
   pageSize: number;
   totalPages: number;
+  nextCursor?: string;
+  prevCursor?: string;
 }
 
 // Form data interface with Date object for the form
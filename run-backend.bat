@@ -10,4 +10,4 @@ if %ERRORLEVEL% neq 0 (
 )
 
 echo All tests passed! Starting Backend Application...
-go run cmd/main.go 
\ No newline at end of file
+go run ./cmd 
\ No newline at end of file